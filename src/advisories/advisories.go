@@ -0,0 +1,198 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package advisories contains the recall and safety advisories issued against asset models
+package advisories
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+var log = helpers.GetLogger("Advisories")
+
+const collectionName = "advisories"
+
+// StatusActive is the status of an advisory that is in force
+const StatusActive = "active"
+
+// StatusWithdrawn is the status of an advisory that no longer applies, the flags it left on the ledger are kept
+const StatusWithdrawn = "withdrawn"
+
+// Severities are the severities an advisory can have, from least to most severe
+var Severities = []string{"low", "medium", "high", "critical"}
+
+// ErrAdvisoryExists is an error when an advisory ID is taken
+var ErrAdvisoryExists = errors.New("advisory already exists")
+
+// ErrAdvisoryWithdrawn is an error when a withdrawn advisory is applied or withdrawn again
+var ErrAdvisoryWithdrawn = errors.New("advisory is withdrawn")
+
+// Filter is a type representing the models an advisory affects. Empty fields match any value
+type Filter struct {
+	AssetModelNumber  string `json:"assetModelNumber,omitempty"`
+	AssetManufacturer string `json:"assetManufacturer,omitempty"`
+}
+
+// Fields returns the filter as the asset fields and values it matches on
+func (f Filter) Fields() map[string]string {
+	fields := make(map[string]string)
+	if f.AssetModelNumber != "" {
+		fields["assetModelNumber"] = f.AssetModelNumber
+	}
+	if f.AssetManufacturer != "" {
+		fields["assetManufacturer"] = f.AssetManufacturer
+	}
+	return fields
+}
+
+// Advisory is a type representing a recall or safety advisory
+type Advisory struct {
+	AdvisoryID  string     `json:"advisoryID"`
+	Severity    string     `json:"severity"`
+	Description string     `json:"description"`
+	Affects     Filter     `json:"affects"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	WithdrawnAt *time.Time `json:"withdrawnAt,omitempty"`
+}
+
+// Validate checks the fields of an advisory
+func (a Advisory) Validate() error {
+	if strings.TrimSpace(a.AdvisoryID) == "" {
+		return errors.New("advisoryID is required")
+	}
+	if len(a.Affects.Fields()) == 0 {
+		return errors.New("affects needs an assetModelNumber, an assetManufacturer or both")
+	}
+	for _, severity := range Severities {
+		if a.Severity == severity {
+			return nil
+		}
+	}
+	return errors.New("severity must be one of " + strings.Join(Severities, ", "))
+}
+
+// AdvisoryStore is an interface for the advisories kept by the gateway
+type AdvisoryStore interface {
+	Create(ctx context.Context, advisory Advisory) (Advisory, error)
+	Get(ctx context.Context, advisoryID string) (Advisory, error)
+	List(ctx context.Context) ([]Advisory, error)
+	Withdraw(ctx context.Context, advisoryID string) (Advisory, error)
+}
+
+// StoreAdvisories is an implementation of AdvisoryStore persisted in a store collection
+type StoreAdvisories struct {
+	collection *store.Collection
+}
+
+// NewStoreAdvisories returns the advisory store kept in the gateway data directory
+func NewStoreAdvisories() *StoreAdvisories {
+	return NewStoreAdvisoriesWith(store.NewCollection(collectionName))
+}
+
+// NewStoreAdvisoriesWith returns an advisory store kept in a store collection
+func NewStoreAdvisoriesWith(collection *store.Collection) *StoreAdvisories {
+	return &StoreAdvisories{collection: collection}
+}
+
+// Create stores a new active advisory
+func (s *StoreAdvisories) Create(ctx context.Context, advisory Advisory) (Advisory, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Create advisory")
+	defer span.Finish()
+
+	advisory.Status = StatusActive
+	advisory.CreatedAt = time.Now().UTC()
+	advisory.WithdrawnAt = nil
+	err := s.collection.Create(advisory.AdvisoryID, advisory)
+	if err == store.ErrAlreadyExists {
+		return Advisory{}, ErrAdvisoryExists
+	}
+	if err != nil {
+		return Advisory{}, err
+	}
+	log.Info().Msgf("Created %s advisory %s", advisory.Severity, advisory.AdvisoryID)
+	return advisory, nil
+}
+
+// Get returns an advisory, returns helpers.ErrNotFound when there is none
+func (s *StoreAdvisories) Get(ctx context.Context, advisoryID string) (advisory Advisory, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Get advisory")
+	defer span.Finish()
+
+	err = s.collection.Get(advisoryID, &advisory)
+	return
+}
+
+// List returns all advisories ordered by ID
+func (s *StoreAdvisories) List(ctx context.Context) ([]Advisory, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "List advisories")
+	defer span.Finish()
+
+	ids, err := s.collection.IDs()
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.collection.All()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Advisory, 0, len(ids))
+	for _, id := range ids {
+		var advisory Advisory
+		if raw, exists := all[id]; exists && json.Unmarshal(raw, &advisory) == nil {
+			list = append(list, advisory)
+		}
+	}
+	return list, nil
+}
+
+// Withdraw marks an active advisory as withdrawn
+func (s *StoreAdvisories) Withdraw(ctx context.Context, advisoryID string) (advisory Advisory, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Withdraw advisory")
+	defer span.Finish()
+
+	err = s.collection.Modify(func(records map[string]json.RawMessage) error {
+		raw, exists := records[advisoryID]
+		if !exists {
+			return helpers.ErrNotFound
+		}
+		if err := json.Unmarshal(raw, &advisory); err != nil {
+			return err
+		}
+		if advisory.Status == StatusWithdrawn {
+			return ErrAdvisoryWithdrawn
+		}
+		now := time.Now().UTC()
+		advisory.Status = StatusWithdrawn
+		advisory.WithdrawnAt = &now
+		updated, err := json.Marshal(advisory)
+		records[advisoryID] = updated
+		return err
+	})
+	if err == nil {
+		log.Info().Msgf("Advisory %s withdrawn", advisoryID)
+	}
+	return
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package advisories
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidate tests the checks on the fields of an advisory
+func TestValidate(t *testing.T) {
+	valid := Advisory{AdvisoryID: "ADV-1", Severity: "high", Affects: Filter{AssetModelNumber: "X100"}}
+	assert.NoError(t, valid.Validate(), "Advisory is valid")
+	assert.Error(t, Advisory{Severity: "high", Affects: valid.Affects}.Validate(), "ID is required")
+	assert.Error(t, Advisory{AdvisoryID: "ADV-1", Severity: "high"}.Validate(), "Filter is required")
+	assert.Error(t, Advisory{AdvisoryID: "ADV-1", Severity: "urgent", Affects: valid.Affects}.Validate(),
+		"Severity must be known")
+	assert.Equal(t, map[string]string{"assetModelNumber": "X100"}, valid.Affects.Fields(), "Empty filter fields are left out")
+}
+
+// TestStoreAdvisories tests creating, listing and withdrawing advisories
+func TestStoreAdvisories(t *testing.T) {
+	directory, err := ioutil.TempDir("", "advisories")
+	assert.NoError(t, err, "Data directory is created")
+	defer os.RemoveAll(directory)
+	advisoryStore := NewStoreAdvisoriesWith(store.NewCollectionAt(directory, collectionName))
+	ctx := context.Background()
+
+	created, err := advisoryStore.Create(ctx, Advisory{AdvisoryID: "ADV-1", Severity: "high", Status: StatusWithdrawn,
+		Affects: Filter{AssetModelNumber: "X100"}})
+	assert.NoError(t, err, "Advisory is created")
+	assert.Equal(t, StatusActive, created.Status, "Advisories are created active")
+	_, err = advisoryStore.Create(ctx, created)
+	assert.Equal(t, ErrAdvisoryExists, err, "IDs are taken once")
+	_, _ = advisoryStore.Create(ctx, Advisory{AdvisoryID: "ADV-0", Severity: "low", Affects: Filter{AssetManufacturer: "Acme"}})
+
+	list, err := advisoryStore.List(ctx)
+	assert.NoError(t, err, "Advisories are listed")
+	assert.Equal(t, "ADV-0", list[0].AdvisoryID, "Advisories are ordered by ID")
+
+	withdrawn, err := advisoryStore.Withdraw(ctx, "ADV-1")
+	assert.NoError(t, err, "Advisory is withdrawn")
+	assert.Equal(t, StatusWithdrawn, withdrawn.Status, "Status is withdrawn")
+	assert.NotNil(t, withdrawn.WithdrawnAt, "Withdrawal time is recorded")
+	_, err = advisoryStore.Withdraw(ctx, "ADV-1")
+	assert.Equal(t, ErrAdvisoryWithdrawn, err, "Advisories are withdrawn once")
+	_, err = advisoryStore.Withdraw(ctx, "ADV-9")
+	assert.Equal(t, helpers.ErrNotFound, err, "Unknown advisories are not found")
+	found, _ := advisoryStore.Get(ctx, "ADV-1")
+	assert.Equal(t, StatusWithdrawn, found.Status, "Withdrawal is stored")
+}
@@ -28,6 +28,7 @@ type Agent interface {
 	ListAssets(ctx context.Context, args QueryArgs) (resultStream io.ReadCloser, err error)
 	ListChannels(ctx context.Context, args QueryArgs) (resultStream io.ReadCloser, err error)
 	QueryAssets(ctx context.Context, args QueryArgs, body RichQueryArgs) (result map[string]interface{}, err error)
+	QueryAssetsStream(ctx context.Context, args QueryArgs, body RichQueryArgs) (resultStream io.ReadCloser, err error)
 	QueryStream(ctx context.Context, args QueryArgs) (resultStream io.ReadCloser, err error)
 	QueryAuditTrail(ctx context.Context, args QueryArgs) (result map[string]interface{}, err error)
 	GetHost() string
@@ -20,14 +20,24 @@ package agent
 import (
 	"chainsource-gateway/helpers"
 	"fmt"
+	"io/ioutil"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 var log = helpers.GetLogger("AgentService")
 
+// repoNames maps the lower case repo IDs viper reports to the repo IDs as written in agent-config.yaml
+var repoNames = struct {
+	sync.RWMutex
+	byKey map[string]string
+}{}
+
 // GetAgentConfig uses viper to get and store the agent config from disk.
 // Automatically reloads configuration on any change
 func GetAgentConfig() {
@@ -38,19 +48,99 @@ func GetAgentConfig() {
 		panic(fmt.Errorf("agent-config.yaml could not be loaded: %s \n", err))
 	}
 
-	agents := make(map[string]Config)
-	_ = viper.UnmarshalKey("agents", &agents)
+	loadRepoNames()
+	agents, _ := configuredAgents()
 	logAgentConfig(agents)
 
 	viper.WatchConfig()
 	viper.OnConfigChange(func(in fsnotify.Event) {
 		log.Info().Msgf("Hot Reload: AgentConfig has changed")
-		agents = make(map[string]Config)
-		_ = viper.UnmarshalKey("agents", &agents)
+		loadRepoNames()
+		agents, _ = configuredAgents()
 		logAgentConfig(agents)
 	})
 }
 
+// loadRepoNames reads the repo IDs of the agents from agent-config.yaml. viper lower cases keys, so the IDs are
+// taken from the file as written
+func loadRepoNames() {
+	names := make(map[string]string)
+	raw, err := ioutil.ReadFile(viper.ConfigFileUsed())
+	if err != nil {
+		log.Err(err).Msg("agent-config.yaml could not be read, repo IDs are lower case")
+	}
+	var config map[string]interface{}
+	if err == nil {
+		if err = yaml.Unmarshal(raw, &config); err != nil {
+			log.Err(err).Msg("agent-config.yaml could not be parsed, repo IDs are lower case")
+		}
+	}
+	addRepoNames(names, config["agents"])
+	repoNames.Lock()
+	repoNames.byKey = names
+	repoNames.Unlock()
+}
+
+// addRepoNames adds the repo IDs of the agents section, a map of repo IDs or a list of them, to names
+func addRepoNames(names map[string]string, agents interface{}) {
+	switch agents := agents.(type) {
+	case []interface{}:
+		for _, entry := range agents {
+			addRepoNames(names, entry)
+		}
+	case map[string]interface{}:
+		for repoID := range agents {
+			names[strings.ToLower(repoID)] = repoID
+		}
+	}
+}
+
+// configuredAgents returns the agent configs of agent-config.yaml by repo ID, as written in the file
+func configuredAgents() (map[string]Config, error) {
+	agents := make(map[string]Config)
+	if err := viper.UnmarshalKey("agents", &agents); err != nil {
+		return nil, err
+	}
+	repoNames.RLock()
+	defer repoNames.RUnlock()
+	named := make(map[string]Config, len(agents))
+	for key, config := range agents {
+		if repoID, exists := repoNames.byKey[key]; exists {
+			key = repoID
+		}
+		named[key] = config
+	}
+	return named, nil
+}
+
+// GetConfiguredRepos returns the sorted IDs of the repos with an enabled agent in agent-config.yaml
+func GetConfiguredRepos() ([]string, error) {
+	agents, err := configuredAgents()
+	if err != nil {
+		return nil, err
+	}
+	repoIDs := make([]string, 0, len(agents))
+	for repoID, config := range agents {
+		if config.Enabled {
+			repoIDs = append(repoIDs, repoID)
+		}
+	}
+	sort.Strings(repoIDs)
+	return repoIDs, nil
+}
+
+// defaultQueryParallelism is the number of agent queries a federated query runs at once when it is not configured
+const defaultQueryParallelism = 4
+
+// GetQueryParallelism gets the number of agent queries a federated query runs at once, from
+// federatedQuery.parallelism in agent-config.yaml
+func GetQueryParallelism() int {
+	if parallelism := viper.GetInt("federatedQuery.parallelism"); parallelism > 0 {
+		return parallelism
+	}
+	return defaultQueryParallelism
+}
+
 // Logs the agents loaded from the agent-config fie
 func logAgentConfig(agentMap map[string]Config) {
 	keys := make([]string, 0, len(agentMap))
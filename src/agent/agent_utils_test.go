@@ -61,3 +61,17 @@ func TestGetAgentConfig(t *testing.T) {
 		}, "Panics appropriately")
 	})
 }
+
+// TestGetConfiguredRepos tests that the repos with an enabled agent are listed
+func TestGetConfiguredRepos(t *testing.T) {
+	os.Chdir("../..")
+	defer os.Chdir("./src/agent")
+	defer viper.Reset()
+	GetAgentConfig()
+
+	repoIDs, err := GetConfiguredRepos()
+	assert.NoError(t, err, "Repos are listed")
+	assert.Equal(t, []string{"DB1"}, repoIDs, "Only repos with an enabled agent are listed, as written")
+	_, err = NewHTTPAgentProvider().GetAgentConfigForRepo("DB1")
+	assert.NoError(t, err, "Listed repos are found")
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package agent
+
+import (
+	"chainsource-gateway/helpers"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownAuditTrail is an error when the audit trail returned by an agent is not in the format of a known agent
+var ErrUnknownAuditTrail = errors.New("audit trail returned by the agent is not in a known format")
+
+// timestampKind is how an agent writes the timestamps of its audit entries
+type timestampKind int
+
+const (
+	// timestampRFC3339 is an RFC 3339 string
+	timestampRFC3339 timestampKind = iota
+	// timestampProtobuf is a protobuf Timestamp object with seconds and nanos
+	timestampProtobuf
+)
+
+// auditFormat is the contract of the audit trail of a kind of agent, with the field that holds the list of entries
+// and the fields of an entry. Fields left empty are not reported by the agent
+type auditFormat struct {
+	list          string
+	timestamp     string
+	timestampKind timestampKind
+	commitType    string
+	transactionID string
+	actor         string
+	asset         string
+	assetAsJSON   bool
+}
+
+// auditFormats are the audit trail formats of the supported agents
+var auditFormats = []auditFormat{
+	// The DBoM agent returns its records of the asset, with the commit as an event and the asset as an object
+	{list: "history", timestamp: "timestamp", timestampKind: timestampRFC3339, commitType: "eventType",
+		transactionID: "_id", asset: "payload"},
+	// Ledger agents return the transactions on the key of the asset, with the asset as a JSON string
+	{list: "entries", timestamp: "timestamp", timestampKind: timestampProtobuf, commitType: "type",
+		transactionID: "txId", actor: "creator", asset: "value", assetAsJSON: true},
+}
+
+// AuditEntries adapts the audit trail returned by QueryAuditTrail into typed entries, in the order of the agent.
+// The trail must be in the format of a supported agent, see auditFormats, and every entry must have a timestamp and
+// a commit type. Timestamps are returned in RFC 3339 form in UTC
+func AuditEntries(trail map[string]interface{}) ([]helpers.AuditEntry, error) {
+	for _, format := range auditFormats {
+		if list, ok := trail[format.list].([]interface{}); ok {
+			return format.entries(list)
+		}
+	}
+	return nil, ErrUnknownAuditTrail
+}
+
+// entries adapts the entries of an audit trail in the format
+func (f auditFormat) entries(list []interface{}) ([]helpers.AuditEntry, error) {
+	entries := make([]helpers.AuditEntry, 0, len(list))
+	for i, item := range list {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("audit entry %d is not an object", i+1)
+		}
+		entry, err := f.entry(fields)
+		if err != nil {
+			return nil, fmt.Errorf("audit entry %d %s", i+1, err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// entry adapts one audit entry in the format
+func (f auditFormat) entry(fields map[string]interface{}) (entry helpers.AuditEntry, err error) {
+	if fields[f.timestamp] == nil {
+		return entry, errors.New("has no timestamp")
+	}
+	if entry.Timestamp, err = f.parseTimestamp(fields[f.timestamp]); err != nil {
+		return entry, err
+	}
+	if entry.CommitType, err = auditString(fields, f.commitType); err != nil {
+		return entry, err
+	}
+	if entry.CommitType == "" {
+		return entry, fmt.Errorf("has no %s", f.commitType)
+	}
+	if entry.TransactionID, err = auditString(fields, f.transactionID); err != nil {
+		return entry, err
+	}
+	if entry.Actor, err = auditString(fields, f.actor); err != nil {
+		return entry, err
+	}
+	entry.Asset, err = f.snapshot(fields[f.asset])
+	return entry, err
+}
+
+// auditString returns a string field of an entry, empty when the field is missing or not in the format
+func auditString(fields map[string]interface{}, name string) (string, error) {
+	if name == "" || fields[name] == nil {
+		return "", nil
+	}
+	value, ok := fields[name].(string)
+	if !ok {
+		return "", fmt.Errorf("has a %s that is not a string", name)
+	}
+	return value, nil
+}
+
+// parseTimestamp returns the timestamp of an entry in RFC 3339 form in UTC
+func (f auditFormat) parseTimestamp(value interface{}) (string, error) {
+	switch f.timestampKind {
+	case timestampRFC3339:
+		if v, ok := value.(string); ok {
+			if timestamp, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return timestamp.UTC().Format(time.RFC3339Nano), nil
+			}
+		}
+		return "", fmt.Errorf("has timestamp %v, which is not an RFC 3339 time", value)
+	case timestampProtobuf:
+		v, ok := value.(map[string]interface{})
+		seconds, hasSeconds := v["seconds"].(float64)
+		nanos, _ := v["nanos"].(float64)
+		if !ok || !hasSeconds {
+			return "", fmt.Errorf("has timestamp %v, which is not a protobuf timestamp", value)
+		}
+		return time.Unix(int64(seconds), int64(nanos)).UTC().Format(time.RFC3339Nano), nil
+	}
+	return "", fmt.Errorf("has a timestamp of unknown kind %d", f.timestampKind)
+}
+
+// snapshot returns the asset committed by an entry, nil when the entry has none such as a delete
+func (f auditFormat) snapshot(value interface{}) (*helpers.Asset, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var raw []byte
+	if f.assetAsJSON {
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("has a %s that is not a JSON string", f.asset)
+		}
+		raw = []byte(v)
+	} else {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("has a %s that is not an object", f.asset)
+		}
+		raw, _ = json.Marshal(value)
+	}
+	var asset helpers.Asset
+	if err := json.Unmarshal(raw, &asset); err != nil {
+		return nil, fmt.Errorf("has a %s that is not an asset: %s", f.asset, err.Error())
+	}
+	return &asset, nil
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAuditEntries tests the adaptation of agent audit trails into typed entries
+func TestAuditEntries(t *testing.T) {
+	t.Run("When_History", func(t *testing.T) {
+		file, err := os.Open("../testdata/asset_controller_tests/audit/audit.json")
+		assert.NoError(t, err, "Test data must open")
+		defer file.Close()
+		var trail map[string]interface{}
+		json.NewDecoder(file).Decode(&trail)
+
+		entries, err := AuditEntries(trail)
+
+		assert.NoError(t, err, "No error must be returned")
+		assert.Len(t, entries, 2, "Every entry is adapted")
+		assert.Equal(t, "CREATE", entries[0].CommitType, "Event type is the commit type")
+		assert.Equal(t, "2020-08-12T12:32:02.372Z", entries[0].Timestamp, "Timestamp is kept")
+		assert.Equal(t, "5f33e142916c76c9dd96c266", entries[0].TransactionID, "Record ID is the transaction ID")
+		assert.Equal(t, "Intel Corporation", entries[0].Asset.AssetManufacturer, "Payload is the snapshot")
+	})
+	t.Run("When_Ledger_Shape", func(t *testing.T) {
+		trail := map[string]interface{}{
+			"entries": []interface{}{
+				map[string]interface{}{
+					"txId":      "abc",
+					"type":      "UPDATE",
+					"creator":   "org1-admin",
+					"timestamp": map[string]interface{}{"seconds": 1597235522.0, "nanos": 0.0},
+					"value":     `{"assetType":"HardwareComponent"}`,
+				},
+			},
+		}
+
+		entries, err := AuditEntries(trail)
+
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, "abc", entries[0].TransactionID, "Transaction ID is adapted")
+		assert.Equal(t, "UPDATE", entries[0].CommitType, "Commit type is adapted")
+		assert.Equal(t, "org1-admin", entries[0].Actor, "Creator is the actor")
+		assert.Equal(t, "2020-08-12T12:32:02Z", entries[0].Timestamp, "Timestamp object is formatted")
+		assert.Equal(t, "HardwareComponent", entries[0].Asset.AssetType, "JSON string value is the snapshot")
+	})
+	t.Run("When_Offset_Timestamp", func(t *testing.T) {
+		trail := map[string]interface{}{
+			"history": []interface{}{
+				map[string]interface{}{"eventType": "CREATE", "timestamp": "2020-08-12T14:32:02.372+02:00"},
+			},
+		}
+
+		entries, err := AuditEntries(trail)
+
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, "2020-08-12T12:32:02.372Z", entries[0].Timestamp, "Offset is normalized to UTC")
+		assert.Nil(t, entries[0].Asset, "Entry without a payload has no snapshot")
+	})
+	t.Run("When_Entry_Is_Not_In_The_Format", func(t *testing.T) {
+		for name, entry := range map[string]map[string]interface{}{
+			"Not_A_Time":         {"eventType": "UPDATE", "timestamp": "yesterday"},
+			"Epoch_Seconds":      {"eventType": "UPDATE", "timestamp": 1597235522.0},
+			"No_Timestamp":       {"eventType": "UPDATE"},
+			"Other_Field_Names":  {"commitType": "UPDATE", "time": "2020-08-12T12:32:02Z"},
+			"Payload_Not_Object": {"eventType": "UPDATE", "timestamp": "2020-08-12T12:32:02Z", "payload": "{}"},
+			"Number_ID":          {"eventType": "UPDATE", "timestamp": "2020-08-12T12:32:02Z", "_id": 7.0},
+		} {
+			trail := map[string]interface{}{
+				"history": []interface{}{
+					map[string]interface{}{"eventType": "CREATE", "timestamp": "2020-08-12T12:32:02Z"},
+					entry,
+				},
+			}
+
+			_, err := AuditEntries(trail)
+
+			assert.Error(t, err, "%s is rejected", name)
+			if err != nil {
+				assert.Contains(t, err.Error(), "audit entry 2", "%s reports the entry", name)
+			}
+		}
+	})
+	t.Run("When_Unknown_Shape", func(t *testing.T) {
+		_, err := AuditEntries(map[string]interface{}{"foo": "bar"})
+		assert.Equal(t, ErrUnknownAuditTrail, err, "Unknown trail is rejected")
+		_, err = AuditEntries(map[string]interface{}{"auditTrail": []interface{}{}})
+		assert.Equal(t, ErrUnknownAuditTrail, err, "Trail of an unsupported agent is rejected")
+	})
+}
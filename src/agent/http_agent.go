@@ -27,8 +27,6 @@ import (
 	"io"
 	"strconv"
 
-	"github.com/spf13/viper"
-
 	"github.com/opentracing/opentracing-go"
 )
 
@@ -55,11 +53,49 @@ type QueryArgs struct {
 }
 
 // RichQueryArgs is a type representing the arguments sent to the agent interfaces "rich query" function
+// Agents that page with bookmarks return the bookmark of the next page under BookmarkKey in the query result
 type RichQueryArgs struct {
-	Query  interface{} `json:"query"`
-	Filter []string    `json:"filter"`
-	Skip   int         `json:"skip"`
-	Limit  int         `json:"limit"`
+	Query    interface{} `json:"query"`
+	Filter   []string    `json:"filter"`
+	Skip     int         `json:"skip"`
+	Limit    int         `json:"limit"`
+	Bookmark string      `json:"bookmark,omitempty"`
+}
+
+// BookmarkKey is the key of the bookmark of the next page in the result of a rich query. CouchDB style ledgers reserve
+// IDs starting with an underscore, so it does not collide with an asset
+const BookmarkKey = "_bookmark"
+
+// QueryAssetPage performs a rich query on an agent and separates the bookmark of the next page from the matched assets.
+// Rich queries go through it, so the bookmark is never taken for an asset
+func QueryAssetPage(ctx context.Context, a Agent, args QueryArgs, body RichQueryArgs) (assets map[string]interface{},
+	bookmark string, err error) {
+	assets, err = a.QueryAssets(ctx, args, body)
+	if err != nil {
+		return
+	}
+	bookmark, _ = assets[BookmarkKey].(string)
+	delete(assets, BookmarkKey)
+	return
+}
+
+// QueryAllAssets performs a rich query on an agent and follows the bookmarks of the agent until every match is read.
+// A page without matches, or with the bookmark it was asked for, is the last one
+func QueryAllAssets(ctx context.Context, a Agent, args QueryArgs, body RichQueryArgs) (map[string]interface{}, error) {
+	assets := make(map[string]interface{})
+	for {
+		page, bookmark, err := QueryAssetPage(ctx, a, args, body)
+		if err != nil {
+			return nil, err
+		}
+		for assetID, record := range page {
+			assets[assetID] = record
+		}
+		if bookmark == "" || bookmark == body.Bookmark || len(page) == 0 {
+			return assets, nil
+		}
+		body.Bookmark = bookmark
+	}
 }
 
 // CommitBody is a type representing the request body expected by the agent on it's commit interface
@@ -83,8 +119,7 @@ func (provider *HttpAgentProvider) NewAgent(config *Config) (a Agent) {
 }
 
 func (provider *HttpAgentProvider) GetAgentConfigForRepo(repoID string) (Config, error) {
-	agents := make(map[string]Config)
-	err := viper.UnmarshalKey("agents", &agents)
+	agents, err := configuredAgents()
 	if err != nil {
 		return Config{}, err
 	}
@@ -152,6 +187,29 @@ func (a HttpAgent) QueryAssets(ctx context.Context, args QueryArgs, body RichQue
 	return
 }
 
+// QueryAssetsStream performs a rich query on the agent. Returns it as a io stream
+func (a HttpAgent) QueryAssetsStream(ctx context.Context, args QueryArgs, body RichQueryArgs) (resultStream io.ReadCloser, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Query Assets Stream")
+	defer span.Finish()
+	url := a.AgentURL
+
+	extraHeaders := make(map[string]string)
+	opentracing.GlobalTracer().Inject(
+		span.Context(),
+		opentracing.HTTPHeaders,
+		opentracing.TextMapCarrier(extraHeaders))
+
+	span.SetTag("agent-url", url)
+	queryPath := "/channels/" + args.ChannelID + "/records/_query"
+	bytesRepresentation, err := json.Marshal(body)
+
+	resultStream, err = helpers.PostRequest(url, queryPath, extraHeaders, bytesRepresentation)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Query failed on agent")
+	}
+	return
+}
+
 // ListChannels performs a listChannels on the agent. Returns it as a io stream
 func (a HttpAgent) ListChannels(ctx context.Context, args QueryArgs) (resultStream io.ReadCloser, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "List Channels")
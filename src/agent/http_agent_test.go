@@ -216,6 +216,28 @@ func TestHttpAgent_QueryAssets(t *testing.T) {
 	})
 }
 
+// TestHttpAgent_QueryAssetsStream tests the agent's streamed query method
+func TestHttpAgent_QueryAssetsStream(t *testing.T) {
+	agent := getMockHTTPAgent()
+	t.Run("With_OK_Agent", func(t *testing.T) {
+		setupOKMockRemoteHttpAgent("C1", "A1")
+		defer gock.Off()
+		resultStream, err := agent.QueryAssetsStream(context.Background(), QueryArgs{
+			ChannelID: "C1",
+		}, RichQueryArgs{Query: nil, Filter: nil, Limit: 10, Skip: 0})
+		assert.NoError(t, err, "Completes query successfully")
+		resultStream.Close()
+	})
+	t.Run("With_Fail_Agent", func(t *testing.T) {
+		setupFailMockRemoteHttpAgent("C1", "A1")
+		defer gock.Off()
+		_, err := agent.QueryAssetsStream(context.Background(), QueryArgs{
+			ChannelID: "C1",
+		}, RichQueryArgs{Query: nil, Filter: nil, Limit: 10, Skip: 0})
+		assert.Error(t, err, "Query fails")
+	})
+}
+
 // TestHttpAgent_ListAssets tests the agent's ListAssets method
 func TestHttpAgent_ListAssets(t *testing.T) {
 	agent := getMockHTTPAgent()
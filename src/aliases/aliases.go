@@ -0,0 +1,191 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package aliases contains the index of external identifiers, such as serial numbers or GS1 SGTINs, that resolves them
+// to the assets carrying them
+package aliases
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/spf13/viper"
+)
+
+var log = helpers.GetLogger("Aliases")
+
+const (
+	collectionName = "aliases"
+	pathsConfigKey = "aliasPaths"
+)
+
+// GetPaths gets the paths aliases are read from, from aliasPaths in agent-config.yaml, as dot paths into the asset such
+// as assetMetadata.serialNumber. No aliases are indexed when it is not set
+func GetPaths() []string {
+	var paths []string
+	for _, path := range viper.GetStringSlice(pathsConfigKey) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Of returns the aliases of an asset at the paths. A string or number at a path is an alias, and so is each string or
+// number in an array at a path. Other values and blank strings are skipped
+func Of(asset helpers.Asset, paths []string) []helpers.Alias {
+	document, err := helpers.AssetDocument(asset)
+	if err != nil {
+		return nil
+	}
+	var found []helpers.Alias
+	add := func(path string, value interface{}) {
+		var alias string
+		switch v := value.(type) {
+		case string:
+			alias = strings.TrimSpace(v)
+		case float64:
+			alias = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		if alias != "" {
+			found = append(found, helpers.Alias{Path: path, Value: alias})
+		}
+	}
+	for _, path := range paths {
+		value, _ := helpers.ValueAtPath(document, path)
+		if values, ok := value.([]interface{}); ok {
+			for _, v := range values {
+				add(path, v)
+			}
+		} else {
+			add(path, value)
+		}
+	}
+	return found
+}
+
+// Entry is a type representing the aliases of one asset
+type Entry struct {
+	Asset   helpers.AssetElement `json:"asset"`
+	Aliases []helpers.Alias      `json:"aliases"`
+}
+
+// key returns the record ID of the entry of an asset
+func key(element helpers.AssetElement) string {
+	return element.RepoID + "/" + element.ChannelID + "/" + element.AssetID
+}
+
+// AliasIndex is an interface for the index of aliases
+type AliasIndex interface {
+	Set(ctx context.Context, entry Entry) error
+	Resolve(ctx context.Context, alias string) ([]helpers.AliasMatch, error)
+	Replace(ctx context.Context, entries []Entry, keep func(helpers.AssetElement) bool) error
+}
+
+// StoreIndex is an implementation of AliasIndex persisted in a store collection
+type StoreIndex struct {
+	collection *store.Collection
+}
+
+// NewStoreIndex returns the alias index kept in the gateway data directory
+func NewStoreIndex() *StoreIndex {
+	return NewStoreIndexWith(store.NewCollection(collectionName))
+}
+
+// NewStoreIndexWith returns an alias index kept in a store collection
+func NewStoreIndexWith(collection *store.Collection) *StoreIndex {
+	return &StoreIndex{collection: collection}
+}
+
+// Set replaces the aliases of an asset. An asset without aliases is removed from the index
+func (s *StoreIndex) Set(ctx context.Context, entry Entry) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Set aliases")
+	defer span.Finish()
+
+	return s.collection.Modify(func(records map[string]json.RawMessage) error {
+		if len(entry.Aliases) == 0 {
+			delete(records, key(entry.Asset))
+			return nil
+		}
+		raw, err := json.Marshal(entry)
+		records[key(entry.Asset)] = raw
+		return err
+	})
+}
+
+// Resolve returns the assets carrying an alias, ordered by asset
+func (s *StoreIndex) Resolve(ctx context.Context, alias string) ([]helpers.AliasMatch, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Resolve alias")
+	defer span.Finish()
+
+	alias = strings.TrimSpace(alias)
+	ids, err := s.collection.IDs()
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.collection.All()
+	if err != nil {
+		return nil, err
+	}
+	matches := []helpers.AliasMatch{}
+	for _, id := range ids {
+		var entry Entry
+		raw, exists := all[id]
+		if !exists || json.Unmarshal(raw, &entry) != nil {
+			continue
+		}
+		for _, candidate := range entry.Aliases {
+			if candidate.Value == alias {
+				matches = append(matches, helpers.AliasMatch{AssetElement: entry.Asset, Path: candidate.Path})
+			}
+		}
+	}
+	return matches, nil
+}
+
+// Replace replaces the index with the entries, keeping the entries of the assets keep returns true for, such as
+// the assets of repos that could not be scanned
+func (s *StoreIndex) Replace(ctx context.Context, entries []Entry, keep func(helpers.AssetElement) bool) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Replace aliases")
+	defer span.Finish()
+
+	return s.collection.Modify(func(records map[string]json.RawMessage) error {
+		for id, raw := range records {
+			var entry Entry
+			if json.Unmarshal(raw, &entry) != nil || !keep(entry.Asset) {
+				delete(records, id)
+			}
+		}
+		for _, entry := range entries {
+			if len(entry.Aliases) == 0 {
+				continue
+			}
+			raw, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			records[key(entry.Asset)] = raw
+		}
+		log.Info().Msgf("Alias index rebuilt with %d assets", len(records))
+		return nil
+	})
+}
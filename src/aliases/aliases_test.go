@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package aliases
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var aliasPaths = []string{"assetMetadata.serialNumber", "assetMetadata.sgtin"}
+
+// TestGetPaths tests the alias paths configuration
+func TestGetPaths(t *testing.T) {
+	defer viper.Set(pathsConfigKey, nil)
+	assert.Empty(t, GetPaths(), "No paths by default")
+	viper.Set(pathsConfigKey, []string{" assetMetadata.serialNumber ", ""})
+	assert.Equal(t, []string{"assetMetadata.serialNumber"}, GetPaths(), "Paths are trimmed and blanks dropped")
+}
+
+// TestOf tests reading the aliases of an asset
+func TestOf(t *testing.T) {
+	asset := helpers.Asset{AssetMetadata: map[string]interface{}{
+		"serialNumber": float64(1200345),
+		"sgtin":        []interface{}{" urn:epc:id:sgtin:0614141.112345.400 ", "", true, "SGTIN-2"},
+	}}
+	assert.Equal(t, []helpers.Alias{
+		{Path: "assetMetadata.serialNumber", Value: "1200345"},
+		{Path: "assetMetadata.sgtin", Value: "urn:epc:id:sgtin:0614141.112345.400"},
+		{Path: "assetMetadata.sgtin", Value: "SGTIN-2"},
+	}, Of(asset, aliasPaths), "Strings and numbers are aliases, each in an array")
+	assert.Empty(t, Of(helpers.Asset{}, aliasPaths), "Asset without the paths has no aliases")
+}
+
+// TestStoreIndex tests setting, resolving and replacing aliases
+func TestStoreIndex(t *testing.T) {
+	directory, err := ioutil.TempDir("", "aliases")
+	assert.NoError(t, err, "Data directory is created")
+	defer os.RemoveAll(directory)
+	index := NewStoreIndexWith(store.NewCollectionAt(directory, collectionName))
+	ctx := context.Background()
+	first := helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"}
+	second := helpers.AssetElement{RepoID: "T2", ChannelID: "C1", AssetID: "B1"}
+	serial := func(value string) []helpers.Alias {
+		return []helpers.Alias{{Path: "assetMetadata.serialNumber", Value: value}}
+	}
+
+	assert.NoError(t, index.Set(ctx, Entry{Asset: first, Aliases: serial("SN-1")}), "Aliases are set")
+	assert.NoError(t, index.Set(ctx, Entry{Asset: second, Aliases: serial("SN-1")}), "Aliases are set")
+	matches, err := index.Resolve(ctx, " SN-1 ")
+	assert.NoError(t, err, "Alias is resolved")
+	assert.Equal(t, []helpers.AliasMatch{{AssetElement: first, Path: "assetMetadata.serialNumber"},
+		{AssetElement: second, Path: "assetMetadata.serialNumber"}}, matches, "Every carrier is found in order")
+
+	assert.NoError(t, index.Set(ctx, Entry{Asset: first, Aliases: serial("SN-9")}), "Aliases are replaced")
+	matches, _ = index.Resolve(ctx, "SN-1")
+	assert.Len(t, matches, 1, "Replaced alias no longer resolves to the asset")
+	assert.NoError(t, index.Set(ctx, Entry{Asset: second}), "Aliases are removed")
+	matches, _ = index.Resolve(ctx, "SN-1")
+	assert.Empty(t, matches, "Removed alias does not resolve")
+
+	third := helpers.AssetElement{RepoID: "T3", ChannelID: "C1", AssetID: "D1"}
+	err = index.Replace(ctx, []Entry{{Asset: third, Aliases: serial("SN-3")}}, func(element helpers.AssetElement) bool {
+		return element.RepoID == "T9"
+	})
+	assert.NoError(t, err, "Index is replaced")
+	matches, _ = index.Resolve(ctx, "SN-9")
+	assert.Empty(t, matches, "Entries not kept are dropped")
+	matches, _ = index.Resolve(ctx, "SN-3")
+	assert.Equal(t, []helpers.AliasMatch{{AssetElement: third, Path: "assetMetadata.serialNumber"}}, matches,
+		"New entries are indexed")
+	err = index.Replace(ctx, nil, func(element helpers.AssetElement) bool { return true })
+	assert.NoError(t, err, "Index is replaced")
+	matches, _ = index.Resolve(ctx, "SN-3")
+	assert.Len(t, matches, 1, "Kept entries stay")
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package advisories contains all the controller functions for managing recall and safety advisories
+package advisories
+
+import (
+	"chainsource-gateway/advisories"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/tracing"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+var log = helpers.GetLogger("AdvisoryController")
+
+// storeErrorResponse maps an error of the advisory store to its response
+func storeErrorResponse(err error) render.Renderer {
+	switch err {
+	case helpers.ErrNotFound:
+		return responses.ErrAdvisoryDoesNotExist(err)
+	case advisories.ErrAdvisoryExists:
+		return responses.ErrAlreadyExists(err)
+	case advisories.ErrAdvisoryWithdrawn:
+		return responses.ErrConflict(err)
+	default:
+		return responses.ErrInternalServer(err)
+	}
+}
+
+// CreateAdvisory is a controller function to issue an advisory
+func CreateAdvisory(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Create Advisory")
+	defer span.Finish()
+	advisoryStore := r.Context().Value("advisoryStore").(advisories.AdvisoryStore)
+
+	var advisory advisories.Advisory
+	err := json.NewDecoder(r.Body).Decode(&advisory)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to unmarshal, invalid format")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	if err = advisory.Validate(); err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	created, err := advisoryStore.Create(ctx, advisory)
+	if err != nil {
+		render.Render(w, r, storeErrorResponse(err))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, created)
+}
+
+// ListAdvisories is a controller function to list the advisories
+func ListAdvisories(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "List Advisories")
+	defer span.Finish()
+	advisoryStore := r.Context().Value("advisoryStore").(advisories.AdvisoryStore)
+
+	list, err := advisoryStore.List(ctx)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	render.JSON(w, r, list)
+}
+
+// GetAdvisory is a controller function to get an advisory
+func GetAdvisory(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Advisory")
+	defer span.Finish()
+	advisoryStore := r.Context().Value("advisoryStore").(advisories.AdvisoryStore)
+
+	advisory, err := advisoryStore.Get(ctx, chi.URLParam(r, "advisoryID"))
+	if err != nil {
+		render.Render(w, r, storeErrorResponse(err))
+		return
+	}
+	render.JSON(w, r, advisory)
+}
+
+// WithdrawAdvisory is a controller function to withdraw an advisory. The flags it recorded stay on the ledger,
+// but are no longer shown as active advisories
+func WithdrawAdvisory(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Withdraw Advisory")
+	defer span.Finish()
+	advisoryStore := r.Context().Value("advisoryStore").(advisories.AdvisoryStore)
+
+	advisory, err := advisoryStore.Withdraw(ctx, chi.URLParam(r, "advisoryID"))
+	if err != nil {
+		render.Render(w, r, storeErrorResponse(err))
+		return
+	}
+	render.JSON(w, r, advisory)
+}
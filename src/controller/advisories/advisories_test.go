@@ -0,0 +1,161 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package advisories
+
+import (
+	"chainsource-gateway/advisories"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const validAdvisory = `{"advisoryID":"ADV-1","severity":"high","description":"Capacitor may fail",` +
+	`"affects":{"assetModelNumber":"X100"}}`
+
+// injectAdvisoryContext injects an advisory store and the advisoryID URL parameter into a request
+func injectAdvisoryContext(r *http.Request, advisoryStore advisories.AdvisoryStore, advisoryID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("advisoryID", advisoryID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "advisoryStore", advisoryStore)
+	return r.WithContext(ctx)
+}
+
+// TestCreateAdvisory contains the tests for issuing advisories
+func TestCreateAdvisory(t *testing.T) {
+	t.Run("Valid_Advisory", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		mockStore.EXPECT().Create(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, advisory advisories.Advisory) (advisories.Advisory, error) {
+				assert.Equal(t, "X100", advisory.Affects.AssetModelNumber, "Filter is decoded")
+				return advisory, nil
+			})
+
+		mockRequest := injectAdvisoryContext(httptest.NewRequest("POST", "/", strings.NewReader(validAdvisory)), mockStore, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code, "Response Should be 201 CREATED")
+	})
+	t.Run("Already_Exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		mockStore.EXPECT().Create(gomock.Any(), gomock.Any()).Return(advisories.Advisory{}, advisories.ErrAdvisoryExists)
+
+		mockRequest := injectAdvisoryContext(httptest.NewRequest("POST", "/", strings.NewReader(validAdvisory)), mockStore, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Unknown_Severity", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+
+		body := strings.Replace(validAdvisory, "high", "urgent", 1)
+		mockRequest := injectAdvisoryContext(httptest.NewRequest("POST", "/", strings.NewReader(body)), mockStore, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+}
+
+// TestListAdvisories tests listing the advisories
+func TestListAdvisories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStore := mocks.NewMockAdvisoryStore(ctrl)
+	defer ctrl.Finish()
+	mockStore.EXPECT().List(gomock.Any()).Return([]advisories.Advisory{{AdvisoryID: "ADV-1"}}, nil)
+
+	mockRequest := injectAdvisoryContext(httptest.NewRequest("GET", "/", nil), mockStore, "")
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(ListAdvisories).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	assert.Contains(t, responseRecorder.Body.String(), "ADV-1", "Advisory is listed")
+}
+
+// TestGetAdvisory contains the tests for getting an advisory
+func TestGetAdvisory(t *testing.T) {
+	t.Run("Exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		mockStore.EXPECT().Get(gomock.Any(), "ADV-1").Return(advisories.Advisory{AdvisoryID: "ADV-1"}, nil)
+
+		mockRequest := injectAdvisoryContext(httptest.NewRequest("GET", "/", nil), mockStore, "ADV-1")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	})
+	t.Run("Does_Not_Exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		mockStore.EXPECT().Get(gomock.Any(), "ADV-1").Return(advisories.Advisory{}, helpers.ErrNotFound)
+
+		mockRequest := injectAdvisoryContext(httptest.NewRequest("GET", "/", nil), mockStore, "ADV-1")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+}
+
+// TestWithdrawAdvisory contains the tests for withdrawing an advisory
+func TestWithdrawAdvisory(t *testing.T) {
+	t.Run("Active", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		mockStore.EXPECT().Withdraw(gomock.Any(), "ADV-1").
+			Return(advisories.Advisory{AdvisoryID: "ADV-1", Status: advisories.StatusWithdrawn}, nil)
+
+		mockRequest := injectAdvisoryContext(httptest.NewRequest("POST", "/", nil), mockStore, "ADV-1")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(WithdrawAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	})
+	t.Run("Already_Withdrawn", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		mockStore.EXPECT().Withdraw(gomock.Any(), "ADV-1").Return(advisories.Advisory{}, advisories.ErrAdvisoryWithdrawn)
+
+		mockRequest := injectAdvisoryContext(httptest.NewRequest("POST", "/", nil), mockStore, "ADV-1")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(WithdrawAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/advisories"
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// advisoryCommitType is the commit type of the flags an advisory records on the ledger
+const advisoryCommitType = "ADVISORY"
+
+const (
+	relationAffected = "affected"
+	relationContains = "contains"
+)
+
+// advisoryTarget is an asset an advisory is about to be recorded on
+type advisoryTarget struct {
+	element  helpers.AssetElement
+	relation string
+	parts    []helpers.AssetElement
+}
+
+// withAdvisoryFlag replaces the flag of the same advisory, reports false when the flags already hold an equal flag
+func withAdvisoryFlag(flags []helpers.AdvisoryFlag, flag helpers.AdvisoryFlag) ([]helpers.AdvisoryFlag, bool) {
+	updated := make([]helpers.AdvisoryFlag, 0, len(flags)+1)
+	for _, existing := range flags {
+		if existing.AdvisoryID != flag.AdvisoryID {
+			updated = append(updated, existing)
+			continue
+		}
+		if existing.Relation == flag.Relation && reflect.DeepEqual(existing.Parts, flag.Parts) {
+			return flags, false
+		}
+	}
+	return append(updated, flag), true
+}
+
+// activeAdvisories returns the active advisories by ID, nil when the request has no advisory store.
+// Reads do not fail on the store, assets are returned without their advisories instead
+func activeAdvisories(ctx context.Context) map[string]advisories.Advisory {
+	advisoryStore, ok := ctx.Value("advisoryStore").(advisories.AdvisoryStore)
+	if !ok {
+		return nil
+	}
+	list, err := advisoryStore.List(ctx)
+	if err != nil {
+		log.Err(err).Msg("Advisories could not be read")
+		return nil
+	}
+	active := make(map[string]advisories.Advisory)
+	for _, advisory := range list {
+		if advisory.Status == advisories.StatusActive {
+			active[advisory.AdvisoryID] = advisory
+		}
+	}
+	return active
+}
+
+// annotateAdvisories lists the active advisories of the flags of an asset and of every asset exported with it
+func annotateAdvisories(asset *helpers.Asset, active map[string]advisories.Advisory) {
+	if asset == nil || active == nil {
+		return
+	}
+	asset.ActiveAdvisories = nil
+	for _, flag := range asset.AdvisoryFlags {
+		if advisory, ok := active[flag.AdvisoryID]; ok {
+			asset.ActiveAdvisories = append(asset.ActiveAdvisories, helpers.ActiveAdvisory{
+				AdvisoryID:  advisory.AdvisoryID,
+				Severity:    advisory.Severity,
+				Description: advisory.Description,
+				Relation:    flag.Relation,
+				Parts:       flag.Parts,
+			})
+		}
+	}
+	for _, child := range asset.Children {
+		annotateAdvisories(child, active)
+	}
+	for _, parent := range asset.Parent {
+		annotateAdvisories(parent, active)
+	}
+}
+
+// ApplyAdvisory is a controller function that records an active advisory on every asset matching it, in every
+// channel of every configured repo, and on every ancestor of those assets. Matching assets are flagged as affected,
+// ancestors as containing the affected parts. Flags are committed with the ADVISORY commit type, assets that already
+// carry the same flag are not committed again and read-only assets, copies left behind by a transfer, are skipped
+func ApplyAdvisory(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Apply Advisory")
+	defer span.Finish()
+	advisoryStore := r.Context().Value("advisoryStore").(advisories.AdvisoryStore)
+
+	advisory, err := advisoryStore.Get(ctx, chi.URLParam(r, "advisoryID"))
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrAdvisoryDoesNotExist(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+	if advisory.Status != advisories.StatusActive {
+		render.Render(w, r, responses.ErrConflict(advisories.ErrAdvisoryWithdrawn))
+		return
+	}
+	repoIDs, err := agent.GetConfiguredRepos()
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+
+	result := helpers.AdvisoryApplication{
+		AdvisoryID: advisory.AdvisoryID,
+		Flagged:    []helpers.FlaggedAsset{},
+		Errors:     []helpers.TargetError{},
+	}
+	walker := whereUsedWalker{ctx: ctx, assets: make(map[string]helpers.Asset)}
+	targets := make(map[string]*advisoryTarget)
+	mark := func(element helpers.AssetElement, relation string, part *helpers.AssetElement) {
+		target, ok := targets[elementKey(element)]
+		if !ok {
+			target = &advisoryTarget{element: element, relation: relation}
+			targets[elementKey(element)] = target
+		}
+		if relation == relationAffected {
+			target.relation = relationAffected
+		}
+		if part != nil {
+			target.parts = append(target.parts, *part)
+		}
+	}
+	for _, repoID := range repoIDs {
+		targetErrors := matchingParts(ctx, repoID, advisory.Affects.Fields(), func(part helpers.AssetElement, asset helpers.Asset) {
+			walker.assets[elementKey(part)] = asset
+			path := walker.pathTo(part, asset)
+			mark(part, relationAffected, nil)
+			for _, ancestor := range path.Path[:len(path.Path)-1] {
+				mark(ancestor.AssetElement, relationContains, &part)
+			}
+		})
+		result.Errors = append(result.Errors, targetErrors...)
+	}
+
+	keys := make([]string, 0, len(targets))
+	for key := range targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	flaggedAt := time.Now().UTC().Format(time.RFC3339)
+	for _, key := range keys {
+		target := targets[key]
+		asset := walker.assets[key]
+		if asset.ReadOnly {
+			log.Debug().Msgf("Not flagging read-only %s with advisory %s", key, advisory.AdvisoryID)
+			continue
+		}
+		flags, changed := withAdvisoryFlag(asset.AdvisoryFlags, helpers.AdvisoryFlag{
+			AdvisoryID: advisory.AdvisoryID,
+			Relation:   target.relation,
+			Parts:      target.parts,
+			FlaggedAt:  flaggedAt,
+		})
+		if !changed {
+			result.Unchanged++
+			continue
+		}
+		asset.AdvisoryFlags = flags
+		asset.ActiveAdvisories = nil
+
+		targetAgent, err := agentForRepo(ctx, target.element.RepoID)
+		if err == nil {
+			_, err = targetAgent.Commit(ctx, agent.CommitArgs{
+				ChannelID:  target.element.ChannelID,
+				AssetID:    target.element.AssetID,
+				CommitType: advisoryCommitType,
+				Payload:    asset,
+			})
+		}
+		if err != nil {
+			log.Warn().Msgf("Advisory %s could not be recorded on %s: %s", advisory.AdvisoryID, key, err.Error())
+			result.Errors = append(result.Errors, helpers.TargetError{RepoID: target.element.RepoID,
+				ChannelID: target.element.ChannelID, AssetID: target.element.AssetID, Error: err.Error()})
+			continue
+		}
+		result.Flagged = append(result.Flagged, helpers.FlaggedAsset{AssetElement: target.element, Relation: target.relation})
+	}
+	log.Info().Msgf("Advisory %s recorded on %d assets", advisory.AdvisoryID, len(result.Flagged))
+	render.JSON(w, r, result)
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/advisories"
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const flaggedAssetLocation = "../../testdata/asset_controller_tests/advisory/flaggedAsset.json"
+
+// recallAdvisory is an active advisory against the X100 processors of the where-used test data
+var recallAdvisory = advisories.Advisory{
+	AdvisoryID:  "ADV-1",
+	Severity:    "critical",
+	Description: "Capacitor may fail",
+	Affects:     advisories.Filter{AssetModelNumber: "X100"},
+	Status:      advisories.StatusActive,
+}
+
+// injectAdvisoryStore injects an advisory store and the advisoryID URL parameter into a request
+func injectAdvisoryStore(r *http.Request, advisoryStore advisories.AdvisoryStore, advisoryID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("advisoryID", advisoryID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	return r.WithContext(context.WithValue(ctx, "advisoryStore", advisoryStore))
+}
+
+// TestWithAdvisoryFlag tests that flags are replaced per advisory and equal flags are not recorded again
+func TestWithAdvisoryFlag(t *testing.T) {
+	part := []helpers.AssetElement{{RepoID: "T1", ChannelID: "C1", AssetID: "P1"}}
+	flags := []helpers.AdvisoryFlag{{AdvisoryID: "ADV-0", Relation: "affected"}, {AdvisoryID: "ADV-1", Relation: "contains", Parts: part}}
+
+	_, changed := withAdvisoryFlag(flags, helpers.AdvisoryFlag{AdvisoryID: "ADV-1", Relation: "contains", Parts: part, FlaggedAt: "now"})
+	assert.False(t, changed, "Equal flag is not recorded again")
+	updated, changed := withAdvisoryFlag(flags, helpers.AdvisoryFlag{AdvisoryID: "ADV-1", Relation: "affected"})
+	assert.True(t, changed, "Changed flag is recorded")
+	assert.Len(t, updated, 2, "Flag of the advisory is replaced")
+	assert.Equal(t, "affected", updated[1].Relation, "Flag is updated")
+}
+
+// TestApplyAdvisory contains the tests for recording an advisory on the assets it affects
+func TestApplyAdvisory(t *testing.T) {
+	viper.Set("agents", map[string]agent.Config{
+		"T1": {Enabled: true},
+		"T2": {Enabled: true},
+	})
+	defer viper.Set("agents", nil)
+
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		committed := make(map[string]helpers.Asset)
+		record := func(_ context.Context, args agent.CommitArgs) (map[string]interface{}, error) {
+			if args.AssetID == "P2" {
+				return nil, errors.New("agent is down")
+			}
+			committed[args.AssetID] = args.Payload
+			return getAgentSuccessResponse(), nil
+		}
+
+		mockStore.EXPECT().Get(gomock.Any(), "ADV-1").Return(recallAdvisory, nil)
+		agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		agentT1.EXPECT().QueryAssets(gomock.Any(), gomock.Any(), gomock.Any()).Return(openTestQueryResult("queryT1C1.json"), nil)
+		agentT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).
+			Return(openTestJSON(whereUsedLocation+"board.json"), nil)
+		agentT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "MISSING")).Return(nil, helpers.ErrNotFound)
+		agentT1.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(3).DoAndReturn(record)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1","C2"]`)), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, gomock.Any()).
+			Return(openTestQueryResult("queryT2C1.json"), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C2"}, gomock.Any()).
+			Return(openTestQueryResult("queryT2C2.json"), nil)
+		expectLastPage(agentT2, "C2", queryT2C2Bookmark)
+		agentT2.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "S1")).
+			Return(openTestJSON(whereUsedLocation+"server.json"), nil)
+		agentT2.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(3).DoAndReturn(record)
+
+		mockRequest := injectAdvisoryStore(httptest.NewRequest("POST", "/", nil), mockStore, "ADV-1")
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(ApplyAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var result helpers.AdvisoryApplication
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		assert.Len(t, result.Flagged, 5, "Parts and their ancestors are flagged")
+		assert.Len(t, result.Errors, 1, "Failed commit is reported")
+		assert.Equal(t, "P2", result.Errors[0].AssetID, "Asset of the failed commit is reported")
+
+		assert.Equal(t, relationAffected, committed["P1"].AdvisoryFlags[0].Relation, "Matching part is affected")
+		assert.Equal(t, relationContains, committed["B1"].AdvisoryFlags[0].Relation, "Board contains the advisory")
+		assert.Equal(t, "P1", committed["B1"].AdvisoryFlags[0].Parts[0].AssetID, "Board lists the affected part")
+		assert.Len(t, committed["S1"].AdvisoryFlags[0].Parts, 2, "Server lists both affected parts")
+		assert.Equal(t, "Server", committed["S1"].AssetType, "Ancestor is committed with its own state")
+		assert.NotContains(t, committed, "MISSING", "Unreadable parents are not flagged")
+	})
+	t.Run("Empty_Parent_Link", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		var committed helpers.Asset
+
+		// P2 was detached, its parentAsset is stored as an empty link that must not be followed
+		mockStore.EXPECT().Get(gomock.Any(), "ADV-1").Return(recallAdvisory, nil)
+		agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`[]`)), nil)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C2"]`)), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C2"}, gomock.Any()).
+			Return(openTestQueryResult("queryT2C2.json"), nil)
+		expectLastPage(agentT2, "C2", queryT2C2Bookmark)
+		agentT2.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C2", "P2", "ADVISORY")).
+			Do(func(_ context.Context, args agent.CommitArgs) { committed = args.Payload }).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := injectAdvisoryStore(httptest.NewRequest("POST", "/", nil), mockStore, "ADV-1")
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(ApplyAdvisory).ServeHTTP(responseRecorder, mockRequest)
+
+		var result helpers.AdvisoryApplication
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Len(t, result.Flagged, 1, "Only the part is flagged")
+		assert.Empty(t, result.Errors, "Empty parent link is not an error")
+		assert.Equal(t, relationAffected, committed.AdvisoryFlags[0].Relation, "Part is affected")
+	})
+	t.Run("Withdrawn", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		withdrawn := recallAdvisory
+		withdrawn.Status = advisories.StatusWithdrawn
+		mockStore.EXPECT().Get(gomock.Any(), "ADV-1").Return(withdrawn, nil)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(ApplyAdvisory).ServeHTTP(responseRecorder,
+			injectAdvisoryStore(httptest.NewRequest("POST", "/", nil), mockStore, "ADV-1"))
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 Conflict")
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockStore := mocks.NewMockAdvisoryStore(ctrl)
+		defer ctrl.Finish()
+		mockStore.EXPECT().Get(gomock.Any(), "ADV-9").Return(advisories.Advisory{}, helpers.ErrNotFound)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(ApplyAdvisory).ServeHTTP(responseRecorder,
+			injectAdvisoryStore(httptest.NewRequest("POST", "/", nil), mockStore, "ADV-9"))
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 Not Found")
+	})
+}
+
+// TestRetrieveActiveAdvisories tests that retrieved assets list the active advisories of their flags
+func TestRetrieveActiveAdvisories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	mockStore := mocks.NewMockAdvisoryStore(ctrl)
+	defer ctrl.Finish()
+	mockAgent.EXPECT().GetHost().AnyTimes()
+	mockAgent.EXPECT().GetPort().AnyTimes()
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).Return(openTestJSON(flaggedAssetLocation), nil)
+	mockStore.EXPECT().List(gomock.Any()).Return([]advisories.Advisory{
+		recallAdvisory,
+		{AdvisoryID: "ADV-2", Severity: "low", Status: advisories.StatusWithdrawn},
+	}, nil)
+
+	mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/", nil), "T1", "C1", "B1", mockAgent,
+		mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	mockRequest = injectAdvisoryStore(mockRequest, mockStore, "")
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(RetrieveAsset).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	var result helpers.Asset
+	json.NewDecoder(responseRecorder.Body).Decode(&result)
+	assert.Len(t, result.AdvisoryFlags, 2, "Every flag is returned")
+	assert.Len(t, result.ActiveAdvisories, 1, "Only the active advisory is listed")
+	assert.Equal(t, "critical", result.ActiveAdvisories[0].Severity, "Active advisory has its severity")
+	assert.Equal(t, "contains", result.ActiveAdvisories[0].Relation, "Active advisory has the relation of the flag")
+}
+
+// TestUpdateKeepsAdvisoryFlags tests that an update does not drop the advisory flags on the ledger
+func TestUpdateKeepsAdvisoryFlags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+	var finalAsset helpers.Asset
+	mockAgent.EXPECT().GetHost().AnyTimes()
+	mockAgent.EXPECT().GetPort().AnyTimes()
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).Return(openTestJSON(flaggedAssetLocation), nil)
+	mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "B1", "UPDATE")).
+		DoAndReturn(func(_ context.Context, args agent.CommitArgs) (map[string]interface{}, error) {
+			finalAsset = args.Payload
+			return getAgentSuccessResponse(), nil
+		})
+
+	mockRequest := injectMockAssetContext(httptest.NewRequest("PUT", "/", openTestJSON(assetUpdatePayload)), "T1", "C1", "B1",
+		mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(UpdateAsset).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	assert.Len(t, finalAsset.AdvisoryFlags, 2, "Advisory flags are kept")
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/aliases"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// indexAliases records the aliases of an asset that was committed. A read only asset was transferred away, its aliases
+// are removed so that they resolve to where it was transferred to. The commit stands when the index cannot be
+// updated, a rebuild brings the index up to date
+func indexAliases(ctx context.Context, element helpers.AssetElement, asset helpers.Asset) {
+	index, ok := ctx.Value("aliasIndex").(aliases.AliasIndex)
+	paths := aliases.GetPaths()
+	if !ok || len(paths) == 0 {
+		return
+	}
+	entry := aliases.Entry{Asset: element}
+	if !asset.ReadOnly {
+		entry.Aliases = aliases.Of(asset, paths)
+	}
+	err := index.Set(ctx, entry)
+	if err != nil {
+		log.Warn().Msgf("Aliases of %s not indexed: %s", elementKey(element), err.Error())
+	}
+}
+
+// resolveURI redirects to the asset a URI names, or returns the asset when redirect is false.
+// The redirect is relative to the router the resolve endpoint is mounted on
+func resolveURI(ctx context.Context, w http.ResponseWriter, r *http.Request, uri string) {
+	element, err := helpers.ParseAssetURI(uri)
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	if r.URL.Query().Get("redirect") != "false" {
+		base := strings.TrimSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/resolve")
+		location := fmt.Sprintf("%s/repo/%s/chan/%s/asset/%s", base, url.PathEscape(element.RepoID),
+			url.PathEscape(element.ChannelID), url.PathEscape(element.AssetID))
+		http.Redirect(w, r, location, http.StatusSeeOther)
+		return
+	}
+
+	repoAgent, err := agentForRepo(ctx, element.RepoID)
+	if err != nil {
+		render.Render(w, r, responses.ErrNoAgent(err))
+		return
+	}
+	var asset helpers.Asset
+	resultStream, err := repoAgent.QueryStream(ctx, agent.QueryArgs{ChannelID: element.ChannelID, AssetID: element.AssetID})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&asset)
+	}
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+	render.JSON(w, r, asset)
+}
+
+// Resolve is a controller function that resolves ?uri=dbom://<repoID>/<channelID>/<assetID> to the asset it names, or
+// ?alias= to the locations of the assets carrying the alias, such as a serial number read by a scanner
+func Resolve(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Resolve")
+	defer span.Finish()
+
+	if uri := strings.TrimSpace(r.URL.Query().Get("uri")); uri != "" {
+		resolveURI(ctx, w, r, uri)
+		return
+	}
+	alias := strings.TrimSpace(r.URL.Query().Get("alias"))
+	if alias == "" {
+		render.Render(w, r, responses.ErrInvalidRequest(errors.New("uri or alias is required")))
+		return
+	}
+	index := r.Context().Value("aliasIndex").(aliases.AliasIndex)
+	matches, err := index.Resolve(ctx, alias)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	if len(matches) == 0 {
+		render.Render(w, r, responses.ErrDoesNotExist(errors.New("no asset carries alias "+alias)))
+		return
+	}
+	for i := range matches {
+		matches[i].URI = matches[i].AssetElement.URI()
+	}
+	render.JSON(w, r, matches)
+}
+
+// scanAliases reads the aliases of every asset of a repo, channel by channel. Read only assets were transferred away
+// and are left out
+func scanAliases(ctx context.Context, repoID string, paths []string) ([]aliases.Entry, []helpers.TargetError) {
+	failed := func(channelID string, assetID string, err error) helpers.TargetError {
+		log.Warn().Msgf("Alias scan of %s/%s/%s failed: %s", repoID, channelID, assetID, err.Error())
+		return helpers.TargetError{RepoID: repoID, ChannelID: channelID, AssetID: assetID, Error: err.Error()}
+	}
+	repoAgent, err := agentForRepo(ctx, repoID)
+	if err != nil {
+		return nil, []helpers.TargetError{failed("", "", err)}
+	}
+	var channels []string
+	resultStream, err := repoAgent.ListChannels(ctx, agent.QueryArgs{})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&channels)
+	}
+	if err != nil {
+		return nil, []helpers.TargetError{failed("", "", err)}
+	}
+	sort.Strings(channels)
+
+	var entries []aliases.Entry
+	var targetErrors []helpers.TargetError
+	for _, channelID := range channels {
+		var assetIDs []string
+		resultStream, err := repoAgent.ListAssets(ctx, agent.QueryArgs{ChannelID: channelID})
+		if err == nil {
+			err = json.NewDecoder(resultStream).Decode(&assetIDs)
+		}
+		if err != nil {
+			targetErrors = append(targetErrors, failed(channelID, "", err))
+			continue
+		}
+		sort.Strings(assetIDs)
+		for _, assetID := range assetIDs {
+			var asset helpers.Asset
+			assetStream, err := repoAgent.QueryStream(ctx, agent.QueryArgs{ChannelID: channelID, AssetID: assetID})
+			if err == nil {
+				err = json.NewDecoder(assetStream).Decode(&asset)
+			}
+			if err != nil {
+				targetErrors = append(targetErrors, failed(channelID, assetID, err))
+				continue
+			}
+			if asset.ReadOnly {
+				continue
+			}
+			element := helpers.AssetElement{RepoID: repoID, ChannelID: channelID, AssetID: assetID}
+			entries = append(entries, aliases.Entry{Asset: element, Aliases: aliases.Of(asset, paths)})
+		}
+	}
+	return entries, targetErrors
+}
+
+// RebuildAliases is a controller function that rebuilds the alias index from every asset of every configured repo.
+// Assets in repos, channels or assets that could not be read keep the aliases they had in the index
+func RebuildAliases(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Rebuild Aliases")
+	defer span.Finish()
+	index := r.Context().Value("aliasIndex").(aliases.AliasIndex)
+
+	repoIDs, err := agent.GetConfiguredRepos()
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	paths := aliases.GetPaths()
+	result := helpers.AliasRebuild{Errors: []helpers.TargetError{}}
+	var entries []aliases.Entry
+	for _, repoID := range repoIDs {
+		repoEntries, targetErrors := scanAliases(ctx, repoID, paths)
+		entries = append(entries, repoEntries...)
+		result.Errors = append(result.Errors, targetErrors...)
+	}
+	for _, entry := range entries {
+		if len(entry.Aliases) > 0 {
+			result.Assets++
+			result.Aliases += len(entry.Aliases)
+		}
+	}
+
+	err = index.Replace(ctx, entries, func(element helpers.AssetElement) bool {
+		for _, failed := range result.Errors {
+			if failed.RepoID == element.RepoID &&
+				(failed.ChannelID == "" || failed.ChannelID == element.ChannelID) &&
+				(failed.AssetID == "" || failed.AssetID == element.AssetID) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	render.JSON(w, r, result)
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/aliases"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// serialAlias is the alias of the asset in the duplicates test data
+var serialAlias = helpers.Alias{Path: "assetMetadata.serialNumber", Value: "SN-1"}
+
+// injectAliasIndex injects an alias index into a request
+func injectAliasIndex(r *http.Request, index aliases.AliasIndex) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), "aliasIndex", index))
+}
+
+// TestResolve contains the tests for resolving an alias to the assets carrying it
+func TestResolve(t *testing.T) {
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockIndex := mocks.NewMockAliasIndex(ctrl)
+		defer ctrl.Finish()
+		matches := []helpers.AliasMatch{{AssetElement: helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"},
+			Path: serialAlias.Path}}
+		mockIndex.EXPECT().Resolve(gomock.Any(), "SN-1").Return(matches, nil)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(Resolve).ServeHTTP(responseRecorder,
+			injectAliasIndex(httptest.NewRequest("GET", "/?alias=SN-1", nil), mockIndex))
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var result []helpers.AliasMatch
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		assert.Equal(t, matches, result, "Carriers of the alias are returned")
+		assert.Equal(t, "dbom://T1/C1/A1", result[0].URI, "Carriers have their URI")
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockIndex := mocks.NewMockAliasIndex(ctrl)
+		defer ctrl.Finish()
+		mockIndex.EXPECT().Resolve(gomock.Any(), "SN-0").Return([]helpers.AliasMatch{}, nil)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(Resolve).ServeHTTP(responseRecorder,
+			injectAliasIndex(httptest.NewRequest("GET", "/?alias=SN-0", nil), mockIndex))
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 Not Found")
+	})
+	t.Run("Missing_Alias", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(Resolve).ServeHTTP(responseRecorder,
+			injectAliasIndex(httptest.NewRequest("GET", "/", nil), mocks.NewMockAliasIndex(ctrl)))
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 Bad Request")
+	})
+}
+
+// TestResolveURI contains the tests for resolving an asset URI to the asset
+func TestResolveURI(t *testing.T) {
+	t.Run("Redirect", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(Resolve).ServeHTTP(responseRecorder,
+			httptest.NewRequest("GET", "/api/v1/resolve?uri=dbom://DB1/C1/lot%25207", nil))
+		assert.Equal(t, http.StatusSeeOther, responseRecorder.Code, "Response Should be 303 See Other")
+		assert.Equal(t, "/api/v1/repo/DB1/chan/C1/asset/lot%207", responseRecorder.Header().Get("Location"),
+			"Redirects to the asset")
+	})
+	t.Run("Return", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(duplicatesLocation+"newAsset.json"), nil)
+
+		mockRequest := mocks.InjectAgentProviderIntoRequest(
+			httptest.NewRequest("GET", "/api/v1/resolve?uri=dbom://DB1/C1/A1&redirect=false", nil), ctrl,
+			map[string]agent.Agent{"DB1": mockAgent})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(Resolve).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var result helpers.Asset
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		assert.Equal(t, "X100", result.AssetModelNumber, "Asset is returned")
+	})
+	t.Run("Not_Found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A0")).Return(nil, helpers.ErrNotFound)
+
+		mockRequest := mocks.InjectAgentProviderIntoRequest(
+			httptest.NewRequest("GET", "/api/v1/resolve?uri=dbom://DB1/C1/A0&redirect=false", nil), ctrl,
+			map[string]agent.Agent{"DB1": mockAgent})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(Resolve).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 Not Found")
+	})
+	t.Run("Invalid_URI", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(Resolve).ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/api/v1/resolve?uri=dbom://DB1", nil))
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 Bad Request")
+	})
+}
+
+// TestRebuildAliases tests rebuilding the alias index from the agents, keeping what could not be read
+func TestRebuildAliases(t *testing.T) {
+	viper.Set("agents", map[string]agent.Config{
+		"T1": {Enabled: true},
+		"T2": {Enabled: true},
+	})
+	viper.Set("aliasPaths", []string{"assetMetadata.serialNumber"})
+	defer viper.Set("agents", nil)
+	defer viper.Set("aliasPaths", nil)
+	ctrl := gomock.NewController(t)
+	agentT1 := mocks.NewMockAgent(ctrl)
+	agentT2 := mocks.NewMockAgent(ctrl)
+	mockIndex := mocks.NewMockAliasIndex(ctrl)
+	defer ctrl.Finish()
+
+	agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+	agentT1.EXPECT().ListAssets(gomock.Any(), mocks.AgentQueryFor("C1", "")).
+		Return(ioutil.NopCloser(strings.NewReader(`["A2","A1","A3"]`)), nil)
+	agentT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+		Return(openTestJSON(duplicatesLocation+"newAsset.json"), nil)
+	agentT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A3")).
+		Return(openTestJSON(duplicatesLocation+"transferredAsset.json"), nil)
+	agentT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A2")).Return(nil, errors.New("agent is down"))
+	agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(nil, errors.New("agent is down"))
+	mockIndex.EXPECT().Replace(gomock.Any(), []aliases.Entry{{
+		Asset:   helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"},
+		Aliases: []helpers.Alias{serialAlias},
+	}}, gomock.Any()).DoAndReturn(func(_ context.Context, _ []aliases.Entry, keep func(helpers.AssetElement) bool) error {
+		assert.False(t, keep(helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"}), "Scanned asset is replaced")
+		assert.False(t, keep(helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A3"}), "Transferred asset is dropped")
+		assert.True(t, keep(helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A2"}), "Unread asset is kept")
+		assert.True(t, keep(helpers.AssetElement{RepoID: "T2", ChannelID: "C7", AssetID: "B1"}), "Unread repo is kept")
+		return nil
+	})
+
+	mockRequest := mocks.InjectAgentProviderIntoRequest(httptest.NewRequest("POST", "/", nil), ctrl,
+		map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(RebuildAliases).ServeHTTP(responseRecorder, injectAliasIndex(mockRequest, mockIndex))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	var result helpers.AliasRebuild
+	json.NewDecoder(responseRecorder.Body).Decode(&result)
+	assert.Equal(t, 1, result.Assets, "Assets with aliases are counted")
+	assert.Equal(t, 1, result.Aliases, "Aliases are counted")
+	assert.Len(t, result.Errors, 2, "Unread asset and repo are reported")
+}
+
+// TestCreateIndexesAliases tests that a created asset is added to the alias index
+func TestCreateIndexesAliases(t *testing.T) {
+	viper.Set("aliasPaths", []string{"assetMetadata.serialNumber"})
+	defer viper.Set("aliasPaths", nil)
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	mockIndex := mocks.NewMockAliasIndex(ctrl)
+	defer ctrl.Finish()
+	mockAgent.EXPECT().GetHost().AnyTimes()
+	mockAgent.EXPECT().GetPort().AnyTimes()
+	mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A9", "CREATE")).Return(getAgentSuccessResponse(), nil)
+	mockIndex.EXPECT().Set(gomock.Any(), aliases.Entry{
+		Asset:   helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A9"},
+		Aliases: []helpers.Alias{serialAlias},
+	}).Return(errors.New("disk full"))
+
+	mockRequest := injectMockAssetContext(httptest.NewRequest("POST", "/", openTestJSON(duplicatesLocation+"newAsset.json")),
+		"T1", "C1", "A9", mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(CreateAsset).ServeHTTP(responseRecorder, injectAliasIndex(mockRequest, mockIndex))
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code, "Create stands when the index cannot be updated")
+}
+
+// TestTransferIndexesAliases tests that the aliases of a transferred asset move to its destination
+func TestTransferIndexesAliases(t *testing.T) {
+	viper.Set("aliasPaths", []string{"assetMetadata.serialNumber"})
+	defer viper.Set("aliasPaths", nil)
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	mockIndex := mocks.NewMockAliasIndex(ctrl)
+	defer ctrl.Finish()
+	mockAgent.EXPECT().GetHost().AnyTimes()
+	mockAgent.EXPECT().GetPort().AnyTimes()
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+		Return(openTestJSON(duplicatesLocation+"newAsset.json"), nil)
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).Return(nil, helpers.ErrNotFound)
+	mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-OUT")).Return(getAgentSuccessResponse(), nil)
+	mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C2", "A2", "TRANSFER-IN")).Return(getAgentSuccessResponse(), nil)
+	gomock.InOrder(
+		mockIndex.EXPECT().Set(gomock.Any(), aliases.Entry{
+			Asset: helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"},
+		}).Return(nil),
+		mockIndex.EXPECT().Set(gomock.Any(), aliases.Entry{
+			Asset:   helpers.AssetElement{RepoID: "T1", ChannelID: "C2", AssetID: "A2"},
+			Aliases: []helpers.Alias{serialAlias},
+		}).Return(nil),
+	)
+
+	mockRequest := injectMockAssetContext(httptest.NewRequest("POST", "/", openTestJSON(transferRequestLocation)),
+		"T1", "C1", "A1", mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, injectAliasIndex(mockRequest, mockIndex))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+}
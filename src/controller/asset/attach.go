@@ -41,6 +41,8 @@ import (
 //
 // 3. If the asset is already attached to the asset
 //
+// 4. If the child fits the BOM template of the parent, when bomTemplateAttach is set
+//
 // It updates the parent asset with the new child and the child asset with a new parent.
 // If the child already has a parent, it is replaced.
 func AttachSubasset(w http.ResponseWriter, r *http.Request) {
@@ -134,6 +136,16 @@ func AttachSubasset(w http.ResponseWriter, r *http.Request) {
 		childSpan.Finish()
 		return
 	}
+	if rejection := pendingTransferConflict(requestAsset, "the parent asset"); rejection != nil {
+		render.Render(w, r, rejection)
+		childSpan.Finish()
+		return
+	}
+	if rejection := pendingTransferConflict(childAsset, "the child asset"); rejection != nil {
+		render.Render(w, r, rejection)
+		childSpan.Finish()
+		return
+	}
 	if requestAsset.AttachedChildren == nil {
 		var attachedArray []helpers.AssetLinkElement
 		requestAsset.AttachedChildren = attachedArray
@@ -148,6 +160,11 @@ func AttachSubasset(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if rejection := applyTemplatePolicy(ctx, r, requestAsset, childAssetLinkElement, childAsset); rejection != nil {
+		render.Render(w, r, rejection)
+		childSpan.Finish()
+		return
+	}
 	requestAsset.AttachedChildren = append(requestAsset.AttachedChildren, childAssetLinkElement)
 
 	_, err = requestAgent.Commit(ctx, agent.CommitArgs{
@@ -213,6 +213,60 @@ func TestAttachWithAssetErrorConditions(t *testing.T) {
 
 		assert.Equal(t, http.StatusForbidden, responseRecorder.Result().StatusCode, "Response Should be 403 FORBIDDEN")
 	})
+	t.Run("Parent_Transfer_Pending", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		requestBody := openTestJSON(attachRequestLocation)
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C2", "A2")).
+			Return(openTestJSON(attachChildLocation), nil)
+
+		providerMap := make(map[string]agent.Agent)
+		providerMap["T1"] = mockAgent
+
+		mockRequest := httptest.NewRequest("POST", "/", requestBody)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, providerMap)
+		handler := http.HandlerFunc(AttachSubasset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Result().StatusCode, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Child_Transfer_Pending", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		requestBody := openTestJSON(attachRequestLocation)
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(attachParentLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C2", "A2")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+
+		providerMap := make(map[string]agent.Agent)
+		providerMap["T1"] = mockAgent
+
+		mockRequest := httptest.NewRequest("POST", "/", requestBody)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, providerMap)
+		handler := http.HandlerFunc(AttachSubasset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Result().StatusCode, "Response Should be 409 CONFLICT")
+	})
 	t.Run("Parent_Does_Not_Exist", func (t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockAgent := mocks.NewMockAgent(ctrl)
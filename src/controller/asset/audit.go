@@ -21,16 +21,108 @@ import (
 	"chainsource-gateway/agent"
 	"chainsource-gateway/helpers"
 	"chainsource-gateway/responses"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/render"
 	"github.com/opentracing/opentracing-go"
 )
 
+// auditFilter is a type holding the filters and the page of an audit trail request
+type auditFilter struct {
+	from        *time.Time
+	to          *time.Time
+	commitTypes map[string]bool
+	offset      int
+	limit       int
+}
+
+// parseAuditTime parses a from or to filter, an RFC 3339 timestamp
+func parseAuditTime(name string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, errors.New(name + " must be an RFC 3339 timestamp")
+	}
+	return &parsed, nil
+}
+
+// parseAuditFilter reads the filters and the page of an audit trail request from the query parameters
+func parseAuditFilter(r *http.Request) (filter auditFilter, err error) {
+	query := r.URL.Query()
+	if filter.from, err = parseAuditTime("from", query.Get("from")); err != nil {
+		return
+	}
+	if filter.to, err = parseAuditTime("to", query.Get("to")); err != nil {
+		return
+	}
+	if commitTypes := query.Get("commitType"); commitTypes != "" {
+		filter.commitTypes = make(map[string]bool)
+		for _, commitType := range strings.Split(commitTypes, ",") {
+			filter.commitTypes[strings.TrimSpace(commitType)] = true
+		}
+	}
+	if filter.offset, err = helpers.DecodeCursor(query.Get("cursor")); err != nil {
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		filter.limit, err = strconv.Atoi(limit)
+		if err != nil || filter.limit < 1 {
+			err = errors.New("limit must be a positive number")
+		}
+	}
+	return
+}
+
+// matches reports if an audit entry passes the filters
+func (f auditFilter) matches(entry helpers.AuditEntry) bool {
+	if f.commitTypes != nil && !f.commitTypes[entry.CommitType] {
+		return false
+	}
+	if f.from == nil && f.to == nil {
+		return true
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		return false
+	}
+	if f.from != nil && timestamp.Before(*f.from) {
+		return false
+	}
+	return f.to == nil || timestamp.Before(*f.to)
+}
+
+// page returns the entries passing the filters from the offset on, with the cursor of the next page
+func (f auditFilter) page(entries []helpers.AuditEntry) helpers.AuditTrail {
+	trail := helpers.AuditTrail{History: []helpers.AuditEntry{}}
+	matched := 0
+	for _, entry := range entries {
+		if !f.matches(entry) {
+			continue
+		}
+		matched++
+		if matched <= f.offset {
+			continue
+		}
+		if f.limit > 0 && len(trail.History) == f.limit {
+			trail.NextCursor = helpers.EncodeCursor(f.offset + f.limit)
+			break
+		}
+		trail.History = append(trail.History, entry)
+	}
+	return trail
+}
+
 // AuditAsset is a controller function to perform an audit of an assetID
-// Audits are managed by the agent since it's implementation specific
+// Audits are managed by the agent since it's implementation specific, the trail it returns is adapted into typed entries.
+// Entries can be filtered with from (inclusive) and to (exclusive) RFC 3339 timestamps and a comma separated list of
+// commitType values. Pages of limit entries are followed with the nextCursor passed as cursor
 // No validity checks are performed
-
 func AuditAsset(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Audit Asset")
 	defer span.Finish()
@@ -38,6 +130,12 @@ func AuditAsset(w http.ResponseWriter, r *http.Request) {
 	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
 	requestAgent := r.Context().Value("agent").(agent.Agent)
 
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
 	log.Debug().Msgf("Getting audit trail of %s/%s from agent at %s:%d", assetVars.ChannelID, assetVars.AssetID,
 		requestAgent.GetHost(), requestAgent.GetPort())
 
@@ -47,12 +145,17 @@ func AuditAsset(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
-		if err == helpers.ErrUnauthorized{
+		if err == helpers.ErrUnauthorized {
 			render.Render(w, r, responses.ErrAgentUnauthorized(err))
 		} else {
 			render.Render(w, r, responses.ErrAgent(err))
 		}
 		return
 	}
-	render.JSON(w, r, auditTrail)
+	entries, err := agent.AuditEntries(auditTrail)
+	if err != nil {
+		render.Render(w, r, responses.ErrAgent(err))
+		return
+	}
+	render.JSON(w, r, filter.page(entries))
 }
@@ -51,13 +51,70 @@ func TestAuditWithAssetThatExists(t *testing.T) {
 	handler := http.HandlerFunc(AuditAsset)
 	handler.ServeHTTP(responseRecorder, mockRequest)
 
-	var auditResponse  map[string]interface{}
+	var auditResponse helpers.AuditTrail
 	json.NewDecoder(responseRecorder.Result().Body).Decode(&auditResponse)
 
-	assert.Equal(t,auditFinal, auditResponse,"Retrieved audit trail is received with no changes")
+	assert.Len(t, auditResponse.History, 2, "Retrieved audit trail is received with every entry")
+	assert.Equal(t, "CREATE", auditResponse.History[0].CommitType, "Entries are adapted from the agent")
+	assert.Equal(t, "BX80677I57500", auditResponse.History[0].Asset.AssetModelNumber, "Entries carry the snapshot")
+	assert.Empty(t, auditResponse.NextCursor, "Unpaginated trail has no next page")
 	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
 }
 
+// TestAuditFiltersAndPages contains the tests for the filters and the pagination of the audit trail
+func TestAuditFiltersAndPages(t *testing.T) {
+	getTrail := func(t *testing.T, query string) (int, helpers.AuditTrail) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		var auditFinal map[string]interface{}
+		json.NewDecoder(openTestJSON(auditPath)).Decode(&auditFinal)
+		mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(auditFinal, nil).AnyTimes()
+		mockAgent.EXPECT().GetHost().AnyTimes()
+		mockAgent.EXPECT().GetPort().AnyTimes()
+
+		mockRequest := httptest.NewRequest("GET", "/"+query, nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(AuditAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		var trail helpers.AuditTrail
+		json.NewDecoder(responseRecorder.Body).Decode(&trail)
+		return responseRecorder.Code, trail
+	}
+	t.Run("By_Commit_Type", func(t *testing.T) {
+		code, trail := getTrail(t, "?commitType=ATTACH")
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Len(t, trail.History, 1, "Only matching entries are returned")
+		assert.Equal(t, "ATTACH", trail.History[0].CommitType, "Only matching entries are returned")
+	})
+	t.Run("By_Time", func(t *testing.T) {
+		_, trail := getTrail(t, "?from=2020-08-12T12:33:00Z")
+		assert.Len(t, trail.History, 1, "Entries before from are left out")
+		_, trail = getTrail(t, "?to=2020-08-12T12:33:00Z")
+		assert.Len(t, trail.History, 1, "Entries from to on are left out")
+		assert.Equal(t, "CREATE", trail.History[0].CommitType, "Earlier entry is kept")
+	})
+	t.Run("Paged", func(t *testing.T) {
+		_, first := getTrail(t, "?limit=1")
+		assert.Len(t, first.History, 1, "Page holds limit entries")
+		assert.NotEmpty(t, first.NextCursor, "Next page is linked")
+		_, second := getTrail(t, "?limit=1&cursor="+first.NextCursor)
+		assert.Len(t, second.History, 1, "Next page holds the next entry")
+		assert.Equal(t, "ATTACH", second.History[0].CommitType, "Next page continues the trail")
+		assert.Empty(t, second.NextCursor, "Last page has no next page")
+	})
+	t.Run("Invalid_Parameters", func(t *testing.T) {
+		for _, query := range []string{"?from=yesterday", "?to=1", "?limit=0", "?cursor=bad"} {
+			code, _ := getTrail(t, query)
+			assert.Equal(t, http.StatusBadRequest, code, "Query %s is rejected", query)
+		}
+	})
+}
+
 // TestAuditWithAssetErrorConditions contains the tests that simulate error conditions of the asset
 func TestAuditWithAssetErrorConditions(t *testing.T) {
 	t.Run("Does_Not_Exist", func(t *testing.T) {
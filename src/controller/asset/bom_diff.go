@@ -0,0 +1,225 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// bomNode is a component of an asset tree with the exported record of the component
+type bomNode struct {
+	component helpers.BomComponent
+	asset     *helpers.Asset
+}
+
+// matchKey identifies the components that are interchangeable
+func (n bomNode) matchKey() string {
+	return n.component.Role + "|" + n.component.SubRole + "|" + n.component.AssetType + "|" + n.component.AssetModelNumber
+}
+
+// slotKey identifies the place of a component, a different component in the same place is a substitution
+func (n bomNode) slotKey() string {
+	return n.component.Role + "|" + n.component.SubRole
+}
+
+// buildBOMTree exports the tree of an asset with the links of every component kept
+func buildBOMTree(ctx context.Context, requestAgent agent.Agent, element helpers.AssetElement) (bomNode, render.Renderer) {
+	var root helpers.Asset
+	resultStream, err := queryAssetStream(ctx, requestAgent, agent.QueryArgs{
+		ChannelID: element.ChannelID,
+		AssetID:   element.AssetID,
+	})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&root)
+	}
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			return bomNode{}, responses.ErrDoesNotExist(err)
+		} else if err == helpers.ErrUnauthorized {
+			return bomNode{}, responses.ErrAgentUnauthorized(err)
+		}
+		return bomNode{}, responses.ErrAgent(err)
+	}
+
+	var wg sync.WaitGroup
+	var channel = make(chan exportAsset, 1)
+	var fatalErrors = make(chan error, 1)
+	wg.Add(1)
+	go ExportChildren(context.WithValue(ctx, "exportKeepLinks", true), element.AssetID, root, make([]string, 0),
+		&wg, &channel, &fatalErrors)
+	wg.Wait()
+	close(channel)
+	close(fatalErrors)
+	for err := range fatalErrors {
+		return bomNode{}, responses.ErrFailedExport(err)
+	}
+	exported := <-channel
+	return bomNode{
+		component: helpers.BomComponent{
+			AssetElement:     element,
+			AssetType:        exported.asset.AssetType,
+			AssetModelNumber: exported.asset.AssetModelNumber,
+		},
+		asset: exported.asset,
+	}, nil
+}
+
+// bomChildren returns the attached children of a component, ordered by what they are and then by asset ID
+func bomChildren(parent bomNode) []bomNode {
+	var children []bomNode
+	for _, link := range parent.asset.AttachedChildren {
+		child, ok := parent.asset.Children[link.AssetID]
+		if !ok {
+			continue
+		}
+		children = append(children, bomNode{
+			component: helpers.BomComponent{
+				AssetElement:     helpers.AssetElement{RepoID: link.RepoID, ChannelID: link.ChannelID, AssetID: link.AssetID},
+				Path:             parent.component.Path + "/" + link.Role,
+				Role:             link.Role,
+				SubRole:          link.SubRole,
+				AssetType:        child.AssetType,
+				AssetModelNumber: child.AssetModelNumber,
+			},
+			asset: child,
+		})
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		if children[i].matchKey() != children[j].matchKey() {
+			return children[i].matchKey() < children[j].matchKey()
+		}
+		return children[i].component.AssetID < children[j].component.AssetID
+	})
+	return children
+}
+
+// compareBOM compares the children of two matched components and descends into the children that match
+func compareBOM(diff *helpers.BomDiff, expected bomNode, actual bomNode) {
+	actualChildren := bomChildren(actual)
+	used := make([]bool, len(actualChildren))
+	take := func(matches func(bomNode) bool) (bomNode, bool) {
+		for i, child := range actualChildren {
+			if !used[i] && matches(child) {
+				used[i] = true
+				return child, true
+			}
+		}
+		return bomNode{}, false
+	}
+
+	var unmatched []bomNode
+	for _, expectedChild := range bomChildren(expected) {
+		key := expectedChild.matchKey()
+		if actualChild, ok := take(func(n bomNode) bool { return n.matchKey() == key }); ok {
+			diff.Matched++
+			compareBOM(diff, expectedChild, actualChild)
+		} else {
+			unmatched = append(unmatched, expectedChild)
+		}
+	}
+	for _, expectedChild := range unmatched {
+		slot := expectedChild.slotKey()
+		if actualChild, ok := take(func(n bomNode) bool { return n.slotKey() == slot }); ok {
+			diff.Substituted = append(diff.Substituted, helpers.BomSubstitution{
+				Expected: expectedChild.component,
+				Actual:   actualChild.component,
+			})
+		} else {
+			diff.Missing = append(diff.Missing, expectedChild.component)
+		}
+	}
+	for i, actualChild := range actualChildren {
+		if !used[i] {
+			diff.Extra = append(diff.Extra, actualChild.component)
+		}
+	}
+}
+
+// DiffBOM is a controller function that compares the tree of an asset with the tree of a reference asset,
+// passed as ?repoID=&channelID=&assetID= or as ?uri=dbom://... and possibly in another repo. Both trees are built like an export.
+// Children are matched by role, subRole, assetType and assetModelNumber. Reference children without a match are
+// substituted when the asset has another component in the same role and subRole, and missing otherwise.
+// Children of the asset without a match are extra
+func DiffBOM(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Diff BOM")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	query := r.URL.Query()
+	reference := helpers.AssetElement{
+		RepoID:    query.Get("repoID"),
+		ChannelID: query.Get("channelID"),
+		AssetID:   query.Get("assetID"),
+	}
+	if uri := query.Get("uri"); uri != "" {
+		parsed, err := helpers.ParseAssetURI(uri)
+		if err != nil {
+			render.Render(w, r, responses.ErrInvalidRequest(err))
+			return
+		}
+		reference = parsed
+	}
+	if reference.RepoID == "" || reference.ChannelID == "" || reference.AssetID == "" {
+		render.Render(w, r, responses.ErrInvalidRequest(errors.New("the reference asset must be passed as repoID, channelID and assetID, or as uri")))
+		return
+	}
+	referenceAgent, err := agentForRepo(ctx, reference.RepoID)
+	if err != nil {
+		render.Render(w, r, responses.ErrNoAgent(err))
+		return
+	}
+
+	subject := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID}
+	actual, rejection := buildBOMTree(ctx, requestAgent, subject)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	expected, rejection := buildBOMTree(ctx, referenceAgent, reference)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	diff := helpers.BomDiff{
+		Subject:     subject,
+		Reference:   reference,
+		Missing:     []helpers.BomComponent{},
+		Extra:       []helpers.BomComponent{},
+		Substituted: []helpers.BomSubstitution{},
+	}
+	if expected.matchKey() != actual.matchKey() {
+		diff.Substituted = append(diff.Substituted, helpers.BomSubstitution{Expected: expected.component, Actual: actual.component})
+	}
+	compareBOM(&diff, expected, actual)
+	diff.Identical = len(diff.Missing) == 0 && len(diff.Extra) == 0 && len(diff.Substituted) == 0
+
+	render.JSON(w, r, diff)
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const bomDiffLocation = "../../testdata/asset_controller_tests/bomdiff/"
+
+// expectBOMTree serves the assets of a tree from the bomdiff test data, unknown assets do not exist
+func expectBOMTree(mockAgent *mocks.MockAgent, files map[string]string) {
+	mockAgent.EXPECT().QueryStream(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, args agent.QueryArgs) (io.ReadCloser, error) {
+			file, ok := files[args.AssetID]
+			if !ok {
+				return nil, helpers.ErrNotFound
+			}
+			return openTestJSON(bomDiffLocation + file), nil
+		})
+}
+
+// TestDiffBOM contains the tests for the structural comparison of two asset trees
+func TestDiffBOM(t *testing.T) {
+	reference := map[string]string{
+		"R1": "reference.json", "R2": "referenceCpu.json", "R3": "referenceMemory.json",
+		"R4": "referenceFan.json", "R5": "heatsink.json",
+	}
+	subject := map[string]string{
+		"S1": "subject.json", "S2": "subjectCpu.json", "S3": "subjectMemory.json",
+		"S4": "subjectLight.json", "S5": "heatsink.json",
+	}
+	diffBOM := func(t *testing.T, subjectID string, query string, subjectFiles map[string]string) (int, helpers.BomDiff) {
+		ctrl := gomock.NewController(t)
+		subjectAgent := mocks.NewMockAgent(ctrl)
+		referenceAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectBOMTree(subjectAgent, subjectFiles)
+		expectBOMTree(referenceAgent, reference)
+
+		mockRequest := httptest.NewRequest("GET", "/"+query, nil)
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", subjectID, subjectAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl,
+			map[string]agent.Agent{"T1": subjectAgent, "T2": referenceAgent})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(DiffBOM).ServeHTTP(responseRecorder, mockRequest)
+
+		var result helpers.BomDiff
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		return responseRecorder.Code, result
+	}
+	t.Run("Happy_Path", func(t *testing.T) {
+		code, result := diffBOM(t, "S1", "?repoID=T2&channelID=C1&assetID=R1", subject)
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.False(t, result.Identical, "Trees differ")
+		assert.Equal(t, 2, result.Matched, "Processor and its heatsink match")
+		assert.Len(t, result.Missing, 1, "Fan is missing")
+		assert.Equal(t, "/cooling", result.Missing[0].Path, "Missing component has its path")
+		assert.Equal(t, "R4", result.Missing[0].AssetID, "Missing component is the reference asset")
+		assert.Len(t, result.Extra, 1, "Light is extra")
+		assert.Equal(t, "S4", result.Extra[0].AssetID, "Extra component is the subject asset")
+		assert.Len(t, result.Substituted, 1, "Memory is substituted")
+		assert.Equal(t, "M8", result.Substituted[0].Expected.AssetModelNumber, "Expected model is reported")
+		assert.Equal(t, "M16", result.Substituted[0].Actual.AssetModelNumber, "Actual model is reported")
+		assert.Equal(t, "/memory", result.Substituted[0].Actual.Path, "Substitution has its path")
+	})
+	t.Run("Identical", func(t *testing.T) {
+		code, result := diffBOM(t, "R1", "?repoID=T2&channelID=C1&assetID=R1", reference)
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.True(t, result.Identical, "Tree is identical to itself")
+		assert.Equal(t, 4, result.Matched, "Every component matches")
+		assert.Empty(t, result.Missing, "Nothing is missing")
+	})
+	t.Run("Different_Roots", func(t *testing.T) {
+		code, result := diffBOM(t, "S2", "?repoID=T2&channelID=C1&assetID=R1", subject)
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Equal(t, "", result.Substituted[0].Expected.Path, "Root substitution has an empty path")
+		assert.Equal(t, "Processor", result.Substituted[0].Actual.AssetType, "Root substitution has the subject type")
+	})
+	t.Run("Missing_Reference", func(t *testing.T) {
+		code, _ := diffBOM(t, "S1", "?repoID=T2&channelID=C1", subject)
+		assert.Equal(t, http.StatusBadRequest, code, "Response Should be 400 Bad Request")
+	})
+	t.Run("Reference_Not_Found", func(t *testing.T) {
+		code, _ := diffBOM(t, "S1", "?repoID=T2&channelID=C1&assetID=R9", subject)
+		assert.Equal(t, http.StatusNotFound, code, "Response Should be 404 Not Found")
+	})
+	t.Run("Child_Not_Found", func(t *testing.T) {
+		code, _ := diffBOM(t, "S1", "?repoID=T2&channelID=C1&assetID=R1",
+			map[string]string{"S1": "subject.json", "S2": "subjectCpu.json"})
+		assert.Equal(t, http.StatusBadGateway, code, "Broken tree fails the export")
+	})
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/templateregistry"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// checkConformance checks the children of a component against its template, then every child that has a template itself
+func checkConformance(ctx context.Context, registry templateregistry.TemplateRegistry, result *helpers.Conformance,
+	node bomNode, template templateregistry.BomTemplate) error {
+	children := bomChildren(node)
+	checked := make([]templateregistry.Child, 0, len(children))
+	for _, child := range children {
+		checked = append(checked, templateregistry.Child{
+			Link: helpers.AssetLinkElement{
+				AssetElement: child.component.AssetElement,
+				Role:         child.component.Role,
+				SubRole:      child.component.SubRole,
+			},
+			AssetType: child.component.AssetType,
+		})
+	}
+	result.Checked++
+	result.Deviations = append(result.Deviations, template.Deviations(node.component.Path, checked)...)
+
+	for _, child := range children {
+		childTemplate, err := registry.ForModel(ctx, child.component.AssetType, child.component.AssetModelNumber)
+		if err == helpers.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err = checkConformance(ctx, registry, result, child, childTemplate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTemplatePolicy checks a child about to be attached against the template of the parent, as configured by
+// bomTemplateAttach. Parents without a template take any child. A non nil renderer is returned when the attach must be rejected
+func applyTemplatePolicy(ctx context.Context, r *http.Request, parent helpers.Asset, link helpers.AssetLinkElement,
+	child helpers.Asset) render.Renderer {
+	policy := templateregistry.GetAttachPolicy()
+	registry, ok := r.Context().Value("templateRegistry").(templateregistry.TemplateRegistry)
+	if policy == templateregistry.PolicyOff || !ok {
+		return nil
+	}
+	template, err := registry.ForModel(ctx, parent.AssetType, parent.AssetModelNumber)
+	if err == helpers.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		if policy == templateregistry.PolicyEnforce {
+			return responses.ErrInternalServer(err)
+		}
+		log.Warn().Msgf("Attaching %s unchecked, the template could not be read: %s", link.AssetID, err.Error())
+		return nil
+	}
+
+	err = template.CheckAttach(parent.AttachedChildren, templateregistry.Child{Link: link, AssetType: child.AssetType})
+	if err == nil {
+		return nil
+	}
+	if policy == templateregistry.PolicyEnforce {
+		log.Info().Msgf("Rejecting attach of %s: %s", link.AssetID, err.Error())
+		return responses.ErrTemplateViolation(err)
+	}
+	log.Warn().Msgf("Attaching %s that does not fit the template: %s", link.AssetID, err.Error())
+	return nil
+}
+
+// CheckConformance is a controller function that compares the as-built tree of an asset with the BOM template of its model.
+// Children with a template of their own are checked against it as well, children without one are not descended into
+func CheckConformance(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Check Conformance")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+	registry := r.Context().Value("templateRegistry").(templateregistry.TemplateRegistry)
+
+	subject := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID}
+	root, rejection := buildBOMTree(ctx, requestAgent, subject)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	template, err := registry.ForModel(ctx, root.component.AssetType, root.component.AssetModelNumber)
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrTemplateDoesNotExist(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+
+	result := helpers.Conformance{
+		Asset:      subject,
+		TemplateID: template.TemplateID,
+		Deviations: []helpers.TemplateDeviation{},
+	}
+	if err = checkConformance(ctx, registry, &result, root, template); err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	result.Conforms = len(result.Deviations) == 0
+	render.JSON(w, r, result)
+}
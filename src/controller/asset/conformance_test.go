@@ -0,0 +1,170 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/templateregistry"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// serverTemplate expects the server of the bomdiff test data to have a processor, memory and a fan
+var serverTemplate = templateregistry.BomTemplate{
+	TemplateID:       "server",
+	AssetType:        "Server",
+	AssetModelNumber: "SRV-1",
+	Children: []templateregistry.Slot{
+		{Role: "cpu", AssetTypes: []string{"Processor"}, MinCount: 1, MaxCount: 2},
+		{Role: "memory", AssetTypes: []string{"Memory"}, MinCount: 1},
+		{Role: "cooling", AssetTypes: []string{"Fan"}, MinCount: 1},
+	},
+}
+
+// processorTemplate expects a processor to have exactly one heatsink
+var processorTemplate = templateregistry.BomTemplate{
+	TemplateID:       "processor",
+	AssetType:        "Processor",
+	AssetModelNumber: "X100",
+	Children:         []templateregistry.Slot{{Role: "heatsink", MinCount: 1, MaxCount: 1}},
+}
+
+// newMockTemplateRegistry returns a template registry that finds the templates by model
+func newMockTemplateRegistry(ctrl *gomock.Controller, templates ...templateregistry.BomTemplate) *mocks.MockTemplateRegistry {
+	mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+	mockRegistry.EXPECT().ForModel(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, assetType string, assetModelNumber string) (templateregistry.BomTemplate, error) {
+			for _, template := range templates {
+				if template.AssetType == assetType && template.AssetModelNumber == assetModelNumber {
+					return template, nil
+				}
+			}
+			return templateregistry.BomTemplate{}, helpers.ErrNotFound
+		})
+	return mockRegistry
+}
+
+// TestCheckConformance contains the tests for checking an asset tree against its templates
+func TestCheckConformance(t *testing.T) {
+	conformance := func(t *testing.T, assetID string, templates ...templateregistry.BomTemplate) (int, helpers.Conformance) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectBOMTree(mockAgent, map[string]string{
+			"S1": "subject.json", "S2": "subjectCpu.json", "S3": "subjectMemory.json",
+			"S4": "subjectLight.json", "S5": "heatsink.json",
+		})
+
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", assetID, mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "templateRegistry",
+			newMockTemplateRegistry(ctrl, templates...)))
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CheckConformance).ServeHTTP(responseRecorder, mockRequest)
+
+		var result helpers.Conformance
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		return responseRecorder.Code, result
+	}
+	t.Run("Deviations", func(t *testing.T) {
+		code, result := conformance(t, "S1", serverTemplate, processorTemplate)
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.False(t, result.Conforms, "Server does not conform")
+		assert.Equal(t, "server", result.TemplateID, "Template of the root is reported")
+		assert.Equal(t, 2, result.Checked, "Server and processor are checked")
+		assert.Len(t, result.Deviations, 2, "Light and fan deviate")
+		assert.Equal(t, templateregistry.DeviationUnexpectedRole, result.Deviations[0].Kind, "Light is not in the template")
+		assert.Equal(t, "S4", result.Deviations[0].Asset.AssetID, "Deviating child is reported")
+		assert.Equal(t, templateregistry.DeviationMissing, result.Deviations[1].Kind, "Fan is missing")
+		assert.Equal(t, "/cooling", result.Deviations[1].Path, "Missing slot has its path")
+	})
+	t.Run("Nested_Deviation", func(t *testing.T) {
+		strict := processorTemplate
+		strict.Children = []templateregistry.Slot{{Role: "heatsink", AssetTypes: []string{"Fan"}, MinCount: 1}}
+		code, result := conformance(t, "S2", strict)
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Equal(t, templateregistry.DeviationUnexpectedType, result.Deviations[0].Kind, "Heatsink is not a fan")
+	})
+	t.Run("Conforms", func(t *testing.T) {
+		code, result := conformance(t, "S2", processorTemplate)
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.True(t, result.Conforms, "Processor conforms")
+		assert.Empty(t, result.Deviations, "Nothing deviates")
+	})
+	t.Run("No_Template", func(t *testing.T) {
+		code, _ := conformance(t, "S1", processorTemplate)
+		assert.Equal(t, http.StatusNotFound, code, "Response Should be 404 Not Found")
+	})
+}
+
+// TestAttachTemplatePolicy contains the tests for checking attaches against the template of the parent
+func TestAttachTemplatePolicy(t *testing.T) {
+	defer viper.Set("bomTemplateAttach", nil)
+	parentTemplate := templateregistry.BomTemplate{
+		TemplateID:       "component",
+		AssetType:        "HardwareComponent",
+		AssetModelNumber: "A Valid ModelNumber",
+		Children:         []templateregistry.Slot{{Role: "Another_Role"}},
+	}
+	attach := func(t *testing.T, policy string) int {
+		viper.Set("bomTemplateAttach", policy)
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		mockAgent.EXPECT().GetHost().AnyTimes()
+		mockAgent.EXPECT().GetPort().AnyTimes()
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(attachParentLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(openTestJSON(attachChildLocation), nil)
+		mockAgent.EXPECT().Commit(gomock.Any(), gomock.Any()).Return(getAgentSuccessResponse(), nil).AnyTimes()
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(attachRequestLocation))
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "templateRegistry",
+			newMockTemplateRegistry(ctrl, parentTemplate)))
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(AttachSubasset).ServeHTTP(responseRecorder, mockRequest)
+		return responseRecorder.Code
+	}
+	t.Run("Enforce", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnprocessableEntity, attach(t, "enforce"), "Child in a role outside the template is rejected")
+	})
+	t.Run("Warn", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, attach(t, "warn"), "Child is attached with a warning")
+	})
+	t.Run("Off", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, attach(t, "off"), "Template is not looked at")
+	})
+}
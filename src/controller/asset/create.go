@@ -63,6 +63,15 @@ func CreateAsset(w http.ResponseWriter, r *http.Request) {
 	requestAsset.AttachedChildren = []helpers.AssetLinkElement{}
 	requestAsset.ParentAsset = &helpers.AssetLinkElement{}
 
+	if rejection := applySignaturePolicy(ctx, r, assetVars, &requestAsset); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	if rejection := applyDuplicatePolicy(ctx, assetVars, requestAsset); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
 	// Commit
 	res, err := requestAgent.Commit(ctx, agent.CommitArgs{
 		ChannelID:  assetVars.ChannelID,
@@ -83,6 +92,8 @@ func CreateAsset(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info().Interface("agentResponse", res).Msg("Creation on agent successful")
+	indexAliases(ctx, helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID,
+		AssetID: assetVars.AssetID}, requestAsset)
 
 	render.Render(w, r, responses.SuccessfulCreationResponse())
 
@@ -126,6 +126,16 @@ func DetachSubasset(w http.ResponseWriter, r *http.Request) {
 		childSpan.Finish()
 		return
 	}
+	if rejection := pendingTransferConflict(requestAsset, "the parent asset"); rejection != nil {
+		render.Render(w, r, rejection)
+		childSpan.Finish()
+		return
+	}
+	if rejection := pendingTransferConflict(childAsset, "the child asset"); rejection != nil {
+		render.Render(w, r, rejection)
+		childSpan.Finish()
+		return
+	}
 	if requestAsset.AttachedChildren == nil {
 		err = errors.New("no subassets")
 		tracing.LogAndTraceErr(log, childSpan, err, "Invalid detach operation")
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// linkName returns the location of a link in the form repo/channel/asset
+func linkName(link helpers.AssetLinkElement) string {
+	return elementKey(link.AssetElement)
+}
+
+// linkIdentity identifies a link by where it points and the role it has
+func linkIdentity(link helpers.AssetLinkElement) string {
+	return linkName(link) + "#" + link.Role + "#" + link.SubRole
+}
+
+// presentParent returns the parent of an asset, agents report a missing parent as an empty link
+func presentParent(asset helpers.Asset) *helpers.AssetLinkElement {
+	if asset.ParentAsset == nil || asset.ParentAsset.AssetID == "" {
+		return nil
+	}
+	return asset.ParentAsset
+}
+
+// diffLinks returns the links attached, detached and changed between two states of an asset, and their description
+func diffLinks(from helpers.Asset, to helpers.Asset) (helpers.LinkChanges, []string) {
+	changes := helpers.LinkChanges{
+		AttachedChildren: helpers.ChildLinkChanges{
+			Added:   []helpers.AssetLinkElement{},
+			Removed: []helpers.AssetLinkElement{},
+		},
+	}
+	var described []string
+
+	fromChildren := make(map[string]bool)
+	for _, child := range from.AttachedChildren {
+		fromChildren[linkIdentity(child)] = true
+	}
+	toChildren := make(map[string]bool)
+	for _, child := range to.AttachedChildren {
+		toChildren[linkIdentity(child)] = true
+		if !fromChildren[linkIdentity(child)] {
+			changes.AttachedChildren.Added = append(changes.AttachedChildren.Added, child)
+			described = append(described, "attached child "+linkName(child)+" as "+child.Role)
+		}
+	}
+	for _, child := range from.AttachedChildren {
+		if !toChildren[linkIdentity(child)] {
+			changes.AttachedChildren.Removed = append(changes.AttachedChildren.Removed, child)
+			described = append(described, "detached child "+linkName(child))
+		}
+	}
+
+	fromParent, toParent := presentParent(from), presentParent(to)
+	switch {
+	case fromParent == nil && toParent == nil:
+		return changes, described
+	case fromParent == nil:
+		described = append(described, "attached to parent "+linkName(*toParent))
+	case toParent == nil:
+		described = append(described, "detached from parent "+linkName(*fromParent))
+	case linkIdentity(*fromParent) != linkIdentity(*toParent):
+		described = append(described, "parent changed from "+linkName(*fromParent)+" to "+linkName(*toParent))
+	default:
+		return changes, described
+	}
+	changes.ParentAsset = &helpers.ParentLinkChange{From: fromParent, To: toParent}
+	return changes, described
+}
+
+// isLinkPath reports if a JSON Patch path is inside the links of an asset, which are described by diffLinks
+func isLinkPath(path string) bool {
+	return strings.HasPrefix(path, "/attachedChildren") || strings.HasPrefix(path, "/parentAsset")
+}
+
+// parseRevision reads a revision query parameter, fallback is returned when it is not set
+func parseRevision(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		if fallback > 0 {
+			return fallback, nil
+		}
+		return 0, errors.New(name + " is required")
+	}
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, errors.New(name + " must be a positive revision number")
+	}
+	return revision, nil
+}
+
+// DiffAsset is a controller function that returns the changes to an asset between two revisions of its audit trail
+// from is required, to defaults to the latest revision. The changes are returned as a JSON Patch (RFC 6902) and in
+// human readable form, with the children attached and detached and the change of parent listed separately
+func DiffAsset(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Diff Asset")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	fromRevision, err := parseRevision(r, "from", 0)
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	entries, err := auditEntries(ctx, requestAgent, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+	toRevision, err := parseRevision(r, "to", len(entries))
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	from, _, err := entryAt(entries, historyPoint{revision: fromRevision})
+	if err != nil {
+		render.Render(w, r, responses.ErrDoesNotExist(errors.New("revision "+strconv.Itoa(fromRevision)+" does not exist")))
+		return
+	}
+	to, _, err := entryAt(entries, historyPoint{revision: toRevision})
+	if err != nil {
+		render.Render(w, r, responses.ErrDoesNotExist(errors.New("revision "+strconv.Itoa(toRevision)+" does not exist")))
+		return
+	}
+
+	patch, err := helpers.JSONPatch(from, to)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	diff := helpers.AssetDiff{From: fromRevision, To: toRevision, Patch: patch, Changes: []string{}}
+	for _, op := range patch {
+		if !isLinkPath(op.Path) {
+			diff.Changes = append(diff.Changes, op.Describe())
+		}
+	}
+	var linkChanges []string
+	diff.Links, linkChanges = diffLinks(from, to)
+	diff.Changes = append(diff.Changes, linkChanges...)
+
+	render.JSON(w, r, diff)
+}
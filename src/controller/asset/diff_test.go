@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiffAsset contains the tests for the changes to an asset between revisions
+func TestDiffAsset(t *testing.T) {
+	diff := func(t *testing.T, assetID string, trailLocation string, query string) (int, helpers.AssetDiff, map[string]interface{}) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", assetID)).
+			Return(openTestTrail(trailLocation), nil).AnyTimes()
+
+		mockRequest := httptest.NewRequest("GET", "/"+query, nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", assetID, mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(DiffAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		var result helpers.AssetDiff
+		var raw map[string]interface{}
+		body := responseRecorder.Body.Bytes()
+		json.Unmarshal(body, &result)
+		json.Unmarshal(body, &raw)
+		return responseRecorder.Code, result, raw
+	}
+	t.Run("Child_Attached", func(t *testing.T) {
+		code, result, raw := diff(t, "A1", assemblyTrailLocation, "?from=1")
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Equal(t, 3, result.To, "to defaults to the latest revision")
+		assert.Equal(t, []string{
+			`assetDescription changed from "Assembly" to "Assembly, shipped"`,
+			"attached child T1/C1/B1 as component",
+		}, result.Changes, "Changes are described")
+		assert.Len(t, result.Links.AttachedChildren.Added, 1, "Attached child is listed")
+		assert.Nil(t, result.Links.ParentAsset, "Parent is unchanged")
+		patch := raw["patch"].([]interface{})
+		assert.Equal(t, map[string]interface{}{"op": "replace", "path": "/assetDescription", "value": "Assembly, shipped"},
+			patch[0], "Field change is a JSON Patch operation")
+		assert.Equal(t, "/attachedChildren", patch[1].(map[string]interface{})["path"], "Link change is in the JSON Patch")
+	})
+	t.Run("Parent_Attached", func(t *testing.T) {
+		code, result, _ := diff(t, "B1", boardTrailLocation, "?from=1&to=3")
+
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Contains(t, result.Changes, "attached to parent T1/C1/A1", "Parent change is described")
+		assert.Nil(t, result.Links.ParentAsset.From, "There was no parent")
+		assert.Equal(t, "A1", result.Links.ParentAsset.To.AssetID, "New parent is listed")
+	})
+	t.Run("Reverse", func(t *testing.T) {
+		_, result, _ := diff(t, "A1", assemblyTrailLocation, "?from=3&to=1")
+		assert.Contains(t, result.Changes, "detached child T1/C1/B1", "Detached child is described")
+		assert.Len(t, result.Links.AttachedChildren.Removed, 1, "Detached child is listed")
+	})
+	t.Run("Unknown_Revision", func(t *testing.T) {
+		code, _, _ := diff(t, "A1", assemblyTrailLocation, "?from=1&to=7")
+		assert.Equal(t, http.StatusNotFound, code, "Response Should be 404 NOT FOUND")
+	})
+	t.Run("Invalid_Parameters", func(t *testing.T) {
+		for _, query := range []string{"", "?from=first", "?from=1&to=0"} {
+			code, _, _ := diff(t, "A1", assemblyTrailLocation, query)
+			assert.Equal(t, http.StatusBadRequest, code, "Query %q is rejected", query)
+		}
+	})
+}
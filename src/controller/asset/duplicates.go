@@ -0,0 +1,145 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/identity"
+	"chainsource-gateway/responses"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// findIdentity searches every configured repo for the assets that have the identity values, other than the asset itself.
+// Read only assets are the origins of custody transfers, they are the same asset as the destination and are skipped
+func findIdentity(ctx context.Context, self helpers.AssetElement, values map[string]interface{}) ([]helpers.AssetElement,
+	[]helpers.TargetError, error) {
+	repoIDs, err := agent.GetConfiguredRepos()
+	if err != nil {
+		return nil, nil, err
+	}
+	query := make(map[string]interface{}, len(values))
+	for field, value := range values {
+		query[field] = value
+	}
+	var holders []helpers.AssetElement
+	var targetErrors []helpers.TargetError
+	for _, repoID := range repoIDs {
+		targetErrors = append(targetErrors, queryRepo(ctx, repoID, query, func(element helpers.AssetElement, asset helpers.Asset) {
+			if asset.ReadOnly {
+				return
+			}
+			if element.RepoID != self.RepoID || element.ChannelID != self.ChannelID ||
+				element.AssetID != self.AssetID {
+				holders = append(holders, element)
+			}
+		})...)
+	}
+	return holders, targetErrors, nil
+}
+
+// applyDuplicatePolicy checks that no other asset in any repo has the identity of an asset that is created.
+// In warn mode duplicates are logged, in reject mode they are rejected, and so are creates that could not be checked
+func applyDuplicatePolicy(ctx context.Context, assetVars helpers.AssetRoutingVars, asset helpers.Asset) render.Renderer {
+	mode := identity.GetCreateMode()
+	if mode == identity.ModeOff {
+		return nil
+	}
+	failed := func(err error) render.Renderer {
+		if mode == identity.ModeReject {
+			return responses.ErrInternalServer(err)
+		}
+		log.Warn().Msgf("Creating %s unchecked for duplicates: %s", assetVars.AssetID, err.Error())
+		return nil
+	}
+	fields, err := identity.GetFields()
+	if err != nil {
+		return failed(err)
+	}
+	values, ok := identity.Of(asset, fields)
+	if !ok {
+		return nil
+	}
+
+	self := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID}
+	holders, targetErrors, err := findIdentity(ctx, self, values)
+	if err != nil {
+		return failed(err)
+	}
+	if len(holders) > 0 {
+		held := make([]string, len(holders))
+		for i, holder := range holders {
+			held[i] = elementKey(holder)
+		}
+		err = fmt.Errorf("identity %v is already registered as %s", values, strings.Join(held, ", "))
+		if mode == identity.ModeReject {
+			log.Info().Msgf("Rejecting create of %s: %s", assetVars.AssetID, err.Error())
+			return responses.ErrDuplicateIdentity(err)
+		}
+		log.Warn().Msgf("Creating %s with a duplicate identity: %s", assetVars.AssetID, err.Error())
+		return nil
+	}
+	if len(targetErrors) > 0 && mode == identity.ModeReject {
+		return responses.ErrAgent(fmt.Errorf("identity could not be checked in %s/%s: %s",
+			targetErrors[0].RepoID, targetErrors[0].ChannelID, targetErrors[0].Error))
+	}
+	return nil
+}
+
+// FindDuplicates is a controller function that indexes the identity fields of the assets in every channel of every
+// configured repo and reports the identities held by more than one asset. Assets without every identity field are
+// not indexed, nor are read only assets, which were transferred to the asset that holds the identity now. Repos and
+// channels that cannot be searched are reported, not fatal
+func FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Find Duplicates")
+	defer span.Finish()
+
+	fields, err := identity.GetFields()
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	repoIDs, err := agent.GetConfiguredRepos()
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+
+	query := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		query[field] = map[string]interface{}{"$exists": true}
+	}
+	index := identity.NewIndex(fields)
+	result := helpers.DuplicateReport{Fields: fields, Errors: []helpers.TargetError{}}
+	for _, repoID := range repoIDs {
+		result.Errors = append(result.Errors, queryRepo(ctx, repoID, query, func(element helpers.AssetElement, asset helpers.Asset) {
+			if !asset.ReadOnly {
+				index.Add(element, asset)
+			}
+		})...)
+	}
+	result.Indexed = index.Indexed()
+	result.Duplicates = index.Duplicates()
+	render.JSON(w, r, result)
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const duplicatesLocation = "../../testdata/asset_controller_tests/duplicates/"
+
+// openDuplicatesQueryResult opens a query result of the duplicates test data
+func openDuplicatesQueryResult(file string) map[string]interface{} {
+	var result map[string]interface{}
+	json.NewDecoder(openTestJSON(duplicatesLocation + file)).Decode(&result)
+	return result
+}
+
+// setDuplicateIdentity configures the identity fields, the create check and two repos, returning a function that
+// resets them
+func setDuplicateIdentity(onCreate string) func() {
+	viper.Set("agents", map[string]agent.Config{
+		"T1": {Enabled: true},
+		"T2": {Enabled: true},
+	})
+	viper.Set("duplicateIdentity.fields", []string{"assetManufacturer", "assetMetadata.serialNumber"})
+	viper.Set("duplicateIdentity.onCreate", onCreate)
+	return func() {
+		viper.Set("agents", nil)
+		viper.Set("duplicateIdentity.fields", nil)
+		viper.Set("duplicateIdentity.onCreate", nil)
+	}
+}
+
+// TestFindDuplicates contains the tests for reporting identities held by more than one asset
+func TestFindDuplicates(t *testing.T) {
+	t.Run("Happy_Path", func(t *testing.T) {
+		defer setDuplicateIdentity("off")()
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		selector := agent.RichQueryArgs{Query: map[string]interface{}{
+			"assetManufacturer":          map[string]interface{}{"$exists": true},
+			"assetMetadata.serialNumber": map[string]interface{}{"$exists": true},
+		}}
+
+		agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		agentT1.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, selector).
+			Return(openDuplicatesQueryResult("queryT1C1.json"), nil)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1","C2"]`)), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, selector).
+			Return(openDuplicatesQueryResult("queryT2C1.json"), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C2"}, selector).
+			Return(nil, errors.New("agent is down"))
+
+		mockRequest := mocks.InjectAgentProviderIntoRequest(httptest.NewRequest("GET", "/", nil), ctrl,
+			map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(FindDuplicates).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var result helpers.DuplicateReport
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		assert.Equal(t, 3, result.Indexed, "Assets with every identity field are indexed")
+		assert.Len(t, result.Duplicates, 1, "Serial number registered twice is reported")
+		assert.Equal(t, "SN-1", result.Duplicates[0].Identity["assetMetadata.serialNumber"], "Identity is reported")
+		assert.Equal(t, []helpers.AssetElement{{RepoID: "T1", ChannelID: "C1", AssetID: "A1"},
+			{RepoID: "T2", ChannelID: "C1", AssetID: "B1"}}, result.Duplicates[0].Assets, "Both holders are reported")
+		assert.Len(t, result.Errors, 1, "Channel that could not be searched is reported")
+		assert.Equal(t, "C2", result.Errors[0].ChannelID, "Failed channel is reported")
+	})
+	t.Run("Transferred_Asset", func(t *testing.T) {
+		defer setDuplicateIdentity("off")()
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		// A1 was transferred to B1, the read only origin is the same asset
+		agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		agentT1.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, gomock.Any()).
+			Return(openDuplicatesQueryResult("queryTransferred.json"), nil)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, gomock.Any()).
+			Return(openDuplicatesQueryResult("queryT2C1.json"), nil)
+
+		mockRequest := mocks.InjectAgentProviderIntoRequest(httptest.NewRequest("GET", "/", nil), ctrl,
+			map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(FindDuplicates).ServeHTTP(responseRecorder, mockRequest)
+
+		var result helpers.DuplicateReport
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		assert.Equal(t, 1, result.Indexed, "Read only origin is not indexed")
+		assert.Empty(t, result.Duplicates, "Transferred asset is not a duplicate")
+	})
+	t.Run("No_Fields", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(FindDuplicates).ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code, "Response Should be 500 Internal Server Error")
+	})
+}
+
+// TestCreateDuplicateIdentity contains the tests for checking creates for an identity that already exists
+func TestCreateDuplicateIdentity(t *testing.T) {
+	serialQuery := agent.RichQueryArgs{Query: map[string]interface{}{
+		"assetManufacturer":          "Acme",
+		"assetMetadata.serialNumber": "SN-1",
+	}}
+	create := func(t *testing.T, ctrl *gomock.Controller, mockAgent *mocks.MockAgent, agents map[string]agent.Agent) int {
+		mockAgent.EXPECT().GetHost().AnyTimes()
+		mockAgent.EXPECT().GetPort().AnyTimes()
+		mockRequest := injectMockAssetContext(httptest.NewRequest("POST", "/", openTestJSON(duplicatesLocation+"newAsset.json")),
+			"T1", "C1", "A9", mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, agents)
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateAsset).ServeHTTP(responseRecorder, mockRequest)
+		return responseRecorder.Code
+	}
+	expectSearch := func(mockAgent *mocks.MockAgent, file string) {
+		mockAgent.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		mockAgent.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, serialQuery).
+			Return(openDuplicatesQueryResult(file), nil)
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		defer setDuplicateIdentity("reject")()
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectSearch(agentT1, "queryT1C1.json")
+		expectSearch(agentT2, "queryT2C1.json")
+
+		code := create(t, ctrl, agentT1, map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		assert.Equal(t, http.StatusConflict, code, "Response Should be 409 Conflict")
+	})
+	t.Run("Warn", func(t *testing.T) {
+		defer setDuplicateIdentity("warn")()
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectSearch(agentT1, "queryT1C1.json")
+		expectSearch(agentT2, "queryT2C1.json")
+		agentT1.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A9", "CREATE")).Return(getAgentSuccessResponse(), nil)
+
+		code := create(t, ctrl, agentT1, map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		assert.Equal(t, http.StatusCreated, code, "Response Should be 201 CREATED")
+	})
+	t.Run("Only_Itself", func(t *testing.T) {
+		defer setDuplicateIdentity("reject")()
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectSearch(agentT1, "querySelf.json")
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`[]`)), nil)
+		agentT1.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A9", "CREATE")).Return(getAgentSuccessResponse(), nil)
+
+		code := create(t, ctrl, agentT1, map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		assert.Equal(t, http.StatusCreated, code, "Response Should be 201 CREATED")
+	})
+	t.Run("Only_Transferred_Origin", func(t *testing.T) {
+		defer setDuplicateIdentity("reject")()
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectSearch(agentT1, "queryTransferred.json")
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`[]`)), nil)
+		agentT1.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A9", "CREATE")).Return(getAgentSuccessResponse(), nil)
+
+		code := create(t, ctrl, agentT1, map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		assert.Equal(t, http.StatusCreated, code, "Response Should be 201 CREATED")
+	})
+	t.Run("Search_Failure", func(t *testing.T) {
+		defer setDuplicateIdentity("reject")()
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`[]`)), nil)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(nil, errors.New("agent is down"))
+
+		code := create(t, ctrl, agentT1, map[string]agent.Agent{"T1": agentT1, "T2": agentT2})
+		assert.Equal(t, http.StatusBadGateway, code, "Response Should be 502 Bad Gateway")
+	})
+}
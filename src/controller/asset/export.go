@@ -38,6 +38,8 @@ type exportAsset struct {
 }
 
 // ExportAsset exports a DBoM asset as JSON
+// With asOf, an RFC 3339 timestamp, the asset, its children and its parents are exported as they were at that moment
+// With Accept: application/x-ndjson the assets are streamed one per line as they are read, see streamExport
 func ExportAsset(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Export Asset")
 	defer span.Finish()
@@ -47,8 +49,19 @@ func ExportAsset(w http.ResponseWriter, r *http.Request) {
 	log.Debug().Msgf("Getting %s/%s from agent at %s:%d", assetVars.ChannelID, assetVars.AssetID,
 		requestAgent.GetHost(), requestAgent.GetPort())
 
+	point, err := parseHistoryPoint(r)
+	if err == nil && point.revision > 0 {
+		err = errors.New("revision applies to a single asset, export with asOf")
+	}
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	// The subtree is rebuilt with the state of every asset at asOf
+	ctx = withHistoryPoint(ctx, point)
+
 	var result helpers.Asset
-	resultStream, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+	resultStream, err := queryAssetStream(ctx, requestAgent, agent.QueryArgs{
 		ChannelID: assetVars.ChannelID,
 		AssetID:   assetVars.AssetID,
 	})
@@ -67,6 +80,11 @@ func ExportAsset(w http.ResponseWriter, r *http.Request) {
 		render.Render(w, r, responses.ErrInternalServer(err))
 		return
 	}
+	if helpers.WantsNDJSON(r) {
+		root := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID}
+		streamExport(ctx, w, exportVars, root, result)
+		return
+	}
 
 	var wg sync.WaitGroup
 	var channel = make(chan exportAsset, 1)
@@ -104,6 +122,10 @@ func ExportAsset(w http.ResponseWriter, r *http.Request) {
 		parent := parents[assetVars.AssetID].Parent
 		children[assetVars.AssetID].Parent = parent
 	}
+	active := activeAdvisories(ctx)
+	for _, exported := range children {
+		annotateAdvisories(exported, active)
+	}
 
 	result.AttachedChildren = nil
 
@@ -118,6 +140,14 @@ func ExportAsset(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// reportExportError keeps the first error of an export, later errors are dropped so no goroutine blocks on the channel
+func reportExportError(fatalErrors *chan error, err error) {
+	select {
+	case (*fatalErrors) <- err:
+	default:
+	}
+}
+
 //ExportChildren recursively populates the children of the asset
 func ExportChildren(ctx context.Context, assetID string, asset helpers.Asset, seenAssets []string, parentWG *sync.WaitGroup, parentChan *chan exportAsset, fatalErrors *chan error) {
 	agentProvider := ctx.Value("agentProvider").(agent.Provider)
@@ -134,26 +164,30 @@ func ExportChildren(ctx context.Context, assetID string, asset helpers.Asset, se
 			if Find(seenAssets, child.AssetID) {
 				log.Error().Msg("Parent Child Loop Detected for asset " + assetID)
 				err := errors.New("Parent Child Loop Detected for asset ")
-				(*fatalErrors) <- err
+				wg.Wait()
+				reportExportError(fatalErrors, err)
 				(*parentWG).Done()
 				return
 			}
 			var result2 helpers.Asset
 			agentConfig, err := agentProvider.GetAgentConfigForRepo(child.RepoID)
 			childAgent := agentProvider.NewAgent(&agentConfig)
-			resultStream2, err := childAgent.QueryStream(ctx, agent.QueryArgs{
+			resultStream2, err := queryAssetStream(ctx, childAgent, agent.QueryArgs{
 				ChannelID: child.ChannelID,
 				AssetID:   child.AssetID,
 			})
 			if err != nil {
-				(*fatalErrors) <- err
+				// Children already started still report, the export closes the channels once this one is done
+				wg.Wait()
+				reportExportError(fatalErrors, err)
 				(*parentWG).Done()
 				return
 			}
 			err = json.NewDecoder(resultStream2).Decode(&result2)
 			if err != nil {
+				wg.Wait()
 				(*parentChan) <- exAsset
-				(*fatalErrors) <- err
+				reportExportError(fatalErrors, err)
 				(*parentWG).Done()
 				return
 			}
@@ -166,8 +200,11 @@ func ExportChildren(ctx context.Context, assetID string, asset helpers.Asset, se
 			children[child.id] = child.asset
 		}
 	}
-	asset.AttachedChildren = nil
-	asset.ParentAsset = nil
+	// Trees compared by structure keep the links, they hold the role of each child
+	if keepLinks, _ := ctx.Value("exportKeepLinks").(bool); !keepLinks {
+		asset.AttachedChildren = nil
+		asset.ParentAsset = nil
+	}
 	asset.Children = children
 	exAsset.id = assetID
 	exAsset.asset = &asset
@@ -196,7 +233,7 @@ func ExportParent(ctx context.Context, assetID string, asset helpers.Asset, seen
 		var result2 helpers.Asset
 		agentConfig, err := agentProvider.GetAgentConfigForRepo((*asset.ParentAsset).RepoID)
 		parentAgent := agentProvider.NewAgent(&agentConfig)
-		resultStream2, err := parentAgent.QueryStream(ctx, agent.QueryArgs{
+		resultStream2, err := queryAssetStream(ctx, parentAgent, agent.QueryArgs{
 			ChannelID: (*asset.ParentAsset).ChannelID,
 			AssetID:   (*asset.ParentAsset).AssetID,
 		})
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// exportLevel is a type representing an asset of a streamed export whose children are still to be read
+type exportLevel struct {
+	asset helpers.Asset
+	depth int
+}
+
+// readExportAsset reads an asset linked from an exported asset, as it was at the moment of the export
+func readExportAsset(ctx context.Context, element helpers.AssetElement) (asset helpers.Asset, err error) {
+	linkedAgent, err := agentForRepo(ctx, element.RepoID)
+	if err != nil {
+		return
+	}
+	resultStream, err := queryAssetStream(ctx, linkedAgent, agent.QueryArgs{
+		ChannelID: element.ChannelID,
+		AssetID:   element.AssetID,
+	})
+	if err != nil {
+		return
+	}
+	defer resultStream.Close()
+	err = json.NewDecoder(resultStream).Decode(&asset)
+	return
+}
+
+// streamExport writes the exported asset, then its ancestors nearest first, then its descendants breadth first, one per
+// line as they are read. The assets keep their links, so the tree can be rebuilt from the lines. Failures after the
+// first line end the stream with an error line
+func streamExport(ctx context.Context, w http.ResponseWriter, exportVars helpers.ExportRoutingVars,
+	root helpers.AssetElement, rootAsset helpers.Asset) {
+	if exportVars.InlineResponse != "true" {
+		fileName := exportVars.FileName
+		if fileName == "" {
+			fileName = root.AssetID + ".ndjson"
+		}
+		w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	}
+
+	active := activeAdvisories(ctx)
+	lines := helpers.NewNDJSONWriter(w)
+	write := func(element helpers.AssetElement, depth int, asset helpers.Asset) error {
+		annotateAdvisories(&asset, active)
+		return lines.Write(helpers.ExportRecord{RepoID: element.RepoID, ChannelID: element.ChannelID,
+			AssetID: element.AssetID, Depth: depth, Asset: asset})
+	}
+	seen := map[string]bool{elementKey(root): true}
+	// visit reads a linked asset once, a second visit is a loop in the links
+	visit := func(element helpers.AssetElement) (helpers.Asset, error) {
+		if seen[elementKey(element)] {
+			return helpers.Asset{}, fmt.Errorf("Parent Child Loop Detected for asset %s", elementKey(element))
+		}
+		seen[elementKey(element)] = true
+		return readExportAsset(ctx, element)
+	}
+	if write(root, 0, rootAsset) != nil {
+		return
+	}
+
+	current := rootAsset
+	for depth := -1; current.ParentAsset != nil && current.ParentAsset.AssetID != ""; depth-- {
+		element := helpers.AssetElement{RepoID: current.ParentAsset.RepoID, ChannelID: current.ParentAsset.ChannelID,
+			AssetID: current.ParentAsset.AssetID}
+		parent, err := visit(element)
+		if err != nil {
+			lines.WriteError(err)
+			return
+		}
+		if write(element, depth, parent) != nil {
+			return
+		}
+		current = parent
+	}
+
+	queue := []exportLevel{{asset: rootAsset}}
+	for len(queue) > 0 {
+		level := queue[0]
+		queue = queue[1:]
+		for _, child := range level.asset.AttachedChildren {
+			element := helpers.AssetElement{RepoID: child.RepoID, ChannelID: child.ChannelID, AssetID: child.AssetID}
+			childAsset, err := visit(element)
+			if err != nil {
+				lines.WriteError(err)
+				return
+			}
+			if write(element, level.depth+1, childAsset) != nil {
+				return
+			}
+			queue = append(queue, exportLevel{asset: childAsset, depth: level.depth + 1})
+		}
+	}
+}
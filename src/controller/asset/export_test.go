@@ -22,6 +22,7 @@ import (
 	"chainsource-gateway/helpers"
 	"chainsource-gateway/mocks"
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -581,3 +582,58 @@ func TestExportWithAgentErrorConditions(t *testing.T) {
 		assert.Equal(t, http.StatusBadGateway, responseRecorder.Result().StatusCode, "Response Should be 502 BAD GATEWAY")
 	})
 }
+
+// TestExportNDJSON contains the tests for streaming an export one asset per line
+func TestExportNDJSON(t *testing.T) {
+	export := func(t *testing.T, ctrl *gomock.Controller, files map[string]string) []string {
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), gomock.Any()).AnyTimes().
+			DoAndReturn(func(_ context.Context, args agent.QueryArgs) (io.ReadCloser, error) {
+				return openTestJSON(files[args.AssetID]), nil
+			})
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		mockRequest.Header.Set("Accept", helpers.ContentTypeNDJSON)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		mockRequest = injectNullExportContext(mockRequest)
+		http.HandlerFunc(ExportAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+		assert.Equal(t, "attachment; filename=A1.ndjson", responseRecorder.Header().Get("Content-Disposition"),
+			"Stream is a file")
+		return strings.Split(strings.TrimSuffix(responseRecorder.Body.String(), "\n"), "\n")
+	}
+
+	t.Run("Tree", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		lines := export(t, ctrl, map[string]string{"A0": assetL0Location, "A1": assetL1Location, "A2": assetL2A1Location,
+			"A3": assetL2A2Location})
+		var records []helpers.ExportRecord
+		for _, line := range lines {
+			var record helpers.ExportRecord
+			assert.NoError(t, json.Unmarshal([]byte(line), &record), "Line is a record")
+			records = append(records, record)
+		}
+		assert.Len(t, records, 4, "Asset, parent and two children")
+		for i, expected := range []struct {
+			location string
+			depth    int
+		}{{"C1/A1", 0}, {"C0/A0", -1}, {"C2/A2", 1}, {"C3/A3", 1}} {
+			assert.Equal(t, expected.location, records[i].ChannelID+"/"+records[i].AssetID, "Line %d is in stream order", i)
+			assert.Equal(t, expected.depth, records[i].Depth, "Line %d has its depth", i)
+		}
+		assert.Len(t, records[0].Asset.AttachedChildren, 2, "Links are kept to rebuild the tree")
+	})
+	t.Run("Parent_Loop", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		lines := export(t, ctrl, map[string]string{"A0": assetL0LoopLocation, "A1": assetL1Location})
+		assert.Len(t, lines, 3, "Asset, parent and the error")
+		assert.Contains(t, lines[2], `{"error":"Parent Child Loop Detected for asset T1/C1/A1"}`, "Loop ends the stream")
+	})
+}
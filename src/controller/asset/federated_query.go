@@ -0,0 +1,270 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// allTargets selects every channel of every configured repo as the targets of a federated query
+const allTargets = "all"
+
+// federatedQuery is a type representing the body of a federated query
+type federatedQuery struct {
+	Targets json.RawMessage        `json:"targets"`
+	Query   map[string]interface{} `json:"query"`
+	Filter  []string               `json:"filter"`
+	SortBy  string                 `json:"sortBy"`
+	Skip    int                    `json:"skip"`
+	Limit   int                    `json:"limit"`
+}
+
+// parseTargets returns the targets of a federated query, nil for every configured repo
+func (q federatedQuery) parseTargets() ([]helpers.QueryTarget, error) {
+	var all string
+	if json.Unmarshal(q.Targets, &all) == nil {
+		if all != allTargets {
+			return nil, fmt.Errorf("targets must be %q or a list of repoID and channelID", allTargets)
+		}
+		return nil, nil
+	}
+	var targets []helpers.QueryTarget
+	if err := json.Unmarshal(q.Targets, &targets); err != nil || len(targets) == 0 {
+		return nil, fmt.Errorf("targets must be %q or a list of repoID and channelID", allTargets)
+	}
+	for _, target := range targets {
+		if target.RepoID == "" {
+			return nil, errors.New("every target needs a repoID")
+		}
+	}
+	return targets, nil
+}
+
+// queryChannels returns the channels a federated query searches, in order, with the repos whose channels could not be
+// listed. Repos without a channelID, or every configured repo when targets is nil, are expanded to all their channels
+func queryChannels(ctx context.Context, targets []helpers.QueryTarget) ([]helpers.QueryTarget, []helpers.TargetError) {
+	var targetErrors []helpers.TargetError
+	if targets == nil {
+		repoIDs, err := agent.GetConfiguredRepos()
+		if err != nil {
+			return nil, []helpers.TargetError{{Error: err.Error()}}
+		}
+		for _, repoID := range repoIDs {
+			targets = append(targets, helpers.QueryTarget{RepoID: repoID})
+		}
+	}
+
+	var channels []helpers.QueryTarget
+	seen := make(map[helpers.QueryTarget]bool)
+	add := func(target helpers.QueryTarget) {
+		if !seen[target] {
+			seen[target] = true
+			channels = append(channels, target)
+		}
+	}
+	for _, target := range targets {
+		if target.ChannelID != "" {
+			add(target)
+			continue
+		}
+		var channelIDs []string
+		repoAgent, err := agentForRepo(ctx, target.RepoID)
+		if err == nil {
+			var resultStream io.ReadCloser
+			resultStream, err = repoAgent.ListChannels(ctx, agent.QueryArgs{})
+			if err == nil {
+				err = json.NewDecoder(resultStream).Decode(&channelIDs)
+			}
+		}
+		if err != nil {
+			log.Warn().Msgf("Channels of %s could not be listed: %s", target.RepoID, err.Error())
+			targetErrors = append(targetErrors, helpers.TargetError{RepoID: target.RepoID, Error: err.Error()})
+			continue
+		}
+		sort.Strings(channelIDs)
+		for _, channelID := range channelIDs {
+			add(helpers.QueryTarget{RepoID: target.RepoID, ChannelID: channelID})
+		}
+	}
+	return channels, targetErrors
+}
+
+// compareValues orders the values of two records at the sort path. Numbers compare as numbers, other values as their
+// JSON text, and records without the value come last
+func compareValues(a interface{}, aOK bool, b interface{}, bOK bool) int {
+	if !aOK || !bOK {
+		switch {
+		case aOK:
+			return -1
+		case bOK:
+			return 1
+		}
+		return 0
+	}
+	if aNumber, ok := a.(float64); ok {
+		if bNumber, ok := b.(float64); ok {
+			switch {
+			case aNumber < bNumber:
+				return -1
+			case aNumber > bNumber:
+				return 1
+			}
+			return 0
+		}
+	}
+	text := func(value interface{}) string {
+		if s, ok := value.(string); ok {
+			return s
+		}
+		raw, _ := json.Marshal(value)
+		return string(raw)
+	}
+	return strings.Compare(text(a), text(b))
+}
+
+// sortMatches orders the matches of a federated query by the value at sortBy, when it is set, and then by location
+func sortMatches(matches []helpers.FederatedMatch, sortBy string) {
+	valueOf := func(match helpers.FederatedMatch) (interface{}, bool) {
+		record, ok := match.Record.(map[string]interface{})
+		if !ok || sortBy == "" {
+			return nil, false
+		}
+		return helpers.ValueAtPath(record, sortBy)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, aOK := valueOf(matches[i])
+		b, bOK := valueOf(matches[j])
+		if order := compareValues(a, aOK, b, bOK); order != 0 {
+			return order < 0
+		}
+		if matches[i].RepoID != matches[j].RepoID {
+			return matches[i].RepoID < matches[j].RepoID
+		}
+		if matches[i].ChannelID != matches[j].ChannelID {
+			return matches[i].ChannelID < matches[j].ChannelID
+		}
+		return matches[i].AssetID < matches[j].AssetID
+	})
+}
+
+// FederatedQuery is a controller function that runs a rich query against several repos and channels at once.
+// targets is "all" or a list of repoID and channelID, where a target without a channelID searches every channel of
+// the repo. The agents are queried concurrently, up to federatedQuery.parallelism at a time, and their results are
+// merged, sorted by sortBy and location, and paged with skip and limit. Targets that fail are reported, not fatal
+func FederatedQuery(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Federated Query")
+	defer span.Finish()
+
+	var body federatedQuery
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	targets, err := body.parseTargets()
+	if err == nil && body.Query == nil {
+		err = errors.New("query is required")
+	}
+	if err == nil && (body.Skip < 0 || body.Limit < 0) {
+		err = errors.New("skip and limit must not be negative")
+	}
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	channels, targetErrors := queryChannels(ctx, targets)
+	// Agents return their matches unordered, so any cap per target could drop a match that belongs on the page.
+	// The bookmarks of every target are followed until all of its matches are read, which are ordered and paged once
+	// merged
+	args := agent.RichQueryArgs{Query: body.Query, Filter: body.Filter}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, agent.GetQueryParallelism())
+	matches := []helpers.FederatedMatch{}
+	failed := func(target helpers.QueryTarget, err error) {
+		log.Warn().Msgf("Query of %s/%s failed: %s", target.RepoID, target.ChannelID, err.Error())
+		targetErrors = append(targetErrors, helpers.TargetError{RepoID: target.RepoID, ChannelID: target.ChannelID,
+			Error: err.Error()})
+	}
+	for _, target := range channels {
+		repoAgent, err := agentForRepo(ctx, target.RepoID)
+		if err != nil {
+			failed(target, err)
+			continue
+		}
+		wg.Add(1)
+		go func(target helpers.QueryTarget, repoAgent agent.Agent) {
+			defer wg.Done()
+			slots <- struct{}{}
+			result, err := agent.QueryAllAssets(ctx, repoAgent, agent.QueryArgs{ChannelID: target.ChannelID}, args)
+			<-slots
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				failed(target, err)
+				return
+			}
+			for assetID, record := range result {
+				element := helpers.AssetElement{RepoID: target.RepoID, ChannelID: target.ChannelID, AssetID: assetID}
+				matches = append(matches, helpers.FederatedMatch{RepoID: target.RepoID, ChannelID: target.ChannelID,
+					AssetID: assetID, URI: element.URI(), Record: record})
+			}
+		}(target, repoAgent)
+	}
+	wg.Wait()
+
+	sortMatches(matches, body.SortBy)
+	sort.SliceStable(targetErrors, func(i, j int) bool {
+		if targetErrors[i].RepoID != targetErrors[j].RepoID {
+			return targetErrors[i].RepoID < targetErrors[j].RepoID
+		}
+		return targetErrors[i].ChannelID < targetErrors[j].ChannelID
+	})
+
+	result := helpers.FederatedQueryResult{Skip: body.Skip, Limit: body.Limit, Errors: targetErrors}
+	if result.Errors == nil {
+		result.Errors = []helpers.TargetError{}
+	}
+	end := len(matches)
+	if body.Limit > 0 && body.Skip+body.Limit < end {
+		end = body.Skip + body.Limit
+		result.More = true
+	}
+	if body.Skip < end {
+		result.Results = matches[body.Skip:end]
+	} else {
+		result.Results = []helpers.FederatedMatch{}
+	}
+	render.JSON(w, r, result)
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// serverRecord returns the record of a server of a model, as returned by the rich query of an agent
+func serverRecord(model string) map[string]interface{} {
+	return map[string]interface{}{"assetType": "Server", "assetModelNumber": model}
+}
+
+// postFederatedQuery posts a federated query and decodes the result
+func postFederatedQuery(t *testing.T, ctrl *gomock.Controller, agents map[string]agent.Agent, body string) (int,
+	helpers.FederatedQueryResult) {
+	mockRequest := mocks.InjectAgentProviderIntoRequest(httptest.NewRequest("POST", "/", strings.NewReader(body)), ctrl,
+		agents)
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(FederatedQuery).ServeHTTP(responseRecorder, mockRequest)
+	var result helpers.FederatedQueryResult
+	json.NewDecoder(responseRecorder.Body).Decode(&result)
+	return responseRecorder.Code, result
+}
+
+// TestFederatedQuery contains the tests for querying several repos and channels at once
+func TestFederatedQuery(t *testing.T) {
+	t.Run("Merged_Sorted_And_Paged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		query := map[string]interface{}{"assetType": "Server"}
+		args := agent.RichQueryArgs{Query: query}
+		agentT1.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""), args).
+			Return(map[string]interface{}{"A1": serverRecord("X300"), "A2": serverRecord("X100")}, nil)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C2","C1"]`)), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""), args).
+			Return(map[string]interface{}{"B1": serverRecord("X200")}, nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C2", ""), args).
+			Return(map[string]interface{}{"B2": map[string]interface{}{"assetType": "Server"}}, nil)
+
+		code, result := postFederatedQuery(t, ctrl, map[string]agent.Agent{"T1": agentT1, "T2": agentT2},
+			`{"targets":[{"repoID":"T1","channelID":"C1"},{"repoID":"T2"},{"repoID":"T1","channelID":"C1"}],
+			"query":{"assetType":"Server"},"sortBy":"assetModelNumber","skip":1,"limit":2}`)
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Len(t, result.Results, 2, "Page is limited")
+		assert.Equal(t, "B1", result.Results[0].AssetID, "X100 is skipped, X200 comes next")
+		assert.Equal(t, "dbom://T2/C1/B1", result.Results[0].URI, "Results have their URI")
+		assert.Equal(t, "A1", result.Results[1].AssetID, "X300 comes last of the page")
+		assert.True(t, result.More, "Record without the sort field is on the next page")
+		assert.Empty(t, result.Errors, "Every target answered")
+	})
+	t.Run("Page_Across_Unordered_Agent_Results", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		// The first page by location is A1, which the agent would not have returned within a cap of two
+		agentT1.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""),
+			agent.RichQueryArgs{Query: map[string]interface{}{}}).
+			Return(map[string]interface{}{"A3": serverRecord("X300"), "A2": serverRecord("X200"), "A1": serverRecord("X100")}, nil)
+
+		code, result := postFederatedQuery(t, ctrl, map[string]agent.Agent{"T1": agentT1},
+			`{"targets":[{"repoID":"T1","channelID":"C1"}],"query":{},"limit":1}`)
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Equal(t, "A1", result.Results[0].AssetID, "First match by location is on the first page")
+		assert.True(t, result.More, "Other matches are on the next pages")
+	})
+	t.Run("Bookmarks_Are_Followed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		pages := map[string]map[string]interface{}{
+			"":   {"A1": serverRecord("X100"), agent.BookmarkKey: "g1"},
+			"g1": {"A2": serverRecord("X100"), agent.BookmarkKey: "g2"},
+			"g2": {agent.BookmarkKey: "g2"},
+		}
+		agentT1.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""), gomock.Any()).Times(3).
+			DoAndReturn(func(_ context.Context, _ agent.QueryArgs, body agent.RichQueryArgs) (map[string]interface{}, error) {
+				return pages[body.Bookmark], nil
+			})
+
+		code, result := postFederatedQuery(t, ctrl, map[string]agent.Agent{"T1": agentT1},
+			`{"targets":[{"repoID":"T1","channelID":"C1"}],"query":{}}`)
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Len(t, result.Results, 2, "Matches of every page are returned, the bookmark is not a result")
+		assert.Equal(t, "A2", result.Results[1].AssetID, "Matches of the next pages are returned")
+	})
+	t.Run("All_Targets", func(t *testing.T) {
+		viper.Set("agents", map[string]agent.Config{
+			"T1": {Enabled: true},
+			"T2": {Enabled: true},
+		})
+		defer viper.Set("agents", nil)
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		agentT1.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""), gomock.Any()).
+			Return(map[string]interface{}{"A1": serverRecord("X100")}, nil)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""), gomock.Any()).
+			Return(nil, errors.New("agent is down"))
+
+		code, result := postFederatedQuery(t, ctrl, map[string]agent.Agent{"T1": agentT1, "T2": agentT2},
+			`{"targets":"all","query":{}}`)
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Len(t, result.Results, 1, "Results of the answering repo are returned")
+		assert.False(t, result.More, "Unlimited queries have a single page")
+		assert.Equal(t, []helpers.TargetError{{RepoID: "T2", ChannelID: "C1", Error: "agent is down"}}, result.Errors,
+			"Failed target is reported")
+	})
+	t.Run("Unknown_Repo", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		code, result := postFederatedQuery(t, ctrl, nil, `{"targets":[{"repoID":"NIL_REPO","channelID":"C1"}],"query":{}}`)
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Empty(t, result.Results, "Nothing is found")
+		assert.Len(t, result.Errors, 1, "Unknown repo is reported")
+	})
+	t.Run("Bounded_Parallelism", func(t *testing.T) {
+		viper.Set("federatedQuery.parallelism", 2)
+		defer viper.Set("federatedQuery.parallelism", nil)
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		var mutex sync.Mutex
+		running, peak := 0, 0
+		agentT1.EXPECT().QueryAssets(gomock.Any(), gomock.Any(), gomock.Any()).Times(6).
+			DoAndReturn(func(_ context.Context, args agent.QueryArgs, _ agent.RichQueryArgs) (map[string]interface{}, error) {
+				mutex.Lock()
+				running++
+				if running > peak {
+					peak = running
+				}
+				mutex.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+				return map[string]interface{}{"A-" + args.ChannelID: serverRecord("X100")}, nil
+			})
+
+		targets := make([]string, 6)
+		for i := range targets {
+			targets[i] = `{"repoID":"T1","channelID":"C` + string(rune('1'+i)) + `"}`
+		}
+		code, result := postFederatedQuery(t, ctrl, map[string]agent.Agent{"T1": agentT1},
+			`{"targets":[`+strings.Join(targets, ",")+`],"query":{}}`)
+		assert.Equal(t, http.StatusOK, code, "Response Should be 200 OK")
+		assert.Len(t, result.Results, 6, "Every channel is queried")
+		assert.Equal(t, "C1", result.Results[0].ChannelID, "Results are in location order")
+		assert.LessOrEqual(t, peak, 2, "No more than the configured number of queries run at once")
+	})
+	t.Run("Invalid_Request", func(t *testing.T) {
+		for _, body := range []string{
+			`{"query":{}}`,
+			`{"targets":"some","query":{}}`,
+			`{"targets":[],"query":{}}`,
+			`{"targets":[{"channelID":"C1"}],"query":{}}`,
+			`{"targets":"all"}`,
+			`{"targets":"all","query":{},"limit":-1}`,
+			invalidJSON,
+		} {
+			ctrl := gomock.NewController(t)
+			code, _ := postFederatedQuery(t, ctrl, nil, body)
+			assert.Equal(t, http.StatusBadRequest, code, "Response Should be 400 Bad Request for %s", body)
+			ctrl.Finish()
+		}
+	})
+}
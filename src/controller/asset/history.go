@@ -0,0 +1,135 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"bytes"
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// historyPoint is a type representing the moment of the history of an asset a request asks for.
+// The zero value is the current state
+type historyPoint struct {
+	asOf     *time.Time
+	revision int
+}
+
+// isSet reports if the history point asks for a past state
+func (p historyPoint) isSet() bool {
+	return p.asOf != nil || p.revision > 0
+}
+
+// parseHistoryPoint reads the asOf (RFC 3339) or revision (from 1) query parameter of a request
+func parseHistoryPoint(r *http.Request) (point historyPoint, err error) {
+	query := r.URL.Query()
+	asOf, revision := query.Get("asOf"), query.Get("revision")
+	if asOf != "" && revision != "" {
+		return point, errors.New("asOf and revision can not be combined")
+	}
+	if asOf != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, asOf)
+		if err != nil {
+			return point, errors.New("asOf must be an RFC 3339 timestamp")
+		}
+		point.asOf = &parsed
+	}
+	if revision != "" {
+		point.revision, err = strconv.Atoi(revision)
+		if err != nil || point.revision < 1 {
+			return point, errors.New("revision must be a positive number")
+		}
+	}
+	return point, nil
+}
+
+// withHistoryPoint returns a context in which queryAssetStream returns assets at the history point
+func withHistoryPoint(ctx context.Context, point historyPoint) context.Context {
+	return context.WithValue(ctx, "historyPoint", point)
+}
+
+// assetAt reconstructs an asset at a history point from the audit trail of the agent, and returns its revision.
+// helpers.ErrNotFound is returned when the asset did not exist at that point or was deleted
+func assetAt(ctx context.Context, requestAgent agent.Agent, args agent.QueryArgs, point historyPoint) (helpers.Asset, int, error) {
+	entries, err := auditEntries(ctx, requestAgent, args)
+	if err != nil {
+		return helpers.Asset{}, 0, err
+	}
+	return entryAt(entries, point)
+}
+
+// auditEntries returns the typed audit trail of an asset
+func auditEntries(ctx context.Context, requestAgent agent.Agent, args agent.QueryArgs) ([]helpers.AuditEntry, error) {
+	trail, err := requestAgent.QueryAuditTrail(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return agent.AuditEntries(trail)
+}
+
+// entryAt returns the asset committed by the audit entry of a history point, and its revision.
+// An entry without a timestamp can not be placed before or after asOf, so the point can not be found
+func entryAt(entries []helpers.AuditEntry, point historyPoint) (helpers.Asset, int, error) {
+	revision := point.revision
+	if point.asOf != nil {
+		revision = 0
+		for i, entry := range entries {
+			timestamp, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+			if err != nil {
+				return helpers.Asset{}, 0, fmt.Errorf("audit entry %d has no timestamp", i+1)
+			}
+			if !timestamp.After(*point.asOf) {
+				revision = i + 1
+			}
+		}
+	}
+	if revision < 1 || revision > len(entries) {
+		return helpers.Asset{}, 0, helpers.ErrNotFound
+	}
+	entry := entries[revision-1]
+	if entry.Asset == nil || entry.CommitType == "DELETE" {
+		return helpers.Asset{}, 0, helpers.ErrNotFound
+	}
+	return *entry.Asset, revision, nil
+}
+
+// queryAssetStream queries an asset like QueryStream, at the history point of the context when there is one
+func queryAssetStream(ctx context.Context, requestAgent agent.Agent, args agent.QueryArgs) (io.ReadCloser, error) {
+	point, ok := ctx.Value("historyPoint").(historyPoint)
+	if !ok || !point.isSet() {
+		return requestAgent.QueryStream(ctx, args)
+	}
+	asset, _, err := assetAt(ctx, requestAgent, args, point)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(asset)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(raw)), nil
+}
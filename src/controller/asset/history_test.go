@@ -0,0 +1,165 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const assemblyTrailLocation = "../../testdata/asset_controller_tests/history/assemblyTrail.json"
+const boardTrailLocation = "../../testdata/asset_controller_tests/history/boardTrail.json"
+
+// openTestTrail returns a test audit trail as returned by QueryAuditTrail
+func openTestTrail(path string) map[string]interface{} {
+	var trail map[string]interface{}
+	json.NewDecoder(openTestJSON(path)).Decode(&trail)
+	return trail
+}
+
+// TestRetrieveAssetHistory contains the tests for retrieving past states of an asset
+func TestRetrieveAssetHistory(t *testing.T) {
+	retrieve := func(t *testing.T, query string) (*httptest.ResponseRecorder, helpers.Asset) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().GetHost().AnyTimes()
+		mockAgent.EXPECT().GetPort().AnyTimes()
+		mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestTrail(assemblyTrailLocation), nil).AnyTimes()
+
+		mockRequest := httptest.NewRequest("GET", "/"+query, nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(RetrieveAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		var result helpers.Asset
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		return responseRecorder, result
+	}
+	t.Run("By_Revision", func(t *testing.T) {
+		response, result := retrieve(t, "?revision=1")
+		assert.Equal(t, http.StatusOK, response.Code, "Response Should be 200 OK")
+		assert.Equal(t, "1", response.Header().Get("X-Asset-Revision"), "Revision is reported")
+		assert.Empty(t, result.AttachedChildren, "First revision has no children")
+	})
+	t.Run("As_Of", func(t *testing.T) {
+		response, result := retrieve(t, "?asOf=2021-03-05T00:00:00Z")
+		assert.Equal(t, http.StatusOK, response.Code, "Response Should be 200 OK")
+		assert.Equal(t, "2", response.Header().Get("X-Asset-Revision"), "Latest commit before asOf is used")
+		assert.Equal(t, "Assembly", result.AssetDescription, "State at asOf is returned")
+		assert.Len(t, result.AttachedChildren, 1, "State at asOf is returned")
+	})
+	t.Run("As_Of_Commit_Time", func(t *testing.T) {
+		response, _ := retrieve(t, "?asOf=2021-03-10T10:00:00Z")
+		assert.Equal(t, "3", response.Header().Get("X-Asset-Revision"), "Commit at asOf is included")
+	})
+	t.Run("Before_Creation", func(t *testing.T) {
+		response, _ := retrieve(t, "?asOf=2020-01-01T00:00:00Z")
+		assert.Equal(t, http.StatusNotFound, response.Code, "Response Should be 404 NOT FOUND")
+	})
+	t.Run("Unknown_Revision", func(t *testing.T) {
+		response, _ := retrieve(t, "?revision=4")
+		assert.Equal(t, http.StatusNotFound, response.Code, "Response Should be 404 NOT FOUND")
+	})
+	t.Run("Undated_Entry", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().GetHost().AnyTimes()
+		mockAgent.EXPECT().GetPort().AnyTimes()
+		mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(map[string]interface{}{"history": []interface{}{
+				map[string]interface{}{"eventType": "CREATE", "timestamp": "2021-03-01T10:00:00Z",
+					"payload": map[string]interface{}{"assetDescription": "Assembly"}},
+				map[string]interface{}{"eventType": "UPDATE", "payload": map[string]interface{}{"assetDescription": "Moved"}},
+			}}, nil)
+
+		mockRequest := httptest.NewRequest("GET", "/?asOf=2021-03-05T00:00:00Z", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(RetrieveAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadGateway, responseRecorder.Code, "Undated entry is not skipped")
+		assert.Contains(t, responseRecorder.Body.String(), "audit entry 2 has no timestamp", "Entry is reported")
+	})
+	t.Run("Invalid_Parameters", func(t *testing.T) {
+		for _, query := range []string{"?revision=0", "?asOf=March", "?asOf=2021-03-05T00:00:00Z&revision=1"} {
+			response, _ := retrieve(t, query)
+			assert.Equal(t, http.StatusBadRequest, response.Code, "Query %s is rejected", query)
+		}
+	})
+}
+
+// TestExportAssetHistory tests that a past export rebuilds the subtree with every asset at that moment
+func TestExportAssetHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	mockAgent.EXPECT().GetHost().AnyTimes()
+	mockAgent.EXPECT().GetPort().AnyTimes()
+	mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+		Return(openTestTrail(assemblyTrailLocation), nil)
+	mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).
+		Return(openTestTrail(boardTrailLocation), nil)
+
+	mockRequest := httptest.NewRequest("GET", "/?asOf=2021-03-15T00:00:00Z", nil)
+	responseRecorder := httptest.NewRecorder()
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+		mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+	mockRequest = injectNullExportContext(mockRequest)
+	http.HandlerFunc(ExportAsset).ServeHTTP(responseRecorder, mockRequest)
+
+	var exported map[string]*helpers.Asset
+	json.NewDecoder(responseRecorder.Body).Decode(&exported)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	assert.Equal(t, "Assembly, shipped", exported["A1"].AssetDescription, "Root is exported at asOf")
+	assert.Equal(t, "Board rev 1", exported["A1"].Children["B1"].AssetDescription, "Child is exported at asOf")
+}
+
+// TestExportRejectsRevision tests that revisions are only accepted for single assets
+func TestExportRejectsRevision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	mockAgent.EXPECT().GetHost().AnyTimes()
+	mockAgent.EXPECT().GetPort().AnyTimes()
+	mockRequest := httptest.NewRequest("GET", "/?revision=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+		mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	mockRequest = injectNullExportContext(mockRequest)
+	http.HandlerFunc(ExportAsset).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+}
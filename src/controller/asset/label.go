@@ -0,0 +1,151 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/label"
+	"chainsource-gateway/responses"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// labelModuleSize is the number of pixels per module of the QR code of a PNG label
+const labelModuleSize = 8
+
+// GetLabel is a controller function that renders the label of an asset, a QR code of its URI or of the configured
+// deep link with its assetType, model and ID, as ?format=png (the default) or svg
+func GetLabel(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Label")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = label.FormatPNG
+	}
+	if format != label.FormatPNG && format != label.FormatSVG {
+		render.Render(w, r, responses.ErrInvalidRequest(label.ErrUnsupportedFormat))
+		return
+	}
+
+	var result helpers.Asset
+	resultStream, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&result)
+	}
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+
+	element := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID}
+	rendered, err := label.Render(label.For(element, result), format, labelModuleSize)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	label.Write(w, rendered, format, "")
+}
+
+// GetLabelSheet is a controller function that renders the labels of an asset and of its attached children, recursively,
+// on a printable sheet, as ?format=pdf (the default) or svg. The asset comes first, followed by its children breadth first
+func GetLabelSheet(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Label Sheet")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = label.FormatPDF
+	}
+	if format != label.FormatPDF && format != label.FormatSVG {
+		render.Render(w, r, responses.ErrInvalidRequest(label.ErrUnsupportedFormat))
+		return
+	}
+
+	var root helpers.Asset
+	resultStream, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&root)
+	}
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+
+	rootElement := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID}
+	elements := []helpers.AssetElement{rootElement}
+	assets := []helpers.Asset{root}
+	seen := map[string]bool{elementKey(rootElement): true}
+	for i := 0; i < len(assets); i++ {
+		for _, child := range assets[i].AttachedChildren {
+			element := helpers.AssetElement{RepoID: child.RepoID, ChannelID: child.ChannelID, AssetID: child.AssetID}
+			if seen[elementKey(element)] {
+				continue
+			}
+			seen[elementKey(element)] = true
+			_, childAsset, err := getChildAssetContextFromAssetElement(ctx, element)
+			if err != nil {
+				if err == helpers.ErrUnauthorized {
+					render.Render(w, r, responses.ErrUnauthorizedQueryChild(err))
+				} else {
+					render.Render(w, r, responses.ErrFailedQueryChild(err))
+				}
+				return
+			}
+			elements = append(elements, element)
+			assets = append(assets, childAsset)
+		}
+	}
+
+	labels := make([]label.Label, len(assets))
+	for i := range assets {
+		labels[i] = label.For(elements[i], assets[i])
+	}
+	rendered, err := label.RenderSheet(labels, format)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	label.Write(w, rendered, format, assetVars.AssetID+"-labels")
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"context"
+	"errors"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectExportTree serves A1 and its children A2 and A3 from the export test data, unknown assets do not exist
+func expectExportTree(mockAgent *mocks.MockAgent) {
+	files := map[string]string{"A1": assetL1Location, "A2": assetL2A1Location, "A3": assetL2A2Location}
+	mockAgent.EXPECT().QueryStream(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, args agent.QueryArgs) (io.ReadCloser, error) {
+			file, ok := files[args.AssetID]
+			if !ok {
+				return nil, helpers.ErrNotFound
+			}
+			return openTestJSON(file), nil
+		})
+}
+
+// TestGetLabel contains the tests for rendering the label of an asset
+func TestGetLabel(t *testing.T) {
+	getLabel := func(t *testing.T, assetID string, query string) *httptest.ResponseRecorder {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectExportTree(mockAgent)
+
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/"+query, nil), "T1", "C1", assetID, mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetLabel).ServeHTTP(responseRecorder, mockRequest)
+		return responseRecorder
+	}
+	t.Run("PNG", func(t *testing.T) {
+		responseRecorder := getLabel(t, "A1", "")
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, "image/png", responseRecorder.Header().Get("Content-Type"), "Label is a PNG by default")
+		_, err := png.Decode(responseRecorder.Body)
+		assert.NoError(t, err, "Label decodes")
+	})
+	t.Run("SVG", func(t *testing.T) {
+		responseRecorder := getLabel(t, "A1", "?format=svg")
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, "image/svg+xml", responseRecorder.Header().Get("Content-Type"), "Label is an SVG")
+		assert.Contains(t, responseRecorder.Body.String(), ">HardwareComponent</text>", "Label has the assetType")
+		assert.Contains(t, responseRecorder.Body.String(), ">A1</text>", "Label has the asset ID")
+	})
+	t.Run("Unsupported_Format", func(t *testing.T) {
+		responseRecorder := getLabel(t, "A1", "?format=pdf")
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 Bad Request")
+	})
+	t.Run("Not_Found", func(t *testing.T) {
+		responseRecorder := getLabel(t, "A0", "")
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 Not Found")
+	})
+}
+
+// TestGetLabelSheet contains the tests for rendering the labels of an asset and its children
+func TestGetLabelSheet(t *testing.T) {
+	getLabelSheet := func(t *testing.T, query string, childAgent agent.Agent) *httptest.ResponseRecorder {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		expectExportTree(mockAgent)
+		if childAgent == nil {
+			childAgent = mockAgent
+		}
+
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/"+query, nil), "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": childAgent})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetLabelSheet).ServeHTTP(responseRecorder, mockRequest)
+		return responseRecorder
+	}
+	t.Run("PDF", func(t *testing.T) {
+		responseRecorder := getLabelSheet(t, "", nil)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, "application/pdf", responseRecorder.Header().Get("Content-Type"), "Sheet is a PDF by default")
+		assert.Equal(t, "attachment; filename=A1-labels.pdf", responseRecorder.Header().Get("Content-Disposition"),
+			"Sheet is an attachment")
+		assert.True(t, strings.HasPrefix(responseRecorder.Body.String(), "%PDF-"), "Sheet is a PDF")
+	})
+	t.Run("SVG", func(t *testing.T) {
+		responseRecorder := getLabelSheet(t, "?format=svg", nil)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		body := responseRecorder.Body.String()
+		assert.Equal(t, 3, strings.Count(body, `<g transform="translate(`), "Asset and both children are labelled")
+		assert.True(t, strings.Index(body, ">A1</text>") < strings.Index(body, ">A2</text>"), "Asset comes first")
+		assert.Contains(t, body, ">A3</text>", "Every child is labelled")
+	})
+	t.Run("Child_Failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		childAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		childAgent.EXPECT().QueryStream(gomock.Any(), gomock.Any()).Return(nil, errors.New("ANY"))
+
+		responseRecorder := getLabelSheet(t, "", childAgent)
+		assert.Equal(t, http.StatusBadGateway, responseRecorder.Code, "Response Should be 502 Bad Gateway")
+	})
+	t.Run("Unsupported_Format", func(t *testing.T) {
+		responseRecorder := getLabelSheet(t, "?format=png", nil)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 Bad Request")
+	})
+}
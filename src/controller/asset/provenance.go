@@ -0,0 +1,215 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+const (
+	provenanceBroken = "broken"
+	provenanceForked = "forked"
+)
+
+// isCompletedTransfer reports if a custody transfer event moved the custody, pending and rejected events did not
+func isCompletedTransfer(event helpers.CustodyTransferEvent) bool {
+	return event.Status == "" || event.Status == transferStatusCompleted
+}
+
+// eventSource returns the location of the record a custody transfer event moves out of
+func eventSource(event helpers.CustodyTransferEvent) helpers.AssetElement {
+	return helpers.AssetElement{RepoID: event.SourceRepoID, ChannelID: event.SourceChannelID, AssetID: event.SourceAssetID}
+}
+
+// eventDestination returns the location of the record a custody transfer event moves into
+func eventDestination(event helpers.CustodyTransferEvent) helpers.AssetElement {
+	return helpers.AssetElement{RepoID: event.DestinationRepoID, ChannelID: event.DestinationChannelID, AssetID: event.DestinationAssetID}
+}
+
+// hopOf returns the custody hop of a custody transfer event
+func hopOf(event helpers.CustodyTransferEvent) helpers.CustodyHop {
+	return helpers.CustodyHop{
+		Timestamp:           event.Timestamp,
+		TransferDescription: event.TransferDescription,
+		Source:              eventSource(event),
+		Destination:         eventDestination(event),
+	}
+}
+
+// recordsHop reports if an asset holds the completed transfer from source to destination
+func recordsHop(asset helpers.Asset, source helpers.AssetElement, destination helpers.AssetElement) bool {
+	for _, event := range asset.CustodyTransferEvents {
+		if isCompletedTransfer(event) && eventSource(event) == source && eventDestination(event) == destination {
+			return true
+		}
+	}
+	return false
+}
+
+// provenanceWalker follows the custody transfer events of an asset across repos
+type provenanceWalker struct {
+	ctx        context.Context
+	provenance helpers.Provenance
+	seen       map[string]bool
+}
+
+// flag records an issue of the custody chain
+func (p *provenanceWalker) flag(kind string, location helpers.AssetElement, detail string) {
+	if kind == provenanceBroken {
+		p.provenance.Broken = true
+	} else {
+		p.provenance.Forked = true
+	}
+	p.provenance.Issues = append(p.provenance.Issues, helpers.ProvenanceIssue{Kind: kind, Location: location, Detail: detail})
+}
+
+// outgoing returns the completed transfers out of a record, flagging a fork when it was transferred to more than one destination
+func (p *provenanceWalker) outgoing(location helpers.AssetElement, asset helpers.Asset) []helpers.CustodyTransferEvent {
+	var events []helpers.CustodyTransferEvent
+	destinations := make(map[string]bool)
+	for _, event := range asset.CustodyTransferEvents {
+		if isCompletedTransfer(event) && eventSource(event) == location {
+			events = append(events, event)
+			destinations[elementKey(eventDestination(event))] = true
+		}
+	}
+	if len(destinations) > 1 {
+		p.flag(provenanceForked, location, fmt.Sprintf("custody was transferred to %d destinations", len(destinations)))
+	}
+	return events
+}
+
+// query retrieves a record of the custody chain, flagging the chain broken when it can not be retrieved
+func (p *provenanceWalker) query(location helpers.AssetElement) (helpers.Asset, bool) {
+	if p.seen[elementKey(location)] {
+		p.flag(provenanceBroken, location, "custody chain loops back to this record")
+		return helpers.Asset{}, false
+	}
+	p.seen[elementKey(location)] = true
+	_, asset, err := getChildAssetContextFromAssetElement(p.ctx, location)
+	if err != nil {
+		p.flag(provenanceBroken, location, "record could not be retrieved: "+err.Error())
+		return helpers.Asset{}, false
+	}
+	return asset, true
+}
+
+// walkBackwards follows the transfers into the record back to the first custodian
+func (p *provenanceWalker) walkBackwards(location helpers.AssetElement, asset helpers.Asset) {
+	for {
+		// A record is created by the last completed transfer into it
+		var incoming *helpers.CustodyTransferEvent
+		for i, event := range asset.CustodyTransferEvents {
+			if isCompletedTransfer(event) && eventDestination(event) == location {
+				incoming = &asset.CustodyTransferEvents[i]
+			}
+		}
+		if incoming == nil {
+			return
+		}
+		source := eventSource(*incoming)
+		p.provenance.Custodians = append([]helpers.AssetElement{source}, p.provenance.Custodians...)
+		p.provenance.Transfers = append([]helpers.CustodyHop{hopOf(*incoming)}, p.provenance.Transfers...)
+
+		sourceAsset, ok := p.query(source)
+		if !ok {
+			return
+		}
+		if !recordsHop(sourceAsset, source, location) {
+			p.flag(provenanceBroken, source, "record does not hold the transfer to "+elementKey(location))
+		}
+		p.outgoing(source, sourceAsset)
+		location, asset = source, sourceAsset
+	}
+}
+
+// walkForwards follows the transfers out of the record to the current custodian. At a fork the latest transfer is followed
+func (p *provenanceWalker) walkForwards(location helpers.AssetElement, asset helpers.Asset) {
+	for {
+		events := p.outgoing(location, asset)
+		if len(events) == 0 {
+			return
+		}
+		event := events[len(events)-1]
+		destination := eventDestination(event)
+		p.provenance.Custodians = append(p.provenance.Custodians, destination)
+		p.provenance.Transfers = append(p.provenance.Transfers, hopOf(event))
+
+		destinationAsset, ok := p.query(destination)
+		if !ok {
+			return
+		}
+		if !recordsHop(destinationAsset, location, destination) {
+			p.flag(provenanceBroken, destination, "record does not hold the transfer from "+elementKey(location))
+		}
+		location, asset = destination, destinationAsset
+	}
+}
+
+// GetProvenance is a controller function that returns the custody chain of an asset
+// The custody transfer events are followed backwards to the first custodian and forwards to the current custodian,
+// across all configured repos. The chain is flagged broken when a record can not be retrieved or does not hold the
+// transfer, and forked when a record was transferred to more than one destination
+func GetProvenance(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Provenance")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	var requestAsset helpers.Asset
+	resultStream, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&requestAsset)
+	}
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+
+	location := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID}
+	walker := provenanceWalker{
+		ctx: ctx,
+		provenance: helpers.Provenance{
+			Custodians: []helpers.AssetElement{location},
+			Transfers:  []helpers.CustodyHop{},
+		},
+		seen: map[string]bool{elementKey(location): true},
+	}
+	walker.walkBackwards(location, requestAsset)
+	walker.walkForwards(location, requestAsset)
+
+	render.JSON(w, r, walker.provenance)
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const firstCustodianLocation = "../../testdata/asset_controller_tests/provenance/firstCustodian.json"
+const firstCustodianWithoutTransferLocation = "../../testdata/asset_controller_tests/provenance/firstCustodianWithoutTransfer.json"
+const secondCustodianLocation = "../../testdata/asset_controller_tests/provenance/secondCustodian.json"
+const secondCustodianForkedLocation = "../../testdata/asset_controller_tests/provenance/secondCustodianForked.json"
+const thirdCustodianLocation = "../../testdata/asset_controller_tests/provenance/thirdCustodian.json"
+
+// getProvenance requests the provenance of T1/C2/A2, the second custodian of the chain T1/C1/A1 -> T1/C2/A2 -> T2/C3/A3
+func getProvenance(t *testing.T, ctrl *gomock.Controller, repoT1 agent.Agent, repoT2 agent.Agent) helpers.Provenance {
+	mockRequest := httptest.NewRequest("GET", "/", nil)
+	responseRecorder := httptest.NewRecorder()
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C2", "A2", repoT1, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": repoT1, "T2": repoT2})
+	http.HandlerFunc(GetProvenance).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	var provenance helpers.Provenance
+	json.NewDecoder(responseRecorder.Body).Decode(&provenance)
+	return provenance
+}
+
+// custodianIDs returns the asset IDs of the custodians of a chain
+func custodianIDs(provenance helpers.Provenance) []string {
+	var ids []string
+	for _, custodian := range provenance.Custodians {
+		ids = append(ids, custodian.AssetID)
+	}
+	return ids
+}
+
+// TestGetProvenance contains the tests for following the custody chain of an asset
+func TestGetProvenance(t *testing.T) {
+	t.Run("Complete_Chain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repoT1 := mocks.NewMockAgent(ctrl)
+		repoT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		repoT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).Return(openTestJSON(secondCustodianLocation), nil)
+		repoT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).Return(openTestJSON(firstCustodianLocation), nil)
+		repoT2.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C3", "A3")).Return(openTestJSON(thirdCustodianLocation), nil)
+
+		provenance := getProvenance(t, ctrl, repoT1, repoT2)
+
+		assert.Equal(t, []string{"A1", "A2", "A3"}, custodianIDs(provenance), "Custodians are ordered")
+		assert.Len(t, provenance.Transfers, 2, "Every transfer is reported")
+		assert.Equal(t, "sold", provenance.Transfers[0].TransferDescription, "First transfer comes first")
+		assert.Equal(t, "2020-09-01T10:00:00.000Z", provenance.Transfers[1].Timestamp, "Timestamp is reported")
+		assert.Equal(t, "T2", provenance.Transfers[1].Destination.RepoID, "Transfer crosses repos")
+		assert.False(t, provenance.Broken, "Chain is not broken")
+		assert.False(t, provenance.Forked, "Chain is not forked")
+	})
+	t.Run("Broken_Chain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repoT1 := mocks.NewMockAgent(ctrl)
+		repoT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		repoT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).Return(openTestJSON(secondCustodianLocation), nil)
+		repoT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(firstCustodianWithoutTransferLocation), nil)
+		repoT2.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C3", "A3")).Return(nil, helpers.ErrNotFound)
+
+		provenance := getProvenance(t, ctrl, repoT1, repoT2)
+
+		assert.Equal(t, []string{"A1", "A2", "A3"}, custodianIDs(provenance), "Known custodians are reported")
+		assert.True(t, provenance.Broken, "Chain is broken")
+		assert.Len(t, provenance.Issues, 2, "Both breaks are reported")
+		assert.Equal(t, "A1", provenance.Issues[0].Location.AssetID, "Source without the transfer is flagged")
+		assert.Equal(t, "A3", provenance.Issues[1].Location.AssetID, "Missing destination is flagged")
+	})
+	t.Run("Forked_Chain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repoT1 := mocks.NewMockAgent(ctrl)
+		repoT2 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		repoT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).Return(openTestJSON(secondCustodianForkedLocation), nil)
+		repoT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).Return(openTestJSON(firstCustodianLocation), nil)
+		repoT2.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C3", "A4")).Return(nil, helpers.ErrNotFound)
+
+		provenance := getProvenance(t, ctrl, repoT1, repoT2)
+
+		assert.Equal(t, []string{"A1", "A2", "A4"}, custodianIDs(provenance), "Latest transfer is followed")
+		assert.True(t, provenance.Forked, "Chain is forked")
+		assert.Equal(t, "forked", provenance.Issues[0].Kind, "Fork is reported")
+		assert.Equal(t, "A2", provenance.Issues[0].Location.AssetID, "Fork is at the second custodian")
+	})
+	t.Run("Asset_Does_Not_Exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repoT1 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		repoT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).Return(nil, helpers.ErrNotFound)
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C2", "A2", repoT1, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(GetProvenance).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+}
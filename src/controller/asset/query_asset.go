@@ -19,9 +19,11 @@ package asset
 
 import (
 	"chainsource-gateway/agent"
+	"chainsource-gateway/filter"
 	"chainsource-gateway/helpers"
 	"chainsource-gateway/responses"
 	"chainsource-gateway/schema"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -31,6 +33,9 @@ import (
 )
 
 // QueryAsset is a controller function to query assets from a channel on the repository
+// The query is the agent selector in query, or a filter expression in ?q= such as assetType = "Server"
+// With ?cursor= the result is a page with the cursor of and the link to the next page. An empty cursor starts at skip
+// With Accept: application/x-ndjson the matching records are streamed one per line as the agent returns them
 func QueryAsset(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Query Asset")
 	defer span.Finish()
@@ -47,6 +52,19 @@ func QueryAsset(w http.ResponseWriter, r *http.Request) {
 		query = MergeJSONMaps(query.(map[string]interface{}), js.(map[string]interface{}))
 	}
 
+	// ?q= is a filter expression compiled into the query
+	if q := r.URL.Query().Get("q"); q != "" {
+		selector, err := filter.Compile(q)
+		if err == nil && query.(map[string]interface{})["query"] != nil {
+			err = errors.New("q and query can not be combined")
+		}
+		if err != nil {
+			render.Render(w, r, responses.ErrInvalidRequest(err))
+			return
+		}
+		query = MergeJSONMaps(query.(map[string]interface{}), map[string]interface{}{"query": selector})
+	}
+
 	errStr, isValid, err := assetSchema.ValidateQueryAsset(ctx, query.(map[string]interface{}))
 	if err != nil {
 		render.Render(w, r, responses.ErrInternalServer(err))
@@ -60,7 +78,36 @@ func QueryAsset(w http.ResponseWriter, r *http.Request) {
 	var queryArgs agent.RichQueryArgs
 	jsonString, _ := json.Marshal(query)
 	err = json.Unmarshal(jsonString, &queryArgs)
-	result, err := requestAgent.QueryAssets(ctx, agent.QueryArgs{
+
+	// A cursor parameter, empty for the first page, asks for a page with the cursor of the next one
+	_, paged := r.URL.Query()["cursor"]
+	if paged {
+		cursor, err := helpers.ParseCursor(r.URL.Query().Get("cursor"))
+		if err != nil {
+			render.Render(w, r, responses.ErrInvalidRequest(err))
+			return
+		}
+		if queryArgs.Limit <= 0 {
+			queryArgs.Limit = helpers.DefaultPageLimit
+		}
+		if cursor.Bookmark != "" {
+			queryArgs.Bookmark = cursor.Bookmark
+			queryArgs.Skip = 0
+		} else if r.URL.Query().Get("cursor") != "" {
+			queryArgs.Skip = cursor.Offset
+		}
+	}
+
+	if helpers.WantsNDJSON(r) {
+		if paged {
+			render.Render(w, r, responses.ErrInvalidRequest(helpers.ErrPagedStream))
+			return
+		}
+		streamQuery(ctx, w, r, requestAgent, assetVars.ChannelID, queryArgs)
+		return
+	}
+
+	result, bookmark, err := agent.QueryAssetPage(ctx, requestAgent, agent.QueryArgs{
 		ChannelID: assetVars.ChannelID,
 	}, queryArgs)
 	if err != nil {
@@ -73,8 +120,55 @@ func QueryAsset(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	// err = json.NewDecoder(result).Decode(&result)
-	render.JSON(w, r, result)
+	if !paged {
+		render.JSON(w, r, result)
+		return
+	}
+
+	// Agents that return a bookmark page with it, the others are paged with skip. A full page may have a next one
+	page := helpers.Page{Items: result}
+	if result == nil {
+		page.Items = map[string]interface{}{}
+	}
+	if len(result) == queryArgs.Limit {
+		if bookmark != "" {
+			page.NextCursor = helpers.EncodeBookmarkCursor(bookmark)
+		} else if queryArgs.Bookmark == "" {
+			page.NextCursor = helpers.EncodeCursor(queryArgs.Skip + queryArgs.Limit)
+		}
+	}
+	if page.NextCursor != "" {
+		page.Next = helpers.NextLink(r.URL, page.NextCursor)
+	}
+	render.JSON(w, r, page)
+}
+
+// streamQuery writes the records matching a query one per line, with their asset ID, as they are read from the agent
+func streamQuery(ctx context.Context, w http.ResponseWriter, r *http.Request, requestAgent agent.Agent, channelID string,
+	queryArgs agent.RichQueryArgs) {
+	resultStream, err := requestAgent.QueryAssetsStream(ctx, agent.QueryArgs{ChannelID: channelID}, queryArgs)
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+	defer resultStream.Close()
+
+	lines := helpers.NewNDJSONWriter(w)
+	err = helpers.StreamObject(resultStream, func(assetID string, record json.RawMessage) error {
+		if assetID == agent.BookmarkKey {
+			return nil
+		}
+		return lines.Write(helpers.QueryRecord{AssetID: assetID, Record: record})
+	})
+	if err != nil {
+		lines.WriteError(err)
+	}
 }
 
 // MergeJSONMaps merges json maps together
@@ -18,12 +18,17 @@
 package asset
 
 import (
+	"chainsource-gateway/agent"
 	"chainsource-gateway/helpers"
 	"chainsource-gateway/mocks"
+	"context"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -175,3 +180,153 @@ func TestQueryAllAssetsAgentErrorConditions(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadGateway, responseRecorder.Result().StatusCode, "Response Should be 502 bad gateway")
 }
+
+// TestQueryAssetsPaged tests emulated paging with skip and passing agent bookmarks through
+func TestQueryAssetsPaged(t *testing.T) {
+	query := func(t *testing.T, mockAgent agent.Agent, ctrl *gomock.Controller, link string) helpers.Page {
+		responseRecorder := httptest.NewRecorder()
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", link, nil), "T1", "C1", "", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectMockQueryAssetContext(mockRequest, map[string]interface{}{}, nil, 2, 3)
+		http.HandlerFunc(QueryAsset).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+		var page helpers.Page
+		json.NewDecoder(responseRecorder.Result().Body).Decode(&page)
+		return page
+	}
+
+	t.Run("Emulated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			mockAgent.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""),
+				agent.RichQueryArgs{Query: map[string]interface{}{}, Skip: 3, Limit: 2}).
+				Return(map[string]interface{}{"A4": "x", "A5": "x"}, nil),
+			mockAgent.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""),
+				agent.RichQueryArgs{Query: map[string]interface{}{}, Skip: 5, Limit: 2}).
+				Return(map[string]interface{}{"A6": "x"}, nil),
+		)
+		page := query(t, mockAgent, ctrl, "/api/v1/repo/T1/chan/C1/asset/_query?cursor=")
+		assert.Equal(t, map[string]interface{}{"A4": "x", "A5": "x"}, page.Items, "First page starts at skip")
+		assert.Equal(t, helpers.EncodeCursor(5), page.NextCursor, "Full page has a next one")
+		page = query(t, mockAgent, ctrl, page.Next)
+		assert.Equal(t, map[string]interface{}{"A6": "x"}, page.Items, "Second page starts after the first")
+		assert.Empty(t, page.Next, "Last page has no next link")
+	})
+	t.Run("Bookmarks", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			mockAgent.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""),
+				agent.RichQueryArgs{Query: map[string]interface{}{}, Skip: 3, Limit: 2}).
+				Return(map[string]interface{}{"A4": "x", "A5": "x", agent.BookmarkKey: "g1AAAA"}, nil),
+			mockAgent.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""),
+				agent.RichQueryArgs{Query: map[string]interface{}{}, Limit: 2, Bookmark: "g1AAAA"}).
+				Return(map[string]interface{}{"A6": "x", "A7": "x"}, nil),
+		)
+		page := query(t, mockAgent, ctrl, "/?cursor=")
+		assert.Equal(t, map[string]interface{}{"A4": "x", "A5": "x"}, page.Items, "Bookmark is not a result")
+		assert.Equal(t, helpers.EncodeBookmarkCursor("g1AAAA"), page.NextCursor, "Cursor wraps the bookmark")
+		page = query(t, mockAgent, ctrl, page.Next)
+		assert.Len(t, page.Items, 2, "Second page is read at the bookmark")
+		assert.Empty(t, page.NextCursor, "Bookmarked pages without a new bookmark end the query")
+	})
+	t.Run("Invalid_Cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		responseRecorder := httptest.NewRecorder()
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/?cursor=%25%25", nil), "T1", "C1", "",
+			mocks.NewMockAgent(ctrl), mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectMockQueryAssetContext(mockRequest, map[string]interface{}{}, nil, 2, 3)
+		http.HandlerFunc(QueryAsset).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Result().StatusCode, "Response Should be 400 Bad Request")
+	})
+}
+
+// TestQueryAssetsNDJSON tests streaming the matching records one per line
+func TestQueryAssetsNDJSON(t *testing.T) {
+	query := func(ctrl *gomock.Controller, mockAgent agent.Agent, link string) *httptest.ResponseRecorder {
+		mockRequest := httptest.NewRequest("GET", link, nil)
+		mockRequest.Header.Set("Accept", helpers.ContentTypeNDJSON)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectMockQueryAssetContext(mockRequest, map[string]interface{}{}, nil, 0, 0)
+		http.HandlerFunc(QueryAsset).ServeHTTP(responseRecorder, mockRequest)
+		return responseRecorder
+	}
+
+	t.Run("Streamed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryAssetsStream(gomock.Any(), mocks.AgentQueryFor("C1", ""), gomock.Any()).
+			Return(ioutil.NopCloser(strings.NewReader(`{"A1": {"assetType": "Server"}, "_bookmark": "g1", "A2": {}`)), nil)
+		responseRecorder := query(ctrl, mockAgent, "/")
+		assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+		lines := strings.Split(strings.TrimSuffix(responseRecorder.Body.String(), "\n"), "\n")
+		assert.Len(t, lines, 3, "Two records and an error")
+		assert.Equal(t, `{"assetID":"A1","record":{"assetType":"Server"}}`, lines[0], "Records carry their asset ID")
+		assert.Equal(t, `{"assetID":"A2","record":{}}`, lines[1], "Bookmark is not a record")
+		assert.True(t, strings.HasPrefix(lines[2], `{"error":`), "Truncated result ends with an error line")
+	})
+	t.Run("Agent_Errors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryAssetsStream(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, helpers.ErrUnauthorized)
+		assert.Equal(t, http.StatusUnauthorized, query(ctrl, mockAgent, "/").Result().StatusCode,
+			"Errors before the stream starts keep their status")
+		assert.Equal(t, http.StatusBadRequest, query(ctrl, mockAgent, "/?cursor=").Result().StatusCode,
+			"Streams are not paged")
+	})
+}
+
+// TestQueryAssetsFilterExpression tests querying with a filter expression in ?q=
+func TestQueryAssetsFilterExpression(t *testing.T) {
+	query := func(ctrl *gomock.Controller, mockAgent agent.Agent, q string, selector interface{}) *httptest.ResponseRecorder {
+		mockRequest := httptest.NewRequest("GET", "/?q="+url.QueryEscape(q), nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "assetQueryVars",
+			map[string]interface{}{"query": selector, "limit": 10}))
+		http.HandlerFunc(QueryAsset).ServeHTTP(responseRecorder, mockRequest)
+		return responseRecorder
+	}
+
+	t.Run("Compiled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryAssets(gomock.Any(), mocks.AgentQueryFor("C1", ""), agent.RichQueryArgs{
+			Query: map[string]interface{}{"$and": []interface{}{
+				map[string]interface{}{"assetType": map[string]interface{}{"$eq": "Server"}},
+				map[string]interface{}{"assetMetadata.serial": map[string]interface{}{"$regex": "^AB.*$"}},
+			}},
+			Limit: 10,
+		}).Return(map[string]interface{}{"A1": map[string]interface{}{}}, nil)
+		responseRecorder := query(ctrl, mockAgent, `assetType = "Server" and assetMetadata.serial ~ "AB*"`, nil)
+		assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		responseRecorder := query(ctrl, mockAgent, `assetType = "Server" and`, nil)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Result().StatusCode, "Response Should be 400 Bad Request")
+		assert.Contains(t, responseRecorder.Body.String(), "syntax error at position 25", "Error has its position")
+
+		responseRecorder = query(ctrl, mockAgent, `assetType = "Server"`, map[string]interface{}{})
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Result().StatusCode, "q and query are exclusive")
+	})
+}
@@ -22,13 +22,17 @@ import (
 	"chainsource-gateway/helpers"
 	"chainsource-gateway/responses"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/render"
 	"github.com/opentracing/opentracing-go"
 )
 
 // RetrieveAsset is a controller function to retrieve an asset on a channel on the repository with a given assetID
+// The state of the asset at a past moment is retrieved with asOf, an RFC 3339 timestamp, or with revision, the
+// number of the commit in the audit trail. Both are reconstructed from the audit trail of the agent
 func RetrieveAsset(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Retrieve Asset")
 	defer span.Finish()
@@ -37,11 +41,28 @@ func RetrieveAsset(w http.ResponseWriter, r *http.Request) {
 	log.Debug().Msgf("Getting %s/%s from agent at %s:%d", assetVars.ChannelID, assetVars.AssetID,
 		requestAgent.GetHost(), requestAgent.GetPort())
 
+	point, err := parseHistoryPoint(r)
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
 	var result helpers.Asset
-	resultStream, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+	args := agent.QueryArgs{
 		ChannelID: assetVars.ChannelID,
 		AssetID:   assetVars.AssetID,
-	})
+	}
+	// Past states are reconstructed from the audit trail
+	var revision int
+	if point.isSet() {
+		result, revision, err = assetAt(ctx, requestAgent, args, point)
+	} else {
+		var resultStream io.ReadCloser
+		resultStream, err = requestAgent.QueryStream(ctx, args)
+		if err == nil {
+			json.NewDecoder(resultStream).Decode(&result)
+		}
+	}
 	if err != nil {
 		if err == helpers.ErrNotFound {
 			render.Render(w, r, responses.ErrDoesNotExist(err))
@@ -52,6 +73,9 @@ func RetrieveAsset(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	err = json.NewDecoder(resultStream).Decode(&result)
+	if revision > 0 {
+		w.Header().Set("X-Asset-Revision", strconv.Itoa(revision))
+	}
+	annotateAdvisories(&result, activeAdvisories(ctx))
 	render.JSON(w, r, result)
 }
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keystore"
+	"chainsource-gateway/pgp"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/tracing"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// signKeyIDKey is the optional member of the sign request body that picks a key of the keystore
+const signKeyIDKey = "keyID"
+
+// SignAsset is a controller function that signs an asset with a key held by the gateway
+// The key is bound to the repo or the manufacturer of the asset, or picked with {"keyID": "..."}
+// The canonical signing input is signed as a detached JWS, which sets the manufactureSignature and
+// manufactureFingerprint of the asset, and the asset is committed with a SIGN commit type
+func SignAsset(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Sign asset")
+	defer span.Finish()
+
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+	signingKeys, _ := r.Context().Value("keystore").(keystore.Keystore)
+
+	log.Info().Msgf("Signing %s/%s on agent at %s:%d", assetVars.ChannelID, assetVars.AssetID,
+		requestAgent.GetHost(), requestAgent.GetPort())
+
+	if signingKeys == nil || !signingKeys.Enabled() {
+		render.Render(w, r, responses.ErrSigningDisabled(keystore.ErrKeystoreDisabled))
+		return
+	}
+	body, _ := r.Context().Value("JSONBody").(map[string]interface{})
+	keyID, isString := body[signKeyIDKey].(string)
+	if body[signKeyIDKey] != nil && !isString {
+		render.Render(w, r, responses.ErrInvalidRequest(errors.New("keyID must be a string")))
+		return
+	}
+
+	// Get current asset state from agent
+	result, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+	var asset helpers.Asset
+	if err = json.NewDecoder(result).Decode(&asset); err != nil {
+		render.Render(w, r, responses.ErrAgent(err))
+		return
+	}
+	if asset.ReadOnly {
+		render.Render(w, r, responses.ErrReadOnly())
+		return
+	}
+
+	key, err := signingKeys.Select(ctx, assetVars.RepoID, asset.AssetManufacturer, keyID)
+	if err != nil {
+		if err == keystore.ErrNoSigningKey || err == keystore.ErrKeyNotBound || err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrNoSigningKey(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+
+	meta := map[string]interface{}{}
+	if asset.AssetMetadata != nil {
+		existing, isMap := asset.AssetMetadata.(map[string]interface{})
+		if !isMap {
+			render.Render(w, r, responses.ErrInvalidRequest(errors.New("assetMetadata must be an object to hold the signature")))
+			return
+		}
+		for name, value := range existing {
+			meta[name] = value
+		}
+	}
+	meta[fingerprintKey] = key.KeyID
+	meta[signatureSchemeKey] = key.Scheme
+	asset.AssetMetadata = meta
+
+	mode := pgp.GetCanonicalizationMode(asset.StandardVersion)
+	input, _, _ := extractSigningInput(asset)
+	signingInput, err := pgp.SigningInput(mode, input)
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	asset.ManufactureSignature, err = pgp.SignDetachedJWS(key.KeyID, signingInput, func(message []byte) ([]byte, error) {
+		return signingKeys.Sign(ctx, key.KeyID, message)
+	})
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Signing with the keystore failed")
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+
+	if rejection := applySignaturePolicy(ctx, r, assetVars, &asset); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	// Commit
+	res, err := requestAgent.Commit(ctx, agent.CommitArgs{
+		ChannelID:  assetVars.ChannelID,
+		AssetID:    assetVars.AssetID,
+		CommitType: "SIGN",
+		Payload:    asset,
+	})
+	if err != nil {
+		if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+
+	log.Info().Interface("agentResponse", res).Msgf("Signed with gateway key %s", key.KeyID)
+	render.Render(w, r, responses.SuccessfulSignResponse(key.KeyID, key.Scheme, mode, asset.ManufactureSignature))
+}
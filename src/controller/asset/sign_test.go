@@ -0,0 +1,148 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keystore"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/pgp"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const assetToBeSignedLocation = "../../testdata/asset_controller_tests/sign/assetToBeSigned.json"
+const assetReadOnlyLocation = "../../testdata/asset_controller_tests/update/assetToBeUpdatedReadOnly.json"
+
+// injectKeystore injects a keystore into a request
+func injectKeystore(r *http.Request, signingKeys keystore.Keystore) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), "keystore", signingKeys))
+}
+
+// TestSignAsset contains the tests for signing an asset with a gateway key
+func TestSignAsset(t *testing.T) {
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		defer ctrl.Finish()
+		publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+		var finalAsset helpers.Asset
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(assetToBeSignedLocation), nil)
+		mockKeystore.EXPECT().Enabled().Return(true)
+		mockKeystore.EXPECT().Select(gomock.Any(), "T1", "A Valid Manufacturer", "").
+			Return(keystore.SigningKey{KeyID: "K1", Scheme: pgp.SchemeJWS}, nil)
+		mockKeystore.EXPECT().Sign(gomock.Any(), "K1", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, message []byte) ([]byte, error) {
+				return ed25519.Sign(privateKey, message), nil
+			})
+		mockKeystore.EXPECT().ResolvePublicKey(gomock.Any(), "K1").Return(publicKey, nil)
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "SIGN")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectKeystore(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockKeystore)
+		http.HandlerFunc(SignAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.NotContains(t, responseRecorder.Body.String(), "PRIVATE", "No key material is returned")
+		meta := finalAsset.AssetMetadata.(map[string]interface{})
+		assert.Equal(t, "K1", meta[fingerprintKey], "Fingerprint is set")
+		assert.Equal(t, pgp.SchemeJWS, meta[signatureSchemeKey], "Scheme is set")
+		assert.Equal(t, "A Valid Serial", meta["serialNumber"], "Metadata is kept")
+
+		input, envelope, _ := extractSigningInput(finalAsset)
+		_, err := pgp.NewJWSValidator(mockKeystore).Validate(context.Background(), pgp.ValidateArgs{
+			Fingerprint: envelope.Fingerprint,
+			Signature:   finalAsset.ManufactureSignature,
+			Input:       input,
+			Scheme:      envelope.Scheme,
+		})
+		assert.NoError(t, err, "Committed signature verifies")
+	})
+	t.Run("When_Signing_Disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		defer ctrl.Finish()
+
+		mockKeystore.EXPECT().Enabled().Return(false)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectKeystore(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockKeystore)
+		http.HandlerFunc(SignAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code, "Response Should be 403 FORBIDDEN")
+	})
+	t.Run("When_No_Key_Bound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(assetToBeSignedLocation), nil)
+		mockKeystore.EXPECT().Enabled().Return(true)
+		mockKeystore.EXPECT().Select(gomock.Any(), "T1", "A Valid Manufacturer", "").
+			Return(keystore.SigningKey{}, keystore.ErrNoSigningKey)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectKeystore(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockKeystore)
+		http.HandlerFunc(SignAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code, "Response Should be 422 UNPROCESSABLE ENTITY")
+	})
+	t.Run("When_ReadOnly", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(assetReadOnlyLocation), nil)
+		mockKeystore.EXPECT().Enabled().Return(true)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectKeystore(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockKeystore)
+		http.HandlerFunc(SignAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/pgp"
+	"chainsource-gateway/responses"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// signatureVerificationKey is the assetMetadata key under which the gateway records the signature policy outcome
+var signatureVerificationKey = "manufactureSignatureVerification"
+
+// errNoSignature is an error when an asset carries no manufacture signature
+var errNoSignature = errors.New("asset has no manufacture signature")
+
+// errNoFingerprint is an error when an asset signature needs a fingerprint, but none is present
+var errNoFingerprint = errors.New("asset has no manufacture fingerprint")
+
+// untrustedSignerError is an error when a valid signature was made with a key the registry does not trust for the asset
+type untrustedSignerError struct {
+	cause error
+}
+
+// Error returns the reason the key is not trusted
+func (e untrustedSignerError) Error() string {
+	return e.cause.Error()
+}
+
+const (
+	verificationValid      = "valid"
+	verificationInvalid    = "invalid"
+	verificationUnsigned   = "unsigned"
+	verificationUntrusted  = "untrusted"
+	verificationUnverified = "unverified"
+)
+
+// signatureVerification is a type holding the outcome of a signature check on create or update
+type signatureVerification struct {
+	Status    string `json:"status"`
+	Policy    string `json:"policy"`
+	Scheme    string `json:"scheme,omitempty"`
+	KeyID     string `json:"keyID,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	CheckedAt string `json:"checkedAt"`
+}
+
+// verifyManufactureSignature validates the manufacture signature of an asset with a signature validator.
+// When a key registry is given, the signing key must also be registered, trusted for the assetManufacturer and
+// valid at signedAt, the time the signature was committed
+func verifyManufactureSignature(ctx context.Context, validator pgp.SignatureValidator, registry keyregistry.KeyRegistry,
+	asset helpers.Asset, signedAt time.Time) (map[string]interface{}, error) {
+	if len(asset.ManufactureSignature) == 0 {
+		return nil, errNoSignature
+	}
+	input, envelope, hasMetadata := extractSigningInput(asset)
+	if !hasMetadata {
+		return nil, errNoSignature
+	}
+	// CMS signatures carry the signer certificate, every other scheme needs the fingerprint to find the key
+	if len(envelope.Fingerprint) == 0 && envelope.Scheme != pgp.SchemeCMS {
+		return nil, errNoFingerprint
+	}
+	result, err := validator.Validate(ctx, pgp.ValidateArgs{
+		Fingerprint:      envelope.Fingerprint,
+		Signature:        asset.ManufactureSignature,
+		Input:            input,
+		Canonicalization: pgp.GetCanonicalizationMode(asset.StandardVersion),
+		Scheme:           envelope.Scheme,
+	})
+	if err != nil || registry == nil {
+		return result, err
+	}
+
+	keyID, _ := result["keyID"].(string)
+	if keyID == "" {
+		keyID = envelope.Fingerprint
+	}
+	key, err := registry.Authorize(ctx, keyID, asset.AssetManufacturer, signedAt)
+	if err != nil {
+		return result, untrustedSignerError{cause: err}
+	}
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+	result["owner"] = key.Owner
+	return result, nil
+}
+
+// verifierErrorResponse maps an error of a signature validator to its response, keeping the detail the verifier reported.
+// Errors without a kind are treated as invalid signatures
+func verifierErrorResponse(err error) render.Renderer {
+	detail := pgp.VerifierDetail(err)
+	switch {
+	case errors.Is(err, pgp.ErrVerifierUnavailable):
+		return responses.ErrVerifierUnavailable(err, detail)
+	case errors.Is(err, pgp.ErrVerifierTimeout):
+		return responses.ErrVerifierTimeout(err, detail)
+	case errors.Is(err, pgp.ErrUnknownKey):
+		return responses.ErrUnknownSigningKey(err, detail)
+	default:
+		return responses.ErrInvalidSignature(err, detail)
+	}
+}
+
+// isVerifierOutage reports whether a signature could not be checked, as opposed to being checked and failing
+func isVerifierOutage(err error) bool {
+	return errors.Is(err, pgp.ErrVerifierUnavailable) || errors.Is(err, pgp.ErrVerifierTimeout)
+}
+
+// requestKeyRegistry returns the key registry of a request, nil when signers are not checked against a registry
+func requestKeyRegistry(r *http.Request) keyregistry.KeyRegistry {
+	registry, _ := r.Context().Value("keyRegistry").(keyregistry.KeyRegistry)
+	return registry
+}
+
+// applySignaturePolicy checks the manufacture signature of an asset about to be committed against the policy of the channel.
+// A verification status supplied by the client is always discarded. In warn and enforce mode the outcome is recorded in
+// the asset metadata. A non nil renderer is returned when the asset must be rejected
+func applySignaturePolicy(ctx context.Context, r *http.Request, assetVars helpers.AssetRoutingVars, asset *helpers.Asset) render.Renderer {
+	meta, isMap := asset.AssetMetadata.(map[string]interface{})
+	if isMap {
+		delete(meta, signatureVerificationKey)
+	}
+
+	policy := pgp.GetSignaturePolicy(assetVars.RepoID, assetVars.ChannelID)
+	if policy == pgp.PolicyOff {
+		return nil
+	}
+	validator, ok := r.Context().Value("signatureValidator").(pgp.SignatureValidator)
+	if !ok {
+		return responses.ErrInternalServer(errors.New("no signature validator configured"))
+	}
+
+	// The asset is committed now, so the key must be valid now
+	checkedAt := time.Now().UTC()
+	verification := signatureVerification{
+		Status:    verificationValid,
+		Policy:    policy,
+		CheckedAt: checkedAt.Format(time.RFC3339),
+	}
+	result, err := verifyManufactureSignature(ctx, validator, requestKeyRegistry(r), *asset, checkedAt)
+	if err == errNoSignature {
+		verification.Status = verificationUnsigned
+		verification.Detail = err.Error()
+	} else if isVerifierOutage(err) {
+		// an outage says nothing about the signature, enforce mode fails the request so it can be retried
+		if policy == pgp.PolicyEnforce {
+			return verifierErrorResponse(err)
+		}
+		verification.Status = verificationUnverified
+		verification.Detail = err.Error()
+	} else if _, untrusted := err.(untrustedSignerError); untrusted {
+		verification.Status = verificationUntrusted
+		verification.Detail = err.Error()
+	} else if err != nil {
+		verification.Status = verificationInvalid
+		verification.Detail = err.Error()
+	} else {
+		verification.Scheme, _ = result["scheme"].(string)
+		verification.KeyID, _ = result["keyID"].(string)
+	}
+
+	if policy == pgp.PolicyEnforce && verification.Status != verificationValid {
+		log.Info().Msgf("Rejecting %s/%s, signature is %s", assetVars.ChannelID, assetVars.AssetID, verification.Status)
+		return responses.ErrSignaturePolicy(err)
+	}
+	if verification.Status != verificationValid {
+		log.Warn().Msgf("Committing %s/%s with %s signature", assetVars.ChannelID, assetVars.AssetID, verification.Status)
+	}
+
+	if !isMap {
+		if asset.AssetMetadata != nil {
+			return nil
+		}
+		meta = map[string]interface{}{}
+		asset.AssetMetadata = meta
+	}
+	meta[signatureVerificationKey] = verification
+	return nil
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/pgp"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// setSignaturePolicy configures a signature policy for repo T1 and returns a function restoring the config
+func setSignaturePolicy(mode string) func() {
+	viper.Set("signaturePolicy", []map[string]interface{}{{"repoID": "T1", "mode": mode}})
+	return func() { viper.Set("signaturePolicy", nil) }
+}
+
+// TestCreateWithSignaturePolicy contains the tests for signature checks on create
+func TestCreateWithSignaturePolicy(t *testing.T) {
+	t.Run("Enforce_Valid", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyEnforce)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+		var finalAsset helpers.Asset
+
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(map[string]interface{}{"valid": true, "scheme": "pgp", "keyID": "FP"}, nil)
+		mockAgent.EXPECT().Commit(gomock.Any(),
+			mocks.AgentCommitTo("C1", "A1", "CREATE")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(signedAssetLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		handler := http.HandlerFunc(CreateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Result().StatusCode, "Response Should be 201 CREATED")
+		verification := finalAsset.AssetMetadata.(map[string]interface{})[signatureVerificationKey].(signatureVerification)
+		assert.Equal(t, verificationValid, verification.Status, "Verification status is recorded")
+		assert.Equal(t, "FP", verification.KeyID, "Verifying key is recorded")
+	})
+	t.Run("Enforce_Invalid", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyEnforce)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("bad signature"))
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(signedAssetLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		handler := http.HandlerFunc(CreateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Result().StatusCode, "Response Should be 422 UNPROCESSABLE ENTITY")
+	})
+	t.Run("Enforce_Unsigned", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyEnforce)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(signedAssetEmptySLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		handler := http.HandlerFunc(CreateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Result().StatusCode, "Response Should be 422 UNPROCESSABLE ENTITY")
+	})
+	t.Run("Warn_Invalid", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyWarn)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+		var finalAsset helpers.Asset
+
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("bad signature"))
+		mockAgent.EXPECT().Commit(gomock.Any(),
+			mocks.AgentCommitTo("C1", "A1", "CREATE")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(signedAssetLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		handler := http.HandlerFunc(CreateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Result().StatusCode, "Response Should be 201 CREATED")
+		verification := finalAsset.AssetMetadata.(map[string]interface{})[signatureVerificationKey].(signatureVerification)
+		assert.Equal(t, verificationInvalid, verification.Status, "Verification status is recorded")
+		assert.Equal(t, "bad signature", verification.Detail, "Verification failure is recorded")
+	})
+	t.Run("Enforce_Verifier_Unavailable", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyEnforce)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(nil, &pgp.VerifierError{Kind: pgp.ErrVerifierUnavailable})
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(signedAssetLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		handler := http.HandlerFunc(CreateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Result().StatusCode, "Response Should be 503 SERVICE UNAVAILABLE")
+	})
+	t.Run("Warn_Verifier_Unavailable", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyWarn)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+		var finalAsset helpers.Asset
+
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(nil, &pgp.VerifierError{Kind: pgp.ErrVerifierTimeout})
+		mockAgent.EXPECT().Commit(gomock.Any(),
+			mocks.AgentCommitTo("C1", "A1", "CREATE")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(signedAssetLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		handler := http.HandlerFunc(CreateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Result().StatusCode, "Response Should be 201 CREATED")
+		verification := finalAsset.AssetMetadata.(map[string]interface{})[signatureVerificationKey].(signatureVerification)
+		assert.Equal(t, verificationUnverified, verification.Status, "Outage is recorded as unverified")
+	})
+	t.Run("Other_Repo_Off", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyEnforce)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().Commit(gomock.Any(),
+			mocks.AgentCommitTo("C1", "A1", "CREATE")).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(signedAssetEmptySLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T2", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		handler := http.HandlerFunc(CreateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Result().StatusCode, "Response Should be 201 CREATED")
+	})
+}
+
+// TestUpdateWithSignaturePolicy contains the tests for signature checks on update
+func TestUpdateWithSignaturePolicy(t *testing.T) {
+	t.Run("Enforce_Invalid", func(t *testing.T) {
+		defer setSignaturePolicy(pgp.PolicyEnforce)()
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(assetToBeUpdatedPath), nil)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("bad signature"))
+
+		mockRequest := httptest.NewRequest("PUT", "/", openTestJSON(signedAssetLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		handler := http.HandlerFunc(UpdateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Result().StatusCode, "Response Should be 422 UNPROCESSABLE ENTITY")
+	})
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/pgp"
+	"chainsource-gateway/responses"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// canonicalizationHeader is the response header that reports the canonicalization mode of a signing input
+const canonicalizationHeader = "X-Canonicalization"
+
+// signingMetadata is a type holding the signature envelope fields that are kept in assetMetadata
+type signingMetadata struct {
+	Fingerprint string
+	Scheme      string
+}
+
+// extractSigningInput splits an asset into the input covered by the manufacture signature and the signature envelope.
+// The asset metadata is copied, so the asset passed in is left unmodified
+func extractSigningInput(asset helpers.Asset) (input helpers.AssetNoChildParent, envelope signingMetadata, hasMetadata bool) {
+	meta, _ := asset.AssetMetadata.(map[string]interface{})
+	hasMetadata = len(meta) > 0
+	strippedMeta := make(map[string]interface{}, len(meta))
+	for key, value := range meta {
+		switch key {
+		case fingerprintKey:
+			envelope.Fingerprint, _ = value.(string)
+		case signatureSchemeKey:
+			envelope.Scheme, _ = value.(string)
+		case signatureVerificationKey:
+			// recorded by the gateway after the asset was signed
+		default:
+			strippedMeta[key] = value
+		}
+	}
+	envelope.Scheme = pgp.NormalizeScheme(envelope.Scheme)
+	if meta != nil {
+		asset.AssetMetadata = strippedMeta
+	}
+	input = helpers.AssetNoChildParent(asset)
+	return
+}
+
+// GetSigningInput is a controller function that returns the exact bytes a manufacturer must sign for an asset
+// The canonicalization mode is selected by the standardVersion of the asset, unless overridden with ?canonicalization=
+func GetSigningInput(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get signing input")
+	defer span.Finish()
+
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+	log.Debug().Msgf("Getting signing input of %s/%s from agent at %s:%d", assetVars.ChannelID, assetVars.AssetID,
+		requestAgent.GetHost(), requestAgent.GetPort())
+
+	var result helpers.Asset
+	resultStream, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+		return
+	}
+	err = json.NewDecoder(resultStream).Decode(&result)
+	if err != nil {
+		render.Render(w, r, responses.ErrAgent(err))
+		return
+	}
+
+	mode := r.URL.Query().Get("canonicalization")
+	if mode == "" {
+		mode = pgp.GetCanonicalizationMode(result.StandardVersion)
+	}
+	input, _, _ := extractSigningInput(result)
+	signingInput, err := pgp.SigningInput(mode, input)
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(canonicalizationHeader, mode)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(signingInput)
+}
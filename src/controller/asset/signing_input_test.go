@@ -0,0 +1,105 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/pgp"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetSigningInput is the happy path for the signing input API
+func TestGetSigningInput(t *testing.T) {
+	t.Run("When_Canonicalization_JCS", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(signedAssetLocation), nil)
+		mockRequest := httptest.NewRequest("GET", "/?canonicalization=jcs", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		handler := http.HandlerFunc(GetSigningInput)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		body, _ := ioutil.ReadAll(responseRecorder.Result().Body)
+		assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+		assert.Equal(t, "jcs", responseRecorder.Result().Header.Get(canonicalizationHeader), "Mode is reported")
+		assert.True(t, strings.HasPrefix(string(body), `{"assetDescription":"A Valid Description",`), "Members are sorted")
+		assert.Contains(t, string(body), `"assetMetadata":{}`, "Fingerprint is not part of the signing input")
+		assert.NotContains(t, string(body), "manufactureSignature", "Signature is not part of the signing input")
+	})
+	t.Run("When_Canonicalization_Unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(signedAssetLocation), nil)
+		mockRequest := httptest.NewRequest("GET", "/?canonicalization=xml", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		handler := http.HandlerFunc(GetSigningInput)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Result().StatusCode, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("When_Asset_DoesNotExist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(nil, helpers.ErrNotFound)
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		handler := http.HandlerFunc(GetSigningInput)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Result().StatusCode, "Response Should be 404 NOT FOUND")
+	})
+}
+
+// Test_extractSigningInput tests that signing metadata is stripped without touching the original asset
+func Test_extractSigningInput(t *testing.T) {
+	meta := map[string]interface{}{fingerprintKey: "FP", signatureSchemeKey: "JWS", "serial": "S1"}
+	asset := helpers.Asset{AssetMetadata: meta, ManufactureSignature: "SIG"}
+
+	input, envelope, hasMetadata := extractSigningInput(asset)
+	assert.Equal(t, "FP", envelope.Fingerprint, "Fingerprint is extracted")
+	assert.Equal(t, pgp.SchemeJWS, envelope.Scheme, "Scheme is extracted and normalized")
+	assert.True(t, hasMetadata, "Metadata is present")
+	assert.Equal(t, map[string]interface{}{"serial": "S1"}, input.AssetMetadata, "Envelope is stripped from the input")
+	assert.Equal(t, "FP", meta[fingerprintKey], "Original metadata is left unmodified")
+}
@@ -20,11 +20,14 @@ package asset
 import (
 	"chainsource-gateway/agent"
 	"chainsource-gateway/helpers"
+	"chainsource-gateway/offers"
 	"chainsource-gateway/responses"
 	"chainsource-gateway/schema"
 	"chainsource-gateway/tracing"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -42,6 +45,11 @@ import (
 //
 // After the transfer is complete, the origin asset becomes read only
 // The origin asset can still be transferred again
+//
+// When the request carries a senderSignature over the transfer, the transfer event is recorded as pending and
+// completes once the receiver countersigns it, see CountersignTransfer. It lapses after pendingTransferTTL unless the
+// sender cancels it with CancelTransfer or the receiver declines it with DeclineTransfer first. Receiving channels
+// listed in requireTransferOffers reject unsigned transfers, they are offered with OfferTransfer instead
 func TransferAsset(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Transfer asset")
 	defer span.Finish()
@@ -69,6 +77,14 @@ func TransferAsset(w http.ResponseWriter, r *http.Request) {
 		render.Render(w, r, responses.ErrInvalidRequest(err))
 		return
 	}
+	includeChildren := r.URL.Query().Get("includeChildren") == "true"
+	// Without a countersignature the receiver has no say in a direct transfer, so channels can insist on offers
+	destination := transferDestinationElement.AssetElement
+	if transferDestinationElement.SenderSignature == nil && offers.RequiresOffer(destination.RepoID, destination.ChannelID) {
+		render.Render(w, r, responses.ErrOfferRequired(fmt.Errorf("%s/%s only receives transfers it accepts as offers",
+			destination.RepoID, destination.ChannelID)))
+		return
+	}
 
 	// Get origin asset state from agent
 	log.Debug().Msg("Getting origin asset state")
@@ -92,33 +108,25 @@ func TransferAsset(w http.ResponseWriter, r *http.Request) {
 
 	childSpan.Finish()
 
-	// Get details of destination asset from an agents
-	log.Debug().Msg("Getting current destination asset state")
-	childSpan = opentracing.StartSpan("Get destination asset state", opentracing.ChildOf(span.Context()))
-	ctx = opentracing.ContextWithSpan(ctx, childSpan)
-
-	destinationAssetAgent, _, err := getChildAssetContextFromAssetElement(ctx, transferDestinationElement.AssetElement)
+	// Get details of destination asset from an agents, the destinations of a subtree are checked together
+	var destinationAssetAgent agent.Agent
+	var rejection render.Renderer
+	if !includeChildren {
+		log.Debug().Msg("Getting current destination asset state")
+		childSpan = opentracing.StartSpan("Get destination asset state", opentracing.ChildOf(span.Context()))
+		ctx = opentracing.ContextWithSpan(ctx, childSpan)
 
-	if err == nil {
-		err = errors.New("Destination asset already exists")
-		tracing.LogAndTraceErr(log, childSpan, err, "Invalid transfer operation")
-		render.Render(w, r, responses.ErrAlreadyExists(err))
-		childSpan.Finish()
-		return
-	}
-	if err != helpers.ErrNotFound {
-		if err == helpers.ErrUnauthorized {
-			render.Render(w, r, responses.ErrUnauthorizedQueryDestination(err))
-		} else {
-			render.Render(w, r, responses.ErrFailedQueryDestination(err))
+		destinationAssetAgent, rejection = checkTransferDestination(ctx, transferDestinationElement.AssetElement)
+		if rejection != nil {
+			render.Render(w, r, rejection)
+			childSpan.Finish()
+			return
 		}
+		log.Debug().Msgf("Destination asset is %s/%s from agent at %s:%d", transferDestinationElement.ChannelID, transferDestinationElement.AssetID,
+			destinationAssetAgent.GetHost(), destinationAssetAgent.GetPort())
+
 		childSpan.Finish()
-		return
 	}
-	log.Debug().Msgf("Destination asset is %s/%s from agent at %s:%d", transferDestinationElement.ChannelID, transferDestinationElement.AssetID,
-		destinationAssetAgent.GetHost(), destinationAssetAgent.GetPort())
-
-	childSpan.Finish()
 
 	// Update origin asset state with new transfer event
 	log.Debug().Msg("Committing transfer reference to origin")
@@ -134,13 +142,51 @@ func TransferAsset(w http.ResponseWriter, r *http.Request) {
 		childSpan.Finish()
 		return
 	}
+	if pendingTransferIndex(requestAsset) >= 0 {
+		err = errors.New("a transfer of the asset is waiting for the countersignature of the receiver")
+		render.Render(w, r, responses.ErrConflict(err))
+		childSpan.Finish()
+		return
+	}
 	if requestAsset.CustodyTransferEvents == nil {
 		var attachedArray []helpers.CustodyTransferEvent
 		requestAsset.CustodyTransferEvents = attachedArray
 	}
 
+	subtreeRoot := subtreeNode{
+		originAgent: requestAgent,
+		source:      helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID},
+		destination: transferDestinationElement.AssetElement,
+		asset:       requestAsset,
+	}
+	// Repeating an unsigned subtree transfer with its transferID resumes it after the assets it already moved
+	if includeChildren && transferDestinationElement.SenderSignature == nil {
+		if index := transferEventIndex(requestAsset, transferDestinationElement.TransferID); index >= 0 {
+			transferEvent := requestAsset.CustodyTransferEvents[index]
+			childSpan.Finish()
+			if !transferEvent.IncludeChildren || transferEvent.SenderSignature != nil ||
+				transferEvent.DestinationRepoID != destination.RepoID ||
+				transferEvent.DestinationChannelID != destination.ChannelID ||
+				transferEvent.DestinationAssetID != destination.AssetID {
+				render.Render(w, r, responses.ErrConflict(errors.New("the transferID was already used by another transfer of the asset")))
+				return
+			}
+			log.Info().Msgf("Resuming subtree transfer %s of %s/%s", transferEvent.TransferID, assetVars.ChannelID, assetVars.AssetID)
+			renderSubtreeTransfer(ctx, w, r, span, subtreeRoot, transferEvent)
+			return
+		}
+	}
+
+	transferID, rejection := transferIDFor(requestAsset, transferDestinationElement)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		childSpan.Finish()
+		return
+	}
+	now := time.Now().UTC()
 	transferEvent := helpers.CustodyTransferEvent{
-		Timestamp:            time.Now().UTC().Format("2006-01-02T15:04:05.999Z"),
+		Timestamp:            now.Format(custodyTimestampLayout),
+		TransferID:           transferID,
 		TransferDescription:  transferDestinationElement.TransferDescription,
 		SourceRepoID:         assetVars.RepoID,
 		SourceChannelID:      assetVars.ChannelID,
@@ -148,16 +194,130 @@ func TransferAsset(w http.ResponseWriter, r *http.Request) {
 		DestinationRepoID:    transferDestinationElement.AssetElement.RepoID,
 		DestinationChannelID: transferDestinationElement.AssetElement.ChannelID,
 		DestinationAssetID:   transferDestinationElement.AssetElement.AssetID,
+		IncludeChildren:      includeChildren,
+	}
+
+	// A signed transfer waits for the countersignature of the receiver, the origin can not be changed until then.
+	// The subtree of a signed transfer with its children is checked now and collected again when it is countersigned
+	if transferDestinationElement.SenderSignature != nil {
+		transferEvent.Status = transferStatusPending
+		transferEvent.ExpiresAt = now.Add(offers.GetPendingTransferTTL()).Format(custodyTimestampLayout)
+		transferEvent.SenderSignature = transferDestinationElement.SenderSignature
+		if rejection = verifyTransferSignatures(ctx, r, transferEvent); rejection != nil {
+			render.Render(w, r, rejection)
+			childSpan.Finish()
+			return
+		}
+		if includeChildren {
+			if _, rejection = planSubtree(ctx, span, subtreeRoot, transferID); rejection != nil {
+				render.Render(w, r, rejection)
+				childSpan.Finish()
+				return
+			}
+		}
+		requestAsset.CustodyTransferEvents = append(requestAsset.CustodyTransferEvents, transferEvent)
+
+		_, err = requestAgent.Commit(ctx, agent.CommitArgs{
+			ChannelID:  assetVars.ChannelID,
+			AssetID:    assetVars.AssetID,
+			CommitType: "TRANSFER-PENDING",
+			Payload:    requestAsset,
+		})
+		if err != nil {
+			if err == helpers.ErrUnauthorized {
+				render.Render(w, r, responses.ErrUnauthorizedModifyOrigin(err))
+			} else {
+				render.Render(w, r, responses.ErrFailedModifyOrigin(err))
+			}
+			childSpan.Finish()
+			return
+		}
+		childSpan.Finish()
+		render.Render(w, r, responses.SuccessfulTransferPendingResponse())
+		return
+	}
+
+	// The attached children move with the asset, each gets its own transfer event
+	if includeChildren {
+		childSpan.Finish()
+		renderSubtreeTransfer(ctx, w, r, span, subtreeRoot, transferEvent)
+		return
 	}
 
 	requestAsset.CustodyTransferEvents = append(requestAsset.CustodyTransferEvents, transferEvent)
-	destinationRequestAsset := requestAsset
+	childSpan.Finish()
+	if rejection = commitTransfer(ctx, span, requestAgent, destinationAssetAgent, assetVars, requestAsset,
+		transferDestinationElement.AssetElement); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	render.Render(w, r, responses.SuccessfulTransferResponse())
+
+}
+
+// renderSubtreeTransfer transfers an asset with its attached children and renders the result, which reports where
+// the assets were moved to, or how far the transfer got when it failed
+func renderSubtreeTransfer(ctx context.Context, w http.ResponseWriter, r *http.Request, span opentracing.Span,
+	root subtreeNode, rootEvent helpers.CustodyTransferEvent) {
+	mapping, rejection := transferSubtree(ctx, span, root, rootEvent)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	render.Render(w, r, responses.SuccessfulSubtreeTransferResponse(mapping))
+}
+
+// checkTransferDestination checks that the destination asset of a transfer does not exist yet and returns its agent.
+// A non nil renderer is returned when the transfer can not go ahead
+func checkTransferDestination(ctx context.Context, destination helpers.AssetElement) (agent.Agent, render.Renderer) {
+	destinationAssetAgent, _, err := getChildAssetContextFromAssetElement(ctx, destination)
+
+	if err == nil {
+		err = errors.New("Destination asset already exists")
+		tracing.LogAndTraceErr(log, opentracing.SpanFromContext(ctx), err, "Invalid transfer operation")
+		return nil, responses.ErrAlreadyExists(err)
+	}
+	if err != helpers.ErrNotFound {
+		if err == helpers.ErrUnauthorized {
+			return nil, responses.ErrUnauthorizedQueryDestination(err)
+		}
+		return nil, responses.ErrFailedQueryDestination(err)
+	}
+	return destinationAssetAgent, nil
+}
+
+// commitTransfer makes the origin asset read only with a TRANSFER-OUT commit and creates the destination asset
+// with a TRANSFER-IN commit. The custody transfer event must already be appended to the origin asset.
+// The alias index follows each commit
+func commitTransfer(ctx context.Context, span opentracing.Span, requestAgent agent.Agent, destinationAssetAgent agent.Agent,
+	assetVars helpers.AssetRoutingVars, requestAsset helpers.Asset, destination helpers.AssetElement) render.Renderer {
+	return commitTransferCopy(ctx, span, requestAgent, destinationAssetAgent, assetVars, requestAsset, requestAsset, destination)
+}
+
+// commitTransferCopy is commitTransfer with a destination asset that differs from the origin, such as one with its links
+// rewritten by a subtree transfer
+func commitTransferCopy(ctx context.Context, span opentracing.Span, requestAgent agent.Agent, destinationAssetAgent agent.Agent,
+	assetVars helpers.AssetRoutingVars, requestAsset helpers.Asset, destinationRequestAsset helpers.Asset,
+	destination helpers.AssetElement) render.Renderer {
+	if rejection := commitTransferOut(ctx, span, requestAgent, assetVars, requestAsset); rejection != nil {
+		return rejection
+	}
+	return commitTransferIn(ctx, span, destinationAssetAgent, destinationRequestAsset, destination)
+}
+
+// commitTransferOut makes the origin of a transfer read only with a TRANSFER-OUT commit
+func commitTransferOut(ctx context.Context, span opentracing.Span, requestAgent agent.Agent,
+	assetVars helpers.AssetRoutingVars, requestAsset helpers.Asset) render.Renderer {
+	// Update origin asset state with new transfer event
+	log.Debug().Msg("Committing transfer reference to origin")
+	childSpan := opentracing.StartSpan("Committing transfer reference to origin", opentracing.ChildOf(span.Context()))
+	ctx = opentracing.ContextWithSpan(ctx, childSpan)
+
 	//Make origin asset read only
 	requestAsset.ReadOnly = true
-	//Make destination asset writeable
-	destinationRequestAsset.ReadOnly = false
 
-	_, err = requestAgent.Commit(ctx, agent.CommitArgs{
+	_, err := requestAgent.Commit(ctx, agent.CommitArgs{
 		ChannelID:  assetVars.ChannelID,
 		AssetID:    assetVars.AssetID,
 		CommitType: "TRANSFER-OUT",
@@ -165,41 +325,44 @@ func TransferAsset(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
+		childSpan.Finish()
 		if err == helpers.ErrUnauthorized {
-			render.Render(w, r, responses.ErrUnauthorizedModifyOrigin(err))
-		} else {
-			render.Render(w, r, responses.ErrFailedModifyOrigin(err))
+			return responses.ErrUnauthorizedModifyOrigin(err)
 		}
-		childSpan.Finish()
-		return
+		return responses.ErrFailedModifyOrigin(err)
 	}
+	indexAliases(ctx, helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID,
+		AssetID: assetVars.AssetID}, requestAsset)
 
 	childSpan.Finish()
+	return nil
+}
 
+// commitTransferIn creates the destination asset of a transfer with a TRANSFER-IN commit, once the origin is read only
+func commitTransferIn(ctx context.Context, span opentracing.Span, destinationAssetAgent agent.Agent,
+	destinationRequestAsset helpers.Asset, destination helpers.AssetElement) render.Renderer {
 	// Commit updated destination asset with transfer event
 	log.Debug().Msg("Committing destination asset")
-	childSpan = opentracing.StartSpan("Committing destination asset", opentracing.ChildOf(span.Context()))
+	childSpan := opentracing.StartSpan("Committing destination asset", opentracing.ChildOf(span.Context()))
 	ctx = opentracing.ContextWithSpan(ctx, childSpan)
+	defer childSpan.Finish()
 
-	_, err = destinationAssetAgent.Commit(ctx, agent.CommitArgs{
-		ChannelID:  transferDestinationElement.ChannelID,
-		AssetID:    transferDestinationElement.AssetID,
+	//Make destination asset writeable
+	destinationRequestAsset.ReadOnly = false
+
+	_, err := destinationAssetAgent.Commit(ctx, agent.CommitArgs{
+		ChannelID:  destination.ChannelID,
+		AssetID:    destination.AssetID,
 		CommitType: "TRANSFER-IN",
 		Payload:    destinationRequestAsset,
 	})
 
 	if err != nil {
 		if err == helpers.ErrUnauthorized {
-			render.Render(w, r, responses.ErrUnauthorizedModifyDestination(err))
-		} else {
-			render.Render(w, r, responses.ErrFailedModifyDestination(err))
+			return responses.ErrUnauthorizedModifyDestination(err)
 		}
-		childSpan.Finish()
-		return
+		return responses.ErrFailedModifyDestination(err)
 	}
-
-	childSpan.Finish()
-
-	render.Render(w, r, responses.SuccessfulTransferResponse())
-
+	indexAliases(ctx, destination, destinationRequestAsset)
+	return nil
 }
@@ -0,0 +1,362 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/offers"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/schema"
+	"chainsource-gateway/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// acceptOfferRequest is the body of an offer acceptance, signed offers must be countersigned
+type acceptOfferRequest struct {
+	ReceiverSignature *helpers.TransferSignature `json:"receiverSignature"`
+}
+
+// rejectOfferRequest is the body of an offer rejection
+type rejectOfferRequest struct {
+	Reason string `json:"reason"`
+}
+
+// offerErrorResponse maps an error of the offer store to a response
+func offerErrorResponse(err error) render.Renderer {
+	switch err {
+	case helpers.ErrNotFound:
+		return responses.ErrOfferDoesNotExist(err)
+	case offers.ErrOfferExpired:
+		return responses.ErrOfferExpired(err)
+	case offers.ErrOfferDecided, offers.ErrOfferDeciding:
+		return responses.ErrConflict(err)
+	default:
+		return responses.ErrInternalServer(err)
+	}
+}
+
+// reserveOffer reserves a pending offer before its decision is committed to the origin, so a concurrent decision
+// is refused instead of committing to the origin as well
+func reserveOffer(ctx context.Context, offerStore offers.OfferStore, offerID string) render.Renderer {
+	if _, err := offerStore.Reserve(ctx, offerID); err != nil {
+		return offerErrorResponse(err)
+	}
+	return nil
+}
+
+// releaseOffer makes a reserved offer pending again when its decision could not be committed to the origin
+func releaseOffer(ctx context.Context, offerStore offers.OfferStore, offerID string) {
+	if _, err := offerStore.Release(ctx, offerID); err != nil {
+		log.Err(err).Msgf("Transfer offer %s could not be released", offerID)
+	}
+}
+
+// agentForRepo returns the agent of a repo
+func agentForRepo(ctx context.Context, repoID string) (agent.Agent, error) {
+	agentProvider := ctx.Value("agentProvider").(agent.Provider)
+	agentConfig, err := agentProvider.GetAgentConfigForRepo(repoID)
+	if err != nil {
+		return nil, err
+	}
+	return agentProvider.NewAgent(&agentConfig), nil
+}
+
+// pendingOffer returns a pending offer from the store and the origin asset holding its transfer event
+func pendingOffer(ctx context.Context, offerStore offers.OfferStore, offerID string) (offer offers.Offer, originAgent agent.Agent,
+	origin helpers.Asset, eventIndex int, rejection render.Renderer) {
+	offer, err := offerStore.Get(ctx, offerID)
+	if err == nil && offer.Status == offers.StatusExpired {
+		err = offers.ErrOfferExpired
+	} else if err == nil && offer.Status == offers.StatusDeciding {
+		err = offers.ErrOfferDeciding
+	} else if err == nil && offer.Status != offers.StatusPending {
+		err = offers.ErrOfferDecided
+	}
+	if err != nil {
+		rejection = offerErrorResponse(err)
+		return
+	}
+	originAgent, err = agentForRepo(ctx, offer.Source.RepoID)
+	if err != nil {
+		rejection = responses.ErrNoAgent(err)
+		return
+	}
+	origin, rejection = queryTransferOrigin(ctx, originAgent, helpers.AssetRoutingVars{
+		RepoID:    offer.Source.RepoID,
+		ChannelID: offer.Source.ChannelID,
+		AssetID:   offer.Source.AssetID,
+	})
+	if rejection != nil {
+		return
+	}
+	eventIndex = pendingTransferIndex(origin)
+	if eventIndex < 0 || origin.CustodyTransferEvents[eventIndex].OfferID != offer.OfferID {
+		rejection = responses.ErrConflict(errors.New("the origin asset no longer has the offer pending"))
+	}
+	return
+}
+
+// OfferTransfer is a controller function that offers the custody of an asset to a receiving repo and channel
+// The origin asset is marked with a pending transfer event, and can not be changed while the offer is open. The
+// transfer is only committed when the receiver accepts the offer with AcceptTransferOffer, and can be refused with
+// RejectTransferOffer
+func OfferTransfer(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Offer transfer")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	assetSchema := r.Context().Value("schemaValidator").(schema.AssetSchema)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+	offerStore := r.Context().Value("offerStore").(offers.OfferStore)
+
+	errStr, isValid, err := assetSchema.ValidateTransferAsset(ctx, r.Context().Value("JSONBody").(map[string]interface{}))
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	if !isValid {
+		render.Render(w, r, responses.ErrInvalidRequest(errors.New(errStr)))
+		return
+	}
+
+	var transferDestinationElement helpers.AssetTransferElement
+	err = json.NewDecoder(r.Body).Decode(&transferDestinationElement)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to decode JSON")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	requestAsset, rejection := queryTransferOrigin(ctx, requestAgent, assetVars)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	if _, rejection = checkTransferDestination(ctx, transferDestinationElement.AssetElement); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	if pendingTransferIndex(requestAsset) >= 0 {
+		render.Render(w, r, responses.ErrConflict(errors.New("a transfer of the asset is already pending")))
+		return
+	}
+
+	transferID, rejection := transferIDFor(requestAsset, transferDestinationElement)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	transferEvent := helpers.CustodyTransferEvent{
+		TransferID:           transferID,
+		TransferDescription:  transferDestinationElement.TransferDescription,
+		SourceRepoID:         assetVars.RepoID,
+		SourceChannelID:      assetVars.ChannelID,
+		SourceAssetID:        assetVars.AssetID,
+		DestinationRepoID:    transferDestinationElement.AssetElement.RepoID,
+		DestinationChannelID: transferDestinationElement.AssetElement.ChannelID,
+		DestinationAssetID:   transferDestinationElement.AssetElement.AssetID,
+		Status:               transferStatusPending,
+		SenderSignature:      transferDestinationElement.SenderSignature,
+	}
+	if transferEvent.SenderSignature != nil {
+		if rejection = verifyTransferSignatures(ctx, r, transferEvent); rejection != nil {
+			render.Render(w, r, rejection)
+			return
+		}
+	}
+
+	offer, err := offerStore.Create(ctx, offers.Offer{
+		Source:              helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID},
+		Destination:         transferDestinationElement.AssetElement,
+		TransferID:          transferID,
+		TransferDescription: transferDestinationElement.TransferDescription,
+		SenderSignature:     transferDestinationElement.SenderSignature,
+	})
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	transferEvent.Timestamp = offer.CreatedAt.Format(custodyTimestampLayout)
+	transferEvent.OfferID = offer.OfferID
+	transferEvent.ExpiresAt = offer.ExpiresAt.UTC().Format(custodyTimestampLayout)
+	requestAsset.CustodyTransferEvents = append(requestAsset.CustodyTransferEvents, transferEvent)
+
+	_, err = requestAgent.Commit(ctx, agent.CommitArgs{
+		ChannelID:  assetVars.ChannelID,
+		AssetID:    assetVars.AssetID,
+		CommitType: "TRANSFER-OFFER",
+		Payload:    requestAsset,
+	})
+	if err != nil {
+		// The offer can not be accepted without its event on the origin
+		_, _ = offerStore.Decide(ctx, offer.OfferID, offers.Decision{
+			Status:          offers.StatusRejected,
+			RejectionReason: "the offer could not be recorded on the origin asset",
+		})
+		if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrUnauthorizedModifyOrigin(err))
+		} else {
+			render.Render(w, r, responses.ErrFailedModifyOrigin(err))
+		}
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, offer)
+}
+
+// GetTransferOffer is a controller function that returns a transfer offer
+func GetTransferOffer(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get transfer offer")
+	defer span.Finish()
+	offerStore := r.Context().Value("offerStore").(offers.OfferStore)
+
+	offer, err := offerStore.Get(ctx, chi.URLParam(r, "offerID"))
+	if err != nil {
+		render.Render(w, r, offerErrorResponse(err))
+		return
+	}
+	render.JSON(w, r, offer)
+}
+
+// AcceptTransferOffer is a controller function for the receiver to accept a transfer offer
+// Only acceptance commits the transfer: the origin becomes read only and the destination asset is created.
+// The offer is reserved first, so a concurrent acceptance or rejection can not commit to the origin as well.
+// Offers signed by the sender must be countersigned with a receiverSignature
+func AcceptTransferOffer(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Accept transfer offer")
+	defer span.Finish()
+	offerStore := r.Context().Value("offerStore").(offers.OfferStore)
+
+	var request acceptOfferRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to decode JSON")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	offer, originAgent, origin, index, rejection := pendingOffer(ctx, offerStore, chi.URLParam(r, "offerID"))
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	transferEvent := origin.CustodyTransferEvents[index]
+	if transferEvent.SenderSignature != nil {
+		if request.ReceiverSignature == nil || request.ReceiverSignature.Signature == "" {
+			render.Render(w, r, responses.ErrInvalidRequest(errors.New("a signed offer must be accepted with a receiverSignature")))
+			return
+		}
+		transferEvent.ReceiverSignature = request.ReceiverSignature
+		if rejection = verifyTransferSignatures(ctx, r, transferEvent); rejection != nil {
+			render.Render(w, r, rejection)
+			return
+		}
+	}
+	destinationAssetAgent, rejection := checkTransferDestination(ctx, offer.Destination)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	if rejection = reserveOffer(ctx, offerStore, offer.OfferID); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	transferEvent.Status = transferStatusCompleted
+	origin.CustodyTransferEvents[index] = transferEvent
+	originVars := helpers.AssetRoutingVars{RepoID: offer.Source.RepoID, ChannelID: offer.Source.ChannelID, AssetID: offer.Source.AssetID}
+	if rejection = commitTransferOut(ctx, span, originAgent, originVars, origin); rejection != nil {
+		releaseOffer(ctx, offerStore, offer.OfferID)
+		render.Render(w, r, rejection)
+		return
+	}
+
+	// The origin is read only from here on, so the offer is accepted even if the destination can not be created
+	offer, err := offerStore.Decide(ctx, offer.OfferID, offers.Decision{
+		Status:            offers.StatusAccepted,
+		ReceiverSignature: transferEvent.ReceiverSignature,
+	})
+	if err != nil {
+		render.Render(w, r, offerErrorResponse(err))
+		return
+	}
+	if rejection = commitTransferIn(ctx, span, destinationAssetAgent, origin, offer.Destination); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	render.JSON(w, r, offer)
+}
+
+// RejectTransferOffer is a controller function for the receiver to refuse a transfer offer
+// The transfer event of the origin is marked rejected, and the origin can be offered again
+func RejectTransferOffer(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Reject transfer offer")
+	defer span.Finish()
+	offerStore := r.Context().Value("offerStore").(offers.OfferStore)
+
+	var request rejectOfferRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to decode JSON")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	offer, originAgent, origin, index, rejection := pendingOffer(ctx, offerStore, chi.URLParam(r, "offerID"))
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	if rejection = reserveOffer(ctx, offerStore, offer.OfferID); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	origin.CustodyTransferEvents[index].Status = transferStatusRejected
+	_, err := originAgent.Commit(ctx, agent.CommitArgs{
+		ChannelID:  offer.Source.ChannelID,
+		AssetID:    offer.Source.AssetID,
+		CommitType: "TRANSFER-REJECTED",
+		Payload:    origin,
+	})
+	if err != nil {
+		releaseOffer(ctx, offerStore, offer.OfferID)
+		if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrUnauthorizedModifyOrigin(err))
+		} else {
+			render.Render(w, r, responses.ErrFailedModifyOrigin(err))
+		}
+		return
+	}
+
+	offer, err = offerStore.Decide(ctx, offer.OfferID, offers.Decision{
+		Status:          offers.StatusRejected,
+		RejectionReason: request.Reason,
+	})
+	if err != nil {
+		render.Render(w, r, offerErrorResponse(err))
+		return
+	}
+	render.JSON(w, r, offer)
+}
@@ -0,0 +1,426 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/offers"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const transferOfferedAssetLocation = "../../testdata/asset_controller_tests/transfer/transferOffered.json"
+
+// injectOfferContext injects an offer store and the offerID URL parameter into a request
+func injectOfferContext(r *http.Request, offerStore offers.OfferStore, offerID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("offerID", offerID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "offerStore", offerStore)
+	return r.WithContext(ctx)
+}
+
+// pendingTestOffer returns the pending offer of the transfer from T1/C1/A1 to T1/C2/A2
+func pendingTestOffer() offers.Offer {
+	return offers.Offer{
+		OfferID:             "O1",
+		Status:              offers.StatusPending,
+		Source:              helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"},
+		Destination:         helpers.AssetElement{RepoID: "T1", ChannelID: "C2", AssetID: "A2"},
+		TransferDescription: "sold",
+		CreatedAt:           time.Date(2020, 8, 19, 8, 59, 1, 0, time.UTC),
+		ExpiresAt:           time.Date(2099, 8, 22, 8, 59, 1, 0, time.UTC),
+	}
+}
+
+// TestOfferTransfer contains the tests for offering the custody of an asset
+func TestOfferTransfer(t *testing.T) {
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+		var finalOriginAsset helpers.Asset
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferredOnceAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockOffers.EXPECT().Create(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, offer offers.Offer) (offers.Offer, error) {
+				assert.Equal(t, "A1", offer.Source.AssetID, "Offer is made for the origin")
+				assert.Equal(t, "A2", offer.Destination.AssetID, "Offer is made to the destination")
+				return pendingTestOffer(), nil
+			})
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-OFFER")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalOriginAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, ""), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(OfferTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code, "Response Should be 201 CREATED")
+		events := finalOriginAsset.CustodyTransferEvents
+		assert.Len(t, events, 2, "Transfer event is appended")
+		assert.Equal(t, transferStatusPending, events[1].Status, "Transfer is pending")
+		assert.Equal(t, "O1", events[1].OfferID, "Event refers to the offer")
+		assert.Equal(t, "2099-08-22T08:59:01Z", events[1].ExpiresAt, "Event records the expiry")
+	})
+	t.Run("Origin_Commit_Fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferredOnceAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockOffers.EXPECT().Create(gomock.Any(), gomock.Any()).Return(pendingTestOffer(), nil)
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-OFFER")).
+			Return(nil, errors.New("agent failure"))
+		mockOffers.EXPECT().Decide(gomock.Any(), "O1", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, decision offers.Decision) (offers.Offer, error) {
+				assert.Equal(t, offers.StatusRejected, decision.Status, "Unrecorded offer is withdrawn")
+				return offers.Offer{}, nil
+			})
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, ""), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(OfferTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadGateway, responseRecorder.Code, "Response Should be 502 BAD GATEWAY")
+	})
+	t.Run("Transfer_Already_Pending", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferOfferedAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, ""), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(OfferTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+}
+
+// TestAcceptTransferOffer contains the tests for the receiver accepting a transfer offer
+func TestAcceptTransferOffer(t *testing.T) {
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+		var finalOriginAsset helpers.Asset
+		var finalDestinationAsset helpers.Asset
+
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferOfferedAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockOffers.EXPECT().Reserve(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-OUT")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalOriginAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C2", "A2", "TRANSFER-IN")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalDestinationAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+		mockOffers.EXPECT().Decide(gomock.Any(), "O1", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, decision offers.Decision) (offers.Offer, error) {
+				assert.Equal(t, offers.StatusAccepted, decision.Status, "Offer is accepted")
+				offer := pendingTestOffer()
+				offer.Status = decision.Status
+				return offer, nil
+			})
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, "O1"), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.True(t, finalOriginAsset.ReadOnly, "Origin asset is made ReadOnly")
+		assert.False(t, finalDestinationAsset.ReadOnly, "Destination asset is writeable")
+		assert.Equal(t, transferStatusCompleted, finalOriginAsset.CustodyTransferEvents[0].Status, "Transfer is completed")
+	})
+	t.Run("Offer_Being_Decided", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+
+		// A concurrent decision reserved the offer after it was read, nothing is committed
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferOfferedAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockOffers.EXPECT().Reserve(gomock.Any(), "O1").Return(offers.Offer{}, offers.ErrOfferDeciding)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, "O1"), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Origin_Commit_Failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferOfferedAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockOffers.EXPECT().Reserve(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-OUT")).
+			Return(nil, errors.New("commit failed"))
+		mockOffers.EXPECT().Release(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, "O1"), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadGateway, responseRecorder.Code, "Response Should be 502 BAD GATEWAY")
+	})
+	t.Run("Offer_Does_Not_Exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(offers.Offer{}, helpers.ErrNotFound)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectOfferContext(mockRequest, mockOffers, "O1")
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+	t.Run("Offer_Expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+		offer := pendingTestOffer()
+		offer.Status = offers.StatusExpired
+
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(offer, nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectOfferContext(mockRequest, mockOffers, "O1")
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusGone, responseRecorder.Code, "Response Should be 410 GONE")
+	})
+	t.Run("Offer_Already_Decided", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+		offer := pendingTestOffer()
+		offer.Status = offers.StatusRejected
+
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(offer, nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectOfferContext(mockRequest, mockOffers, "O1")
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Signed_Offer_Without_Receiver_Signature", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+		offer := pendingTestOffer()
+		offer.OfferID = ""
+
+		// The signed pending transfer has no offer ID
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(offer, nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, "O1"), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Signed_Offer_Countersigned_With_Sender_Key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+		offer := pendingTestOffer()
+		offer.OfferID = ""
+
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(offer, nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Times(2).Return(signingServiceSuccessReturn(), nil)
+
+		body := `{"receiverSignature":{"signature":"<sender-signature>","fingerprint":"SENDER"}}`
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectValidateContext(injectOfferContext(mockRequest, mockOffers, "O1"), mockValidator)
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Origin_Not_Offered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockOffers := mocks.NewMockOfferStore(ctrl)
+		defer ctrl.Finish()
+
+		mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, "O1"), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(AcceptTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+}
+
+// TestDirectTransferRequiringOffers tests that channels requiring offers refuse direct transfers
+func TestDirectTransferRequiringOffers(t *testing.T) {
+	viper.Set("requireTransferOffers", []map[string]interface{}{{"repoID": "T1", "channelID": "C2"}})
+	defer viper.Set("requireTransferOffers", nil)
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestLocation))
+	responseRecorder := httptest.NewRecorder()
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+		mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code, "Response Should be 422 UNPROCESSABLE ENTITY")
+}
+
+// TestRejectTransferOffer contains the tests for the receiver rejecting a transfer offer
+func TestRejectTransferOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	mockOffers := mocks.NewMockOfferStore(ctrl)
+	defer ctrl.Finish()
+	var finalOriginAsset helpers.Asset
+
+	mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+		Return(openTestJSON(transferOfferedAssetLocation), nil)
+	mockOffers.EXPECT().Reserve(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+	mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-REJECTED")).
+		Do(func(ctx context.Context, args agent.CommitArgs) {
+			finalOriginAsset = args.Payload
+		}).
+		Return(getAgentSuccessResponse(), nil)
+	mockOffers.EXPECT().Decide(gomock.Any(), "O1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, decision offers.Decision) (offers.Offer, error) {
+			assert.Equal(t, offers.StatusRejected, decision.Status, "Offer is rejected")
+			assert.Equal(t, "wrong part", decision.RejectionReason, "Reason is recorded")
+			return pendingTestOffer(), nil
+		})
+
+	mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{"reason":"wrong part"}`))
+	responseRecorder := httptest.NewRecorder()
+	mockRequest = mocks.InjectAgentProviderIntoRequest(injectOfferContext(mockRequest, mockOffers, "O1"), ctrl,
+		map[string]agent.Agent{"T1": mockAgent})
+	http.HandlerFunc(RejectTransferOffer).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	assert.False(t, finalOriginAsset.ReadOnly, "Origin asset stays writeable")
+	assert.Equal(t, transferStatusRejected, finalOriginAsset.CustodyTransferEvents[0].Status, "Transfer is rejected")
+}
+
+// TestGetTransferOffer contains the tests for retrieving a transfer offer
+func TestGetTransferOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockOffers := mocks.NewMockOfferStore(ctrl)
+	defer ctrl.Finish()
+
+	mockOffers.EXPECT().Get(gomock.Any(), "O1").Return(pendingTestOffer(), nil)
+	mockOffers.EXPECT().Get(gomock.Any(), "O2").Return(offers.Offer{}, helpers.ErrNotFound)
+
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(GetTransferOffer).ServeHTTP(responseRecorder,
+		injectOfferContext(httptest.NewRequest("GET", "/", nil), mockOffers, "O1"))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+
+	responseRecorder = httptest.NewRecorder()
+	http.HandlerFunc(GetTransferOffer).ServeHTTP(responseRecorder,
+		injectOfferContext(httptest.NewRequest("GET", "/", nil), mockOffers, "O2"))
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+}
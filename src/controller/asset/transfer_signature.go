@@ -0,0 +1,448 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/pgp"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// transferStatusPending marks a signed transfer that waits for the countersignature of the receiver
+const transferStatusPending = "pending"
+
+// transferStatusCompleted marks a signed transfer that was countersigned by the receiver
+const transferStatusCompleted = "completed"
+
+// transferStatusRejected marks a transfer the sender cancelled or the receiver rejected
+const transferStatusRejected = "rejected"
+
+// custodyTimestampLayout is the layout of the timestamps of custody transfer events
+const custodyTimestampLayout = "2006-01-02T15:04:05.999Z"
+
+// transferIDHeader is the response header that reports the transfer ID of a signing input
+const transferIDHeader = "X-Transfer-ID"
+
+// transferStatement is a type representing the part of a custody transfer event that both parties sign.
+// The transfer ID is unique per transfer, so a signature can not be replayed for another transfer of the same asset.
+// A transfer that includes the attached children says so, the signatures then cover the whole subtree
+type transferStatement struct {
+	TransferID           string `json:"transferID"`
+	IncludeChildren      bool   `json:"includeChildren,omitempty"`
+	TransferDescription  string `json:"transferDescription"`
+	SourceRepoID         string `json:"sourceRepoID"`
+	SourceChannelID      string `json:"sourceChannelID"`
+	SourceAssetID        string `json:"sourceAssetID"`
+	DestinationRepoID    string `json:"destinationRepoID"`
+	DestinationChannelID string `json:"destinationChannelID"`
+	DestinationAssetID   string `json:"destinationAssetID"`
+}
+
+// transferSigningInput returns the RFC 8785 canonical bytes the sender and the receiver of a transfer sign.
+// The timestamp is set by the gateway and is not signed, so the parties can sign before the transfer is requested
+func transferSigningInput(event helpers.CustodyTransferEvent) ([]byte, error) {
+	return helpers.CanonicalJSON(transferStatement{
+		TransferID:           event.TransferID,
+		IncludeChildren:      event.IncludeChildren,
+		TransferDescription:  event.TransferDescription,
+		SourceRepoID:         event.SourceRepoID,
+		SourceChannelID:      event.SourceChannelID,
+		SourceAssetID:        event.SourceAssetID,
+		DestinationRepoID:    event.DestinationRepoID,
+		DestinationChannelID: event.DestinationChannelID,
+		DestinationAssetID:   event.DestinationAssetID,
+	})
+}
+
+// newTransferID returns a random ID for a transfer
+func newTransferID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// transferIDFor returns the transfer ID of a transfer request. Signed requests carry the ID of the signing input
+// they signed, which must not have been used by an earlier transfer of the asset, unsigned requests get a new ID.
+// A non nil renderer is returned when the ID can not be used
+func transferIDFor(asset helpers.Asset, request helpers.AssetTransferElement) (string, render.Renderer) {
+	if request.SenderSignature == nil {
+		transferID, err := newTransferID()
+		if err != nil {
+			return "", responses.ErrInternalServer(err)
+		}
+		return transferID, nil
+	}
+	if request.TransferID == "" {
+		return "", responses.ErrInvalidRequest(errors.New("a signed transfer must carry the transferID of its signing input"))
+	}
+	for _, event := range asset.CustodyTransferEvents {
+		if event.TransferID == request.TransferID {
+			return "", responses.ErrConflict(errors.New("the transferID was already used by a transfer of the asset"))
+		}
+	}
+	return request.TransferID, nil
+}
+
+// pendingTransferIndex returns the index of the transfer event waiting for the receiver, -1 if there is none.
+// Signed transfers and transfer offers stop being pending when they expire
+func pendingTransferIndex(asset helpers.Asset) int {
+	now := time.Now().UTC()
+	for index := len(asset.CustodyTransferEvents) - 1; index >= 0; index-- {
+		event := asset.CustodyTransferEvents[index]
+		if event.Status != transferStatusPending {
+			continue
+		}
+		if expiresAt, err := time.Parse(custodyTimestampLayout, event.ExpiresAt); err == nil && !now.Before(expiresAt) {
+			continue
+		}
+		return index
+	}
+	return -1
+}
+
+// pendingTransferConflict rejects changing an asset while a transfer of it is pending, nil if there is none.
+// The asset is copied to the receiver as it is when the transfer completes, so updates, attachments and detachments
+// wait until the transfer completes, expires or is rejected
+func pendingTransferConflict(asset helpers.Asset, description string) render.Renderer {
+	if pendingTransferIndex(asset) < 0 {
+		return nil
+	}
+	return responses.ErrConflict(fmt.Errorf("%s can not be changed while a transfer is pending", description))
+}
+
+// verifyTransferSignature checks the signature of one party over a transfer with the configured SignatureValidator.
+// Returns the ID of the key that made the signature
+func verifyTransferSignature(ctx context.Context, validator pgp.SignatureValidator, signingInput []byte,
+	signature *helpers.TransferSignature) (string, error) {
+	result, err := validator.Validate(ctx, pgp.ValidateArgs{
+		Fingerprint:      signature.Fingerprint,
+		Signature:        signature.Signature,
+		Scheme:           signature.Scheme,
+		Canonicalization: pgp.CanonicalizationJCS,
+		Payload:          signingInput,
+	})
+	keyID, _ := result["keyID"].(string)
+	if keyID == "" {
+		keyID = signature.Fingerprint
+	}
+	return keyregistry.NormalizeFingerprint(keyID), err
+}
+
+// transferRequestedAt returns when the sender requested a transfer, the timestamp of its event. Events not yet
+// committed have no timestamp, they are requested now
+func transferRequestedAt(event helpers.CustodyTransferEvent) time.Time {
+	if requestedAt, err := time.Parse(custodyTimestampLayout, event.Timestamp); err == nil {
+		return requestedAt
+	}
+	return time.Now().UTC()
+}
+
+// verifyTransferSignatures checks the sender signature of a transfer event and, when present, the receiver
+// signature. The receiver must sign with a key of its own. When signers are checked against a key registry the
+// sender key must be trusted for the source repo and channel when the transfer was requested, and the receiver key
+// for the destination repo and channel when it is countersigned. A non nil renderer is returned when a signature
+// does not verify
+func verifyTransferSignatures(ctx context.Context, r *http.Request, event helpers.CustodyTransferEvent) render.Renderer {
+	validator, ok := r.Context().Value("signatureValidator").(pgp.SignatureValidator)
+	if !ok {
+		return responses.ErrInternalServer(errors.New("no signature validator configured"))
+	}
+	signingInput, err := transferSigningInput(event)
+	if err != nil {
+		return responses.ErrInvalidRequest(err)
+	}
+	if event.SenderSignature == nil {
+		return responses.ErrNoSignature()
+	}
+	senderKey, err := verifyTransferSignature(ctx, validator, signingInput, event.SenderSignature)
+	if err != nil {
+		log.Info().Err(err).Msgf("Sender signature of transfer from %s/%s rejected", event.SourceChannelID, event.SourceAssetID)
+		return verifierErrorResponse(err)
+	}
+	registry := requestKeyRegistry(r)
+	if registry != nil {
+		_, err = registry.AuthorizeParty(ctx, senderKey, event.SourceRepoID, event.SourceChannelID, transferRequestedAt(event))
+		if err != nil {
+			log.Info().Err(err).Msgf("Sender key %s is not trusted for %s/%s", senderKey, event.SourceRepoID,
+				event.SourceChannelID)
+			return responses.ErrUntrustedSigner(err)
+		}
+	}
+	if event.ReceiverSignature == nil {
+		return nil
+	}
+	receiverKey, err := verifyTransferSignature(ctx, validator, signingInput, event.ReceiverSignature)
+	if err != nil {
+		log.Info().Err(err).Msgf("Receiver signature of transfer from %s/%s rejected", event.SourceChannelID, event.SourceAssetID)
+		return verifierErrorResponse(err)
+	}
+	if receiverKey == senderKey {
+		return responses.ErrInvalidRequest(errors.New("the receiver must countersign with a key other than the sender's"))
+	}
+	if registry != nil {
+		_, err = registry.AuthorizeParty(ctx, receiverKey, event.DestinationRepoID, event.DestinationChannelID, time.Now().UTC())
+		if err != nil {
+			log.Info().Err(err).Msgf("Receiver key %s is not trusted for %s/%s", receiverKey, event.DestinationRepoID,
+				event.DestinationChannelID)
+			return responses.ErrUntrustedSigner(err)
+		}
+	}
+	return nil
+}
+
+// queryTransferOrigin returns the current state of the origin asset of a transfer.
+// A non nil renderer is returned when the asset can not be read
+func queryTransferOrigin(ctx context.Context, requestAgent agent.Agent, assetVars helpers.AssetRoutingVars) (asset helpers.Asset, rejection render.Renderer) {
+	result, err := requestAgent.QueryStream(ctx, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			return asset, responses.ErrDoesNotExist(err)
+		} else if err == helpers.ErrUnauthorized {
+			return asset, responses.ErrAgentUnauthorized(err)
+		}
+		return asset, responses.ErrAgent(err)
+	}
+	if err = json.NewDecoder(result).Decode(&asset); err != nil {
+		return asset, responses.ErrAgent(err)
+	}
+	return asset, nil
+}
+
+// countersignedSubtreeIndex returns the index of the completed subtree transfer the countersignature was given for,
+// -1 if there is none. A subtree transfer that stopped part way resumes when the receiver countersigns it again
+func countersignedSubtreeIndex(asset helpers.Asset, countersignature helpers.TransferSignature) int {
+	for index := len(asset.CustodyTransferEvents) - 1; index >= 0; index-- {
+		event := asset.CustodyTransferEvents[index]
+		if event.IncludeChildren && event.Status == transferStatusCompleted && event.ReceiverSignature != nil &&
+			*event.ReceiverSignature == countersignature {
+			return index
+		}
+	}
+	return -1
+}
+
+// CountersignTransfer is a controller function for the receiver of a signed transfer to countersign it
+// Both signatures are verified, then the origin asset becomes read only and the destination asset is created.
+// A transfer that includes the attached children moves the whole subtree, repeating the countersignature resumes it
+// when it stopped part way
+func CountersignTransfer(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Countersign transfer")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	var countersignature helpers.TransferSignature
+	err := json.NewDecoder(r.Body).Decode(&countersignature)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to decode JSON")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	if strings.TrimSpace(countersignature.Signature) == "" {
+		render.Render(w, r, responses.ErrInvalidRequest(errors.New("signature is required")))
+		return
+	}
+
+	requestAsset, rejection := queryTransferOrigin(ctx, requestAgent, assetVars)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	index := pendingTransferIndex(requestAsset)
+	if index < 0 && requestAsset.ReadOnly {
+		index = countersignedSubtreeIndex(requestAsset, countersignature)
+	}
+	if index < 0 {
+		render.Render(w, r, responses.ErrNoPendingTransfer(errors.New("the asset has no transfer to countersign")))
+		return
+	}
+	transferEvent := requestAsset.CustodyTransferEvents[index]
+	transferEvent.ReceiverSignature = &countersignature
+	if rejection = verifyTransferSignatures(ctx, r, transferEvent); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	if transferEvent.IncludeChildren {
+		transferEvent.Status = transferStatusCompleted
+		renderSubtreeTransfer(ctx, w, r, span, subtreeNode{
+			originAgent: requestAgent,
+			source:      helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetVars.AssetID},
+			destination: helpers.AssetElement{
+				RepoID:    transferEvent.DestinationRepoID,
+				ChannelID: transferEvent.DestinationChannelID,
+				AssetID:   transferEvent.DestinationAssetID,
+			},
+			asset: requestAsset,
+		}, transferEvent)
+		return
+	}
+
+	destination := helpers.AssetElement{
+		RepoID:    transferEvent.DestinationRepoID,
+		ChannelID: transferEvent.DestinationChannelID,
+		AssetID:   transferEvent.DestinationAssetID,
+	}
+	destinationAssetAgent, rejection := checkTransferDestination(ctx, destination)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	transferEvent.Status = transferStatusCompleted
+	requestAsset.CustodyTransferEvents[index] = transferEvent
+	if rejection = commitTransfer(ctx, span, requestAgent, destinationAssetAgent, assetVars, requestAsset, destination); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
+	log.Info().Msgf("Transfer of %s/%s countersigned", assetVars.ChannelID, assetVars.AssetID)
+	render.Render(w, r, responses.SuccessfulTransferResponse())
+}
+
+// GetTransferSigningInput is a controller function that returns the exact bytes the parties of a transfer sign
+// The sender passes the destination as ?repoID=&channelID=&assetID=&transferDescription=, with an optional transferID
+// and includeChildren=true for a transfer of the asset with its attached children.
+// Without one a new transferID is assigned, the sender sends it with the signed transfer request. Without a destination
+// the signing input of the pending transfer is returned for the receiver to countersign
+func GetTransferSigningInput(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get transfer signing input")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	query := r.URL.Query()
+	transferEvent := helpers.CustodyTransferEvent{
+		TransferID:           query.Get("transferID"),
+		IncludeChildren:      query.Get("includeChildren") == "true",
+		TransferDescription:  query.Get("transferDescription"),
+		SourceRepoID:         assetVars.RepoID,
+		SourceChannelID:      assetVars.ChannelID,
+		SourceAssetID:        assetVars.AssetID,
+		DestinationRepoID:    query.Get("repoID"),
+		DestinationChannelID: query.Get("channelID"),
+		DestinationAssetID:   query.Get("assetID"),
+	}
+	if transferEvent.DestinationRepoID == "" && transferEvent.DestinationChannelID == "" && transferEvent.DestinationAssetID == "" {
+		requestAsset, rejection := queryTransferOrigin(ctx, requestAgent, assetVars)
+		if rejection != nil {
+			render.Render(w, r, rejection)
+			return
+		}
+		index := pendingTransferIndex(requestAsset)
+		if index < 0 {
+			render.Render(w, r, responses.ErrNoPendingTransfer(errors.New("the asset has no transfer to countersign")))
+			return
+		}
+		transferEvent = requestAsset.CustodyTransferEvents[index]
+	} else if transferEvent.DestinationRepoID == "" || transferEvent.DestinationChannelID == "" || transferEvent.DestinationAssetID == "" {
+		render.Render(w, r, responses.ErrInvalidRequest(errors.New("repoID, channelID and assetID of the destination are required")))
+		return
+	} else if transferEvent.TransferID == "" {
+		transferID, err := newTransferID()
+		if err != nil {
+			render.Render(w, r, responses.ErrInternalServer(err))
+			return
+		}
+		transferEvent.TransferID = transferID
+	}
+
+	signingInput, err := transferSigningInput(transferEvent)
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(canonicalizationHeader, pgp.CanonicalizationJCS)
+	w.Header().Set(transferIDHeader, transferEvent.TransferID)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(signingInput)
+}
+
+// CancelTransfer is a controller function for the sender to withdraw a signed transfer before it is countersigned
+func CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	rejectPendingTransfer(w, r, "Cancel transfer", "TRANSFER-CANCELLED")
+}
+
+// DeclineTransfer is a controller function for the receiver to refuse a signed transfer instead of countersigning it
+func DeclineTransfer(w http.ResponseWriter, r *http.Request) {
+	rejectPendingTransfer(w, r, "Decline transfer", "TRANSFER-REJECTED")
+}
+
+// rejectPendingTransfer marks the pending signed transfer of the asset rejected, the origin can be transferred again.
+// Transfer offers are decided through the offer, so the offer store stays in step with the origin
+func rejectPendingTransfer(w http.ResponseWriter, r *http.Request, operation string, commitType string) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), operation)
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	requestAsset, rejection := queryTransferOrigin(ctx, requestAgent, assetVars)
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	index := pendingTransferIndex(requestAsset)
+	if index < 0 {
+		render.Render(w, r, responses.ErrNoPendingTransfer(errors.New("the asset has no pending transfer")))
+		return
+	}
+	if requestAsset.CustodyTransferEvents[index].OfferID != "" {
+		render.Render(w, r, responses.ErrConflict(errors.New("the pending transfer is an offer, reject it with its offer")))
+		return
+	}
+	requestAsset.CustodyTransferEvents[index].Status = transferStatusRejected
+	_, err := requestAgent.Commit(ctx, agent.CommitArgs{
+		ChannelID:  assetVars.ChannelID,
+		AssetID:    assetVars.AssetID,
+		CommitType: commitType,
+		Payload:    requestAsset,
+	})
+	if err != nil {
+		if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrUnauthorizedModifyOrigin(err))
+		} else {
+			render.Render(w, r, responses.ErrFailedModifyOrigin(err))
+		}
+		return
+	}
+
+	log.Info().Msgf("Transfer %s of %s/%s rejected with %s", requestAsset.CustodyTransferEvents[index].TransferID,
+		assetVars.ChannelID, assetVars.AssetID, commitType)
+	render.Render(w, r, responses.SuccessfulTransferRejectedResponse())
+}
@@ -0,0 +1,495 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/offers"
+	"chainsource-gateway/pgp"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const transferRequestSignedLocation = "../../testdata/asset_controller_tests/transfer/transferRequestSigned.json"
+const transferPendingAssetLocation = "../../testdata/asset_controller_tests/transfer/transferPending.json"
+const transferRejectedAssetLocation = "../../testdata/asset_controller_tests/transfer/transferRejected.json"
+
+// expectedTransferStatement is the signing input of the transfer from T1/C1/A1 to T1/C2/A2
+const expectedTransferStatement = `{"destinationAssetID":"A2","destinationChannelID":"C2","destinationRepoID":"T1",` +
+	`"sourceAssetID":"A1","sourceChannelID":"C1","sourceRepoID":"T1","transferDescription":"sold","transferID":"T-1"}`
+
+// injectKeyRegistry injects the registry the signers of transfers are checked against into a request
+func injectKeyRegistry(r *http.Request, registry keyregistry.KeyRegistry) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), "keyRegistry", registry))
+}
+
+// TestSignedTransfer contains the tests for transfers carrying a sender signature
+func TestSignedTransfer(t *testing.T) {
+	t.Run("Pending_Until_Countersigned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+		var finalOriginAsset helpers.Asset
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferredOnceAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, args pgp.ValidateArgs) (map[string]interface{}, error) {
+				assert.Equal(t, expectedTransferStatement, string(args.Payload), "Transfer statement is verified")
+				assert.Equal(t, "SENDER", args.Fingerprint, "Sender key is used")
+				return signingServiceSuccessReturn(), nil
+			})
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-PENDING")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalOriginAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestSignedLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectValidateContext(mockRequest, mockValidator), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusAccepted, responseRecorder.Code, "Response Should be 202 ACCEPTED")
+		events := finalOriginAsset.CustodyTransferEvents
+		assert.Len(t, events, 2, "Transfer event is appended")
+		assert.Equal(t, transferStatusPending, events[1].Status, "Transfer is pending")
+		assert.Equal(t, "SENDER", events[1].SenderSignature.Fingerprint, "Sender signature is recorded")
+		assert.Equal(t, "T-1", events[1].TransferID, "Signed transfer ID is recorded")
+		timestamp, _ := time.Parse(custodyTimestampLayout, events[1].Timestamp)
+		expiresAt, err := time.Parse(custodyTimestampLayout, events[1].ExpiresAt)
+		assert.NoError(t, err, "Pending transfer expires")
+		assert.Equal(t, offers.GetPendingTransferTTL(), expiresAt.Sub(timestamp), "Pending transfer expires after the TTL")
+	})
+	t.Run("Replayed_Transfer_ID", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		// The signature of the rejected transfer T-1 is sent again
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferRejectedAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestSignedLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Signed_Without_Transfer_ID", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferredOnceAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+
+		body := `{"repoID":"T1","channelID":"C2","assetID":"A2","senderSignature":{"signature":"<sender-signature>"}}`
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Invalid_Sender_Signature", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferredOnceAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(nil, &pgp.VerifierError{Kind: pgp.ErrBadSignature})
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestSignedLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectValidateContext(mockRequest, mockValidator), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Transfer_Already_Pending", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+
+		mockRequest := httptest.NewRequest("POST", "/", openTestJSON(transferRequestLocation))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+}
+
+// TestCountersignTransfer contains the tests for the receiver countersigning a transfer
+func TestCountersignTransfer(t *testing.T) {
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		var finalOriginAsset helpers.Asset
+		var finalDestinationAsset helpers.Asset
+		var verifiedKeys []string
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Times(2).
+			DoAndReturn(func(_ context.Context, args pgp.ValidateArgs) (map[string]interface{}, error) {
+				assert.Equal(t, expectedTransferStatement, string(args.Payload), "Transfer statement is verified")
+				verifiedKeys = append(verifiedKeys, args.Fingerprint)
+				return signingServiceSuccessReturn(), nil
+			})
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-OUT")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalOriginAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C2", "A2", "TRANSFER-IN")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				finalDestinationAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		requestedAt := time.Date(2020, 8, 19, 8, 59, 1, 806000000, time.UTC)
+		mockRegistry.EXPECT().AuthorizeParty(gomock.Any(), "SENDER", "T1", "C1", requestedAt).
+			Return(keyregistry.ManufacturerKey{Fingerprint: "SENDER"}, nil)
+		mockRegistry.EXPECT().AuthorizeParty(gomock.Any(), "RECEIVER", "T1", "C2", gomock.Any()).
+			Return(keyregistry.ManufacturerKey{Fingerprint: "RECEIVER"}, nil)
+
+		body := strings.NewReader(`{"signature":"<receiver-signature>","fingerprint":"RECEIVER","scheme":"jws"}`)
+		mockRequest := httptest.NewRequest("POST", "/", body)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectKeyRegistry(injectValidateContext(mockRequest, mockValidator), mockRegistry)
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		http.HandlerFunc(CountersignTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, []string{"SENDER", "RECEIVER"}, verifiedKeys, "Both signatures are verified")
+		assert.True(t, finalOriginAsset.ReadOnly, "Origin asset is made ReadOnly")
+		assert.False(t, finalDestinationAsset.ReadOnly, "Destination asset is writeable")
+		event := finalOriginAsset.CustodyTransferEvents[0]
+		assert.Equal(t, transferStatusCompleted, event.Status, "Transfer is completed")
+		assert.Equal(t, "RECEIVER", event.ReceiverSignature.Fingerprint, "Receiver signature is recorded")
+	})
+	t.Run("Invalid_Receiver_Signature", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		gomock.InOrder(
+			mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(signingServiceSuccessReturn(), nil),
+			mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+				Return(nil, &pgp.VerifierError{Kind: pgp.ErrUnknownKey}),
+		)
+
+		body := strings.NewReader(`{"signature":"<receiver-signature>","fingerprint":"RECEIVER"}`)
+		mockRequest := httptest.NewRequest("POST", "/", body)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectValidateContext(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockValidator)
+		http.HandlerFunc(CountersignTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code, "Response Should be 422 UNPROCESSABLE ENTITY")
+	})
+	t.Run("Receiver_Uses_Sender_Key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Times(2).Return(signingServiceSuccessReturn(), nil)
+
+		body := strings.NewReader(`{"signature":"<sender-signature>","fingerprint":"sender"}`)
+		mockRequest := httptest.NewRequest("POST", "/", body)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectValidateContext(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockValidator)
+		http.HandlerFunc(CountersignTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Receiver_Key_Not_Trusted_For_Destination", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Times(2).Return(signingServiceSuccessReturn(), nil)
+		mockRegistry.EXPECT().AuthorizeParty(gomock.Any(), "SENDER", "T1", "C1", gomock.Any()).
+			Return(keyregistry.ManufacturerKey{Fingerprint: "SENDER"}, nil)
+		mockRegistry.EXPECT().AuthorizeParty(gomock.Any(), "OTHER", "T1", "C2", gomock.Any()).
+			Return(keyregistry.ManufacturerKey{}, keyregistry.ErrPartyNotAuthorized)
+
+		body := strings.NewReader(`{"signature":"<other-signature>","fingerprint":"OTHER"}`)
+		mockRequest := httptest.NewRequest("POST", "/", body)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectValidateContext(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockValidator)
+		http.HandlerFunc(CountersignTransfer).ServeHTTP(responseRecorder, injectKeyRegistry(mockRequest, mockRegistry))
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+		assert.Contains(t, responseRecorder.Body.String(), keyregistry.ErrPartyNotAuthorized.Error(), "Untrusted key is reported")
+	})
+	t.Run("Sender_Key_Not_Trusted_For_Source", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(signingServiceSuccessReturn(), nil)
+		mockRegistry.EXPECT().AuthorizeParty(gomock.Any(), "SENDER", "T1", "C1", gomock.Any()).
+			Return(keyregistry.ManufacturerKey{}, keyregistry.ErrPartyNotAuthorized)
+
+		body := strings.NewReader(`{"signature":"<receiver-signature>","fingerprint":"RECEIVER"}`)
+		mockRequest := httptest.NewRequest("POST", "/", body)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectValidateContext(injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl)), mockValidator)
+		http.HandlerFunc(CountersignTransfer).ServeHTTP(responseRecorder, injectKeyRegistry(mockRequest, mockRegistry))
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+		assert.Contains(t, responseRecorder.Body.String(), keyregistry.ErrPartyNotAuthorized.Error(), "Untrusted key is reported")
+	})
+	t.Run("No_Pending_Transfer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferredOnceAssetLocation), nil)
+
+		body := strings.NewReader(`{"signature":"<receiver-signature>"}`)
+		mockRequest := httptest.NewRequest("POST", "/", body)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(CountersignTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+}
+
+// pendingTransferAsset returns the asset with a pending signed transfer, changed by edit
+func pendingTransferAsset(edit func(event *helpers.CustodyTransferEvent)) io.ReadCloser {
+	var asset helpers.Asset
+	_ = json.NewDecoder(openTestJSON(transferPendingAssetLocation)).Decode(&asset)
+	edit(&asset.CustodyTransferEvents[0])
+	body, _ := json.Marshal(asset)
+	return ioutil.NopCloser(strings.NewReader(string(body)))
+}
+
+// TestRejectPendingTransfer contains the tests for the sender cancelling and the receiver declining a signed transfer
+func TestRejectPendingTransfer(t *testing.T) {
+	for name, test := range map[string]struct {
+		handler    http.HandlerFunc
+		commitType string
+	}{
+		"Sender_Cancels":    {CancelTransfer, "TRANSFER-CANCELLED"},
+		"Receiver_Declines": {DeclineTransfer, "TRANSFER-REJECTED"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockAgent := mocks.NewMockAgent(ctrl)
+			defer ctrl.Finish()
+			var finalOriginAsset helpers.Asset
+
+			mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+				Return(openTestJSON(transferPendingAssetLocation), nil)
+			mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", test.commitType)).
+				Do(func(ctx context.Context, args agent.CommitArgs) {
+					finalOriginAsset = args.Payload
+				}).
+				Return(getAgentSuccessResponse(), nil)
+
+			mockRequest := httptest.NewRequest("POST", "/", nil)
+			responseRecorder := httptest.NewRecorder()
+			mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+				mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+			test.handler.ServeHTTP(responseRecorder, mockRequest)
+
+			assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+			assert.Equal(t, transferStatusRejected, finalOriginAsset.CustodyTransferEvents[0].Status, "Transfer is rejected")
+			assert.False(t, finalOriginAsset.ReadOnly, "Origin stays writeable")
+		})
+	}
+	t.Run("Expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(pendingTransferAsset(func(event *helpers.CustodyTransferEvent) {
+				event.ExpiresAt = "2020-08-22T08:59:01.806Z"
+			}), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(DeclineTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+	t.Run("Offer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(pendingTransferAsset(func(event *helpers.CustodyTransferEvent) {
+				event.OfferID = "O-1"
+			}), nil)
+
+		mockRequest := httptest.NewRequest("POST", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(CancelTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+}
+
+// TestGetTransferSigningInput contains the tests for the signing input of a transfer
+func TestGetTransferSigningInput(t *testing.T) {
+	t.Run("For_Sender", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockRequest := httptest.NewRequest("GET", "/?repoID=T1&channelID=C2&assetID=A2&transferDescription=sold&transferID=T-1", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(GetTransferSigningInput).ServeHTTP(responseRecorder, mockRequest)
+
+		body, _ := ioutil.ReadAll(responseRecorder.Body)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, expectedTransferStatement, string(body), "Transfer statement is returned")
+		assert.Equal(t, "T-1", responseRecorder.Header().Get(transferIDHeader), "Transfer ID is reported")
+	})
+	t.Run("New_Transfer_ID", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockRequest := httptest.NewRequest("GET", "/?repoID=T1&channelID=C2&assetID=A2", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(GetTransferSigningInput).ServeHTTP(responseRecorder, mockRequest)
+
+		transferID := responseRecorder.Header().Get(transferIDHeader)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Len(t, transferID, 32, "A transfer ID is assigned")
+		assert.Contains(t, responseRecorder.Body.String(), `"transferID":"`+transferID+`"`, "Transfer ID is signed")
+	})
+	t.Run("Include_Children", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockRequest := httptest.NewRequest("GET",
+			"/?repoID=T1&channelID=C2&assetID=A2&transferDescription=sold&transferID=T-1&includeChildren=true", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(GetTransferSigningInput).ServeHTTP(responseRecorder, mockRequest)
+
+		body, _ := ioutil.ReadAll(responseRecorder.Body)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, expectedSubtreeTransferStatement, string(body), "Statement covers the children")
+	})
+	t.Run("For_Receiver", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(GetTransferSigningInput).ServeHTTP(responseRecorder, mockRequest)
+
+		body, _ := ioutil.ReadAll(responseRecorder.Body)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, expectedTransferStatement, string(body), "Statement of the pending transfer is returned")
+	})
+}
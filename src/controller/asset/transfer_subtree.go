@@ -0,0 +1,245 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"context"
+	"fmt"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// subtreeNode is an asset of a subtree transfer, with where it is moved to.
+// An earlier attempt of the same transfer may already have moved the asset out of its origin, or also into its destination
+type subtreeNode struct {
+	originAgent      agent.Agent
+	destinationAgent agent.Agent
+	source           helpers.AssetElement
+	destination      helpers.AssetElement
+	asset            helpers.Asset
+	transferredOut   bool
+	transferredIn    bool
+}
+
+// elementKey identifies the location of an asset
+func elementKey(element helpers.AssetElement) string {
+	return element.RepoID + "/" + element.ChannelID + "/" + element.AssetID
+}
+
+// transferEventIndex returns the index of the custody transfer event of a transfer, -1 if the asset has none
+func transferEventIndex(asset helpers.Asset, transferID string) int {
+	for index, event := range asset.CustodyTransferEvents {
+		if transferID != "" && event.TransferID == transferID {
+			return index
+		}
+	}
+	return -1
+}
+
+// transferredOutBy tells whether a transfer already made the origin asset read only
+func transferredOutBy(asset helpers.Asset, transferID string) bool {
+	return asset.ReadOnly && transferEventIndex(asset, transferID) >= 0
+}
+
+// collectSubtree queries the attached children of the root asset, recursively, and assigns each a destination.
+// Children keep their asset ID and move to the repo and channel of the destination of the root.
+// Children made read only by an earlier attempt of the same transfer are collected, so the transfer can resume
+func collectSubtree(ctx context.Context, root subtreeNode, transferID string) ([]subtreeNode, render.Renderer) {
+	nodes := []subtreeNode{root}
+	seen := map[string]bool{elementKey(root.source): true}
+	destinations := map[string]string{elementKey(root.destination): elementKey(root.source)}
+
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodes[i].asset.AttachedChildren {
+			source := helpers.AssetElement{RepoID: child.RepoID, ChannelID: child.ChannelID, AssetID: child.AssetID}
+			if seen[elementKey(source)] {
+				continue
+			}
+			seen[elementKey(source)] = true
+
+			childAgent, childAsset, err := getChildAssetContextFromAssetElement(ctx, source)
+			if err != nil {
+				if err == helpers.ErrUnauthorized {
+					return nil, responses.ErrUnauthorizedQueryChild(err)
+				}
+				return nil, responses.ErrFailedQueryChild(err)
+			}
+			if childAsset.ReadOnly && !transferredOutBy(childAsset, transferID) {
+				return nil, responses.ErrConflict(fmt.Errorf("attached child %s is read only", elementKey(source)))
+			}
+			if pendingTransferIndex(childAsset) >= 0 {
+				return nil, responses.ErrConflict(fmt.Errorf("a transfer of attached child %s is already pending", elementKey(source)))
+			}
+
+			destination := helpers.AssetElement{
+				RepoID:    root.destination.RepoID,
+				ChannelID: root.destination.ChannelID,
+				AssetID:   child.AssetID,
+			}
+			if other, taken := destinations[elementKey(destination)]; taken {
+				return nil, responses.ErrConflict(fmt.Errorf("%s and %s would both be transferred to %s",
+					other, elementKey(source), elementKey(destination)))
+			}
+			destinations[elementKey(destination)] = elementKey(source)
+			nodes = append(nodes, subtreeNode{
+				originAgent: childAgent,
+				source:      source,
+				destination: destination,
+				asset:       childAsset,
+			})
+		}
+	}
+	return nodes, nil
+}
+
+// relinkedCopy returns the destination copy of an asset with the links into the subtree moved to their destinations.
+// Links to assets outside the subtree, such as the parent of the root, are kept
+func relinkedCopy(asset helpers.Asset, moved map[string]helpers.AssetElement) helpers.Asset {
+	relink := func(link helpers.AssetLinkElement) helpers.AssetLinkElement {
+		if destination, ok := moved[elementKey(link.AssetElement)]; ok {
+			link.AssetElement = helpers.AssetElement{
+				RepoID:    destination.RepoID,
+				ChannelID: destination.ChannelID,
+				AssetID:   destination.AssetID,
+			}
+		}
+		return link
+	}
+
+	destinationAsset := asset
+	if asset.AttachedChildren != nil {
+		destinationAsset.AttachedChildren = make([]helpers.AssetLinkElement, len(asset.AttachedChildren))
+		for i, child := range asset.AttachedChildren {
+			destinationAsset.AttachedChildren[i] = relink(child)
+		}
+	}
+	if asset.ParentAsset != nil {
+		parent := relink(*asset.ParentAsset)
+		destinationAsset.ParentAsset = &parent
+	}
+	return destinationAsset
+}
+
+// planSubtree collects the subtree of the root asset and checks every destination before anything is committed.
+// Assets an earlier attempt of the transfer already moved are marked, their destinations may exist
+func planSubtree(ctx context.Context, span opentracing.Span, root subtreeNode, transferID string) ([]subtreeNode,
+	render.Renderer) {
+	childSpan := opentracing.StartSpan("Collecting attached children", opentracing.ChildOf(span.Context()))
+	defer childSpan.Finish()
+	ctx = opentracing.ContextWithSpan(ctx, childSpan)
+
+	nodes, rejection := collectSubtree(ctx, root, transferID)
+	if rejection != nil {
+		return nil, rejection
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		node.transferredOut = transferredOutBy(node.asset, transferID)
+		if !node.transferredOut {
+			node.destinationAgent, rejection = checkTransferDestination(ctx, node.destination)
+			if rejection != nil {
+				return nil, rejection
+			}
+			continue
+		}
+		var err error
+		node.destinationAgent, _, err = getChildAssetContextFromAssetElement(ctx, node.destination)
+		if err == nil {
+			node.transferredIn = true
+		} else if err == helpers.ErrUnauthorized {
+			return nil, responses.ErrUnauthorizedQueryDestination(err)
+		} else if err != helpers.ErrNotFound {
+			return nil, responses.ErrFailedQueryDestination(err)
+		}
+	}
+	return nodes, nil
+}
+
+// transferMappings reports where the assets of a subtree transfer are moved to
+func transferMappings(nodes []subtreeNode) []responses.TransferMapping {
+	mapping := make([]responses.TransferMapping, 0, len(nodes))
+	for _, node := range nodes {
+		mapping = append(mapping, responses.TransferMapping{Source: node.source, Destination: node.destination})
+	}
+	return mapping
+}
+
+// transferSubtree transfers the root asset together with all of its attached children. Every origin asset is made read only
+// with its own custody transfer event, and the destination assets link to each other at their new locations.
+// rootEvent is the custody transfer event of the root, the events of the children are derived from it. It replaces
+// an event of the root with the same transfer ID, such as the pending event of a signed transfer.
+// Every destination is checked before anything is committed. When a commit fails the transfer stops, and the
+// response reports the assets that were moved and the ones that remain. Transferring the root again with the same
+// transfer ID resumes after the moved assets
+func transferSubtree(ctx context.Context, span opentracing.Span, root subtreeNode,
+	rootEvent helpers.CustodyTransferEvent) ([]responses.TransferMapping, render.Renderer) {
+	nodes, rejection := planSubtree(ctx, span, root, rootEvent.TransferID)
+	if rejection != nil {
+		return nil, rejection
+	}
+
+	moved := make(map[string]helpers.AssetElement, len(nodes))
+	for _, node := range nodes {
+		moved[elementKey(node.source)] = node.destination
+	}
+
+	for i, node := range nodes {
+		if node.transferredIn {
+			continue
+		}
+		originVars := helpers.AssetRoutingVars{
+			RepoID:    node.source.RepoID,
+			ChannelID: node.source.ChannelID,
+			AssetID:   node.source.AssetID,
+		}
+		if node.transferredOut {
+			rejection = commitTransferIn(ctx, span, node.destinationAgent, relinkedCopy(node.asset, moved), node.destination)
+		} else {
+			// The children are moved by the transfer of the root, only the root event carries the signatures
+			event := rootEvent
+			if i > 0 {
+				event.IncludeChildren, event.SenderSignature, event.ReceiverSignature = false, nil, nil
+			}
+			event.SourceRepoID, event.SourceChannelID, event.SourceAssetID =
+				node.source.RepoID, node.source.ChannelID, node.source.AssetID
+			event.DestinationRepoID, event.DestinationChannelID, event.DestinationAssetID =
+				node.destination.RepoID, node.destination.ChannelID, node.destination.AssetID
+			events := append([]helpers.CustodyTransferEvent(nil), node.asset.CustodyTransferEvents...)
+			if index := transferEventIndex(node.asset, event.TransferID); index >= 0 {
+				events[index] = event
+			} else {
+				events = append(events, event)
+			}
+			node.asset.CustodyTransferEvents = events
+			rejection = commitTransferCopy(ctx, span, node.originAgent, node.destinationAgent, originVars, node.asset,
+				relinkedCopy(node.asset, moved), node.destination)
+		}
+		if rejection != nil {
+			log.Error().Msgf("Subtree transfer %s stopped at %s after %d of %d assets", rootEvent.TransferID,
+				elementKey(node.source), i, len(nodes))
+			mapping := transferMappings(nodes)
+			return mapping[:i], responses.ErrPartialTransfer(rejection, rootEvent.TransferID, mapping[:i], mapping[i],
+				mapping[i+1:])
+		}
+	}
+	return transferMappings(nodes), nil
+}
@@ -0,0 +1,315 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/pgp"
+	"chainsource-gateway/responses"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const subtreeRootLocation = "../../testdata/asset_controller_tests/transfer/subtreeRoot.json"
+const subtreeChildLocation = "../../testdata/asset_controller_tests/transfer/subtreeChild.json"
+const subtreeGrandchildLocation = "../../testdata/asset_controller_tests/transfer/subtreeGrandchild.json"
+const subtreeRootPendingLocation = "../../testdata/asset_controller_tests/transfer/subtreeRootPending.json"
+const subtreeRootTransferredLocation = "../../testdata/asset_controller_tests/transfer/subtreeRootTransferred.json"
+const subtreeChildTransferredLocation = "../../testdata/asset_controller_tests/transfer/subtreeChildTransferred.json"
+
+// expectedSubtreeTransferStatement is the signing input of the transfer of T1/C1/A1 with its children to T1/C2/A2
+const expectedSubtreeTransferStatement = `{"destinationAssetID":"A2","destinationChannelID":"C2","destinationRepoID":"T1",` +
+	`"includeChildren":true,"sourceAssetID":"A1","sourceChannelID":"C1","sourceRepoID":"T1","transferDescription":"sold",` +
+	`"transferID":"T-1"}`
+
+// expectSubtreeQueries sets up the origin subtree A1 -> B1 -> B2 in T1/C1
+func expectSubtreeQueries(mockAgent *mocks.MockAgent) {
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+		Return(openTestJSON(subtreeRootLocation), nil)
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).
+		Return(openTestJSON(subtreeChildLocation), nil)
+	mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B2")).
+		Return(openTestJSON(subtreeGrandchildLocation), nil)
+}
+
+// newSubtreeTransferRequest returns a transfer request of A1 to T1/C2/A2 including the attached children
+func newSubtreeTransferRequest(ctrl *gomock.Controller, mockAgent agent.Agent, requestLocation string) *http.Request {
+	mockRequest := httptest.NewRequest("POST", "/?includeChildren=true", openTestJSON(requestLocation))
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+		mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	return mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+}
+
+// TestTransferSubtree contains the tests for transferring an asset together with its attached children
+func TestTransferSubtree(t *testing.T) {
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		committed := make(map[string]helpers.Asset)
+
+		expectSubtreeQueries(mockAgent)
+		for _, assetID := range []string{"A2", "B1", "B2"} {
+			mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", assetID)).
+				Return(nil, helpers.ErrNotFound)
+		}
+		mockAgent.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(6).
+			DoAndReturn(func(_ context.Context, args agent.CommitArgs) (map[string]interface{}, error) {
+				committed[args.CommitType+" "+args.ChannelID+"/"+args.AssetID] = args.Payload
+				return getAgentSuccessResponse(), nil
+			})
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder,
+			newSubtreeTransferRequest(ctrl, mockAgent, transferRequestLocation))
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var response responses.SubtreeTransferResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		assert.Len(t, response.Transferred, 3, "Every asset of the subtree is reported")
+		assert.Equal(t, "B2", response.Transferred[2].Source.AssetID, "Source is reported")
+		assert.Equal(t, "C2", response.Transferred[2].Destination.ChannelID, "Destination is reported")
+
+		for _, origin := range []string{"C1/A1", "C1/B1", "C1/B2"} {
+			asset := committed["TRANSFER-OUT "+origin]
+			assert.True(t, asset.ReadOnly, "Origin %s is made ReadOnly", origin)
+			assert.Len(t, asset.CustodyTransferEvents, 1, "Origin %s records its transfer", origin)
+			assert.Equal(t, "C2", asset.CustodyTransferEvents[0].DestinationChannelID, "Event of %s has its destination", origin)
+		}
+		assert.Equal(t, "C1", committed["TRANSFER-OUT C1/A1"].AttachedChildren[0].ChannelID, "Origin links are kept")
+
+		root := committed["TRANSFER-IN C2/A2"]
+		child := committed["TRANSFER-IN C2/B1"]
+		grandchild := committed["TRANSFER-IN C2/B2"]
+		assert.False(t, root.ReadOnly, "Destination asset is writeable")
+		assert.Equal(t, "C2", root.AttachedChildren[0].ChannelID, "Child link is rewritten")
+		assert.Equal(t, "component", root.AttachedChildren[0].Role, "Child link keeps its role")
+		assert.Equal(t, "C1", root.ParentAsset.ChannelID, "Parent outside the subtree is kept")
+		assert.Equal(t, "A2", child.ParentAsset.AssetID, "Parent link is rewritten to the new root")
+		assert.Equal(t, "C2", child.AttachedChildren[0].ChannelID, "Grandchild link is rewritten")
+		assert.Equal(t, "C2", grandchild.ParentAsset.ChannelID, "Parent link of the grandchild is rewritten")
+	})
+	t.Run("Child_Destination_Exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		expectSubtreeQueries(mockAgent)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(nil, helpers.ErrNotFound)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "B1")).
+			Return(openTestJSON(subtreeChildLocation), nil)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder,
+			newSubtreeTransferRequest(ctrl, mockAgent, transferRequestLocation))
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Stops_Part_Way", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		var commits []string
+
+		expectSubtreeQueries(mockAgent)
+		for _, assetID := range []string{"A2", "B1", "B2"} {
+			mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", assetID)).
+				Return(nil, helpers.ErrNotFound)
+		}
+		mockAgent.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(4).
+			DoAndReturn(func(_ context.Context, args agent.CommitArgs) (map[string]interface{}, error) {
+				commits = append(commits, args.CommitType+" "+args.ChannelID+"/"+args.AssetID)
+				if args.CommitType == "TRANSFER-IN" && args.AssetID == "B1" {
+					return nil, errors.New("agent is down")
+				}
+				return getAgentSuccessResponse(), nil
+			})
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder,
+			newSubtreeTransferRequest(ctrl, mockAgent, transferRequestLocation))
+
+		assert.Equal(t, http.StatusBadGateway, responseRecorder.Code, "Response Should be 502 BAD GATEWAY")
+		assert.Equal(t, []string{"TRANSFER-OUT C1/A1", "TRANSFER-IN C2/A2", "TRANSFER-OUT C1/B1", "TRANSFER-IN C2/B1"},
+			commits, "Transfer stops at the failed commit")
+		var response responses.PartialTransferResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		assert.NotEmpty(t, response.TransferID, "Transfer ID to resume with is reported")
+		assert.Len(t, response.Transferred, 1, "Moved root is reported")
+		assert.Equal(t, "B1", response.Failed.Source.AssetID, "Failed asset is reported")
+		assert.Len(t, response.Remaining, 1, "Grandchild remains")
+		assert.Equal(t, "B2", response.Remaining[0].Source.AssetID, "Remaining asset is reported")
+	})
+	t.Run("Resumed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		committed := make(map[string]helpers.Asset)
+
+		// A1 and B1 were moved out of C1 by T-9, which stopped before B1 was created in C2
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(subtreeRootTransferredLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).
+			Return(openTestJSON(subtreeChildTransferredLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B2")).
+			Return(openTestJSON(subtreeGrandchildLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", "A2")).
+			Return(openTestJSON(subtreeRootLocation), nil)
+		for _, assetID := range []string{"B1", "B2"} {
+			mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", assetID)).
+				Return(nil, helpers.ErrNotFound)
+		}
+		mockAgent.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(3).
+			DoAndReturn(func(_ context.Context, args agent.CommitArgs) (map[string]interface{}, error) {
+				committed[args.CommitType+" "+args.ChannelID+"/"+args.AssetID] = args.Payload
+				return getAgentSuccessResponse(), nil
+			})
+
+		body := `{"repoID":"T1","channelID":"C2","assetID":"A2","transferID":"T-9"}`
+		mockRequest := httptest.NewRequest("POST", "/?includeChildren=true", strings.NewReader(body))
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl, map[string]agent.Agent{"T1": mockAgent})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var response responses.SubtreeTransferResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		assert.Len(t, response.Transferred, 3, "Every asset of the subtree is reported")
+		assert.Len(t, committed, 3, "Only the missing commits are made")
+		child := committed["TRANSFER-IN C2/B1"]
+		assert.False(t, child.ReadOnly, "Destination of the moved child is writeable")
+		assert.Equal(t, "A2", child.ParentAsset.AssetID, "Parent link is rewritten")
+		grandchild := committed["TRANSFER-OUT C1/B2"]
+		assert.Equal(t, "T-9", grandchild.CustodyTransferEvents[0].TransferID, "Remaining asset is moved by the same transfer")
+		assert.Contains(t, committed, "TRANSFER-IN C2/B2", "Remaining asset is created")
+	})
+	t.Run("Signed_Pending_Until_Countersigned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+		var pendingAsset helpers.Asset
+
+		expectSubtreeQueries(mockAgent)
+		for _, assetID := range []string{"A2", "B1", "B2"} {
+			mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", assetID)).
+				Return(nil, helpers.ErrNotFound)
+		}
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, args pgp.ValidateArgs) (map[string]interface{}, error) {
+				assert.Equal(t, expectedSubtreeTransferStatement, string(args.Payload), "Signature covers the children")
+				return signingServiceSuccessReturn(), nil
+			})
+		mockAgent.EXPECT().Commit(gomock.Any(), mocks.AgentCommitTo("C1", "A1", "TRANSFER-PENDING")).
+			Do(func(ctx context.Context, args agent.CommitArgs) {
+				pendingAsset = args.Payload
+			}).
+			Return(getAgentSuccessResponse(), nil)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, injectValidateContext(
+			newSubtreeTransferRequest(ctrl, mockAgent, transferRequestSignedLocation), mockValidator))
+
+		assert.Equal(t, http.StatusAccepted, responseRecorder.Code, "Response Should be 202 ACCEPTED")
+		event := pendingAsset.CustodyTransferEvents[0]
+		assert.Equal(t, transferStatusPending, event.Status, "Transfer is pending")
+		assert.True(t, event.IncludeChildren, "Pending transfer includes the children")
+	})
+	t.Run("Signed_Child_Read_Only", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(subtreeRootLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).
+			Return(openTestJSON(subtreeChildTransferredLocation), nil)
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(signingServiceSuccessReturn(), nil)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(TransferAsset).ServeHTTP(responseRecorder, injectValidateContext(
+			newSubtreeTransferRequest(ctrl, mockAgent, transferRequestSignedLocation), mockValidator))
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Countersigned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		defer ctrl.Finish()
+		committed := make(map[string]helpers.Asset)
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(subtreeRootPendingLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).
+			Return(openTestJSON(subtreeChildLocation), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B2")).
+			Return(openTestJSON(subtreeGrandchildLocation), nil)
+		for _, assetID := range []string{"A2", "B1", "B2"} {
+			mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C2", assetID)).
+				Return(nil, helpers.ErrNotFound)
+		}
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).Times(2).
+			DoAndReturn(func(_ context.Context, args pgp.ValidateArgs) (map[string]interface{}, error) {
+				assert.Equal(t, expectedSubtreeTransferStatement, string(args.Payload), "Signatures cover the children")
+				return signingServiceSuccessReturn(), nil
+			})
+		mockAgent.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(6).
+			DoAndReturn(func(_ context.Context, args agent.CommitArgs) (map[string]interface{}, error) {
+				committed[args.CommitType+" "+args.ChannelID+"/"+args.AssetID] = args.Payload
+				return getAgentSuccessResponse(), nil
+			})
+
+		body := strings.NewReader(`{"signature":"<receiver-signature>","fingerprint":"RECEIVER","scheme":"jws"}`)
+		mockRequest := httptest.NewRequest("POST", "/", body)
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = mocks.InjectAgentProviderIntoRequest(injectValidateContext(mockRequest, mockValidator), ctrl,
+			map[string]agent.Agent{"T1": mockAgent})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CountersignTransfer).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var response responses.SubtreeTransferResponse
+		json.NewDecoder(responseRecorder.Body).Decode(&response)
+		assert.Len(t, response.Transferred, 3, "Every asset of the subtree is reported")
+
+		root := committed["TRANSFER-OUT C1/A1"]
+		assert.Len(t, root.CustodyTransferEvents, 1, "Pending event of the root is completed in place")
+		assert.Equal(t, transferStatusCompleted, root.CustodyTransferEvents[0].Status, "Transfer is completed")
+		assert.Equal(t, "RECEIVER", root.CustodyTransferEvents[0].ReceiverSignature.Fingerprint, "Countersignature is recorded")
+		child := committed["TRANSFER-OUT C1/B1"].CustodyTransferEvents[0]
+		assert.Equal(t, "T-1", child.TransferID, "Child is moved by the signed transfer")
+		assert.Nil(t, child.SenderSignature, "Signatures over the root are not copied to the child")
+		assert.Equal(t, "A2", committed["TRANSFER-IN C2/B1"].ParentAsset.AssetID, "Parent link is rewritten")
+	})
+}
@@ -94,13 +94,16 @@ func TestTransferOnHappyPath(t *testing.T) {
 
 	assert.Equal(t, 2, len(finalParentAsset.CustodyTransferEvents), "Parent should have 2 Custody Transfer Events")
 	assert.NotEqual(t, "", finalParentAsset.CustodyTransferEvents[1].Timestamp, "Parent Custody Transfer Event with Timestamp")
+	assert.NotEqual(t, "", finalParentAsset.CustodyTransferEvents[1].TransferID, "Custody Transfer Event with TransferID")
 	finalParentAsset.CustodyTransferEvents[1].Timestamp = ""
+	finalParentAsset.CustodyTransferEvents[1].TransferID = ""
 	assert.Equal(t, expectedCustodyTransferEvent, finalParentAsset.CustodyTransferEvents[1], "Parent must have expected Custody Transfer Events")
 	assert.True(t, finalParentAsset.ReadOnly, "Parent asset to be marked ReadOnly")
 
 	assert.Equal(t, 2, len(finalChildAsset.CustodyTransferEvents), "Child should have 2 Custody Transfer Events")
 	assert.NotEqual(t, "", finalChildAsset.CustodyTransferEvents[0].Timestamp, "Child Custody Transfer Event with Timestamp")
 	finalChildAsset.CustodyTransferEvents[1].Timestamp = ""
+	finalChildAsset.CustodyTransferEvents[1].TransferID = ""
 	assert.Equal(t, expectedCustodyTransferEvent, finalChildAsset.CustodyTransferEvents[1], "Child must have expected Custody Transfer Events")
 	assert.False(t, finalChildAsset.ReadOnly, "Child asset not to be marked ReadOnly")
 }
@@ -162,12 +165,15 @@ func TestTransferOnHappyPathWithNeverTransferredAsset(t *testing.T) {
 	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
 
 	assert.Equal(t, 1, len(finalParentAsset.CustodyTransferEvents), "Parent should have 1 Custody Transfer Event")
+	assert.NotEqual(t, "", finalParentAsset.CustodyTransferEvents[0].TransferID, "Custody Transfer Event with TransferID")
 	finalParentAsset.CustodyTransferEvents[0].Timestamp = ""
+	finalParentAsset.CustodyTransferEvents[0].TransferID = ""
 	assert.Equal(t, expectedCustodyTransferEvent, finalParentAsset.CustodyTransferEvents[0], "Parent must have expected Custody Transfer Events")
 	assert.True(t, finalParentAsset.ReadOnly, "Parent asset to be marked ReadOnly")
 
 	assert.Equal(t, 1, len(finalChildAsset.CustodyTransferEvents), "Child should have 1 Custody Transfer Event")
 	finalChildAsset.CustodyTransferEvents[0].Timestamp = ""
+	finalChildAsset.CustodyTransferEvents[0].TransferID = ""
 	assert.Equal(t, expectedCustodyTransferEvent, finalChildAsset.CustodyTransferEvents[0], "Child must have expected Custody Transfer Events")
 	assert.False(t, finalChildAsset.ReadOnly, "Child asset not to be marked ReadOnly")
 }
@@ -232,13 +238,16 @@ func TestTransferOnHappyPathOverMultipleRepos(t *testing.T) {
 
 	assert.Equal(t, 2, len(finalParentAsset.CustodyTransferEvents), "Parent should have 2 Custody Transfer Events")
 	assert.NotEqual(t, "", finalParentAsset.CustodyTransferEvents[1].Timestamp, "Parent Custody Transfer Event with Timestamp")
+	assert.NotEqual(t, "", finalParentAsset.CustodyTransferEvents[1].TransferID, "Custody Transfer Event with TransferID")
 	finalParentAsset.CustodyTransferEvents[1].Timestamp = ""
+	finalParentAsset.CustodyTransferEvents[1].TransferID = ""
 	assert.Equal(t, expectedCustodyTransferEvent, finalParentAsset.CustodyTransferEvents[1], "Parent must have expected Custody Transfer Events")
 	assert.True(t, finalParentAsset.ReadOnly, "Parent asset to be marked ReadOnly")
 
 	assert.Equal(t, 2, len(finalChildAsset.CustodyTransferEvents), "Child should have 2 Custody Transfer Events")
 	assert.NotEqual(t, "", finalChildAsset.CustodyTransferEvents[0].Timestamp, "Child Custody Transfer Event with Timestamp")
 	finalChildAsset.CustodyTransferEvents[1].Timestamp = ""
+	finalChildAsset.CustodyTransferEvents[1].TransferID = ""
 	assert.Equal(t, expectedCustodyTransferEvent, finalChildAsset.CustodyTransferEvents[1], "Child must have expected Custody Transfer Events")
 	assert.False(t, finalChildAsset.ReadOnly, "Child asset not to be marked ReadOnly")
 }
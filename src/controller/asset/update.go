@@ -86,8 +86,20 @@ func UpdateAsset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if rejection := pendingTransferConflict(assetOnAgent, "the asset"); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+
 	requestAsset.AttachedChildren = assetOnAgent.AttachedChildren
+	requestAsset.CustodyTransferEvents = assetOnAgent.CustodyTransferEvents
 	requestAsset.ParentAsset = assetOnAgent.ParentAsset
+	requestAsset.AdvisoryFlags = assetOnAgent.AdvisoryFlags
+
+	if rejection := applySignaturePolicy(ctx, r, assetVars, &requestAsset); rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
 
 	// Commit
 	res, err := requestAgent.Commit(ctx, agent.CommitArgs{
@@ -107,5 +119,7 @@ func UpdateAsset(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info().Interface("agentResponse", res).Msg("Updation on agent successful")
+	indexAliases(ctx, helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID,
+		AssetID: assetVars.AssetID}, requestAsset)
 	render.Render(w, r, responses.SuccessfulUpdateResponse())
 }
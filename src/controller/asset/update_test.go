@@ -65,6 +65,7 @@ func TestUpdateWithAssetIDThatExists(t *testing.T) {
 	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
 	assert.Equal(t, 1, len(finalAsset.AttachedChildren), "Child asset links are not changed by update")
 	assert.Equal(t, "DB1", finalAsset.ParentAsset.RepoID, "Parent asset link update is ignored")
+	assert.Equal(t, 1, len(finalAsset.CustodyTransferEvents), "Custody history is kept by update")
 }
 
 // TestUpdateWithAssetErrorConditions contains the tests that simulate error conditions of the asset
@@ -87,6 +88,22 @@ func TestUpdateWithAssetErrorConditions(t *testing.T){
 
 		assert.Equal(t, http.StatusBadGateway, responseRecorder.Result().StatusCode, "Response Should be 502 BAD GATEWAY")
 	} )
+	t.Run("Transfer_Pending", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(transferPendingAssetLocation), nil)
+
+		mockRequest := httptest.NewRequest("PUT", "/", openTestJSON(assetUpdatePayload))
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(UpdateAsset).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
 	t.Run("Read_Only_Asset",func (t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockAgent := mocks.NewMockAgent(ctrl)
@@ -22,8 +22,11 @@ import (
 	"chainsource-gateway/helpers"
 	"chainsource-gateway/pgp"
 	"chainsource-gateway/responses"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 	"github.com/opentracing/opentracing-go"
@@ -32,6 +35,7 @@ import (
 var logger = helpers.GetLogger("validateAsset")
 var fingerprintKey = "manufactureFingerprint"
 var signatureKey = "manufactureSignature"
+var signatureSchemeKey = "manufactureSignatureScheme"
 
 // validateAsset is a controller function to validate the signature of an asset
 func ValidateAsset(w http.ResponseWriter, r *http.Request) {
@@ -61,38 +65,62 @@ func ValidateAsset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	err = json.NewDecoder(resultStream).Decode(&result)
-	signature := result.ManufactureSignature
-	if len(signature) == 0 {
-		render.Render(w, r, responses.ErrNoSignature())
-		return
+	registry := requestKeyRegistry(r)
+	signedAt := time.Now().UTC()
+	if registry != nil && len(result.ManufactureSignature) > 0 {
+		signedAt, err = signatureCommittedAt(ctx, requestAgent, assetVars, result)
+		if err != nil {
+			if err == helpers.ErrUnauthorized {
+				render.Render(w, r, responses.ErrAgentUnauthorized(err))
+			} else {
+				render.Render(w, r, responses.ErrAgent(err))
+			}
+			return
+		}
 	}
-	meta := result.AssetMetadata.(map[string]interface{})
-	if meta == nil || len(meta) < 1 {
+	res, err := verifyManufactureSignature(ctx, signingService, registry, result, signedAt)
+	if err == errNoSignature {
 		render.Render(w, r, responses.ErrNoSignature())
 		return
 	}
-	fingerprint := ""
-	if _, ok := meta[fingerprintKey]; ok {
-		fingerprint = meta[fingerprintKey].(string)
-	}
-	if len(fingerprint) == 0 {
+	if err == errNoFingerprint {
 		render.Render(w, r, responses.ErrNoFingerprint())
 		return
 	}
-	delete(meta, fingerprintKey)
-	result.AssetMetadata = meta
-	input := helpers.AssetNoChildParent(result)
-
-	res, err := signingService.Validate(ctx, pgp.ValidateArgs{
-		Fingerprint: fingerprint,
-		Signature:   signature,
-		Input:       input,
-	})
-
+	if untrusted, ok := err.(untrustedSignerError); ok {
+		render.Render(w, r, responses.ErrUntrustedSigner(untrusted.cause))
+		return
+	}
 	if err != nil {
-		render.Render(w, r, responses.ErrInvalidSignature())
+		render.Render(w, r, verifierErrorResponse(err))
 		return
 	}
 
 	render.JSON(w, r, res)
 }
+
+// signatureCommittedAt returns when the manufacture signature of an asset was committed, the timestamp of the first
+// commit of the audit trail since which the asset carries the signature. Keys are checked at that time, so a signature
+// stays trusted after the validity window of its key ends
+func signatureCommittedAt(ctx context.Context, requestAgent agent.Agent, assetVars helpers.AssetRoutingVars,
+	asset helpers.Asset) (time.Time, error) {
+	entries, err := auditEntries(ctx, requestAgent, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+		AssetID:   assetVars.AssetID,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	committedAt := ""
+	for _, entry := range entries {
+		if entry.Asset == nil || entry.Asset.ManufactureSignature != asset.ManufactureSignature {
+			committedAt = ""
+		} else if committedAt == "" {
+			committedAt = entry.Timestamp
+		}
+	}
+	if committedAt == "" {
+		return time.Time{}, errors.New("the commit of the manufacture signature is not in the audit trail")
+	}
+	return time.Parse(time.RFC3339Nano, committedAt)
+}
@@ -19,21 +19,25 @@ package asset
 
 import (
 	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
 	"chainsource-gateway/mocks"
 	"chainsource-gateway/pgp"
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 const signedAssetLocation = "../../testdata/asset_controller_tests/validate/assetToBeValidated.json"
 const signedAssetNoFPLocation = "../../testdata/asset_controller_tests/validate/assetToBeValidatedNoFP.json"
 const signedAssetEmptyFPLocation = "../../testdata/asset_controller_tests/validate/assetToBeValidatedEmptyFP.json"
 const signedAssetEmptySLocation = "../../testdata/asset_controller_tests/validate/assetToBeValidatedEmptySignature.json"
+const signedAssetCMSLocation = "../../testdata/asset_controller_tests/validate/assetToBeValidatedCMS.json"
 
 // injectValidateContext adds a mock validator into a request
 func injectValidateContext(r *http.Request, validator pgp.SignatureValidator) (reqWithContext *http.Request) {
@@ -74,6 +78,137 @@ func TestValidateWithSignedAssetThatExists(t *testing.T) {
 	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
 }
 
+// TestValidateWithCMSSignedAsset validates a CMS signed asset, which does not need a fingerprint
+func TestValidateWithCMSSignedAsset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	mockValidator := mocks.NewMockSignatureValidator(ctrl)
+	var validateArgs pgp.ValidateArgs
+	mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, args pgp.ValidateArgs) { validateArgs = args }).
+		Return(signingServiceSuccessReturn(), nil)
+	defer ctrl.Finish()
+
+	assetC1A1 := openTestJSON(signedAssetCMSLocation)
+	mockAgent.EXPECT().QueryStream(gomock.Any(),
+		mocks.AgentQueryFor("C1", "A1")).
+		Return(assetC1A1, nil)
+	mockRequest := httptest.NewRequest("GET", "/", nil)
+	responseRecorder := httptest.NewRecorder()
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+		mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	mockRequest = injectValidateContext(mockRequest, mockValidator)
+	handler := http.HandlerFunc(ValidateAsset)
+	handler.ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+	assert.Equal(t, pgp.SchemeCMS, validateArgs.Scheme, "Scheme is passed to the validator")
+	assert.Empty(t, validateArgs.Input.AssetMetadata, "Scheme is not part of the signing input")
+}
+
+// signedAssetTrail returns an audit trail in which the signature of the signed asset is committed at signedAt,
+// after a commit without it and before a commit keeping it
+func signedAssetTrail(signedAt string) map[string]interface{} {
+	var signed map[string]interface{}
+	_ = json.NewDecoder(openTestJSON(signedAssetLocation)).Decode(&signed)
+	unsigned := make(map[string]interface{}, len(signed))
+	for key, value := range signed {
+		unsigned[key] = value
+	}
+	unsigned["manufactureSignature"] = ""
+	return map[string]interface{}{
+		"history": []interface{}{
+			map[string]interface{}{"eventType": "CREATE", "timestamp": "2020-08-01T00:00:00Z", "payload": unsigned},
+			map[string]interface{}{"eventType": "UPDATE", "timestamp": signedAt, "payload": signed},
+			map[string]interface{}{"eventType": "UPDATE", "timestamp": "2020-09-01T00:00:00Z", "payload": signed},
+		},
+	}
+}
+
+// TestValidateWithKeyRegistry contains the tests that check the signing key against the key registry
+func TestValidateWithKeyRegistry(t *testing.T) {
+	t.Run("Key_Trusted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(map[string]interface{}{"valid": true, "keyID": "FP"}, nil)
+		signedAt := time.Date(2020, 8, 12, 12, 32, 2, 0, time.UTC)
+		mockRegistry.EXPECT().Authorize(gomock.Any(), "FP", "A Valid Manufacturer", signedAt).
+			Return(keyregistry.ManufacturerKey{Owner: keyregistry.KeyOwner{Name: "Supplier"}}, nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(signedAssetLocation), nil)
+		mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(signedAssetTrail("2020-08-12T12:32:02Z"), nil)
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "keyRegistry", mockRegistry))
+		handler := http.HandlerFunc(ValidateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+		assert.Contains(t, responseRecorder.Body.String(), "Supplier", "Key owner is reported")
+	})
+	t.Run("Key_Revoked", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+			Return(signingServiceSuccessReturn(), nil)
+		mockRegistry.EXPECT().Authorize(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(keyregistry.ManufacturerKey{}, keyregistry.ErrKeyRevoked)
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(signedAssetLocation), nil)
+		mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(signedAssetTrail("2020-08-12T12:32:02Z"), nil)
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "keyRegistry", mockRegistry))
+		handler := http.HandlerFunc(ValidateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Result().StatusCode, "Response Should be 400 BAD REQUEST")
+		assert.Contains(t, responseRecorder.Body.String(), keyregistry.ErrKeyRevoked.Error(), "Revocation is reported")
+	})
+	t.Run("Signature_Not_In_Trail", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		mockValidator := mocks.NewMockSignatureValidator(ctrl)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		mockAgent.EXPECT().QueryStream(gomock.Any(),
+			mocks.AgentQueryFor("C1", "A1")).
+			Return(openTestJSON(signedAssetLocation), nil)
+		mockAgent.EXPECT().QueryAuditTrail(gomock.Any(), mocks.AgentQueryFor("C1", "A1")).
+			Return(map[string]interface{}{"history": []interface{}{}}, nil)
+		mockRequest := httptest.NewRequest("GET", "/", nil)
+		responseRecorder := httptest.NewRecorder()
+		mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		mockRequest = injectValidateContext(mockRequest, mockValidator)
+		mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "keyRegistry", mockRegistry))
+		handler := http.HandlerFunc(ValidateAsset)
+		handler.ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadGateway, responseRecorder.Result().StatusCode, "Response Should be 502 BAD GATEWAY")
+	})
+}
+
 // TestValidateWithAssetErrorConditions contains the tests that simulate error conditions of the asset
 func TestValidateWithAssetErrorConditions(t *testing.T) {
 	t.Run("Asset_DoesNotExist", func(t *testing.T) {
@@ -205,6 +340,44 @@ func TestValidateWithAgentFailureConditions(t *testing.T) {
 	})
 }
 
+// TestValidateWithVerifierErrorKinds tests that each kind of verifier error maps to its own response
+func TestValidateWithVerifierErrorKinds(t *testing.T) {
+	cases := []struct {
+		name       string
+		kind       error
+		statusCode int
+	}{
+		{"Verifier_Unavailable", pgp.ErrVerifierUnavailable, http.StatusServiceUnavailable},
+		{"Verifier_Timeout", pgp.ErrVerifierTimeout, http.StatusGatewayTimeout},
+		{"Unknown_Key", pgp.ErrUnknownKey, http.StatusUnprocessableEntity},
+		{"Bad_Signature", pgp.ErrBadSignature, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockAgent := mocks.NewMockAgent(ctrl)
+			mockValidator := mocks.NewMockSignatureValidator(ctrl)
+			mockValidator.EXPECT().Validate(gomock.Any(), gomock.Any()).
+				Return(nil, &pgp.VerifierError{Kind: c.kind, Detail: map[string]interface{}{"status": "verifier says"}})
+			defer ctrl.Finish()
+
+			mockAgent.EXPECT().QueryStream(gomock.Any(),
+				mocks.AgentQueryFor("C1", "A1")).
+				Return(openTestJSON(signedAssetLocation), nil)
+			mockRequest := httptest.NewRequest("GET", "/", nil)
+			responseRecorder := httptest.NewRecorder()
+			mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "A1", mockAgent,
+				mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+			mockRequest = injectValidateContext(mockRequest, mockValidator)
+			handler := http.HandlerFunc(ValidateAsset)
+			handler.ServeHTTP(responseRecorder, mockRequest)
+
+			assert.Equal(t, c.statusCode, responseRecorder.Result().StatusCode, "Response status matches the error kind")
+			assert.Contains(t, responseRecorder.Body.String(), "verifier says", "Verifier detail is reported")
+		})
+	}
+}
+
 // TestValidateWithSigningFailureConditions contains the test for signing service failures
 func TestValidateWithSigningFailureConditions(t *testing.T) {
 	t.Run("Failure", func(t *testing.T) {
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// whereUsedFilterFields are the query parameters a where-used search filters parts on
+var whereUsedFilterFields = []string{"assetModelNumber", "assetManufacturer"}
+
+// whereUsedWalker follows parent links upward, reading every asset once
+type whereUsedWalker struct {
+	ctx    context.Context
+	assets map[string]helpers.Asset
+}
+
+// get returns an asset, read from its agent the first time
+func (w *whereUsedWalker) get(element helpers.AssetElement) (helpers.Asset, error) {
+	if asset, ok := w.assets[elementKey(element)]; ok {
+		return asset, nil
+	}
+	_, asset, err := getChildAssetContextFromAssetElement(w.ctx, element)
+	if err != nil {
+		return helpers.Asset{}, err
+	}
+	w.assets[elementKey(element)] = asset
+	return asset, nil
+}
+
+// pathTo walks from a part up to its root. The path starts at the root and every component has the roles down to it
+func (w *whereUsedWalker) pathTo(part helpers.AssetElement, asset helpers.Asset) helpers.WhereUsedPath {
+	result := helpers.WhereUsedPath{Part: part}
+	chain := []helpers.BomComponent{{AssetElement: part, AssetType: asset.AssetType, AssetModelNumber: asset.AssetModelNumber}}
+	seen := map[string]bool{elementKey(part): true}
+	// Creates and detaches store an empty link, not a missing one, for an asset without a parent
+	for link := presentParent(asset); link != nil; link = presentParent(asset) {
+		parent := helpers.AssetElement{RepoID: link.RepoID, ChannelID: link.ChannelID, AssetID: link.AssetID}
+		if seen[elementKey(parent)] {
+			log.Error().Msgf("Parent loop above %s", elementKey(part))
+			result.Broken = true
+			break
+		}
+		parentAsset, err := w.get(parent)
+		if err != nil {
+			log.Warn().Msgf("Parent %s of a where-used path could not be read: %s", elementKey(parent), err.Error())
+			result.Broken = true
+			break
+		}
+		chain[len(chain)-1].Role = link.Role
+		chain[len(chain)-1].SubRole = link.SubRole
+		chain = append(chain, helpers.BomComponent{AssetElement: parent, AssetType: parentAsset.AssetType,
+			AssetModelNumber: parentAsset.AssetModelNumber})
+		seen[elementKey(parent)] = true
+		asset = parentAsset
+	}
+
+	result.Path = make([]helpers.BomComponent, 0, len(chain))
+	path := ""
+	for i := len(chain) - 1; i >= 0; i-- {
+		component := chain[i]
+		if i < len(chain)-1 {
+			path += "/" + component.Role
+		}
+		component.Path = path
+		result.Path = append(result.Path, component)
+	}
+	return result
+}
+
+// matchingParts queries every channel of a repo for the parts matching a filter
+func matchingParts(ctx context.Context, repoID string, filter map[string]string,
+	found func(helpers.AssetElement, helpers.Asset)) []helpers.TargetError {
+	query := make(map[string]interface{})
+	for field, value := range filter {
+		query[field] = value
+	}
+	return queryRepo(ctx, repoID, query, found)
+}
+
+// queryRepo queries every channel of a repo with a rich query, following the bookmarks of the agent, passing each asset
+// found in channel and assetID order
+func queryRepo(ctx context.Context, repoID string, query map[string]interface{},
+	found func(helpers.AssetElement, helpers.Asset)) []helpers.TargetError {
+	failed := func(channelID string, err error) []helpers.TargetError {
+		log.Warn().Msgf("Search of %s/%s failed: %s", repoID, channelID, err.Error())
+		return []helpers.TargetError{{RepoID: repoID, ChannelID: channelID, Error: err.Error()}}
+	}
+	repoAgent, err := agentForRepo(ctx, repoID)
+	if err != nil {
+		return failed("", err)
+	}
+	var channels []string
+	resultStream, err := repoAgent.ListChannels(ctx, agent.QueryArgs{})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&channels)
+	}
+	if err != nil {
+		return failed("", err)
+	}
+	sort.Strings(channels)
+
+	var targetErrors []helpers.TargetError
+	for _, channelID := range channels {
+		result, err := agent.QueryAllAssets(ctx, repoAgent, agent.QueryArgs{ChannelID: channelID},
+			agent.RichQueryArgs{Query: query})
+		if err != nil {
+			targetErrors = append(targetErrors, failed(channelID, err)...)
+			continue
+		}
+		assetIDs := make([]string, 0, len(result))
+		for assetID := range result {
+			assetIDs = append(assetIDs, assetID)
+		}
+		sort.Strings(assetIDs)
+		for _, assetID := range assetIDs {
+			var asset helpers.Asset
+			raw, _ := json.Marshal(result[assetID])
+			if err = json.Unmarshal(raw, &asset); err != nil {
+				targetErrors = append(targetErrors, failed(channelID, err)...)
+				continue
+			}
+			found(helpers.AssetElement{RepoID: repoID, ChannelID: channelID, AssetID: assetID}, asset)
+		}
+	}
+	return targetErrors
+}
+
+// WhereUsed is a controller function that finds the parts matching ?assetModelNumber= and ?assetManufacturer= in every
+// channel of every configured repo, and follows their parent links up to the assemblies that contain them.
+// A part without a parent is its own assembly. Repos and channels that cannot be searched are reported, not fatal
+func WhereUsed(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Where Used")
+	defer span.Finish()
+
+	filter := make(map[string]string)
+	for _, field := range whereUsedFilterFields {
+		if value := r.URL.Query().Get(field); value != "" {
+			filter[field] = value
+		}
+	}
+	if len(filter) == 0 {
+		render.Render(w, r, responses.ErrInvalidRequest(errors.New("filter on assetModelNumber, assetManufacturer or both")))
+		return
+	}
+	repoIDs, err := agent.GetConfiguredRepos()
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+
+	result := helpers.WhereUsed{
+		Filter:     filter,
+		Assemblies: []helpers.AffectedAssembly{},
+		Errors:     []helpers.TargetError{},
+	}
+	walker := whereUsedWalker{ctx: ctx, assets: make(map[string]helpers.Asset)}
+	assemblies := make(map[string]*helpers.AffectedAssembly)
+	var roots []string
+	for _, repoID := range repoIDs {
+		targetErrors := matchingParts(ctx, repoID, filter, func(part helpers.AssetElement, asset helpers.Asset) {
+			walker.assets[elementKey(part)] = asset
+			path := walker.pathTo(part, asset)
+			root := path.Path[0]
+			assembly, ok := assemblies[elementKey(root.AssetElement)]
+			if !ok {
+				assembly = &helpers.AffectedAssembly{Root: root}
+				assemblies[elementKey(root.AssetElement)] = assembly
+				roots = append(roots, elementKey(root.AssetElement))
+			}
+			assembly.Parts = append(assembly.Parts, path)
+			result.Matches++
+		})
+		result.Errors = append(result.Errors, targetErrors...)
+	}
+	sort.Strings(roots)
+	for _, root := range roots {
+		result.Assemblies = append(result.Assemblies, *assemblies[root])
+	}
+	render.JSON(w, r, result)
+}
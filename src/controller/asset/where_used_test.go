@@ -0,0 +1,137 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package asset
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const whereUsedLocation = "../../testdata/asset_controller_tests/whereused/"
+
+// queryT2C2Bookmark is the bookmark of the page after queryT2C2.json
+const queryT2C2Bookmark = "g1AAAAB4eJzLYWBgYMpgSmHgKy5JLCrJTq2MT8lPzkzJBYqrGBiaGRgYmJgAAP5SC0w"
+
+// expectLastPage expects the query of the page after the last one of a channel, which has no matches
+func expectLastPage(mockAgent *mocks.MockAgent, channelID string, bookmark string) {
+	mockAgent.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: channelID}, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ agent.QueryArgs, body agent.RichQueryArgs) (map[string]interface{}, error) {
+			if body.Bookmark != bookmark {
+				return nil, errors.New("unexpected bookmark " + body.Bookmark)
+			}
+			return map[string]interface{}{agent.BookmarkKey: bookmark}, nil
+		})
+}
+
+// openTestQueryResult returns a rich query result from the where-used test data
+func openTestQueryResult(file string) map[string]interface{} {
+	var result map[string]interface{}
+	json.NewDecoder(openTestJSON(whereUsedLocation + file)).Decode(&result)
+	return result
+}
+
+// TestWhereUsed contains the tests for finding the assemblies that contain a part
+func TestWhereUsed(t *testing.T) {
+	viper.Set("agents", map[string]agent.Config{
+		"T1": {Enabled: true},
+		"T2": {Enabled: true},
+		"T3": {Enabled: true},
+		"T4": {Enabled: false},
+	})
+	defer viper.Set("agents", nil)
+
+	t.Run("Happy_Path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		agentT1 := mocks.NewMockAgent(ctrl)
+		agentT2 := mocks.NewMockAgent(ctrl)
+		agentT3 := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+
+		filter := agent.RichQueryArgs{Query: map[string]interface{}{"assetModelNumber": "X100"}}
+		agentT1.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C1"]`)), nil)
+		agentT1.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, filter).
+			Return(openTestQueryResult("queryT1C1.json"), nil)
+		agentT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "B1")).
+			Return(openTestJSON(whereUsedLocation+"board.json"), nil)
+		agentT1.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "MISSING")).
+			Return(nil, helpers.ErrNotFound)
+		agentT2.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(strings.NewReader(`["C2","C1"]`)), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C1"}, filter).
+			Return(openTestQueryResult("queryT2C1.json"), nil)
+		agentT2.EXPECT().QueryAssets(gomock.Any(), agent.QueryArgs{ChannelID: "C2"}, filter).
+			Return(openTestQueryResult("queryT2C2.json"), nil)
+		expectLastPage(agentT2, "C2", queryT2C2Bookmark)
+		agentT2.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "S1")).
+			Return(openTestJSON(whereUsedLocation+"server.json"), nil).Times(1)
+		agentT3.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(nil, errors.New("agent is down"))
+
+		mockRequest := httptest.NewRequest("GET", "/?assetModelNumber=X100", nil)
+		mockRequest = mocks.InjectAgentProviderIntoRequest(mockRequest, ctrl,
+			map[string]agent.Agent{"T1": agentT1, "T2": agentT2, "T3": agentT3})
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(WhereUsed).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		var result helpers.WhereUsed
+		json.NewDecoder(responseRecorder.Body).Decode(&result)
+		assert.Equal(t, 4, result.Matches, "Every matching part is found")
+		assert.Len(t, result.Assemblies, 3, "Parts are grouped by root")
+
+		broken := result.Assemblies[0]
+		assert.Equal(t, "P4", broken.Root.AssetID, "Broken path ends at the last readable asset")
+		assert.True(t, broken.Parts[0].Broken, "Broken path is flagged")
+
+		stock := result.Assemblies[2]
+		assert.Equal(t, "P2", stock.Root.AssetID, "Part without a parent is its own root")
+		assert.Len(t, stock.Parts[0].Path, 1, "Path of a root part is the part")
+		assert.False(t, stock.Parts[0].Broken, "Empty parent link of a detached part ends the path")
+
+		server := result.Assemblies[1]
+		assert.Equal(t, "S1", server.Root.AssetID, "Server is affected")
+		assert.Equal(t, "Server", server.Root.AssetType, "Root has its type")
+		assert.Len(t, server.Parts, 2, "Both parts in the server are listed")
+		nested := server.Parts[0]
+		assert.Equal(t, "P1", nested.Part.AssetID, "Part is reported")
+		assert.Len(t, nested.Path, 3, "Path goes from the server through the board to the part")
+		assert.False(t, nested.Broken, "Empty parent link of the server ends the path")
+		assert.Equal(t, "/board", nested.Path[1].Path, "Board has its role path")
+		assert.Equal(t, "/board/cpu", nested.Path[2].Path, "Part has its role path")
+		assert.Equal(t, "spare", server.Parts[1].Path[1].Role, "Part directly in the server has its role")
+
+		assert.Len(t, result.Errors, 1, "Failed repo is reported")
+		assert.Equal(t, "T3", result.Errors[0].RepoID, "Repo of the failure is reported")
+	})
+	t.Run("No_Filter", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(WhereUsed).ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 Bad Request")
+	})
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package channel
+
+import (
+	"chainsource-gateway/agent"
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/label"
+	"chainsource-gateway/responses"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// GetLabelSheet is a controller function that renders the labels of every asset in a channel on a printable sheet,
+// in assetID order, as ?format=pdf (the default) or svg
+func GetLabelSheet(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Channel Label Sheet")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	requestAgent := r.Context().Value("agent").(agent.Agent)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = label.FormatPDF
+	}
+	if format != label.FormatPDF && format != label.FormatSVG {
+		render.Render(w, r, responses.ErrInvalidRequest(label.ErrUnsupportedFormat))
+		return
+	}
+
+	agentError := func(err error) {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrDoesNotExist(err))
+		} else if err == helpers.ErrUnauthorized {
+			render.Render(w, r, responses.ErrAgentUnauthorized(err))
+		} else {
+			render.Render(w, r, responses.ErrAgent(err))
+		}
+	}
+
+	var assetIDs []string
+	resultStream, err := requestAgent.ListAssets(ctx, agent.QueryArgs{
+		ChannelID: assetVars.ChannelID,
+	})
+	if err == nil {
+		err = json.NewDecoder(resultStream).Decode(&assetIDs)
+	}
+	if err != nil {
+		agentError(err)
+		return
+	}
+	sort.Strings(assetIDs)
+
+	labels := make([]label.Label, 0, len(assetIDs))
+	for _, assetID := range assetIDs {
+		var asset helpers.Asset
+		resultStream, err = requestAgent.QueryStream(ctx, agent.QueryArgs{
+			ChannelID: assetVars.ChannelID,
+			AssetID:   assetID,
+		})
+		if err == nil {
+			err = json.NewDecoder(resultStream).Decode(&asset)
+		}
+		// Assets deleted since they were listed have no label
+		if err == helpers.ErrNotFound {
+			log.Warn().Msgf("Asset %s of channel %s was not found, it has no label", assetID, assetVars.ChannelID)
+			continue
+		}
+		if err != nil {
+			agentError(err)
+			return
+		}
+		element := helpers.AssetElement{RepoID: assetVars.RepoID, ChannelID: assetVars.ChannelID, AssetID: assetID}
+		labels = append(labels, label.For(element, asset))
+	}
+
+	rendered, err := label.RenderSheet(labels, format)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	label.Write(w, rendered, format, assetVars.ChannelID+"-labels")
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package channel
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var labelAssetPath = "../../testdata/asset_controller_tests/duplicates/newAsset.json"
+
+// TestGetLabelSheet contains the tests for rendering the labels of every asset in a channel
+func TestGetLabelSheet(t *testing.T) {
+	t.Run("SVG", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		mockAgent.EXPECT().ListAssets(gomock.Any(), mocks.AgentQueryFor("C1", "")).Return(openTestJSON(assetsPath), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "assets1")).
+			Return(openTestJSON(labelAssetPath), nil)
+		mockAgent.EXPECT().QueryStream(gomock.Any(), mocks.AgentQueryFor("C1", "assets2")).Return(nil, helpers.ErrNotFound)
+
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/?format=svg", nil), "T1", "C1", "", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetLabelSheet).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		assert.Equal(t, "image/svg+xml", responseRecorder.Header().Get("Content-Type"), "Sheet is an SVG")
+		assert.Equal(t, "attachment; filename=C1-labels.svg", responseRecorder.Header().Get("Content-Disposition"),
+			"Sheet is named after the channel")
+		body := responseRecorder.Body.String()
+		assert.Equal(t, 1, strings.Count(body, `<g transform="translate(`), "Deleted assets have no label")
+		assert.Contains(t, body, ">X100</text>", "Label has the model")
+	})
+	t.Run("Agent_Error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockAgent := mocks.NewMockAgent(ctrl)
+		defer ctrl.Finish()
+		mockAgent.EXPECT().ListAssets(gomock.Any(), mocks.AgentQueryFor("C1", "")).Return(nil, helpers.ErrUnauthorized)
+
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/", nil), "T1", "C1", "", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetLabelSheet).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code, "Response Should be 401 not authorized")
+	})
+	t.Run("Unsupported_Format", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/?format=png", nil), "T1", "C1", "",
+			mocks.NewMockAgent(ctrl), mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetLabelSheet).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 Bad Request")
+	})
+}
@@ -22,8 +22,11 @@ import (
 	"chainsource-gateway/mocks"
 	"encoding/json"
 	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -128,3 +131,72 @@ func TestListAssetsErrorConditions(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, responseRecorder.Result().StatusCode, "Response Should be 404 not found")
 }
+
+// TestListAssetsPaged tests following the next links through the pages of a listing
+func TestListAssetsPaged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	mockAgent.EXPECT().ListAssets(gomock.Any(), mocks.AgentQueryFor("C1", "")).
+		DoAndReturn(func(_, _ interface{}) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(`["A1", "A2", "A3"]`)), nil
+		}).Times(2)
+
+	var pages []helpers.Page
+	for link := "/api/v1/repo/T1/chan/C1/asset?limit=2"; link != ""; link = pages[len(pages)-1].Next {
+		responseRecorder := httptest.NewRecorder()
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", link, nil), "T1", "C1", "", mockAgent,
+			mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(ListAssets).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+		var page helpers.Page
+		json.NewDecoder(responseRecorder.Result().Body).Decode(&page)
+		pages = append(pages, page)
+	}
+	assert.Len(t, pages, 2, "Listing has two pages")
+	assert.Equal(t, []interface{}{"A1", "A2"}, pages[0].Items, "First page is full")
+	assert.NotEmpty(t, pages[0].NextCursor, "First page has a cursor")
+	assert.Equal(t, []interface{}{"A3"}, pages[1].Items, "Second page has the rest")
+	assert.Empty(t, pages[1].NextCursor, "Last page has no cursor")
+}
+
+// TestListAssetsInvalidCursor checks that cursors not issued by the gateway are rejected
+func TestListAssetsInvalidCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	for _, cursor := range []string{"%25%25", helpers.EncodeBookmarkCursor("g1AAAA")} {
+		responseRecorder := httptest.NewRecorder()
+		mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/?cursor="+cursor, nil), "T1", "C1", "",
+			mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+		http.HandlerFunc(ListAssets).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Result().StatusCode, "Response Should be 400 Bad Request")
+	}
+}
+
+// TestListAssetsNDJSON tests streaming the asset ids one per line
+func TestListAssetsNDJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	mockAgent.EXPECT().ListAssets(gomock.Any(), mocks.AgentQueryFor("C1", "")).Return(openTestJSON(assetsPath), nil)
+	mockRequest := httptest.NewRequest("GET", "/", nil)
+	mockRequest.Header.Set("Accept", helpers.ContentTypeNDJSON)
+	responseRecorder := httptest.NewRecorder()
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "", mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	http.HandlerFunc(ListAssets).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+	assert.Equal(t, helpers.ContentTypeNDJSON, responseRecorder.Header().Get("Content-Type"), "Response is NDJSON")
+	assert.Equal(t, "\"assets1\"\n\"assets2\"\n", responseRecorder.Body.String(), "One asset id per line")
+
+	mockRequest = httptest.NewRequest("GET", "/?limit=1", nil)
+	mockRequest.Header.Set("Accept", helpers.ContentTypeNDJSON)
+	responseRecorder = httptest.NewRecorder()
+	mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "", mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	http.HandlerFunc(ListAssets).ServeHTTP(responseRecorder, mockRequest)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Result().StatusCode, "Streams are not paged")
+}
@@ -29,6 +29,8 @@ import (
 )
 
 // ListAssets is a controller function to retrieve asset ids for a channel
+// With ?cursor= or ?limit= the ids are paged, read from the agent listing without holding all of it. With
+// Accept: application/x-ndjson the ids are streamed one per line as they are read
 func ListAssets(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "List Assets")
 	defer span.Finish()
@@ -37,6 +39,19 @@ func ListAssets(w http.ResponseWriter, r *http.Request) {
 	log.Debug().Msgf("Getting assets for channel %s from agent at %s:%d", assetVars.ChannelID,
 		requestAgent.GetHost(), requestAgent.GetPort())
 
+	page, paged, err := helpers.ParsePageRequest(r.URL.Query())
+	if err == nil && page.Cursor.Bookmark != "" {
+		err = helpers.ErrInvalidCursor
+	}
+	stream := helpers.WantsNDJSON(r)
+	if err == nil && stream && paged {
+		err = helpers.ErrPagedStream
+	}
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
 	var result interface{}
 	resultStream, err := requestAgent.ListAssets(ctx, agent.QueryArgs{
 		ChannelID: assetVars.ChannelID,
@@ -51,6 +66,32 @@ func ListAssets(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if stream {
+		defer resultStream.Close()
+		lines := helpers.NewNDJSONWriter(w)
+		err = helpers.StreamArray(resultStream, func(entry json.RawMessage) error {
+			return lines.Write(entry)
+		})
+		if err != nil {
+			lines.WriteError(err)
+		}
+		return
+	}
+	if paged {
+		defer resultStream.Close()
+		entries, more, err := helpers.ReadPage(resultStream, page.Cursor.Offset, page.Limit)
+		if err != nil {
+			render.Render(w, r, responses.ErrAgent(err))
+			return
+		}
+		result := helpers.Page{Items: entries}
+		if more {
+			result.NextCursor = helpers.EncodeCursor(page.Cursor.Offset + page.Limit)
+			result.Next = helpers.NextLink(r.URL, result.NextCursor)
+		}
+		render.JSON(w, r, result)
+		return
+	}
 	err = json.NewDecoder(resultStream).Decode(&result)
 	render.JSON(w, r, result)
 }
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package channel
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/offers"
+	"chainsource-gateway/responses"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// ListTransferOffers is a controller function to list the transfer offers made to a channel
+// Pending offers are listed by default, ?status= selects another status and ?status=all lists every offer
+func ListTransferOffers(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "List Transfer Offers")
+	defer span.Finish()
+	assetVars := r.Context().Value("assetVars").(helpers.AssetRoutingVars)
+	offerStore := r.Context().Value("offerStore").(offers.OfferStore)
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = offers.StatusPending
+	} else if status == "all" {
+		status = ""
+	}
+
+	incoming, err := offerStore.ListIncoming(ctx, assetVars.RepoID, assetVars.ChannelID, status)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	render.JSON(w, r, incoming)
+}
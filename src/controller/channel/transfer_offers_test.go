@@ -0,0 +1,61 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package channel
+
+import (
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/offers"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestListTransferOffers checks the status filter of the incoming offers of a channel
+func TestListTransferOffers(t *testing.T) {
+	cases := []struct {
+		name   string
+		query  string
+		status string
+	}{
+		{"Pending_By_Default", "", offers.StatusPending},
+		{"By_Status", "?status=rejected", offers.StatusRejected},
+		{"All", "?status=all", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockAgent := mocks.NewMockAgent(ctrl)
+			mockOffers := mocks.NewMockOfferStore(ctrl)
+			defer ctrl.Finish()
+
+			mockOffers.EXPECT().ListIncoming(gomock.Any(), "T1", "C1", c.status).Return([]offers.Offer{}, nil)
+			mockRequest := httptest.NewRequest("GET", "/"+c.query, nil)
+			responseRecorder := httptest.NewRecorder()
+			mockRequest = injectMockAssetContext(mockRequest, "T1", "C1", "", mockAgent,
+				mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+			mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "offerStore", mockOffers))
+			http.HandlerFunc(ListTransferOffers).ServeHTTP(responseRecorder, mockRequest)
+
+			assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+		})
+	}
+}
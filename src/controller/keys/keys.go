@@ -0,0 +1,160 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package keys contains all the controller functions for managing the trusted manufacturer keys
+package keys
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/tracing"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+var log = helpers.GetLogger("KeyController")
+
+// revokeRequest is the body of a revocation
+type revokeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// validateKey checks the fields of a key registration
+func validateKey(key keyregistry.ManufacturerKey) error {
+	if strings.TrimSpace(key.Fingerprint) == "" {
+		return errors.New("fingerprint is required")
+	}
+	if strings.TrimSpace(key.Owner.Name) == "" {
+		return errors.New("owner.name is required")
+	}
+	if len(key.Manufacturers) == 0 && len(key.Parties) == 0 {
+		return errors.New("at least one trusted manufacturer or transfer party is required")
+	}
+	for _, manufacturer := range key.Manufacturers {
+		if strings.TrimSpace(manufacturer) == "" {
+			return errors.New("manufacturers must not be empty")
+		}
+	}
+	// Parties are the repo and channel a key signs transfers for, as repoID/channelID
+	for _, party := range key.Parties {
+		if parts := strings.Split(strings.TrimSpace(party), "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return errors.New("parties must be written as repoID/channelID")
+		}
+	}
+	if key.ValidFrom != nil && key.ValidUntil != nil && !key.ValidUntil.After(*key.ValidFrom) {
+		return errors.New("validUntil must be after validFrom")
+	}
+	return nil
+}
+
+// RegisterKey is a controller function to register a trusted manufacturer key
+func RegisterKey(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Register Key")
+	defer span.Finish()
+	registry := r.Context().Value("keyRegistry").(keyregistry.KeyRegistry)
+
+	var key keyregistry.ManufacturerKey
+	err := json.NewDecoder(r.Body).Decode(&key)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to unmarshal, invalid format")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+	if err = validateKey(key); err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	registered, err := registry.Register(ctx, key)
+	if err != nil {
+		if err == keyregistry.ErrKeyExists {
+			render.Render(w, r, responses.ErrAlreadyExists(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, registered)
+}
+
+// ListKeys is a controller function to list the registered manufacturer keys
+func ListKeys(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "List Keys")
+	defer span.Finish()
+	registry := r.Context().Value("keyRegistry").(keyregistry.KeyRegistry)
+
+	keys, err := registry.List(ctx)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	render.JSON(w, r, keys)
+}
+
+// GetKey is a controller function to get a registered manufacturer key
+func GetKey(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Key")
+	defer span.Finish()
+	registry := r.Context().Value("keyRegistry").(keyregistry.KeyRegistry)
+
+	key, err := registry.Get(ctx, chi.URLParam(r, "fingerprint"))
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrKeyDoesNotExist(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+	render.JSON(w, r, key)
+}
+
+// RevokeKey is a controller function to revoke a registered manufacturer key
+func RevokeKey(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Revoke Key")
+	defer span.Finish()
+	registry := r.Context().Value("keyRegistry").(keyregistry.KeyRegistry)
+
+	var request revokeRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to unmarshal, invalid format")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	key, err := registry.Revoke(ctx, chi.URLParam(r, "fingerprint"), request.Reason)
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrKeyDoesNotExist(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+	log.Info().Msgf("Key %s revoked: %s", key.Fingerprint, request.Reason)
+	render.JSON(w, r, key)
+}
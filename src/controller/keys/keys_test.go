@@ -0,0 +1,190 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package keys
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/mocks"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const validKey = `{"fingerprint":"ABCD","owner":{"name":"Supplier"},"manufacturers":["A Valid Manufacturer"]}`
+
+// injectKeyContext injects a key registry and the fingerprint URL parameter into a request
+func injectKeyContext(r *http.Request, registry keyregistry.KeyRegistry, fingerprint string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("fingerprint", fingerprint)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "keyRegistry", registry)
+	return r.WithContext(ctx)
+}
+
+// TestRegisterKey contains the tests for registering keys
+func TestRegisterKey(t *testing.T) {
+	t.Run("Valid_Key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Register(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, key keyregistry.ManufacturerKey) (keyregistry.ManufacturerKey, error) {
+				return key, nil
+			})
+
+		mockRequest := injectKeyContext(httptest.NewRequest("POST", "/", strings.NewReader(validKey)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(RegisterKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code, "Response Should be 201 CREATED")
+	})
+	t.Run("Already_Registered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Register(gomock.Any(), gomock.Any()).
+			Return(keyregistry.ManufacturerKey{}, keyregistry.ErrKeyExists)
+
+		mockRequest := injectKeyContext(httptest.NewRequest("POST", "/", strings.NewReader(validKey)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(RegisterKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Missing_Manufacturers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		body := `{"fingerprint":"ABCD","owner":{"name":"Supplier"}}`
+		mockRequest := injectKeyContext(httptest.NewRequest("POST", "/", strings.NewReader(body)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(RegisterKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Invalid_Party", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		body := `{"fingerprint":"ABCD","owner":{"name":"Receiver"},"parties":["T1"]}`
+		mockRequest := injectKeyContext(httptest.NewRequest("POST", "/", strings.NewReader(body)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(RegisterKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Inverted_Window", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+
+		body := `{"fingerprint":"ABCD","owner":{"name":"Supplier"},"manufacturers":["M"],` +
+			`"validFrom":"2021-01-01T00:00:00Z","validUntil":"2020-01-01T00:00:00Z"}`
+		mockRequest := injectKeyContext(httptest.NewRequest("POST", "/", strings.NewReader(body)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(RegisterKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+}
+
+// TestListKeys tests listing the registered keys
+func TestListKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+	defer ctrl.Finish()
+	mockRegistry.EXPECT().List(gomock.Any()).
+		Return([]keyregistry.ManufacturerKey{{Fingerprint: "ABCD"}}, nil)
+
+	mockRequest := injectKeyContext(httptest.NewRequest("GET", "/", nil), mockRegistry, "")
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(ListKeys).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	assert.Contains(t, responseRecorder.Body.String(), "ABCD", "Key is listed")
+}
+
+// TestGetKey contains the tests for getting a key
+func TestGetKey(t *testing.T) {
+	t.Run("Registered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Get(gomock.Any(), "ABCD").
+			Return(keyregistry.ManufacturerKey{Fingerprint: "ABCD"}, nil)
+
+		mockRequest := injectKeyContext(httptest.NewRequest("GET", "/", nil), mockRegistry, "ABCD")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	})
+	t.Run("Not_Registered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Get(gomock.Any(), "ABCD").
+			Return(keyregistry.ManufacturerKey{}, helpers.ErrNotFound)
+
+		mockRequest := injectKeyContext(httptest.NewRequest("GET", "/", nil), mockRegistry, "ABCD")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+}
+
+// TestRevokeKey contains the tests for revoking a key
+func TestRevokeKey(t *testing.T) {
+	t.Run("Registered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Revoke(gomock.Any(), "ABCD", "compromised").
+			Return(keyregistry.ManufacturerKey{Fingerprint: "ABCD", Revoked: true}, nil)
+
+		body := strings.NewReader(`{"reason":"compromised"}`)
+		mockRequest := injectKeyContext(httptest.NewRequest("POST", "/", body), mockRegistry, "ABCD")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(RevokeKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	})
+	t.Run("Not_Registered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Revoke(gomock.Any(), "ABCD", "").
+			Return(keyregistry.ManufacturerKey{}, helpers.ErrNotFound)
+
+		mockRequest := injectKeyContext(httptest.NewRequest("POST", "/", strings.NewReader(`{}`)), mockRegistry, "ABCD")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(RevokeKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+}
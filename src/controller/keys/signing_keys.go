@@ -0,0 +1,121 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package keys
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/keystore"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/tracing"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+// signingKeyRequest is the body of a signing key generation
+type signingKeyRequest struct {
+	RepoID       string `json:"repoID"`
+	Manufacturer string `json:"manufacturer"`
+}
+
+// CreateSigningKey is a controller function to generate a key the gateway signs assets with
+// Only the public key is returned, the private key stays encrypted in the keystore. A key bound to a manufacturer is
+// registered in the key registry as trusted for it
+func CreateSigningKey(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Create Signing Key")
+	defer span.Finish()
+	signingKeys := r.Context().Value("keystore").(keystore.Keystore)
+
+	if !signingKeys.Enabled() {
+		render.Render(w, r, responses.ErrSigningDisabled(keystore.ErrKeystoreDisabled))
+		return
+	}
+	var request signingKeyRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to unmarshal, invalid format")
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
+	key, err := signingKeys.Generate(ctx, request.RepoID, request.Manufacturer)
+	if err != nil {
+		if err == keystore.ErrMissingBinding {
+			render.Render(w, r, responses.ErrInvalidRequest(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+
+	// Keys of a manufacturer are trusted for it, so the gateway's own signatures pass a registry check
+	if key.Manufacturer != "" {
+		registry := r.Context().Value("keyRegistry").(keyregistry.KeyRegistry)
+		_, err = registry.Register(ctx, keyregistry.ManufacturerKey{
+			Fingerprint:   key.KeyID,
+			Scheme:        key.Scheme,
+			PublicKey:     key.PublicKey,
+			Owner:         keyregistry.KeyOwner{Name: key.Manufacturer},
+			Manufacturers: []string{key.Manufacturer},
+		})
+		if err != nil {
+			tracing.LogAndTraceErr(log, span, err, "Failed to register signing key")
+			render.Render(w, r, responses.ErrInternalServer(err))
+			return
+		}
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, key)
+}
+
+// ListSigningKeys is a controller function to list the keys the gateway signs assets with
+func ListSigningKeys(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "List Signing Keys")
+	defer span.Finish()
+	signingKeys := r.Context().Value("keystore").(keystore.Keystore)
+
+	keys, err := signingKeys.List(ctx)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	render.JSON(w, r, keys)
+}
+
+// GetSigningKey is a controller function to get the public part of a key the gateway signs assets with
+func GetSigningKey(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Signing Key")
+	defer span.Finish()
+	signingKeys := r.Context().Value("keystore").(keystore.Keystore)
+
+	key, err := signingKeys.Get(ctx, chi.URLParam(r, "keyID"))
+	if err != nil {
+		if err == helpers.ErrNotFound {
+			render.Render(w, r, responses.ErrKeyDoesNotExist(err))
+		} else {
+			render.Render(w, r, responses.ErrInternalServer(err))
+		}
+		return
+	}
+	render.JSON(w, r, key)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package keys
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/keystore"
+	"chainsource-gateway/mocks"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// injectKeystoreContext injects a keystore and the keyID URL parameter into a request
+func injectKeystoreContext(r *http.Request, signingKeys keystore.Keystore, keyID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("keyID", keyID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "keystore", signingKeys)
+	return r.WithContext(ctx)
+}
+
+// TestCreateSigningKey contains the tests for generating signing keys
+func TestCreateSigningKey(t *testing.T) {
+	t.Run("Valid_Binding", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		defer ctrl.Finish()
+		mockKeystore.EXPECT().Enabled().Return(true)
+		mockKeystore.EXPECT().Generate(gomock.Any(), "R1", "").
+			Return(keystore.SigningKey{KeyID: "K1", RepoID: "R1"}, nil)
+
+		body := strings.NewReader(`{"repoID":"R1"}`)
+		mockRequest := injectKeystoreContext(httptest.NewRequest("POST", "/", body), mockKeystore, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateSigningKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code, "Response Should be 201 CREATED")
+		assert.Contains(t, responseRecorder.Body.String(), "K1", "Key ID is returned")
+	})
+	t.Run("Manufacturer_Key_Is_Registered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		mockRegistry := mocks.NewMockKeyRegistry(ctrl)
+		defer ctrl.Finish()
+		mockKeystore.EXPECT().Enabled().Return(true)
+		mockKeystore.EXPECT().Generate(gomock.Any(), "", "M1").
+			Return(keystore.SigningKey{KeyID: "K1", Scheme: "jws", PublicKey: "<public-key>", Manufacturer: "M1"}, nil)
+		mockRegistry.EXPECT().Register(gomock.Any(), keyregistry.ManufacturerKey{
+			Fingerprint:   "K1",
+			Scheme:        "jws",
+			PublicKey:     "<public-key>",
+			Owner:         keyregistry.KeyOwner{Name: "M1"},
+			Manufacturers: []string{"M1"},
+		}).Return(keyregistry.ManufacturerKey{Fingerprint: "K1"}, nil)
+
+		body := strings.NewReader(`{"manufacturer":"M1"}`)
+		mockRequest := injectKeystoreContext(httptest.NewRequest("POST", "/", body), mockKeystore, "")
+		mockRequest = mockRequest.WithContext(context.WithValue(mockRequest.Context(), "keyRegistry", mockRegistry))
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateSigningKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code, "Response Should be 201 CREATED")
+	})
+	t.Run("Missing_Binding", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		defer ctrl.Finish()
+		mockKeystore.EXPECT().Enabled().Return(true)
+		mockKeystore.EXPECT().Generate(gomock.Any(), "", "").
+			Return(keystore.SigningKey{}, keystore.ErrMissingBinding)
+
+		mockRequest := injectKeystoreContext(httptest.NewRequest("POST", "/", strings.NewReader(`{}`)), mockKeystore, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateSigningKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+	t.Run("Signing_Disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockKeystore := mocks.NewMockKeystore(ctrl)
+		defer ctrl.Finish()
+		mockKeystore.EXPECT().Enabled().Return(false)
+
+		body := strings.NewReader(`{"repoID":"R1"}`)
+		mockRequest := injectKeystoreContext(httptest.NewRequest("POST", "/", body), mockKeystore, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateSigningKey).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code, "Response Should be 403 FORBIDDEN")
+	})
+}
+
+// TestListSigningKeys tests listing the signing keys
+func TestListSigningKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockKeystore := mocks.NewMockKeystore(ctrl)
+	defer ctrl.Finish()
+	mockKeystore.EXPECT().List(gomock.Any()).Return([]keystore.SigningKey{{KeyID: "K1"}}, nil)
+
+	mockRequest := injectKeystoreContext(httptest.NewRequest("GET", "/", nil), mockKeystore, "")
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(ListSigningKeys).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	assert.Contains(t, responseRecorder.Body.String(), "K1", "Key is listed")
+}
+
+// TestGetSigningKey tests getting a signing key that does not exist
+func TestGetSigningKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockKeystore := mocks.NewMockKeystore(ctrl)
+	defer ctrl.Finish()
+	mockKeystore.EXPECT().Get(gomock.Any(), "K1").Return(keystore.SigningKey{}, helpers.ErrNotFound)
+
+	mockRequest := injectKeystoreContext(httptest.NewRequest("GET", "/", nil), mockKeystore, "K1")
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(GetSigningKey).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+}
@@ -22,8 +22,10 @@ import (
 	"chainsource-gateway/mocks"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -128,3 +130,39 @@ func TestListChannelsErrorConditions(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, responseRecorder.Result().StatusCode, "Response Should be 404 not found")
 }
+
+// TestListChannelsPaged tests paging through the channels of a repo
+func TestListChannelsPaged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	mockAgent.EXPECT().ListChannels(gomock.Any(), gomock.Any()).Return(openTestJSON(channelsPath), nil)
+	responseRecorder := httptest.NewRecorder()
+	mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/api/v1/repo/T1/chan?limit=1", nil), "T1", "",
+		"", mockAgent, mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	http.HandlerFunc(ListChannels).ServeHTTP(responseRecorder, mockRequest)
+
+	var page helpers.Page
+	json.NewDecoder(responseRecorder.Result().Body).Decode(&page)
+	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode, "Response Should be 200 OK")
+	assert.Equal(t, []interface{}{"channel1"}, page.Items, "Page holds one channel")
+	assert.Equal(t, helpers.EncodeCursor(1), page.NextCursor, "Next page starts at the second channel")
+	assert.Equal(t, "/api/v1/repo/T1/chan?cursor="+page.NextCursor+"&limit=1", page.Next, "Next link carries the cursor")
+}
+
+// TestListChannelsNotAList checks that agent listings that are not arrays fail when paged
+func TestListChannelsNotAList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAgent := mocks.NewMockAgent(ctrl)
+	defer ctrl.Finish()
+
+	mockAgent.EXPECT().ListChannels(gomock.Any(), gomock.Any()).
+		Return(ioutil.NopCloser(strings.NewReader(`{"channel1": {}}`)), nil)
+	responseRecorder := httptest.NewRecorder()
+	mockRequest := injectMockAssetContext(httptest.NewRequest("GET", "/?cursor=", nil), "T1", "", "", mockAgent,
+		mocks.NewMockAssetSchemaAlwaysValid(ctrl))
+	http.HandlerFunc(ListChannels).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusBadGateway, responseRecorder.Result().StatusCode, "Response Should be 502 bad gateway")
+}
@@ -29,6 +29,7 @@ import (
 )
 
 // ListChannels is a controller function to retrieve channel ids
+// With ?cursor= or ?limit= the ids are paged, read from the agent listing without holding all of it
 func ListChannels(w http.ResponseWriter, r *http.Request) {
 	span, ctx := opentracing.StartSpanFromContext(r.Context(), "List Channels")
 	defer span.Finish()
@@ -37,6 +38,15 @@ func ListChannels(w http.ResponseWriter, r *http.Request) {
 	log.Debug().Msgf("Getting channels from agent at %s:%d",
 		requestAgent.GetHost(), requestAgent.GetPort())
 
+	page, paged, err := helpers.ParsePageRequest(r.URL.Query())
+	if err == nil && page.Cursor.Bookmark != "" {
+		err = helpers.ErrInvalidCursor
+	}
+	if err != nil {
+		render.Render(w, r, responses.ErrInvalidRequest(err))
+		return
+	}
+
 	var result interface{}
 	resultStream, err := requestAgent.ListChannels(ctx, agent.QueryArgs{
 		ChannelID: assetVars.ChannelID,
@@ -51,6 +61,21 @@ func ListChannels(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if paged {
+		defer resultStream.Close()
+		entries, more, err := helpers.ReadPage(resultStream, page.Cursor.Offset, page.Limit)
+		if err != nil {
+			render.Render(w, r, responses.ErrAgent(err))
+			return
+		}
+		result := helpers.Page{Items: entries}
+		if more {
+			result.NextCursor = helpers.EncodeCursor(page.Cursor.Offset + page.Limit)
+			result.Next = helpers.NextLink(r.URL, result.NextCursor)
+		}
+		render.JSON(w, r, result)
+		return
+	}
 	err = json.NewDecoder(resultStream).Decode(&result)
 	render.JSON(w, r, result)
 }
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package templates contains all the controller functions for managing the BOM templates
+package templates
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/responses"
+	"chainsource-gateway/templateregistry"
+	"chainsource-gateway/tracing"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/opentracing/opentracing-go"
+)
+
+var log = helpers.GetLogger("TemplateController")
+
+// registryErrorResponse maps an error of the template registry to its response
+func registryErrorResponse(err error) render.Renderer {
+	switch err {
+	case helpers.ErrNotFound:
+		return responses.ErrTemplateDoesNotExist(err)
+	case templateregistry.ErrTemplateExists:
+		return responses.ErrAlreadyExists(err)
+	default:
+		return responses.ErrInternalServer(err)
+	}
+}
+
+// decodeTemplate reads and validates the template in the body of a request. A templateID from the path replaces the one in the body
+func decodeTemplate(r *http.Request, span opentracing.Span, templateID string) (template templateregistry.BomTemplate, rejection render.Renderer) {
+	err := json.NewDecoder(r.Body).Decode(&template)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Failed to unmarshal, invalid format")
+		return template, responses.ErrInvalidRequest(err)
+	}
+	if templateID != "" {
+		template.TemplateID = templateID
+	}
+	if err = template.Validate(); err != nil {
+		return template, responses.ErrInvalidRequest(err)
+	}
+	return template, nil
+}
+
+// CreateTemplate is a controller function to create the BOM template of a model
+func CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Create Template")
+	defer span.Finish()
+	registry := r.Context().Value("templateRegistry").(templateregistry.TemplateRegistry)
+
+	template, rejection := decodeTemplate(r, span, "")
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	created, err := registry.Create(ctx, template)
+	if err != nil {
+		render.Render(w, r, registryErrorResponse(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, created)
+}
+
+// ListTemplates is a controller function to list the BOM templates
+func ListTemplates(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "List Templates")
+	defer span.Finish()
+	registry := r.Context().Value("templateRegistry").(templateregistry.TemplateRegistry)
+
+	list, err := registry.List(ctx)
+	if err != nil {
+		render.Render(w, r, responses.ErrInternalServer(err))
+		return
+	}
+	render.JSON(w, r, list)
+}
+
+// GetTemplate is a controller function to get a BOM template
+func GetTemplate(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Get Template")
+	defer span.Finish()
+	registry := r.Context().Value("templateRegistry").(templateregistry.TemplateRegistry)
+
+	template, err := registry.Get(ctx, chi.URLParam(r, "templateID"))
+	if err != nil {
+		render.Render(w, r, registryErrorResponse(err))
+		return
+	}
+	render.JSON(w, r, template)
+}
+
+// ReplaceTemplate is a controller function to replace a BOM template, the ID in the path takes precedence over the body
+func ReplaceTemplate(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Replace Template")
+	defer span.Finish()
+	registry := r.Context().Value("templateRegistry").(templateregistry.TemplateRegistry)
+
+	template, rejection := decodeTemplate(r, span, chi.URLParam(r, "templateID"))
+	if rejection != nil {
+		render.Render(w, r, rejection)
+		return
+	}
+	replaced, err := registry.Replace(ctx, template)
+	if err != nil {
+		render.Render(w, r, registryErrorResponse(err))
+		return
+	}
+	render.JSON(w, r, replaced)
+}
+
+// DeleteTemplate is a controller function to delete a BOM template
+func DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	span, ctx := opentracing.StartSpanFromContext(r.Context(), "Delete Template")
+	defer span.Finish()
+	registry := r.Context().Value("templateRegistry").(templateregistry.TemplateRegistry)
+
+	templateID := chi.URLParam(r, "templateID")
+	if err := registry.Delete(ctx, templateID); err != nil {
+		render.Render(w, r, registryErrorResponse(err))
+		return
+	}
+	log.Info().Msgf("Template %s deleted", templateID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package templates
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/mocks"
+	"chainsource-gateway/templateregistry"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const validTemplate = `{"templateID":"server","assetType":"Server","assetModelNumber":"SRV-1",` +
+	`"children":[{"role":"cpu","assetTypes":["Processor"],"minCount":1,"maxCount":2}]}`
+
+// injectTemplateContext injects a template registry and the templateID URL parameter into a request
+func injectTemplateContext(r *http.Request, registry templateregistry.TemplateRegistry, templateID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("templateID", templateID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, "templateRegistry", registry)
+	return r.WithContext(ctx)
+}
+
+// TestCreateTemplate contains the tests for creating templates
+func TestCreateTemplate(t *testing.T) {
+	t.Run("Valid_Template", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Create(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, template templateregistry.BomTemplate) (templateregistry.BomTemplate, error) {
+				assert.Equal(t, 2, template.Children[0].MaxCount, "Slots are decoded")
+				return template, nil
+			})
+
+		mockRequest := injectTemplateContext(httptest.NewRequest("POST", "/", strings.NewReader(validTemplate)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code, "Response Should be 201 CREATED")
+	})
+	t.Run("Already_Exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Create(gomock.Any(), gomock.Any()).
+			Return(templateregistry.BomTemplate{}, templateregistry.ErrTemplateExists)
+
+		mockRequest := injectTemplateContext(httptest.NewRequest("POST", "/", strings.NewReader(validTemplate)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusConflict, responseRecorder.Code, "Response Should be 409 CONFLICT")
+	})
+	t.Run("Missing_Model", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+		defer ctrl.Finish()
+
+		body := `{"templateID":"server","assetType":"Server"}`
+		mockRequest := injectTemplateContext(httptest.NewRequest("POST", "/", strings.NewReader(body)), mockRegistry, "")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(CreateTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "Response Should be 400 BAD REQUEST")
+	})
+}
+
+// TestListTemplates tests listing the templates
+func TestListTemplates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+	defer ctrl.Finish()
+	mockRegistry.EXPECT().List(gomock.Any()).
+		Return([]templateregistry.BomTemplate{{TemplateID: "server"}}, nil)
+
+	mockRequest := injectTemplateContext(httptest.NewRequest("GET", "/", nil), mockRegistry, "")
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(ListTemplates).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	assert.Contains(t, responseRecorder.Body.String(), "server", "Template is listed")
+}
+
+// TestGetTemplate contains the tests for getting a template
+func TestGetTemplate(t *testing.T) {
+	t.Run("Exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Get(gomock.Any(), "server").
+			Return(templateregistry.BomTemplate{TemplateID: "server"}, nil)
+
+		mockRequest := injectTemplateContext(httptest.NewRequest("GET", "/", nil), mockRegistry, "server")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+	})
+	t.Run("Does_Not_Exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Get(gomock.Any(), "server").
+			Return(templateregistry.BomTemplate{}, helpers.ErrNotFound)
+
+		mockRequest := injectTemplateContext(httptest.NewRequest("GET", "/", nil), mockRegistry, "server")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(GetTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+}
+
+// TestReplaceTemplate tests that a template is replaced under the ID of the path
+func TestReplaceTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+	defer ctrl.Finish()
+	mockRegistry.EXPECT().Replace(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, template templateregistry.BomTemplate) (templateregistry.BomTemplate, error) {
+			assert.Equal(t, "server-b", template.TemplateID, "ID of the path is used")
+			return template, nil
+		})
+
+	mockRequest := injectTemplateContext(httptest.NewRequest("PUT", "/", strings.NewReader(validTemplate)), mockRegistry, "server-b")
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(ReplaceTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "Response Should be 200 OK")
+}
+
+// TestDeleteTemplate contains the tests for deleting a template
+func TestDeleteTemplate(t *testing.T) {
+	t.Run("Exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Delete(gomock.Any(), "server").Return(nil)
+
+		mockRequest := injectTemplateContext(httptest.NewRequest("DELETE", "/", nil), mockRegistry, "server")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(DeleteTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNoContent, responseRecorder.Code, "Response Should be 204 NO CONTENT")
+	})
+	t.Run("Does_Not_Exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRegistry := mocks.NewMockTemplateRegistry(ctrl)
+		defer ctrl.Finish()
+		mockRegistry.EXPECT().Delete(gomock.Any(), "server").Return(helpers.ErrNotFound)
+
+		mockRequest := injectTemplateContext(httptest.NewRequest("DELETE", "/", nil), mockRegistry, "server")
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(DeleteTemplate).ServeHTTP(responseRecorder, mockRequest)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code, "Response Should be 404 NOT FOUND")
+	})
+}
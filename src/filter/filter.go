@@ -0,0 +1,251 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package filter contains the filter expressions of the query API, such as
+// assetType = "Server" and assetMetadata.serial ~ "AB*", and their compilation into the selector agents query with
+package filter
+
+import (
+	"chainsource-gateway/helpers"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// selectorOperators are the selector operators of the comparison operators
+var selectorOperators = map[string]string{"=": "$eq", "!=": "$ne", "<": "$lt", "<=": "$lte", ">": "$gt",
+	">=": "$gte", "~": "$regex", "in": "$in"}
+
+// assetFields are the fields of an asset a path can start with
+var assetFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	assetType := reflect.TypeOf(helpers.Asset{})
+	for i := 0; i < assetType.NumField(); i++ {
+		name := strings.TrimSpace(strings.Split(assetType.Field(i).Tag.Get("json"), ",")[0])
+		fields[name] = true
+	}
+	return fields
+}()
+
+// parser is a recursive descent parser over the tokens of a filter expression
+type parser struct {
+	tokens []token
+	next   int
+}
+
+// peek returns the next token without consuming it
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+// take consumes and returns the next token
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEnd {
+		p.next++
+	}
+	return t
+}
+
+// isKeyword reports if the next token is a keyword
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenKeyword && t.text == keyword
+}
+
+// parseOr parses terms joined by or
+func (p *parser) parseOr() (map[string]interface{}, error) {
+	return p.parseJoined("or", "$or", p.parseAnd)
+}
+
+// parseAnd parses terms joined by and
+func (p *parser) parseAnd() (map[string]interface{}, error) {
+	return p.parseJoined("and", "$and", p.parseNot)
+}
+
+// parseJoined parses terms joined by a keyword into a selector combining them with an operator. Nested combinations
+// with the same operator are flattened
+func (p *parser) parseJoined(keyword string, operator string,
+	parseTerm func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	first, err := parseTerm()
+	if err != nil || !p.isKeyword(keyword) {
+		return first, err
+	}
+	var terms []interface{}
+	add := func(term map[string]interface{}) {
+		if nested, ok := term[operator].([]interface{}); ok && len(term) == 1 {
+			terms = append(terms, nested...)
+		} else {
+			terms = append(terms, term)
+		}
+	}
+	add(first)
+	for p.isKeyword(keyword) {
+		p.take()
+		term, err := parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		add(term)
+	}
+	return map[string]interface{}{operator: terms}, nil
+}
+
+// parseNot parses a negated term or a primary term
+func (p *parser) parseNot() (map[string]interface{}, error) {
+	if !p.isKeyword("not") {
+		return p.parsePrimary()
+	}
+	p.take()
+	term, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"$nor": []interface{}{term}}, nil
+}
+
+// parsePrimary parses a parenthesized expression or a comparison
+func (p *parser) parsePrimary() (map[string]interface{}, error) {
+	if p.peek().kind == tokenLeftParen {
+		p.take()
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.take(); t.kind != tokenRightParen {
+			return nil, syntaxError(t.position, "expected \")\", found %s", t)
+		}
+		return expression, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses a path compared with a value, or with a list of values with in
+func (p *parser) parseComparison() (map[string]interface{}, error) {
+	path := p.take()
+	if path.kind != tokenPath {
+		return nil, syntaxError(path.position, "expected a field, found %s", path)
+	}
+	if root := strings.Split(path.text, ".")[0]; !assetFields[root] {
+		return nil, syntaxError(path.position, "unknown field %q", root)
+	}
+
+	operator := p.take()
+	if operator.kind == tokenKeyword && operator.text == "in" {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{path.text: map[string]interface{}{"$in": values}}, nil
+	}
+	if operator.kind != tokenOperator {
+		return nil, syntaxError(operator.position, "expected an operator, found %s", operator)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	switch operator.text {
+	case "~":
+		pattern, ok := value.value.(string)
+		if !ok {
+			return nil, syntaxError(value.position, "~ matches a string pattern, found %s", value)
+		}
+		return map[string]interface{}{path.text: map[string]interface{}{"$regex": patternRegex(pattern)}}, nil
+	case "<", "<=", ">", ">=":
+		if value.kind != tokenString && value.kind != tokenNumber {
+			return nil, syntaxError(value.position, "%s compares with a string or a number, found %s", operator.text,
+				value)
+		}
+	}
+	return map[string]interface{}{path.text: map[string]interface{}{selectorOperators[operator.text]: value.value}}, nil
+}
+
+// parseList parses a parenthesized, comma separated list of values
+func (p *parser) parseList() ([]interface{}, error) {
+	if t := p.take(); t.kind != tokenLeftParen {
+		return nil, syntaxError(t.position, "expected \"(\" after in, found %s", t)
+	}
+	var values []interface{}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value.value)
+		t := p.take()
+		if t.kind == tokenRightParen {
+			return values, nil
+		}
+		if t.kind != tokenComma {
+			return nil, syntaxError(t.position, "expected \",\" or \")\", found %s", t)
+		}
+	}
+}
+
+// parseValue parses a string, number, true, false or null
+func (p *parser) parseValue() (token, error) {
+	t := p.take()
+	switch {
+	case t.kind == tokenString || t.kind == tokenNumber:
+		return t, nil
+	case t.kind == tokenKeyword && (t.text == "true" || t.text == "false"):
+		t.value = t.text == "true"
+		return t, nil
+	case t.kind == tokenKeyword && t.text == "null":
+		return t, nil
+	}
+	return t, syntaxError(t.position, "expected a value, found %s", t)
+}
+
+// patternRegex returns the anchored regular expression of a pattern where * matches any text and ? any character
+func patternRegex(pattern string) string {
+	var regex strings.Builder
+	regex.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			regex.WriteString(".*")
+		case '?':
+			regex.WriteString(".")
+		default:
+			regex.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	regex.WriteString("$")
+	return regex.String()
+}
+
+// Compile parses a filter expression and returns the selector of the query API that matches the same assets.
+// Comparisons are a field path, an operator (=, !=, <, <=, >, >=, ~ or in) and a value, combined with and, or, not and
+// parentheses. Errors are SyntaxErrors with the position of the offending character
+func Compile(expression string) (map[string]interface{}, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	selector, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, syntaxError(t.position, "unexpected %s", t)
+	}
+	return selector, nil
+}
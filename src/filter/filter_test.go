@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package filter
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompile tests compiling expressions into selectors
+func TestCompile(t *testing.T) {
+	for expression, expected := range map[string]string{
+		`assetType = "Server"`: `{"assetType":{"$eq":"Server"}}`,
+		`assetType = "Server" and assetMetadata.serial ~ "AB*"`: `{"$and":[{"assetType":{"$eq":"Server"}},` +
+			`{"assetMetadata.serial":{"$regex":"^AB.*$"}}]}`,
+		`standardVersion >= 2 AND standardVersion < 3.5 and readOnly != true`: `{"$and":[` +
+			`{"standardVersion":{"$gte":2}},{"standardVersion":{"$lt":3.5}},{"readOnly":{"$ne":true}}]}`,
+		`assetType = "Server" or assetType = "Rack" and not readOnly = true`: `{"$or":[` +
+			`{"assetType":{"$eq":"Server"}},{"$and":[{"assetType":{"$eq":"Rack"}},{"$nor":[{"readOnly":{"$eq":true}}]}]}]}`,
+		`(assetType = "A" or assetType = "B") or parentAsset = null`: `{"$or":[{"assetType":{"$eq":"A"}},` +
+			`{"assetType":{"$eq":"B"}},{"parentAsset":{"$eq":null}}]}`,
+		`assetModelNumber in ("X100", "X200", -1e3)`: `{"assetModelNumber":{"$in":["X100","X200",-1000]}}`,
+		`assetDescription ~ "a.b?\"c\\"`:             `{"assetDescription":{"$regex":"^a\\.b.\"c\\\\$"}}`,
+		`custodyTransferEvents.0.status = "pending"`: `{"custodyTransferEvents.0.status":{"$eq":"pending"}}`,
+	} {
+		selector, err := Compile(expression)
+		assert.NoError(t, err, "Expression %s compiles", expression)
+		compiled, _ := json.Marshal(selector)
+		assert.JSONEq(t, expected, string(compiled), "Selector of %s", expression)
+	}
+}
+
+// TestPatternRegex tests that patterns match like globs
+func TestPatternRegex(t *testing.T) {
+	regex := regexp.MustCompile(patternRegex("AB*-?.x"))
+	assert.True(t, regex.MatchString("AB123-7.x"), "Wildcards match")
+	assert.False(t, regex.MatchString("AB123-7yx"), "Dots are literal")
+	assert.False(t, regex.MatchString("xAB-7.x"), "Patterns are anchored")
+}
+
+// TestCompileErrors tests the syntax errors and their positions
+func TestCompileErrors(t *testing.T) {
+	for expression, expected := range map[string]SyntaxError{
+		``:                                {1, "expected a field, found end of expression"},
+		`assetType`:                       {10, "expected an operator, found end of expression"},
+		`assetType = `:                    {13, "expected a value, found end of expression"},
+		`assetType = "Server`:             {13, "unterminated string"},
+		`assetType = "a\q"`:               {15, `unknown escape \q`},
+		`assetType == "Server"`:           {12, `expected a value, found "="`},
+		`assetType ! "Server"`:            {11, `expected "!="`},
+		`assetType = Server`:              {13, `expected a value, found "Server"`},
+		`color = "red"`:                   {1, `unknown field "color"`},
+		`assetMetadata. = 1`:              {15, `expected a field name after "."`},
+		`assetType = "A" and`:             {20, "expected a field, found end of expression"},
+		`(assetType = "A"`:                {17, `expected ")", found end of expression`},
+		`assetType = "A" assetType = "B"`: {17, `unexpected "assetType"`},
+		`standardVersion < true`:          {19, `< compares with a string or a number, found "true"`},
+		`assetType ~ 1`:                   {13, `~ matches a string pattern, found "1"`},
+		`assetType in "A"`:                {14, `expected "(" after in, found "\"A\""`},
+		`assetType in ("A" "B")`:          {19, `expected "," or ")", found "\"B\""`},
+		`standardVersion = 1.2.3`:         {19, `invalid number "1.2.3"`},
+		`assetType = "A" # comment`:       {17, `unexpected character '#'`},
+		`assetType = "Übersee" and x = 1`: {27, `unknown field "x"`},
+	} {
+		_, err := Compile(expression)
+		if assert.IsType(t, &SyntaxError{}, err, "Expression %q fails", expression) {
+			assert.Equal(t, expected, *err.(*SyntaxError), "Error of %q", expression)
+		}
+	}
+	_, err := Compile(`assetType = `)
+	assert.EqualError(t, err, "syntax error at position 13: expected a value, found end of expression",
+		"Errors carry their position")
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a token of a filter expression
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenPath
+	tokenKeyword
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+// keywords are the reserved words of the language, matched without case
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "true": true, "false": true,
+	"null": true}
+
+// token is a type representing a token of a filter expression, at the 1-based position of its first character
+type token struct {
+	kind     tokenKind
+	text     string
+	value    interface{}
+	position int
+}
+
+// String describes a token in syntax errors
+func (t token) String() string {
+	if t.kind == tokenEnd {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// SyntaxError is an error in a filter expression, at the 1-based position of the offending character
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+// Error describes the error with its position
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Position, e.Message)
+}
+
+// syntaxError returns a SyntaxError at a position
+func syntaxError(position int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Position: position, Message: fmt.Sprintf(format, args...)}
+}
+
+// isPathStart reports if a character starts a path segment
+func isPathStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isPathPart reports if a character continues a path segment
+func isPathPart(r rune) bool {
+	return isPathStart(r) || unicode.IsDigit(r)
+}
+
+// lex splits a filter expression into tokens, ending with a tokenEnd
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+	for i := 0; i < len(runes); {
+		start := i
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", position: start + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", position: start + 1})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", position: start + 1})
+			i++
+		case r == '=' || r == '~':
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r), position: start + 1})
+			i++
+		case r == '<' || r == '>' || r == '!':
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			} else if r == '!' {
+				return nil, syntaxError(start+1, "expected \"!=\"")
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: string(runes[start:i]), position: start + 1})
+		case r == '"':
+			var text strings.Builder
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, syntaxError(start+1, "unterminated string")
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				if runes[i] == '\\' {
+					i++
+					if i >= len(runes) {
+						return nil, syntaxError(start+1, "unterminated string")
+					}
+					switch runes[i] {
+					case '"', '\\':
+						text.WriteRune(runes[i])
+					case 'n':
+						text.WriteRune('\n')
+					case 't':
+						text.WriteRune('\t')
+					default:
+						return nil, syntaxError(i, "unknown escape \\%c", runes[i])
+					}
+					continue
+				}
+				text.WriteRune(runes[i])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[start:i]), value: text.String(),
+				position: start + 1})
+		case r == '-' || unicode.IsDigit(r):
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune(".eE+-", runes[i])); i++ {
+			}
+			number, err := strconv.ParseFloat(string(runes[start:i]), 64)
+			if err != nil {
+				return nil, syntaxError(start+1, "invalid number %q", string(runes[start:i]))
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), value: number,
+				position: start + 1})
+		case isPathStart(r):
+			for {
+				for i++; i < len(runes) && isPathPart(runes[i]); i++ {
+				}
+				if i >= len(runes) || runes[i] != '.' {
+					break
+				}
+				if i+1 >= len(runes) || !isPathPart(runes[i+1]) {
+					return nil, syntaxError(i+2, "expected a field name after \".\"")
+				}
+				i++
+			}
+			text := string(runes[start:i])
+			kind := tokenPath
+			if keywords[strings.ToLower(text)] {
+				kind = tokenKeyword
+				text = strings.ToLower(text)
+			}
+			tokens = append(tokens, token{kind: kind, text: text, position: start + 1})
+		default:
+			return nil, syntaxError(start+1, "unexpected character %q", r)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, position: len(runes) + 1}), nil
+}
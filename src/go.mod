@@ -9,12 +9,18 @@ require (
 	github.com/go-chi/render v1.0.2
 	github.com/golang/mock v1.6.0
 	github.com/magiconair/properties v1.8.6
+	github.com/makiuchi-d/gozxing v0.1.1
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/qri-io/jsonschema v0.1.2
 	github.com/rs/zerolog v1.28.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	github.com/smallstep/pkcs7 v0.2.3
 	github.com/spf13/viper v1.13.0
 	github.com/stretchr/testify v1.8.2
 	github.com/uber/jaeger-client-go v2.24.0+incompatible
 	github.com/uber/jaeger-lib v2.4.1+incompatible
+	golang.org/x/crypto v0.0.0-20220722155217-630584e8d5aa
+	golang.org/x/image v0.0.0-20190802002840-cff245a6509b
 	gopkg.in/h2non/gock.v1 v1.1.2
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,50 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// AssetDocument returns an asset as the JSON document it is committed as, for reading fields by path
+func AssetDocument(asset Asset) (map[string]interface{}, error) {
+	raw, err := json.Marshal(asset)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	err = json.Unmarshal(raw, &document)
+	return document, err
+}
+
+// ValueAtPath returns the value at a dot path into a JSON document, such as assetMetadata.serialNumber.
+// Returns false when a step of the path is missing or not an object
+func ValueAtPath(document map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = document
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
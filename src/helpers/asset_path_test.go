@@ -0,0 +1,42 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValueAtPath tests reading asset fields by dot path
+func TestValueAtPath(t *testing.T) {
+	document, err := AssetDocument(Asset{AssetModelNumber: "X100",
+		AssetMetadata: map[string]interface{}{"ids": map[string]interface{}{"serialNumber": "SN-1"}}})
+	assert.NoError(t, err, "Asset is converted")
+
+	value, ok := ValueAtPath(document, "assetModelNumber")
+	assert.True(t, ok, "Top level field is found")
+	assert.Equal(t, "X100", value, "Top level value is read")
+	value, ok = ValueAtPath(document, "assetMetadata.ids.serialNumber")
+	assert.True(t, ok, "Nested field is found")
+	assert.Equal(t, "SN-1", value, "Nested value is read")
+	_, ok = ValueAtPath(document, "assetMetadata.gtin")
+	assert.False(t, ok, "Missing field is not found")
+	_, ok = ValueAtPath(document, "assetModelNumber.part")
+	assert.False(t, ok, "Path through a scalar is not found")
+}
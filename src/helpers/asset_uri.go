@@ -0,0 +1,58 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// AssetURIScheme is the scheme of the URI form of an asset element, dbom://<repoID>/<channelID>/<assetID>
+const AssetURIScheme = "dbom"
+
+// ErrInvalidAssetURI is an error when a string is not an asset URI
+var ErrInvalidAssetURI = errors.New("asset URI must be dbom://<repoID>/<channelID>/<assetID>")
+
+// URI returns the canonical URI form of an asset element. IDs are path escaped
+func (e AssetElement) URI() string {
+	return fmt.Sprintf("%s://%s/%s/%s", AssetURIScheme, url.PathEscape(e.RepoID), url.PathEscape(e.ChannelID),
+		url.PathEscape(e.AssetID))
+}
+
+// ParseAssetURI parses the URI form of an asset element. The scheme is case insensitive
+func ParseAssetURI(uri string) (AssetElement, error) {
+	prefix := AssetURIScheme + "://"
+	uri = strings.TrimSpace(uri)
+	if len(uri) < len(prefix) || !strings.EqualFold(uri[:len(prefix)], prefix) {
+		return AssetElement{}, ErrInvalidAssetURI
+	}
+	parts := strings.Split(uri[len(prefix):], "/")
+	if len(parts) != 3 {
+		return AssetElement{}, ErrInvalidAssetURI
+	}
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil || unescaped == "" {
+			return AssetElement{}, ErrInvalidAssetURI
+		}
+		parts[i] = unescaped
+	}
+	return AssetElement{RepoID: parts[0], ChannelID: parts[1], AssetID: parts[2]}, nil
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAssetURI tests formatting and parsing the URI form of asset elements
+func TestAssetURI(t *testing.T) {
+	element := AssetElement{RepoID: "DB1", ChannelID: "C1", AssetID: "A1"}
+	assert.Equal(t, "dbom://DB1/C1/A1", element.URI(), "URI is formatted")
+	parsed, err := ParseAssetURI("DBOM://DB1/C1/A1")
+	assert.NoError(t, err, "URI is parsed")
+	assert.Equal(t, element, parsed, "Scheme is case insensitive")
+
+	escaped := AssetElement{RepoID: "DB1", ChannelID: "C1", AssetID: "lot 7/unit 2"}
+	assert.Equal(t, "dbom://DB1/C1/lot%207%2Funit%202", escaped.URI(), "IDs are escaped")
+	parsed, err = ParseAssetURI(escaped.URI())
+	assert.NoError(t, err, "Escaped URI is parsed")
+	assert.Equal(t, escaped, parsed, "Escaped IDs round trip")
+
+	for _, invalid := range []string{"", "dbom://DB1/C1", "dbom://DB1/C1/A1/extra", "dbom://DB1//A1", "http://DB1/C1/A1",
+		"dbom://DB1/C1/%zz"} {
+		_, err = ParseAssetURI(invalid)
+		assert.Equal(t, ErrInvalidAssetURI, err, "Invalid URI %q is rejected", invalid)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCanonicalJSON tests the JSON Canonicalization Scheme serializer
+func TestCanonicalJSON(t *testing.T) {
+	t.Run("When_Keys_Unordered", func(t *testing.T) {
+		input := map[string]interface{}{
+			"b": 1,
+			"a": map[string]interface{}{"z": true, "y": nil},
+			"c": []interface{}{"x", 2.5},
+		}
+		result, err := CanonicalJSON(input)
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, `{"a":{"y":null,"z":true},"b":1,"c":["x",2.5]}`, string(result), "Members are sorted")
+	})
+	t.Run("When_Keys_Need_UTF16_Ordering", func(t *testing.T) {
+		input := map[string]interface{}{
+			"\u20ac":     "Euro Sign",
+			"\r":         "Carriage Return",
+			"\ufb33":     "Hebrew Letter Dalet With Dagesh",
+			"1":          "One",
+			"\U0001f600": "Emoji: Grinning Face",
+			"\u0080":     "Control",
+			"\u00f6":     "Latin Small Letter O With Diaeresis",
+		}
+		result, err := CanonicalJSON(input)
+		assert.NoError(t, err, "No error must be returned")
+		expected := "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+			"\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"
+		assert.Equal(t, expected, string(result), "Members are sorted by UTF-16 code units")
+	})
+	t.Run("When_Strings_Need_Escaping", func(t *testing.T) {
+		result, err := CanonicalJSON("\u0001\"\\/<>\n\u2028")
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, "\"\\u0001\\\"\\\\/<>\\n\u2028\"", string(result), "Only the required characters are escaped")
+	})
+	t.Run("When_Struct_Input", func(t *testing.T) {
+		result, err := CanonicalJSON(AssetNoChildParent{
+			StandardVersion: 2,
+			AssetType:       "Server",
+			AssetMetadata:   map[string]interface{}{"serial": "S1"},
+		})
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, `{"assetDescription":"","assetManufacturer":"","assetMetadata":{"serial":"S1"},"assetModelNumber":"",`+
+			`"assetSubType":"","assetType":"Server","documentCreatedDate":"","documentCreator":"","documentName":"","standardVersion":2}`,
+			string(result), "Struct fields are ordered by name, not declaration order")
+	})
+	t.Run("When_Not_Representable", func(t *testing.T) {
+		_, err := CanonicalJSON(math.NaN())
+		assert.Error(t, err, "Error must be returned")
+	})
+}
+
+// TestCanonicalNumber tests number serialization against the RFC 8785 sample values
+func TestCanonicalNumber(t *testing.T) {
+	cases := map[float64]string{
+		0:                       "0",
+		math.Copysign(0, -1):    "0",
+		1:                       "1",
+		-1.5:                    "-1.5",
+		1e21:                    "1e+21",
+		1e20:                    "100000000000000000000",
+		333333333.3333333:       "333333333.3333333",
+		1e-7:                    "1e-7",
+		0.000001:                "0.000001",
+		5e-324:                  "5e-324",
+		1.7976931348623157e308:  "1.7976931348623157e+308",
+		9007199254740992:        "9007199254740992",
+		295147905179352830000:   "295147905179352830000",
+		4.50:                    "4.5",
+		2e-3:                    "0.002",
+		0.000001234:             "0.000001234",
+		123456789012345680000.0: "123456789012345680000",
+	}
+	for input, expected := range cases {
+		actual, err := canonicalNumber(input)
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, expected, actual, "Number is serialized like ECMAScript")
+	}
+}
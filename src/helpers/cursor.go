@@ -0,0 +1,76 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is an error when a pagination cursor was not issued by the gateway
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// cursorPrefix marks a cursor as an offset into a listing
+const cursorPrefix = "offset:"
+
+// bookmarkPrefix marks a cursor as a bookmark issued by an agent
+const bookmarkPrefix = "bookmark:"
+
+// Cursor is a type representing where a page starts, an offset emulated by the gateway or the bookmark of an agent
+type Cursor struct {
+	Offset   int
+	Bookmark string
+}
+
+// EncodeCursor returns the opaque cursor of the page starting at offset
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset of the page a cursor points at. The empty cursor is the first page
+func DecodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return offset, nil
+}
+
+// EncodeBookmarkCursor returns the opaque cursor of the page starting at an agent bookmark
+func EncodeBookmarkCursor(bookmark string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(bookmarkPrefix + bookmark))
+}
+
+// ParseCursor returns the offset or the agent bookmark a cursor points at. The empty cursor is the first page
+func ParseCursor(cursor string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(raw), bookmarkPrefix) && len(raw) > len(bookmarkPrefix) {
+		return Cursor{Bookmark: strings.TrimPrefix(string(raw), bookmarkPrefix)}, nil
+	}
+	offset, err := DecodeCursor(cursor)
+	return Cursor{Offset: offset}, err
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCursor tests the round trip and the rejection of pagination cursors
+func TestCursor(t *testing.T) {
+	offset, err := DecodeCursor(EncodeCursor(42))
+	assert.NoError(t, err, "No error must be returned")
+	assert.Equal(t, 42, offset, "Offset survives the round trip")
+
+	offset, err = DecodeCursor("")
+	assert.NoError(t, err, "Empty cursor is the first page")
+	assert.Equal(t, 0, offset, "Empty cursor is the first page")
+
+	for _, cursor := range []string{"%%%", EncodeCursor(-1), "b2Zmc2V0Onh5", "aGVsbG8"} {
+		_, err = DecodeCursor(cursor)
+		assert.Equal(t, ErrInvalidCursor, err, "Cursor %q is rejected", cursor)
+	}
+}
+
+// TestParseCursor tests telling offset cursors from agent bookmarks
+func TestParseCursor(t *testing.T) {
+	cursor, err := ParseCursor(EncodeBookmarkCursor("g1AAAA+/x"))
+	assert.NoError(t, err, "No error must be returned")
+	assert.Equal(t, Cursor{Bookmark: "g1AAAA+/x"}, cursor, "Bookmark survives the round trip")
+
+	cursor, err = ParseCursor(EncodeCursor(7))
+	assert.NoError(t, err, "No error must be returned")
+	assert.Equal(t, Cursor{Offset: 7}, cursor, "Offset survives the round trip")
+
+	_, err = ParseCursor(EncodeBookmarkCursor(""))
+	assert.Equal(t, ErrInvalidCursor, err, "Empty bookmark is rejected")
+	_, err = DecodeCursor(EncodeBookmarkCursor("g1AAAA"))
+	assert.Equal(t, ErrInvalidCursor, err, "Bookmarks are not offsets")
+}
@@ -85,6 +85,45 @@ func PostJSONRequest(base string, path string, headerExtra map[string]string, by
 	return
 }
 
+// PostRequest executes a POST request to a url with a Text/JSON body and optionally additional headers. Returns the
+// response body as a stream for the caller to close
+func PostRequest(base string, path string, headerExtra map[string]string, bytesRepresentation []byte) (result io.ReadCloser, err error) {
+
+	client := &http.Client{
+		//Timeout:timeout,
+	}
+	req, err := http.NewRequest("POST", base+path, bytes.NewBuffer(bytesRepresentation))
+	if err != nil {
+		log.Err(err)
+		return
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if headerExtra != nil {
+		for key, value := range headerExtra {
+			req.Header.Add(key, value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Err(err)
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			err = ErrNotFound
+		} else if resp.StatusCode == http.StatusUnauthorized {
+			err = ErrUnauthorized
+		} else {
+			err = errors.New("Got non OK statuscode: " + strconv.Itoa(resp.StatusCode))
+		}
+		return
+	}
+	result = resp.Body
+
+	return
+}
+
 // GetRequest executes a GET request to a URL, with optional headers
 func GetRequest(url string, path string, headerExtra map[string]string) (result io.ReadCloser, err error) {
 
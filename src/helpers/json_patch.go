@@ -0,0 +1,169 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is a type representing an operation of a JSON Patch (RFC 6902)
+type PatchOperation struct {
+	Op    string
+	Path  string
+	Value interface{}
+
+	previous interface{}
+}
+
+// MarshalJSON writes the operation, add and replace always carry a value even when it is null
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{o.Op, o.Path, o.Value})
+}
+
+// Describe returns the operation in human readable form, with its path in dotted form
+func (o PatchOperation) Describe() string {
+	field := strings.Replace(strings.TrimPrefix(o.Path, "/"), "/", ".", -1)
+	field = strings.Replace(strings.Replace(field, "~1", "/", -1), "~0", "~", -1)
+	switch o.Op {
+	case "add":
+		return fmt.Sprintf("%s added: %s", field, describeValue(o.Value))
+	case "remove":
+		return fmt.Sprintf("%s removed, was %s", field, describeValue(o.previous))
+	default:
+		return fmt.Sprintf("%s changed from %s to %s", field, describeValue(o.previous), describeValue(o.Value))
+	}
+}
+
+// describeValue returns a value as compact JSON
+func describeValue(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(raw)
+}
+
+// JSONPatch returns the JSON Patch that turns the JSON form of from into the JSON form of to.
+// Objects are compared member by member, arrays element by element
+func JSONPatch(from interface{}, to interface{}) ([]PatchOperation, error) {
+	fromValue, err := genericJSON(from)
+	if err != nil {
+		return nil, err
+	}
+	toValue, err := genericJSON(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffValues("", fromValue, toValue, []PatchOperation{}), nil
+}
+
+// genericJSON returns the decoded JSON form of a value
+func genericJSON(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(raw, &generic)
+	return generic, err
+}
+
+// pointerToken escapes a member name for a JSON Pointer (RFC 6901)
+func pointerToken(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
+
+// diffValues appends the operations turning from into to at path
+func diffValues(path string, from interface{}, to interface{}, ops []PatchOperation) []PatchOperation {
+	fromObject, fromIsObject := from.(map[string]interface{})
+	toObject, toIsObject := to.(map[string]interface{})
+	if fromIsObject && toIsObject {
+		return diffObjects(path, fromObject, toObject, ops)
+	}
+	fromArray, fromIsArray := from.([]interface{})
+	toArray, toIsArray := to.([]interface{})
+	if fromIsArray && toIsArray {
+		return diffArrays(path, fromArray, toArray, ops)
+	}
+	if !reflect.DeepEqual(from, to) {
+		ops = append(ops, PatchOperation{Op: "replace", Path: path, Value: to, previous: from})
+	}
+	return ops
+}
+
+// diffObjects appends the operations for the members of two objects, in member order
+func diffObjects(path string, from map[string]interface{}, to map[string]interface{}, ops []PatchOperation) []PatchOperation {
+	names := make([]string, 0, len(from)+len(to))
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		memberPath := path + "/" + pointerToken(name)
+		fromMember, inFrom := from[name]
+		toMember, inTo := to[name]
+		switch {
+		case !inTo:
+			ops = append(ops, PatchOperation{Op: "remove", Path: memberPath, previous: fromMember})
+		case !inFrom:
+			ops = append(ops, PatchOperation{Op: "add", Path: memberPath, Value: toMember})
+		default:
+			ops = diffValues(memberPath, fromMember, toMember, ops)
+		}
+	}
+	return ops
+}
+
+// diffArrays appends the operations for two arrays. Extra elements are added in ascending order and removed
+// from the end, so the operations apply in sequence
+func diffArrays(path string, from []interface{}, to []interface{}, ops []PatchOperation) []PatchOperation {
+	common := len(from)
+	if len(to) < common {
+		common = len(to)
+	}
+	for i := 0; i < common; i++ {
+		ops = diffValues(path+"/"+strconv.Itoa(i), from[i], to[i], ops)
+	}
+	for i := common; i < len(to); i++ {
+		ops = append(ops, PatchOperation{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: to[i]})
+	}
+	for i := len(from) - 1; i >= common; i-- {
+		ops = append(ops, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i), previous: from[i]})
+	}
+	return ops
+}
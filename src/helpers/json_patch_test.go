@@ -0,0 +1,69 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJSONPatch tests the JSON Patch generator
+func TestJSONPatch(t *testing.T) {
+	t.Run("When_Members_Change", func(t *testing.T) {
+		from := map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": "x", "d/e": true}, "gone": "y"}
+		to := map[string]interface{}{"a": 2, "b": map[string]interface{}{"c": "x", "d/e": nil}, "new": []int{1}}
+
+		ops, err := JSONPatch(from, to)
+
+		assert.NoError(t, err, "No error must be returned")
+		raw, _ := json.Marshal(ops)
+		assert.JSONEq(t, `[
+			{"op":"replace","path":"/a","value":2},
+			{"op":"replace","path":"/b/d~1e","value":null},
+			{"op":"remove","path":"/gone"},
+			{"op":"add","path":"/new","value":[1]}
+		]`, string(raw), "Patch is generated in member order")
+	})
+	t.Run("When_Arrays_Change", func(t *testing.T) {
+		ops, _ := JSONPatch([]string{"a", "b", "c"}, []string{"a", "x"})
+		raw, _ := json.Marshal(ops)
+		assert.JSONEq(t, `[{"op":"replace","path":"/1","value":"x"},{"op":"remove","path":"/2"}]`, string(raw),
+			"Extra elements are removed from the end")
+
+		ops, _ = JSONPatch([]string{"a"}, []string{"a", "b", "c"})
+		raw, _ = json.Marshal(ops)
+		assert.JSONEq(t, `[{"op":"add","path":"/1","value":"b"},{"op":"add","path":"/2","value":"c"}]`, string(raw),
+			"Extra elements are added in order")
+	})
+	t.Run("When_Equal", func(t *testing.T) {
+		ops, _ := JSONPatch(map[string]interface{}{"a": []int{1}}, map[string]interface{}{"a": []int{1}})
+		assert.Empty(t, ops, "Equal documents have an empty patch")
+	})
+	t.Run("Describe", func(t *testing.T) {
+		ops, _ := JSONPatch(map[string]interface{}{"m": map[string]interface{}{"k": "v1"}, "old": 1},
+			map[string]interface{}{"m": map[string]interface{}{"k": "v2"}, "new": "n"})
+		var described []string
+		for _, op := range ops {
+			described = append(described, op.Describe())
+		}
+		assert.Equal(t, []string{`m.k changed from "v1" to "v2"`, `new added: "n"`, `old removed, was 1`}, described,
+			"Operations are described with their previous value")
+	})
+}
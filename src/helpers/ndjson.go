@@ -0,0 +1,124 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ContentTypeNDJSON is the media type of newline delimited JSON, one record per line
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// ErrPagedStream is an error when a streamed response is asked for a page
+var ErrPagedStream = errors.New("streamed responses hold the whole result and are not paged")
+
+// ErrNotAnObject is an error when an agent query result is not a JSON object
+var ErrNotAnObject = errors.New("agent query result is not a JSON object")
+
+// WantsNDJSON reports if a request accepts newline delimited JSON
+func WantsNDJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.Split(accepted, ";")[0])
+		if strings.EqualFold(mediaType, ContentTypeNDJSON) {
+			return true
+		}
+	}
+	return false
+}
+
+// NDJSONWriter writes records to a response one per line, flushing each line to the client as it is written
+type NDJSONWriter struct {
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+// streamError is a type representing the last line of a stream that failed after the response started
+type streamError struct {
+	Error string `json:"error"`
+}
+
+// NewNDJSONWriter starts a newline delimited JSON response
+func NewNDJSONWriter(w http.ResponseWriter) *NDJSONWriter {
+	w.Header().Set("Content-Type", ContentTypeNDJSON)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &NDJSONWriter{encoder: json.NewEncoder(w), flusher: flusher}
+}
+
+// Write writes a record as a line
+func (n *NDJSONWriter) Write(record interface{}) error {
+	if err := n.encoder.Encode(record); err != nil {
+		return err
+	}
+	if n.flusher != nil {
+		n.flusher.Flush()
+	}
+	return nil
+}
+
+// WriteError ends a stream that failed after the response started with a line holding the error
+func (n *NDJSONWriter) WriteError(err error) {
+	log.Warn().Msgf("Stream ended early: %s", err.Error())
+	n.Write(streamError{Error: err.Error()})
+}
+
+// StreamArray calls write with each entry of a JSON array as it is read
+func StreamArray(stream io.Reader, write func(entry json.RawMessage) error) error {
+	decoder := json.NewDecoder(stream)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return ErrNotAList
+	}
+	for decoder.More() {
+		var entry json.RawMessage
+		if err := decoder.Decode(&entry); err != nil {
+			return err
+		}
+		if err := write(entry); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// StreamObject calls write with each member of a JSON object as it is read
+func StreamObject(stream io.Reader, write func(key string, value json.RawMessage) error) error {
+	decoder := json.NewDecoder(stream)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return ErrNotAnObject
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		if err := write(token.(string), value); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWantsNDJSON tests reading the accepted media types
+func TestWantsNDJSON(t *testing.T) {
+	for accept, wants := range map[string]bool{
+		"":                     false,
+		"application/json":     false,
+		"application/x-ndjson": true,
+		"application/json;q=0.5, application/x-ndjson": true,
+		"Application/X-NDJSON; charset=utf-8":          true,
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", accept)
+		assert.Equal(t, wants, WantsNDJSON(r), "Accept %q", accept)
+	}
+}
+
+// TestNDJSONWriter tests writing records and a failure as lines
+func TestNDJSONWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	lines := NewNDJSONWriter(recorder)
+	assert.NoError(t, lines.Write(map[string]int{"a": 1}), "Record is written")
+	assert.NoError(t, lines.Write("b"), "Record is written")
+	lines.WriteError(errors.New("agent went away"))
+
+	assert.Equal(t, ContentTypeNDJSON, recorder.Header().Get("Content-Type"), "Response is NDJSON")
+	assert.Equal(t, "{\"a\":1}\n\"b\"\n{\"error\":\"agent went away\"}\n", recorder.Body.String(), "One record per line")
+	assert.True(t, recorder.Flushed, "Lines are flushed")
+}
+
+// TestStreamArray tests reading the entries of a JSON array one by one
+func TestStreamArray(t *testing.T) {
+	var entries []string
+	collect := func(entry json.RawMessage) error {
+		entries = append(entries, string(entry))
+		return nil
+	}
+	assert.NoError(t, StreamArray(strings.NewReader(`["A1", {"b": [1]}]`), collect), "Array is read")
+	assert.Equal(t, []string{`"A1"`, `{"b": [1]}`}, entries, "Entries are read in order")
+
+	assert.Equal(t, ErrNotAList, StreamArray(strings.NewReader(`{}`), collect), "Objects are not arrays")
+	assert.Error(t, StreamArray(strings.NewReader(`["A1", `), collect), "Truncated arrays fail")
+	stop := errors.New("stop")
+	assert.Equal(t, stop, StreamArray(strings.NewReader(`["A1"]`), func(json.RawMessage) error { return stop }),
+		"Write errors end the stream")
+}
+
+// TestStreamObject tests reading the members of a JSON object one by one
+func TestStreamObject(t *testing.T) {
+	var keys []string
+	collect := func(key string, value json.RawMessage) error {
+		keys = append(keys, key+"="+string(value))
+		return nil
+	}
+	assert.NoError(t, StreamObject(strings.NewReader(`{"A1": {"x": 1}, "A2": null}`), collect), "Object is read")
+	assert.Equal(t, []string{`A1={"x": 1}`, `A2=null`}, keys, "Members are read in order")
+
+	assert.Equal(t, ErrNotAnObject, StreamObject(strings.NewReader(`[]`), collect), "Arrays are not objects")
+	assert.Error(t, StreamObject(strings.NewReader(`{"A1": `), collect), "Truncated objects fail")
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+)
+
+// DefaultPageLimit is the number of entries in a page when the request does not set a limit
+const DefaultPageLimit = 100
+
+// ErrNotAList is an error when an agent listing is not a JSON array
+var ErrNotAList = errors.New("agent listing is not a JSON array")
+
+// PageRequest is a type representing the page of a listing asked for with ?cursor= and ?limit=
+type PageRequest struct {
+	Cursor Cursor
+	Limit  int
+}
+
+// ParsePageRequest reads the page a request asks for. Requests are paged when they carry a cursor parameter, empty for
+// the first page, or a limit. Unpaged requests get the whole listing
+func ParsePageRequest(values url.Values) (page PageRequest, paged bool, err error) {
+	_, hasCursor := values["cursor"]
+	_, hasLimit := values["limit"]
+	if !hasCursor && !hasLimit {
+		return PageRequest{}, false, nil
+	}
+	if page.Cursor, err = ParseCursor(values.Get("cursor")); err != nil {
+		return PageRequest{}, true, err
+	}
+	page.Limit = DefaultPageLimit
+	if hasLimit {
+		page.Limit, err = strconv.Atoi(values.Get("limit"))
+		if err != nil || page.Limit < 1 {
+			return PageRequest{}, true, errors.New("limit must be a positive number")
+		}
+	}
+	return page, true, nil
+}
+
+// ReadPage reads limit entries from the offset on out of a JSON array, without holding the rest of the array in
+// memory, and reports if more entries follow
+func ReadPage(stream io.Reader, offset int, limit int) (entries []json.RawMessage, more bool, err error) {
+	decoder := json.NewDecoder(stream)
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('[') {
+		return nil, false, ErrNotAList
+	}
+	entries = []json.RawMessage{}
+	for index := 0; decoder.More(); index++ {
+		if len(entries) == limit {
+			return entries, true, nil
+		}
+		var entry json.RawMessage
+		if err = decoder.Decode(&entry); err != nil {
+			return nil, false, err
+		}
+		if index >= offset {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, false, nil
+}
+
+// NextLink returns the link to the page at cursor, the request URL with its cursor parameter replaced
+func NextLink(requestURL *url.URL, cursor string) string {
+	values := requestURL.Query()
+	values.Set("cursor", cursor)
+	next := url.URL{Path: requestURL.Path, RawQuery: values.Encode()}
+	return next.String()
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package helpers
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParsePageRequest tests reading the page asked for from the query parameters
+func TestParsePageRequest(t *testing.T) {
+	_, paged, err := ParsePageRequest(url.Values{"query": {"x"}})
+	assert.NoError(t, err, "No error must be returned")
+	assert.False(t, paged, "Requests without cursor or limit are not paged")
+
+	page, paged, err := ParsePageRequest(url.Values{"cursor": {""}})
+	assert.NoError(t, err, "No error must be returned")
+	assert.True(t, paged, "Empty cursor asks for the first page")
+	assert.Equal(t, PageRequest{Limit: DefaultPageLimit}, page, "Limit defaults")
+
+	page, _, err = ParsePageRequest(url.Values{"cursor": {EncodeCursor(20)}, "limit": {"10"}})
+	assert.NoError(t, err, "No error must be returned")
+	assert.Equal(t, PageRequest{Cursor: Cursor{Offset: 20}, Limit: 10}, page, "Cursor and limit are read")
+
+	for _, values := range []url.Values{{"limit": {"0"}}, {"limit": {"ten"}}, {"cursor": {"%%%"}}} {
+		_, paged, err = ParsePageRequest(values)
+		assert.True(t, paged, "Request is paged")
+		assert.Error(t, err, "Invalid page %v is rejected", values)
+	}
+}
+
+// TestReadPage tests reading a page out of a JSON array
+func TestReadPage(t *testing.T) {
+	listing := `["a", {"b": [1, 2]}, "c", "d"]`
+	entries, more, err := ReadPage(strings.NewReader(listing), 1, 2)
+	assert.NoError(t, err, "No error must be returned")
+	assert.Equal(t, []json.RawMessage{json.RawMessage(`{"b": [1, 2]}`), json.RawMessage(`"c"`)}, entries,
+		"Entries from the offset are read")
+	assert.True(t, more, "An entry follows the page")
+
+	entries, more, err = ReadPage(strings.NewReader(listing), 2, 2)
+	assert.NoError(t, err, "No error must be returned")
+	assert.Len(t, entries, 2, "Last page is full")
+	assert.False(t, more, "Nothing follows the last page")
+
+	entries, more, err = ReadPage(strings.NewReader(listing), 9, 2)
+	assert.NoError(t, err, "No error must be returned")
+	assert.Empty(t, entries, "Page past the end is empty")
+	assert.False(t, more, "Nothing follows the last page")
+
+	_, _, err = ReadPage(strings.NewReader(`{"a": 1}`), 0, 2)
+	assert.Equal(t, ErrNotAList, err, "Objects are not listings")
+	_, _, err = ReadPage(strings.NewReader(`["a", `), 0, 2)
+	assert.Error(t, err, "Truncated listings fail")
+}
+
+// TestNextLink tests replacing the cursor of the request URL
+func TestNextLink(t *testing.T) {
+	requestURL, _ := url.Parse("/api/v1/repo/DB1/chan/C1/asset?cursor=abc&limit=10")
+	assert.Equal(t, "/api/v1/repo/DB1/chan/C1/asset?cursor=def&limit=10", NextLink(requestURL, "def"),
+		"Cursor is replaced and other parameters are kept")
+}
@@ -17,6 +17,8 @@
 
 package helpers
 
+import "encoding/json"
+
 // AssetRoutingVars is a type to hold the asset context derived from the parametrized url
 type AssetRoutingVars struct {
 	RepoID    string
@@ -58,6 +60,8 @@ type Asset struct {
 	Children              map[string]*Asset      `json:"children,omitempty"`
 	Parent                map[string]*Asset      `json:"parent,omitempty"`
 	ReadOnly              bool                   `json:"readOnly,omitempty"`
+	AdvisoryFlags         []AdvisoryFlag         `json:"advisoryFlags,omitempty"`
+	ActiveAdvisories      []ActiveAdvisory       `json:"activeAdvisories,omitempty"`
 }
 
 // AssetElement is a type representing a link element (parent or child)
@@ -78,19 +82,293 @@ type AssetLinkElement struct {
 // AssetTransferElement is a type representing a link element (parent or child)
 type AssetTransferElement struct {
 	AssetElement
-	TransferDescription string `json:"transferDescription"`
+	TransferDescription string             `json:"transferDescription"`
+	TransferID          string             `json:"transferID,omitempty"`
+	SenderSignature     *TransferSignature `json:"senderSignature,omitempty"`
+}
+
+// TransferSignature is a type representing the signature of a party over a custody transfer
+type TransferSignature struct {
+	Signature   string `json:"signature"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
 }
 
 // CustodyTransferEvent is a type representing a custody transfer event
 type CustodyTransferEvent struct {
-	Timestamp            string `json:"timestamp,omitempty"`
-	TransferDescription  string `json:"transferDescription,omitempty"`
-	SourceRepoID         string `json:"sourceRepoID"`
-	SourceChannelID      string `json:"sourceChannelID"`
-	SourceAssetID        string `json:"sourceAssetID"`
-	DestinationRepoID    string `json:"destinationRepoID"`
-	DestinationChannelID string `json:"destinationChannelID"`
-	DestinationAssetID   string `json:"destinationAssetID"`
+	Timestamp            string             `json:"timestamp,omitempty"`
+	TransferID           string             `json:"transferID,omitempty"`
+	TransferDescription  string             `json:"transferDescription,omitempty"`
+	SourceRepoID         string             `json:"sourceRepoID"`
+	SourceChannelID      string             `json:"sourceChannelID"`
+	SourceAssetID        string             `json:"sourceAssetID"`
+	DestinationRepoID    string             `json:"destinationRepoID"`
+	DestinationChannelID string             `json:"destinationChannelID"`
+	DestinationAssetID   string             `json:"destinationAssetID"`
+	Status               string             `json:"status,omitempty"`
+	OfferID              string             `json:"offerID,omitempty"`
+	ExpiresAt            string             `json:"expiresAt,omitempty"`
+	IncludeChildren      bool               `json:"includeChildren,omitempty"`
+	SenderSignature      *TransferSignature `json:"senderSignature,omitempty"`
+	ReceiverSignature    *TransferSignature `json:"receiverSignature,omitempty"`
+}
+
+// CustodyHop is a type representing a completed custody transfer between two records of an asset
+type CustodyHop struct {
+	Timestamp           string       `json:"timestamp,omitempty"`
+	TransferDescription string       `json:"transferDescription,omitempty"`
+	Source              AssetElement `json:"source"`
+	Destination         AssetElement `json:"destination"`
+}
+
+// ProvenanceIssue is a type representing a custody chain that could not be followed (broken) or that splits (forked)
+type ProvenanceIssue struct {
+	Kind     string       `json:"kind"`
+	Location AssetElement `json:"location"`
+	Detail   string       `json:"detail"`
+}
+
+// Provenance is a type representing the custody chain of an asset across repos, ordered from the first custodian
+type Provenance struct {
+	Custodians []AssetElement    `json:"custodians"`
+	Transfers  []CustodyHop      `json:"transfers"`
+	Broken     bool              `json:"broken"`
+	Forked     bool              `json:"forked"`
+	Issues     []ProvenanceIssue `json:"issues,omitempty"`
+}
+
+// AuditEntry is a type representing a commit in the audit trail of an asset, as adapted from the agent
+type AuditEntry struct {
+	Timestamp     string `json:"timestamp"`
+	CommitType    string `json:"commitType"`
+	TransactionID string `json:"transactionID,omitempty"`
+	Actor         string `json:"actor,omitempty"`
+	Asset         *Asset `json:"asset,omitempty"`
+}
+
+// AuditTrail is a type representing a page of the audit trail of an asset
+type AuditTrail struct {
+	History    []AuditEntry `json:"history"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+// Page is a type representing a page of a listing or a query, with the cursor of and the link to the next page
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	Next       string      `json:"next,omitempty"`
+}
+
+// QueryRecord is a type representing a line of a streamed query, a matching record with its asset ID
+type QueryRecord struct {
+	AssetID string          `json:"assetID"`
+	Record  json.RawMessage `json:"record"`
+}
+
+// ExportRecord is a type representing a line of a streamed export, an asset with its location and its distance from
+// the exported asset, negative for its ancestors
+type ExportRecord struct {
+	RepoID    string `json:"repoID"`
+	ChannelID string `json:"channelID"`
+	AssetID   string `json:"assetID"`
+	Depth     int    `json:"depth"`
+	Asset     Asset  `json:"asset"`
+}
+
+// AssetDiff is a type representing the changes to an asset between two revisions
+type AssetDiff struct {
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Patch   []PatchOperation `json:"patch"`
+	Changes []string         `json:"changes"`
+	Links   LinkChanges      `json:"links"`
+}
+
+// LinkChanges is a type representing the links of an asset that changed between two revisions
+type LinkChanges struct {
+	AttachedChildren ChildLinkChanges  `json:"attachedChildren"`
+	ParentAsset      *ParentLinkChange `json:"parentAsset,omitempty"`
+}
+
+// ChildLinkChanges is a type representing the children attached and detached between two revisions
+type ChildLinkChanges struct {
+	Added   []AssetLinkElement `json:"added"`
+	Removed []AssetLinkElement `json:"removed"`
+}
+
+// ParentLinkChange is a type representing a change of the parent of an asset, nil when there is no parent
+type ParentLinkChange struct {
+	From *AssetLinkElement `json:"from"`
+	To   *AssetLinkElement `json:"to"`
+}
+
+// BomComponent is a type representing a component of an asset tree, at the path of roles leading to it
+type BomComponent struct {
+	AssetElement
+	Path             string `json:"path"`
+	Role             string `json:"role"`
+	SubRole          string `json:"subRole"`
+	AssetType        string `json:"assetType"`
+	AssetModelNumber string `json:"assetModelNumber"`
+}
+
+// BomSubstitution is a type representing a component in the place of a different expected component
+type BomSubstitution struct {
+	Expected BomComponent `json:"expected"`
+	Actual   BomComponent `json:"actual"`
+}
+
+// BomDiff is a type representing the structural differences of an asset tree from a reference tree
+type BomDiff struct {
+	Subject     AssetElement      `json:"subject"`
+	Reference   AssetElement      `json:"reference"`
+	Identical   bool              `json:"identical"`
+	Matched     int               `json:"matched"`
+	Missing     []BomComponent    `json:"missing"`
+	Extra       []BomComponent    `json:"extra"`
+	Substituted []BomSubstitution `json:"substituted"`
+}
+
+// TemplateDeviation is a type representing a difference between the children of an asset and its BOM template
+type TemplateDeviation struct {
+	Kind    string        `json:"kind"`
+	Path    string        `json:"path"`
+	Role    string        `json:"role"`
+	SubRole string        `json:"subRole,omitempty"`
+	Detail  string        `json:"detail"`
+	Asset   *AssetElement `json:"asset,omitempty"`
+}
+
+// Conformance is a type representing the check of an asset tree against the BOM templates of its models
+type Conformance struct {
+	Asset      AssetElement        `json:"asset"`
+	TemplateID string              `json:"templateID"`
+	Conforms   bool                `json:"conforms"`
+	Checked    int                 `json:"checked"`
+	Deviations []TemplateDeviation `json:"deviations"`
+}
+
+// WhereUsedPath is a type representing the chain of assets from a root down to a matching part.
+// A broken path ends at the last parent that could be read
+type WhereUsedPath struct {
+	Part   AssetElement   `json:"part"`
+	Path   []BomComponent `json:"path"`
+	Broken bool           `json:"broken,omitempty"`
+}
+
+// AffectedAssembly is a type representing a root asset with the matching parts it contains
+type AffectedAssembly struct {
+	Root  BomComponent    `json:"root"`
+	Parts []WhereUsedPath `json:"parts"`
+}
+
+// TargetError is a type representing a repo, channel or asset that could not be searched or modified
+type TargetError struct {
+	RepoID    string `json:"repoID"`
+	ChannelID string `json:"channelID,omitempty"`
+	AssetID   string `json:"assetID,omitempty"`
+	Error     string `json:"error"`
+}
+
+// WhereUsed is a type representing the assemblies that contain the parts matching a filter
+type WhereUsed struct {
+	Filter     map[string]string  `json:"filter"`
+	Matches    int                `json:"matches"`
+	Assemblies []AffectedAssembly `json:"assemblies"`
+	Errors     []TargetError      `json:"errors"`
+}
+
+// AdvisoryFlag is a type representing an advisory recorded on an asset. An affected asset matches the advisory itself,
+// an asset that contains the advisory has affected parts below it
+type AdvisoryFlag struct {
+	AdvisoryID string         `json:"advisoryID"`
+	Relation   string         `json:"relation"`
+	Parts      []AssetElement `json:"parts,omitempty"`
+	FlaggedAt  string         `json:"flaggedAt"`
+}
+
+// ActiveAdvisory is a type representing an advisory flag of an asset whose advisory is still active.
+// It is added when an asset is read and never committed
+type ActiveAdvisory struct {
+	AdvisoryID  string         `json:"advisoryID"`
+	Severity    string         `json:"severity"`
+	Description string         `json:"description"`
+	Relation    string         `json:"relation"`
+	Parts       []AssetElement `json:"parts,omitempty"`
+}
+
+// FlaggedAsset is a type representing an asset an advisory was recorded on
+type FlaggedAsset struct {
+	AssetElement
+	Relation string `json:"relation"`
+}
+
+// AdvisoryApplication is a type representing the assets an advisory was applied to
+type AdvisoryApplication struct {
+	AdvisoryID string         `json:"advisoryID"`
+	Flagged    []FlaggedAsset `json:"flagged"`
+	Unchanged  int            `json:"unchanged"`
+	Errors     []TargetError  `json:"errors"`
+}
+
+// DuplicateIdentity is a type representing an identity held by more than one asset
+type DuplicateIdentity struct {
+	Identity map[string]interface{} `json:"identity"`
+	Assets   []AssetElement         `json:"assets"`
+}
+
+// DuplicateReport is a type representing the identities held by more than one asset across every repo
+type DuplicateReport struct {
+	Fields     []string            `json:"fields"`
+	Indexed    int                 `json:"indexed"`
+	Duplicates []DuplicateIdentity `json:"duplicates"`
+	Errors     []TargetError       `json:"errors"`
+}
+
+// QueryTarget is a type representing a repo, or a channel of a repo, that a federated query searches.
+// A target without a channelID searches every channel of the repo
+type QueryTarget struct {
+	RepoID    string `json:"repoID"`
+	ChannelID string `json:"channelID,omitempty"`
+}
+
+// FederatedMatch is a type representing an asset found by a federated query, with the record returned by its agent
+type FederatedMatch struct {
+	RepoID    string      `json:"repoID"`
+	ChannelID string      `json:"channelID"`
+	AssetID   string      `json:"assetID"`
+	URI       string      `json:"uri"`
+	Record    interface{} `json:"record"`
+}
+
+// FederatedQueryResult is a type representing a page of the merged results of a federated query.
+// More is set when results beyond the page were found
+type FederatedQueryResult struct {
+	Skip    int              `json:"skip"`
+	Limit   int              `json:"limit,omitempty"`
+	More    bool             `json:"more"`
+	Results []FederatedMatch `json:"results"`
+	Errors  []TargetError    `json:"errors"`
+}
+
+// Alias is a type representing an external identifier of an asset and the path it is read from
+type Alias struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// AliasMatch is a type representing an asset carrying an alias
+type AliasMatch struct {
+	AssetElement
+	URI  string `json:"uri,omitempty"`
+	Path string `json:"path"`
+}
+
+// AliasRebuild is a type representing the result of rebuilding the alias index from the agents
+type AliasRebuild struct {
+	Assets  int           `json:"assets"`
+	Aliases int           `json:"aliases"`
+	Errors  []TargetError `json:"errors"`
 }
 
 // Fingerprint is a type representing a manufacture fingerprint
@@ -100,9 +378,11 @@ type Fingerprint struct {
 
 // ValidateBody is a type representing the request body expected by the pgp service for validating a signature
 type ValidateBody struct {
-	Fingerprint string             `json:"fingerprint"`
-	Signature   string             `json:"signature"`
-	Input       AssetNoChildParent `json:"input"`
+	Fingerprint      string             `json:"fingerprint"`
+	Signature        string             `json:"signature"`
+	Input            AssetNoChildParent `json:"input"`
+	Canonicalization string             `json:"canonicalization,omitempty"`
+	CanonicalInput   string             `json:"canonicalInput,omitempty"`
 }
 
 // AssetNoChildParent is a type representing an asset recognized by the gateway
@@ -126,4 +406,6 @@ type AssetNoChildParent struct {
 	Children              map[string]*Asset      `json:"-"`
 	Parent                map[string]*Asset      `json:"-"`
 	ReadOnly              bool                   `json:"-"`
+	AdvisoryFlags         []AdvisoryFlag         `json:"-"`
+	ActiveAdvisories      []ActiveAdvisory       `json:"-"`
 }
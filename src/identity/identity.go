@@ -0,0 +1,174 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package identity contains the detection of assets registered more than once under the same identity, such as a
+// serial number that shows up under two assetIDs or in two repos
+package identity
+
+import (
+	"chainsource-gateway/helpers"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var log = helpers.GetLogger("Identity")
+
+const (
+	fieldsConfigKey   = "duplicateIdentity.fields"
+	onCreateConfigKey = "duplicateIdentity.onCreate"
+)
+
+// ModeOff does not check creates for duplicates
+const ModeOff = "off"
+
+// ModeWarn logs creates of an identity that already exists
+const ModeWarn = "warn"
+
+// ModeReject rejects creates of an identity that already exists
+const ModeReject = "reject"
+
+// ErrNoFields is an error when no identity fields are configured
+var ErrNoFields = errors.New("no duplicateIdentity fields are configured")
+
+// GetFields gets the identity fields from duplicateIdentity.fields in agent-config.yaml, as dot paths into the asset
+// such as assetMetadata.serialNumber
+func GetFields() ([]string, error) {
+	var fields []string
+	for _, field := range viper.GetStringSlice(fieldsConfigKey) {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, ErrNoFields
+	}
+	return fields, nil
+}
+
+// GetCreateMode gets how creates are checked for duplicates, from duplicateIdentity.onCreate in agent-config.yaml.
+// Creates are not checked when it is not set, and unrecognized modes fail closed to reject
+func GetCreateMode() string {
+	switch mode := strings.ToLower(strings.TrimSpace(viper.GetString(onCreateConfigKey))); mode {
+	case "", ModeOff, "false":
+		return ModeOff
+	case ModeWarn:
+		return ModeWarn
+	case ModeReject:
+		return ModeReject
+	default:
+		log.Error().Msgf("Invalid %s %q, using %s", onCreateConfigKey, mode, ModeReject)
+		return ModeReject
+	}
+}
+
+// Of returns the values of the identity fields of an asset. An asset that lacks any of the fields, or has an empty or
+// non scalar value in one, has no identity
+func Of(asset helpers.Asset, fields []string) (map[string]interface{}, bool) {
+	document, err := helpers.AssetDocument(asset)
+	if err != nil {
+		return nil, false
+	}
+	values := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, _ := helpers.ValueAtPath(document, field)
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return nil, false
+			}
+		case float64, bool:
+		default:
+			return nil, false
+		}
+		values[field] = value
+	}
+	return values, true
+}
+
+// key returns a comparable key of identity values, in the order of the fields
+func key(values map[string]interface{}, fields []string) string {
+	ordered := make([]interface{}, len(fields))
+	for i, field := range fields {
+		ordered[i] = values[field]
+	}
+	raw, _ := json.Marshal(ordered)
+	return string(raw)
+}
+
+// Index is a type indexing assets by their identity
+type Index struct {
+	fields   []string
+	values   map[string]map[string]interface{}
+	assets   map[string][]helpers.AssetElement
+	keys     []string
+	indexed  int
+	elements map[string]bool
+}
+
+// NewIndex returns an empty index of the identity fields
+func NewIndex(fields []string) *Index {
+	return &Index{
+		fields:   fields,
+		values:   make(map[string]map[string]interface{}),
+		assets:   make(map[string][]helpers.AssetElement),
+		elements: make(map[string]bool),
+	}
+}
+
+// Add indexes an asset, returning false when it has no identity. An asset added twice is indexed once
+func (i *Index) Add(element helpers.AssetElement, asset helpers.Asset) bool {
+	values, ok := Of(asset, i.fields)
+	if !ok {
+		return false
+	}
+	elementKey := fmt.Sprintf("%s/%s/%s", element.RepoID, element.ChannelID, element.AssetID)
+	if i.elements[elementKey] {
+		return true
+	}
+	i.elements[elementKey] = true
+	k := key(values, i.fields)
+	if _, ok := i.values[k]; !ok {
+		i.values[k] = values
+		i.keys = append(i.keys, k)
+	}
+	i.assets[k] = append(i.assets[k], element)
+	i.indexed++
+	return true
+}
+
+// Indexed returns the number of assets with an identity in the index
+func (i *Index) Indexed() int {
+	return i.indexed
+}
+
+// Duplicates returns the identities held by more than one asset, ordered by identity
+func (i *Index) Duplicates() []helpers.DuplicateIdentity {
+	keys := append([]string{}, i.keys...)
+	sort.Strings(keys)
+	duplicates := []helpers.DuplicateIdentity{}
+	for _, k := range keys {
+		if len(i.assets[k]) > 1 {
+			duplicates = append(duplicates, helpers.DuplicateIdentity{Identity: i.values[k], Assets: i.assets[k]})
+		}
+	}
+	return duplicates
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package identity
+
+import (
+	"chainsource-gateway/helpers"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var serialFields = []string{"assetManufacturer", "assetMetadata.serialNumber"}
+
+// serialAsset returns an asset of Acme with a serial number in its metadata
+func serialAsset(serial interface{}) helpers.Asset {
+	return helpers.Asset{AssetManufacturer: "Acme", AssetMetadata: map[string]interface{}{"serialNumber": serial}}
+}
+
+// TestGetFields tests the identity fields configuration
+func TestGetFields(t *testing.T) {
+	defer viper.Set(fieldsConfigKey, nil)
+	_, err := GetFields()
+	assert.Equal(t, ErrNoFields, err, "Fields must be configured")
+	viper.Set(fieldsConfigKey, []string{" assetModelNumber ", ""})
+	fields, err := GetFields()
+	assert.NoError(t, err, "Configured fields are read")
+	assert.Equal(t, []string{"assetModelNumber"}, fields, "Fields are trimmed and blanks dropped")
+}
+
+// TestGetCreateMode tests the create check configuration
+func TestGetCreateMode(t *testing.T) {
+	defer viper.Set(onCreateConfigKey, nil)
+	assert.Equal(t, ModeOff, GetCreateMode(), "Creates are not checked by default")
+	viper.Set(onCreateConfigKey, "Warn")
+	assert.Equal(t, ModeWarn, GetCreateMode(), "Configured mode is used")
+	viper.Set(onCreateConfigKey, "block")
+	assert.Equal(t, ModeReject, GetCreateMode(), "Unknown modes fail closed")
+}
+
+// TestOf tests reading the identity of an asset
+func TestOf(t *testing.T) {
+	values, ok := Of(serialAsset("SN-1"), serialFields)
+	assert.True(t, ok, "Asset has an identity")
+	assert.Equal(t, map[string]interface{}{"assetManufacturer": "Acme", "assetMetadata.serialNumber": "SN-1"}, values,
+		"Values are read by path")
+	values, ok = Of(serialAsset(float64(42)), serialFields)
+	assert.True(t, ok, "Numbers are identities")
+	assert.Equal(t, float64(42), values["assetMetadata.serialNumber"], "Number is kept")
+
+	_, ok = Of(serialAsset(""), serialFields)
+	assert.False(t, ok, "Empty values are no identity")
+	_, ok = Of(serialAsset(map[string]interface{}{"value": "SN-1"}), serialFields)
+	assert.False(t, ok, "Objects are no identity")
+	_, ok = Of(helpers.Asset{AssetManufacturer: "Acme"}, serialFields)
+	assert.False(t, ok, "Missing values are no identity")
+	_, ok = Of(helpers.Asset{AssetManufacturer: "Acme", AssetMetadata: "SN-1"}, serialFields)
+	assert.False(t, ok, "Paths through scalars are no identity")
+}
+
+// TestIndex tests finding the identities held by more than one asset
+func TestIndex(t *testing.T) {
+	index := NewIndex(serialFields)
+	first := helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"}
+	copied := helpers.AssetElement{RepoID: "T2", ChannelID: "C1", AssetID: "B7"}
+	assert.True(t, index.Add(first, serialAsset("SN-2")), "Asset is indexed")
+	assert.True(t, index.Add(first, serialAsset("SN-2")), "Asset is indexed once")
+	assert.True(t, index.Add(helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A2"}, serialAsset("SN-3")),
+		"Other asset is indexed")
+	assert.False(t, index.Add(helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A3"}, serialAsset(nil)),
+		"Asset without identity is not indexed")
+	assert.True(t, index.Add(copied, serialAsset("SN-2")), "Duplicate is indexed")
+	assert.True(t, index.Add(helpers.AssetElement{RepoID: "T2", ChannelID: "C1", AssetID: "B1"}, serialAsset("SN-1")),
+		"Other asset is indexed")
+	assert.True(t, index.Add(helpers.AssetElement{RepoID: "T2", ChannelID: "C2", AssetID: "B2"}, serialAsset("SN-1")),
+		"Duplicate is indexed")
+
+	assert.Equal(t, 5, index.Indexed(), "Every asset with an identity is counted once")
+	duplicates := index.Duplicates()
+	assert.Len(t, duplicates, 2, "Identities of more than one asset are reported")
+	assert.Equal(t, "SN-1", duplicates[0].Identity["assetMetadata.serialNumber"], "Duplicates are ordered by identity")
+	assert.Equal(t, []helpers.AssetElement{first, copied}, duplicates[1].Assets, "Assets are listed in the order found")
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package keyregistry contains the registry of manufacturer keys trusted to sign assets
+package keyregistry
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/spf13/viper"
+)
+
+var log = helpers.GetLogger("KeyRegistry")
+
+const collectionName = "manufacturer-keys"
+const enforceConfigKey = "enforceKeyRegistry"
+
+// ErrKeyExists is an error when a key is registered twice
+var ErrKeyExists = errors.New("key is already registered")
+
+// ErrKeyNotRegistered is an error when an asset is signed by a key that is not in the registry
+var ErrKeyNotRegistered = errors.New("signing key is not registered")
+
+// ErrKeyRevoked is an error when an asset is signed by a revoked key
+var ErrKeyRevoked = errors.New("signing key is revoked")
+
+// ErrKeyExpired is an error when an asset is validated after the validity window of its key
+var ErrKeyExpired = errors.New("signing key is expired")
+
+// ErrKeyNotYetValid is an error when an asset is validated before the validity window of its key
+var ErrKeyNotYetValid = errors.New("signing key is not yet valid")
+
+// ErrManufacturerNotAuthorized is an error when a key is not trusted for the assetManufacturer of an asset
+var ErrManufacturerNotAuthorized = errors.New("signing key is not authorized for the asset manufacturer")
+
+// ErrPartyNotAuthorized is an error when a key is not trusted to sign transfers for a repo and channel
+var ErrPartyNotAuthorized = errors.New("signing key is not authorized for the transfer party")
+
+// KeyOwner is a type representing the identity a key is registered to
+type KeyOwner struct {
+	Name         string `json:"name"`
+	Organization string `json:"organization,omitempty"`
+	Email        string `json:"email,omitempty"`
+}
+
+// ManufacturerKey is a type representing a registered manufacturer key
+type ManufacturerKey struct {
+	Fingerprint      string     `json:"fingerprint"`
+	Scheme           string     `json:"scheme,omitempty"`
+	PublicKey        string     `json:"publicKey,omitempty"`
+	Owner            KeyOwner   `json:"owner"`
+	Manufacturers    []string   `json:"manufacturers"`
+	Parties          []string   `json:"parties,omitempty"`
+	ValidFrom        *time.Time `json:"validFrom,omitempty"`
+	ValidUntil       *time.Time `json:"validUntil,omitempty"`
+	Revoked          bool       `json:"revoked"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	RevocationReason string     `json:"revocationReason,omitempty"`
+	RegisteredAt     time.Time  `json:"registeredAt"`
+}
+
+// KeyRegistry is an interface for the registry of trusted manufacturer keys
+type KeyRegistry interface {
+	Register(ctx context.Context, key ManufacturerKey) (ManufacturerKey, error)
+	Get(ctx context.Context, fingerprint string) (ManufacturerKey, error)
+	List(ctx context.Context) ([]ManufacturerKey, error)
+	Revoke(ctx context.Context, fingerprint string, reason string) (ManufacturerKey, error)
+	Authorize(ctx context.Context, fingerprint string, manufacturer string, at time.Time) (ManufacturerKey, error)
+	AuthorizeParty(ctx context.Context, fingerprint string, repoID string, channelID string, at time.Time) (ManufacturerKey, error)
+}
+
+// StoreRegistry is an implementation of KeyRegistry persisted in a store collection
+type StoreRegistry struct {
+	collection *store.Collection
+}
+
+// IsEnforced reports if signers are checked against the registry, from enforceKeyRegistry in agent-config.yaml.
+// Signatures are only checked by the signature validator when it is not set
+func IsEnforced() bool {
+	return viper.GetBool(enforceConfigKey)
+}
+
+// NewStoreRegistry returns a key registry kept in the gateway data directory
+func NewStoreRegistry() *StoreRegistry {
+	return NewStoreRegistryWith(store.NewCollection(collectionName))
+}
+
+// NewStoreRegistryWith returns a key registry kept in a store collection
+func NewStoreRegistryWith(collection *store.Collection) *StoreRegistry {
+	return &StoreRegistry{collection: collection}
+}
+
+// NormalizeFingerprint returns the form keys are registered and looked up by.
+// PGP fingerprints are often written in groups or with colons, so separators and case are ignored
+func NormalizeFingerprint(fingerprint string) string {
+	replacer := strings.NewReplacer(" ", "", ":", "", "\t", "")
+	return strings.ToUpper(replacer.Replace(strings.TrimSpace(fingerprint)))
+}
+
+// Register adds a key to the registry
+func (s *StoreRegistry) Register(ctx context.Context, key ManufacturerKey) (ManufacturerKey, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Register manufacturer key")
+	defer span.Finish()
+
+	key.Fingerprint = NormalizeFingerprint(key.Fingerprint)
+	key.Revoked = false
+	key.RevokedAt = nil
+	key.RevocationReason = ""
+	key.RegisteredAt = time.Now().UTC()
+	err := s.collection.Create(key.Fingerprint, key)
+	if err == store.ErrAlreadyExists {
+		return ManufacturerKey{}, ErrKeyExists
+	}
+	if err != nil {
+		return ManufacturerKey{}, err
+	}
+	log.Info().Msgf("Registered key %s of %s", key.Fingerprint, key.Owner.Name)
+	return key, nil
+}
+
+// Get returns a registered key, returns helpers.ErrNotFound when the key is not registered
+func (s *StoreRegistry) Get(ctx context.Context, fingerprint string) (key ManufacturerKey, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Get manufacturer key")
+	defer span.Finish()
+
+	err = s.collection.Get(NormalizeFingerprint(fingerprint), &key)
+	return
+}
+
+// List returns all registered keys ordered by fingerprint
+func (s *StoreRegistry) List(ctx context.Context) ([]ManufacturerKey, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "List manufacturer keys")
+	defer span.Finish()
+
+	ids, err := s.collection.IDs()
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.collection.All()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]ManufacturerKey, 0, len(ids))
+	for _, id := range ids {
+		var key ManufacturerKey
+		if raw, exists := all[id]; exists && json.Unmarshal(raw, &key) == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Revoke marks a key as revoked. Revoking a revoked key keeps the original revocation
+func (s *StoreRegistry) Revoke(ctx context.Context, fingerprint string, reason string) (key ManufacturerKey, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Revoke manufacturer key")
+	defer span.Finish()
+
+	id := NormalizeFingerprint(fingerprint)
+	err = s.collection.Modify(func(records map[string]json.RawMessage) error {
+		raw, exists := records[id]
+		if !exists {
+			return helpers.ErrNotFound
+		}
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return err
+		}
+		if key.Revoked {
+			return nil
+		}
+		now := time.Now().UTC()
+		key.Revoked = true
+		key.RevokedAt = &now
+		key.RevocationReason = reason
+		updated, err := json.Marshal(key)
+		records[id] = updated
+		return err
+	})
+	if err == nil {
+		log.Info().Msgf("Revoked key %s", id)
+	}
+	return
+}
+
+// Authorize checks that a key may sign assets of a manufacturer at a point in time
+func (s *StoreRegistry) Authorize(ctx context.Context, fingerprint string, manufacturer string, at time.Time) (key ManufacturerKey, err error) {
+	key, err = s.Get(ctx, fingerprint)
+	if err == helpers.ErrNotFound {
+		return key, ErrKeyNotRegistered
+	}
+	if err != nil {
+		return
+	}
+	err = CheckKey(key, manufacturer, at)
+	return
+}
+
+// AuthorizeParty checks that a key may sign transfers for a repo and channel at a point in time
+func (s *StoreRegistry) AuthorizeParty(ctx context.Context, fingerprint string, repoID string, channelID string,
+	at time.Time) (key ManufacturerKey, err error) {
+	key, err = s.Get(ctx, fingerprint)
+	if err == helpers.ErrNotFound {
+		return key, ErrKeyNotRegistered
+	}
+	if err != nil {
+		return
+	}
+	err = CheckParty(key, repoID, channelID, at)
+	return
+}
+
+// PartyName returns the form a repo and channel is listed by in the parties of a key
+func PartyName(repoID string, channelID string) string {
+	return repoID + "/" + channelID
+}
+
+// checkValidity checks the revocation and validity window of a key
+func checkValidity(key ManufacturerKey, at time.Time) error {
+	if key.Revoked {
+		return ErrKeyRevoked
+	}
+	if key.ValidFrom != nil && at.Before(*key.ValidFrom) {
+		return ErrKeyNotYetValid
+	}
+	if key.ValidUntil != nil && at.After(*key.ValidUntil) {
+		return ErrKeyExpired
+	}
+	return nil
+}
+
+// CheckParty checks the revocation, validity window and transfer parties of a key
+func CheckParty(key ManufacturerKey, repoID string, channelID string, at time.Time) error {
+	if err := checkValidity(key, at); err != nil {
+		return err
+	}
+	party := PartyName(repoID, channelID)
+	for _, trusted := range key.Parties {
+		if strings.TrimSpace(trusted) == party {
+			return nil
+		}
+	}
+	return ErrPartyNotAuthorized
+}
+
+// CheckKey checks the revocation, validity window and manufacturers of a key
+func CheckKey(key ManufacturerKey, manufacturer string, at time.Time) error {
+	if err := checkValidity(key, at); err != nil {
+		return err
+	}
+	for _, trusted := range key.Manufacturers {
+		if strings.EqualFold(strings.TrimSpace(trusted), strings.TrimSpace(manufacturer)) {
+			return nil
+		}
+	}
+	return ErrManufacturerNotAuthorized
+}
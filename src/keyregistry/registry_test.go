@@ -0,0 +1,100 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package keyregistry
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeFingerprint tests that separators and case are ignored
+func TestNormalizeFingerprint(t *testing.T) {
+	assert.Equal(t, "A1B2C3D4", NormalizeFingerprint(" a1b2 c3:d4 "), "Fingerprint is normalized")
+}
+
+// TestStoreRegistry tests registering, revoking and authorizing keys
+func TestStoreRegistry(t *testing.T) {
+	directory, err := ioutil.TempDir("", "keyregistry")
+	assert.NoError(t, err, "Data directory is created")
+	defer os.RemoveAll(directory)
+	registry := NewStoreRegistryWith(store.NewCollectionAt(directory, collectionName))
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	from, until := now.Add(-time.Hour), now.Add(time.Hour)
+	key, err := registry.Register(ctx, ManufacturerKey{
+		Fingerprint:   "ab cd",
+		Owner:         KeyOwner{Name: "Supplier"},
+		Manufacturers: []string{"A Valid Manufacturer"},
+		Parties:       []string{"T1/C2"},
+		ValidFrom:     &from,
+		ValidUntil:    &until,
+		Revoked:       true,
+	})
+	assert.NoError(t, err, "Key is registered")
+	assert.Equal(t, "ABCD", key.Fingerprint, "Fingerprint is normalized")
+	assert.False(t, key.Revoked, "Keys cannot be registered revoked")
+
+	_, err = registry.Register(ctx, ManufacturerKey{Fingerprint: "abcd"})
+	assert.Equal(t, ErrKeyExists, err, "Keys are registered once")
+
+	keys, err := registry.List(ctx)
+	assert.NoError(t, err, "Keys are listed")
+	assert.Len(t, keys, 1, "Registered key is listed")
+
+	t.Run("Authorize", func(t *testing.T) {
+		_, err := registry.Authorize(ctx, "ABCD", "a valid manufacturer", now)
+		assert.NoError(t, err, "Key is authorized for its manufacturer")
+		_, err = registry.Authorize(ctx, "ABCD", "Another Manufacturer", now)
+		assert.Equal(t, ErrManufacturerNotAuthorized, err, "Key is not authorized for other manufacturers")
+		_, err = registry.Authorize(ctx, "ABCD", "A Valid Manufacturer", now.Add(2*time.Hour))
+		assert.Equal(t, ErrKeyExpired, err, "Key is expired after its window")
+		_, err = registry.Authorize(ctx, "ABCD", "A Valid Manufacturer", now.Add(-2*time.Hour))
+		assert.Equal(t, ErrKeyNotYetValid, err, "Key is not valid before its window")
+		_, err = registry.Authorize(ctx, "EF01", "A Valid Manufacturer", now)
+		assert.Equal(t, ErrKeyNotRegistered, err, "Unknown keys are not authorized")
+	})
+	t.Run("Authorize_Party", func(t *testing.T) {
+		_, err := registry.AuthorizeParty(ctx, "ABCD", "T1", "C2", now)
+		assert.NoError(t, err, "Key is authorized for its party")
+		_, err = registry.AuthorizeParty(ctx, "ABCD", "T1", "C1", now)
+		assert.Equal(t, ErrPartyNotAuthorized, err, "Key is not authorized for other channels")
+		_, err = registry.AuthorizeParty(ctx, "ABCD", "T1", "C2", now.Add(2*time.Hour))
+		assert.Equal(t, ErrKeyExpired, err, "Key is expired after its window")
+		_, err = registry.AuthorizeParty(ctx, "EF01", "T1", "C2", now)
+		assert.Equal(t, ErrKeyNotRegistered, err, "Unknown keys are not authorized")
+	})
+	t.Run("Revoke", func(t *testing.T) {
+		revoked, err := registry.Revoke(ctx, "abcd", "compromised")
+		assert.NoError(t, err, "Key is revoked")
+		assert.True(t, revoked.Revoked, "Key is marked revoked")
+		again, _ := registry.Revoke(ctx, "abcd", "other")
+		assert.Equal(t, "compromised", again.RevocationReason, "First revocation is kept")
+		_, err = registry.Authorize(ctx, "ABCD", "A Valid Manufacturer", now)
+		assert.Equal(t, ErrKeyRevoked, err, "Revoked key is not authorized")
+		_, err = registry.Revoke(ctx, "EF01", "")
+		assert.Equal(t, helpers.ErrNotFound, err, "Unknown keys cannot be revoked")
+	})
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package keystore contains the encrypted keystore the gateway signs assets with on behalf of manufacturers
+package keystore
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/pgp"
+	"chainsource-gateway/store"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var log = helpers.GetLogger("Keystore")
+
+const collectionName = "signing-keys"
+const passphraseVar = "GATEWAY_KEYSTORE_PASSPHRASE"
+const defaultIterations = 100000
+
+// ErrKeystoreDisabled is an error when the gateway is asked to sign, but no keystore passphrase is configured
+var ErrKeystoreDisabled = errors.New("gateway signing is not enabled")
+
+// ErrNoSigningKey is an error when no key of the keystore is bound to the repo or manufacturer of an asset
+var ErrNoSigningKey = errors.New("no signing key is bound to the repo or manufacturer of the asset")
+
+// ErrKeyNotBound is an error when a key is requested for an asset outside of its repo or manufacturer
+var ErrKeyNotBound = errors.New("signing key is not bound to the repo or manufacturer of the asset")
+
+// ErrMissingBinding is an error when a key is generated without a repo or manufacturer
+var ErrMissingBinding = errors.New("a signing key must be bound to a repo or a manufacturer")
+
+// ErrDecryptionFailed is an error when a private key can not be decrypted, usually because the passphrase changed
+var ErrDecryptionFailed = errors.New("signing key could not be decrypted")
+
+// SigningKey is a type representing the public part of a key in the keystore.
+// The key is used for assets of its repo, its manufacturer, or both when both are set
+type SigningKey struct {
+	KeyID        string    `json:"keyID"`
+	Algorithm    string    `json:"algorithm"`
+	Scheme       string    `json:"scheme"`
+	PublicKey    string    `json:"publicKey"`
+	RepoID       string    `json:"repoID,omitempty"`
+	Manufacturer string    `json:"manufacturer,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// storedKey is a type representing a key as it is persisted, with the private key encrypted
+type storedKey struct {
+	SigningKey
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Keystore is an interface for the keys the gateway signs with. Implementations never expose private keys
+type Keystore interface {
+	Enabled() bool
+	Generate(ctx context.Context, repoID string, manufacturer string) (SigningKey, error)
+	Get(ctx context.Context, keyID string) (SigningKey, error)
+	List(ctx context.Context) ([]SigningKey, error)
+	Select(ctx context.Context, repoID string, manufacturer string, keyID string) (SigningKey, error)
+	Sign(ctx context.Context, keyID string, message []byte) ([]byte, error)
+	ResolvePublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error)
+}
+
+// StoreKeystore is an implementation of Keystore persisted in a store collection.
+// Private keys are encrypted with AES-256-GCM under a key derived from the passphrase with PBKDF2
+type StoreKeystore struct {
+	collection *store.Collection
+	passphrase string
+}
+
+// GetKeystorePassphrase gets the passphrase of the keystore, gateway signing is disabled without one
+func GetKeystorePassphrase() string {
+	return os.Getenv(passphraseVar)
+}
+
+// NewStoreKeystore returns the keystore kept in the gateway data directory
+func NewStoreKeystore() *StoreKeystore {
+	return NewStoreKeystoreWith(store.NewCollection(collectionName), GetKeystorePassphrase())
+}
+
+// NewStoreKeystoreWith returns a keystore kept in a store collection, encrypted with a passphrase
+func NewStoreKeystoreWith(collection *store.Collection, passphrase string) *StoreKeystore {
+	return &StoreKeystore{collection: collection, passphrase: passphrase}
+}
+
+// Enabled reports whether the keystore can generate keys and sign
+func (k *StoreKeystore) Enabled() bool {
+	return k.passphrase != ""
+}
+
+// Generate creates an Ed25519 key bound to a repo, a manufacturer or both
+func (k *StoreKeystore) Generate(ctx context.Context, repoID string, manufacturer string) (SigningKey, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Generate signing key")
+	defer span.Finish()
+
+	if !k.Enabled() {
+		return SigningKey{}, ErrKeystoreDisabled
+	}
+	repoID, manufacturer = strings.TrimSpace(repoID), strings.TrimSpace(manufacturer)
+	if repoID == "" && manufacturer == "" {
+		return SigningKey{}, ErrMissingBinding
+	}
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	digest := sha256.Sum256(der)
+
+	key := storedKey{
+		SigningKey: SigningKey{
+			KeyID:        strings.ToUpper(hex.EncodeToString(digest[:20])),
+			Algorithm:    "EdDSA",
+			Scheme:       pgp.SchemeJWS,
+			PublicKey:    string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			RepoID:       repoID,
+			Manufacturer: manufacturer,
+			CreatedAt:    time.Now().UTC(),
+		},
+		Iterations: defaultIterations,
+	}
+	if err = k.seal(&key, privateKey.Seed()); err != nil {
+		return SigningKey{}, err
+	}
+	if err = k.collection.Create(key.KeyID, key); err != nil {
+		return SigningKey{}, err
+	}
+	log.Info().Msgf("Generated signing key %s for repo %q manufacturer %q", key.KeyID, repoID, manufacturer)
+	return key.SigningKey, nil
+}
+
+// Get returns the public part of a key, returns helpers.ErrNotFound when the key does not exist
+func (k *StoreKeystore) Get(ctx context.Context, keyID string) (SigningKey, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Get signing key")
+	defer span.Finish()
+
+	var key storedKey
+	err := k.collection.Get(strings.ToUpper(strings.TrimSpace(keyID)), &key)
+	return key.SigningKey, err
+}
+
+// List returns the public part of all keys ordered by key ID
+func (k *StoreKeystore) List(ctx context.Context) ([]SigningKey, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "List signing keys")
+	defer span.Finish()
+
+	all, err := k.collection.All()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]SigningKey, 0, len(all))
+	for _, raw := range all {
+		var key storedKey
+		if json.Unmarshal(raw, &key) == nil {
+			keys = append(keys, key.SigningKey)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KeyID < keys[j].KeyID })
+	return keys, nil
+}
+
+// bindingScore ranks how closely a key is bound to an asset. A key bound to both the repo and the manufacturer
+// beats a key bound to the manufacturer, which beats a key bound to the repo. Zero means the key may not be used
+func bindingScore(key SigningKey, repoID string, manufacturer string) int {
+	score := 0
+	if key.RepoID != "" {
+		if key.RepoID != repoID {
+			return 0
+		}
+		score++
+	}
+	if key.Manufacturer != "" {
+		if !strings.EqualFold(key.Manufacturer, strings.TrimSpace(manufacturer)) {
+			return 0
+		}
+		score += 2
+	}
+	return score
+}
+
+// Select returns the key to sign an asset of a repo and manufacturer with. When keyID is set that key is
+// returned if it is bound to the asset, otherwise the closest bound key is chosen, the newest on a tie
+func (k *StoreKeystore) Select(ctx context.Context, repoID string, manufacturer string, keyID string) (SigningKey, error) {
+	if keyID != "" {
+		key, err := k.Get(ctx, keyID)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		if bindingScore(key, repoID, manufacturer) == 0 {
+			return SigningKey{}, ErrKeyNotBound
+		}
+		return key, nil
+	}
+
+	keys, err := k.List(ctx)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	var selected SigningKey
+	best := 0
+	for _, key := range keys {
+		score := bindingScore(key, repoID, manufacturer)
+		if score > best || (score == best && score > 0 && key.CreatedAt.After(selected.CreatedAt)) {
+			selected, best = key, score
+		}
+	}
+	if best == 0 {
+		return SigningKey{}, ErrNoSigningKey
+	}
+	return selected, nil
+}
+
+// Sign signs a message with a key. The private key is only decrypted for the duration of the call
+func (k *StoreKeystore) Sign(ctx context.Context, keyID string, message []byte) ([]byte, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Sign with signing key")
+	defer span.Finish()
+
+	if !k.Enabled() {
+		return nil, ErrKeystoreDisabled
+	}
+	var key storedKey
+	if err := k.collection.Get(strings.ToUpper(strings.TrimSpace(keyID)), &key); err != nil {
+		return nil, err
+	}
+	seed, err := k.open(key)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	signature := ed25519.Sign(privateKey, message)
+	wipe(seed)
+	wipe(privateKey)
+	return signature, nil
+}
+
+// ResolvePublicKey returns the public key of a key in the keystore, so gateway signatures verify as JWS
+func (k *StoreKeystore) ResolvePublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	key, err := k.Get(ctx, keyID)
+	if err != nil {
+		return nil, pgp.ErrKeyNotFound
+	}
+	return pgp.ParsePublicKeyPEM([]byte(key.PublicKey))
+}
+
+// deriveKey derives the AES-256 key of a stored key from a passphrase with PBKDF2-HMAC-SHA256 (RFC 8018)
+func deriveKey(passphrase []byte, salt []byte, iterations int) []byte {
+	return pbkdf2.Key(passphrase, salt, iterations, 32, sha256.New)
+}
+
+// aead returns the cipher for a key, derived from the passphrase and the salt of the key
+func (k *StoreKeystore) aead(key storedKey) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey([]byte(k.passphrase), key.Salt, key.Iterations))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts a private key seed into a stored key. The key ID is authenticated, so records can not be swapped
+func (k *StoreKeystore) seal(key *storedKey, seed []byte) error {
+	key.Salt = make([]byte, 16)
+	if _, err := rand.Read(key.Salt); err != nil {
+		return err
+	}
+	gcm, err := k.aead(*key)
+	if err != nil {
+		return err
+	}
+	key.Nonce = make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(key.Nonce); err != nil {
+		return err
+	}
+	key.Ciphertext = gcm.Seal(nil, key.Nonce, seed, []byte(key.KeyID))
+	return nil
+}
+
+// open decrypts the private key seed of a stored key
+func (k *StoreKeystore) open(key storedKey) ([]byte, error) {
+	gcm, err := k.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := gcm.Open(nil, key.Nonce, key.Ciphertext, []byte(key.KeyID))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrDecryptionFailed
+	}
+	return seed, nil
+}
+
+// wipe overwrites key material that is no longer needed
+func wipe(secret []byte) {
+	for i := range secret {
+		secret[i] = 0
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package keystore
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/pgp"
+	"chainsource-gateway/store"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_deriveKey tests the key derivation against the PBKDF2-HMAC-SHA256 vector of RFC 7914, so stored keys still open
+func Test_deriveKey(t *testing.T) {
+	derived := deriveKey([]byte("passwd"), []byte("salt"), 1)
+	assert.Equal(t, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc", hex.EncodeToString(derived),
+		"Key is derived")
+}
+
+// TestStoreKeystore tests generating, selecting and signing with gateway keys
+func TestStoreKeystore(t *testing.T) {
+	directory, err := ioutil.TempDir("", "keystore")
+	assert.NoError(t, err, "Data directory is created")
+	defer os.RemoveAll(directory)
+	collection := store.NewCollectionAt(directory, collectionName)
+	keystore := NewStoreKeystoreWith(collection, "correct horse")
+	ctx := context.Background()
+
+	_, err = keystore.Generate(ctx, "", " ")
+	assert.Equal(t, ErrMissingBinding, err, "Keys must be bound")
+
+	repoKey, err := keystore.Generate(ctx, "R1", "")
+	assert.NoError(t, err, "Repo key is generated")
+	manufacturerKey, err := keystore.Generate(ctx, "", "A Valid Manufacturer")
+	assert.NoError(t, err, "Manufacturer key is generated")
+	assert.Len(t, manufacturerKey.KeyID, 40, "Key ID is a hex fingerprint")
+
+	raw, _ := ioutil.ReadFile(filepath.Join(directory, collectionName+".json"))
+	assert.False(t, strings.Contains(string(raw), "PRIVATE"), "Private keys are not stored in clear")
+
+	selected, err := keystore.Select(ctx, "R1", "a valid manufacturer", "")
+	assert.NoError(t, err, "A key is selected")
+	assert.Equal(t, manufacturerKey.KeyID, selected.KeyID, "Manufacturer keys beat repo keys")
+	selected, err = keystore.Select(ctx, "R1", "Other", "")
+	assert.NoError(t, err, "A key is selected")
+	assert.Equal(t, repoKey.KeyID, selected.KeyID, "Repo key is used for other manufacturers")
+	_, err = keystore.Select(ctx, "R2", "Other", "")
+	assert.Equal(t, ErrNoSigningKey, err, "No key is bound")
+	_, err = keystore.Select(ctx, "R2", "Other", repoKey.KeyID)
+	assert.Equal(t, ErrKeyNotBound, err, "Requested keys must be bound to the asset")
+	_, err = keystore.Select(ctx, "R1", "", "unknown")
+	assert.Equal(t, helpers.ErrNotFound, err, "Requested keys must exist")
+
+	signature, err := keystore.Sign(ctx, repoKey.KeyID, []byte("message"))
+	assert.NoError(t, err, "Message is signed")
+	publicKey, err := keystore.ResolvePublicKey(ctx, strings.ToLower(repoKey.KeyID))
+	assert.NoError(t, err, "Public key is resolved")
+	assert.True(t, ed25519.Verify(publicKey.(ed25519.PublicKey), []byte("message"), signature), "Signature verifies")
+
+	_, err = NewStoreKeystoreWith(collection, "wrong").Sign(ctx, repoKey.KeyID, []byte("message"))
+	assert.Equal(t, ErrDecryptionFailed, err, "Wrong passphrase fails to decrypt")
+
+	disabled := NewStoreKeystoreWith(collection, "")
+	_, err = disabled.Sign(ctx, repoKey.KeyID, []byte("message"))
+	assert.Equal(t, ErrKeystoreDisabled, err, "Signing requires a passphrase")
+	_, err = disabled.ResolvePublicKey(ctx, repoKey.KeyID)
+	assert.NoError(t, err, "Public keys resolve without a passphrase")
+	_, err = disabled.ResolvePublicKey(ctx, "unknown")
+	assert.Equal(t, pgp.ErrKeyNotFound, err, "Unknown keys are not found")
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package label contains the rendering of physical asset labels, a QR code of the asset URI or a deep link with the
+// assetType, model and ID of the asset, as PNG or SVG and as printable SVG or PDF sheets
+package label
+
+import (
+	"bytes"
+	"chainsource-gateway/helpers"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const linkConfigKey = "labelLink"
+
+// maxTextLength is the number of characters of a text line of a label, longer values are cut short
+const maxTextLength = 32
+
+// quietZone is the light border around a QR code, in modules
+const quietZone = 4
+
+// Formats of labels and label sheets
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+	FormatPDF = "pdf"
+)
+
+// ErrUnsupportedFormat is an error when a label or label sheet is requested in a format it is not rendered in
+var ErrUnsupportedFormat = errors.New("unsupported label format")
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	switch format {
+	case FormatPNG:
+		return "image/png"
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "application/octet-stream"
+}
+
+// Write writes a rendered label or label sheet with the media type of its format. Sheets are sent as an attachment
+// named fileName, single labels have no fileName and are sent inline
+func Write(w http.ResponseWriter, rendered []byte, format string, fileName string) {
+	w.Header().Set("Content-Type", ContentType(format))
+	if fileName != "" {
+		w.Header().Set("Content-Disposition", "attachment; filename="+fileName+"."+format)
+	}
+	w.Write(rendered)
+}
+
+// Label is a type representing what is printed on the label of an asset
+type Label struct {
+	Content   string
+	AssetType string
+	Model     string
+	AssetID   string
+}
+
+// ContentFor returns what the QR code of an asset encodes, the deep link of labelLink in agent-config.yaml or the asset
+// URI when it is not set. The link is a template where {repoID}, {channelID} and {assetID} are replaced with the path
+// escaped IDs and {uri} with the query escaped asset URI
+func ContentFor(element helpers.AssetElement) string {
+	link := strings.TrimSpace(viper.GetString(linkConfigKey))
+	if link == "" {
+		return element.URI()
+	}
+	return strings.NewReplacer(
+		"{repoID}", url.PathEscape(element.RepoID),
+		"{channelID}", url.PathEscape(element.ChannelID),
+		"{assetID}", url.PathEscape(element.AssetID),
+		"{uri}", url.QueryEscape(element.URI()),
+	).Replace(link)
+}
+
+// For returns the label of an asset
+func For(element helpers.AssetElement, asset helpers.Asset) Label {
+	return Label{
+		Content:   ContentFor(element),
+		AssetType: asset.AssetType,
+		Model:     asset.AssetModelNumber,
+		AssetID:   element.AssetID,
+	}
+}
+
+// lines returns the human readable text of a label, one line per field
+func (l Label) lines() []string {
+	lines := []string{l.AssetType, l.Model, l.AssetID}
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > maxTextLength {
+			lines[i] = string(runes[:maxTextLength-3]) + "..."
+		}
+	}
+	return lines
+}
+
+// PNG renders a label as a PNG image with moduleSize pixels per module of the QR code
+func PNG(l Label, moduleSize int) ([]byte, error) {
+	code, err := EncodeQR([]byte(l.Content))
+	if err != nil {
+		return nil, err
+	}
+	face := basicfont.Face7x13
+	lineHeight := face.Metrics().Height.Ceil()
+	codeWidth := (code.Size + 2*quietZone) * moduleSize
+	width := codeWidth
+	for _, line := range l.lines() {
+		if textWidth := font.MeasureString(face, line).Ceil() + 2*quietZone*moduleSize; textWidth > width {
+			width = textWidth
+		}
+	}
+	height := codeWidth + len(l.lines())*lineHeight + quietZone*moduleSize
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	left := (width - codeWidth) / 2
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Dark(x, y) {
+				module := image.Rect(0, 0, moduleSize, moduleSize).Add(
+					image.Pt(left+(x+quietZone)*moduleSize, (y+quietZone)*moduleSize))
+				draw.Draw(img, module, image.Black, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(color.Black), Face: face}
+	for i, line := range l.lines() {
+		drawer.Dot = fixed.P((width-font.MeasureString(face, line).Ceil())/2,
+			codeWidth+i*lineHeight+face.Metrics().Ascent.Ceil())
+		drawer.DrawString(line)
+	}
+
+	var buffer bytes.Buffer
+	if err = png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Render renders a single label as PNG with moduleSize pixels per module, or as SVG
+func Render(l Label, format string, moduleSize int) ([]byte, error) {
+	switch format {
+	case FormatPNG:
+		return PNG(l, moduleSize)
+	case FormatSVG:
+		return SVG(l)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// svgLabelWidth and svgLabelHeight are the size of a label in an SVG, in user units
+const (
+	svgLabelWidth  = 200
+	svgLabelHeight = 250
+	svgFontSize    = 12
+)
+
+// writeSVGLabel writes a label as an SVG group at x, y. The QR code fills the width of the label and the text is
+// centered under it
+func writeSVGLabel(buffer *bytes.Buffer, l Label, x, y int) error {
+	code, err := EncodeQR([]byte(l.Content))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(buffer, `<g transform="translate(%d %d)">`, x, y)
+	moduleSize := float64(svgLabelWidth) / float64(code.Size+2*quietZone)
+	fmt.Fprintf(buffer, `<g transform="scale(%.4f)"><path fill="#000" d="`, moduleSize)
+	for row := 0; row < code.Size; row++ {
+		for column := 0; column < code.Size; column++ {
+			if code.Dark(column, row) {
+				fmt.Fprintf(buffer, "M%d %dh1v1h-1z", column+quietZone, row+quietZone)
+			}
+		}
+	}
+	buffer.WriteString(`"/></g>`)
+	for i, line := range l.lines() {
+		fmt.Fprintf(buffer, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="middle">`,
+			svgLabelWidth/2, svgLabelWidth+(i+1)*(svgFontSize+2)-4, svgFontSize)
+		xmlEscape(buffer, line)
+		buffer.WriteString("</text>")
+	}
+	buffer.WriteString("</g>")
+	return nil
+}
+
+// xmlEscape writes text escaped for XML character data
+func xmlEscape(buffer *bytes.Buffer, text string) {
+	buffer.WriteString(strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;").
+		Replace(text))
+}
+
+// SVG renders a label as an SVG document
+func SVG(l Label) ([]byte, error) {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		svgLabelWidth, svgLabelHeight, svgLabelWidth, svgLabelHeight)
+	buffer.WriteString(`<rect width="100%" height="100%" fill="#fff"/>`)
+	if err := writeSVGLabel(&buffer, l, 0, 0); err != nil {
+		return nil, err
+	}
+	buffer.WriteString("</svg>\n")
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package label
+
+import (
+	"bytes"
+	"chainsource-gateway/helpers"
+	"fmt"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLabel is a label with text that needs escaping
+var testLabel = Label{Content: "dbom://DB1/C1/A1", AssetType: "Server <rack>", Model: "X100 (rev B)", AssetID: "A1"}
+
+// TestContentFor tests the asset URI and the configured deep link as the content of labels
+func TestContentFor(t *testing.T) {
+	element := helpers.AssetElement{RepoID: "DB1", ChannelID: "C1", AssetID: "lot 7"}
+	viper.Set(linkConfigKey, "")
+	assert.Equal(t, "dbom://DB1/C1/lot%207", ContentFor(element), "Asset URI without a link")
+
+	viper.Set(linkConfigKey, "https://dbom.example.com/{repoID}/{channelID}/{assetID}?uri={uri}")
+	defer viper.Set(linkConfigKey, "")
+	assert.Equal(t, "https://dbom.example.com/DB1/C1/lot%207?uri=dbom%3A%2F%2FDB1%2FC1%2Flot%25207", ContentFor(element),
+		"Link placeholders are replaced")
+
+	l := For(element, helpers.Asset{AssetType: "Server", AssetModelNumber: strings.Repeat("X", 40)})
+	assert.Equal(t, []string{"Server", strings.Repeat("X", 29) + "...", "lot 7"}, l.lines(), "Long text is cut short")
+}
+
+// TestPNG tests rendering a label as PNG
+func TestPNG(t *testing.T) {
+	rendered, err := Render(testLabel, FormatPNG, 4)
+	assert.NoError(t, err, "Label is rendered")
+	img, err := png.Decode(bytes.NewReader(rendered))
+	assert.NoError(t, err, "Label is a PNG")
+	assert.Equal(t, (25+2*quietZone)*4, img.Bounds().Dx(), "QR code sets the width")
+	assert.Greater(t, img.Bounds().Dy(), img.Bounds().Dx(), "Text is under the QR code")
+
+	_, err = Render(Label{Content: strings.Repeat("x", 300)}, FormatPNG, 4)
+	assert.Equal(t, ErrContentTooLong, err, "Content too long for a QR code is rejected")
+	_, err = Render(testLabel, FormatPDF, 4)
+	assert.Equal(t, ErrUnsupportedFormat, err, "Single labels are not rendered as PDF")
+}
+
+// TestSVG tests rendering a label and a sheet as SVG
+func TestSVG(t *testing.T) {
+	rendered, err := Render(testLabel, FormatSVG, 4)
+	assert.NoError(t, err, "Label is rendered")
+	assert.True(t, strings.HasPrefix(string(rendered), "<svg "), "Label is an SVG")
+	assert.Contains(t, string(rendered), ">Server &lt;rack&gt;</text>", "Text is escaped")
+
+	sheet, err := RenderSheet([]Label{testLabel, testLabel, testLabel, testLabel}, FormatSVG)
+	assert.NoError(t, err, "Sheet is rendered")
+	assert.Equal(t, 4, strings.Count(string(sheet), `<g transform="translate(`), "Every label is on the sheet")
+	assert.Contains(t, string(sheet), `height="560"`, "Labels are in rows of three")
+}
+
+// TestSheetPDF tests rendering a sheet as PDF
+func TestSheetPDF(t *testing.T) {
+	labels := make([]Label, sheetColumns*pdfRows+1)
+	for i := range labels {
+		labels[i] = testLabel
+	}
+	sheet, err := RenderSheet(labels, FormatPDF)
+	assert.NoError(t, err, "Sheet is rendered")
+	assert.True(t, bytes.HasPrefix(sheet, []byte("%PDF-1.4\n")), "Sheet is a PDF")
+	assert.Contains(t, string(sheet), "/Count 2 >>", "Labels overflow to a second page")
+	assert.True(t, bytes.HasSuffix(sheet, []byte("%%EOF\n")), "PDF is complete")
+
+	// Every object is where the cross reference table says it is
+	xref := regexp.MustCompile(`(?m)^(\d{10}) 00000 n $`).FindAllStringSubmatch(string(sheet), -1)
+	assert.Len(t, xref, 3+2*2, "Catalog, pages, font and two pages with their content")
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(entry[1])
+		assert.True(t, bytes.HasPrefix(sheet[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))),
+			"Object %d is at its offset", i+1)
+	}
+
+	assert.Equal(t, `(X100 \(rev B\) caf\351?)`, pdfString("X100 (rev B) café€"), "Text is escaped for WinAnsi")
+	_, err = RenderSheet(labels, FormatPNG)
+	assert.Equal(t, ErrUnsupportedFormat, err, "Sheets are not rendered as PNG")
+}
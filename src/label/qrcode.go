@@ -0,0 +1,54 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package label
+
+import (
+	"errors"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// ErrContentTooLong is an error when the content of a label does not fit the largest supported QR code
+var ErrContentTooLong = errors.New("label content is too long for a QR code")
+
+// maxVersion is the largest QR code version printed on a label, 57 modules wide. At error correction level M it
+// holds up to 213 bytes
+const maxVersion = 10
+
+// QRCode is a type representing the modules of a QR code, true for dark
+type QRCode struct {
+	Size    int
+	modules [][]bool
+}
+
+// Dark returns whether the module at column x and row y is dark
+func (q *QRCode) Dark(x, y int) bool {
+	return q.modules[y][x]
+}
+
+// EncodeQR encodes content as the smallest QR code at error correction level M that holds it, without a quiet zone
+func EncodeQR(content []byte) (*QRCode, error) {
+	// go-qrcode only fails on content beyond version 40
+	code, err := qrcode.New(string(content), qrcode.Medium)
+	if err != nil || code.VersionNumber > maxVersion {
+		return nil, ErrContentTooLong
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+	return &QRCode{Size: len(modules), modules: modules}, nil
+}
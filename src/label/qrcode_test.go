@@ -0,0 +1,76 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package label
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeQR renders a QR code with a quiet zone and reads it back with a QR code reader
+func decodeQR(t *testing.T, code *QRCode) string {
+	const scale = 4
+	size := (code.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			mx, my := x/scale-quietZone, y/scale-quietZone
+			dark := mx >= 0 && my >= 0 && mx < code.Size && my < code.Size && code.Dark(mx, my)
+			if dark {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	assert.NoError(t, err, "QR code is binarized")
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, nil)
+	if !assert.NoError(t, err, "QR code is decoded") {
+		return ""
+	}
+	return result.GetText()
+}
+
+// TestEncodeQR checks that content of every length up to the largest supported version reads back
+func TestEncodeQR(t *testing.T) {
+	for _, length := range []int{1, 14, 15, 26, 27, 60, 100, 150, 200, 213} {
+		content := strings.Repeat("dbom://db1/c1/a", 15)[:length]
+		code, err := EncodeQR([]byte(content))
+		assert.NoError(t, err, "Content is encoded")
+		assert.Equal(t, 0, (code.Size-17)%4, "Size is the size of a version")
+		assert.LessOrEqual(t, code.Size, 17+4*maxVersion, "Version fits a label")
+		assert.Equal(t, content, decodeQR(t, code), "%d bytes read back", length)
+	}
+}
+
+// TestEncodeQRTooLong checks that content beyond the largest supported version is rejected
+func TestEncodeQRTooLong(t *testing.T) {
+	_, err := EncodeQR([]byte(strings.Repeat("a", 214)))
+	assert.Equal(t, ErrContentTooLong, err, "Content beyond version 10 is rejected")
+	code, err := EncodeQR([]byte("dbom://DB1/C1/A1"))
+	assert.NoError(t, err, "Short content is encoded")
+	assert.Equal(t, 25, code.Size, "Short content uses version 2")
+	assert.Equal(t, "dbom://DB1/C1/A1", decodeQR(t, code), "Short content reads back")
+}
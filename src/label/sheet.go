@@ -0,0 +1,176 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package label
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// sheetColumns is the number of labels side by side on a sheet
+const sheetColumns = 3
+
+// sheetGap is the space between the labels of an SVG sheet, in user units
+const sheetGap = 20
+
+// RenderSheet renders labels as a PDF or SVG sheet
+func RenderSheet(labels []Label, format string) ([]byte, error) {
+	switch format {
+	case FormatPDF:
+		return SheetPDF(labels)
+	case FormatSVG:
+		return SheetSVG(labels)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// SheetSVG renders labels as a single SVG sheet, in rows of three
+func SheetSVG(labels []Label) ([]byte, error) {
+	rows := (len(labels) + sheetColumns - 1) / sheetColumns
+	width := sheetColumns*(svgLabelWidth+sheetGap) + sheetGap
+	height := rows*(svgLabelHeight+sheetGap) + sheetGap
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		width, height, width, height)
+	buffer.WriteString(`<rect width="100%" height="100%" fill="#fff"/>`)
+	for i, l := range labels {
+		x := sheetGap + (i%sheetColumns)*(svgLabelWidth+sheetGap)
+		y := sheetGap + (i/sheetColumns)*(svgLabelHeight+sheetGap)
+		if err := writeSVGLabel(&buffer, l, x, y); err != nil {
+			return nil, fmt.Errorf("label of %s: %s", l.AssetID, err.Error())
+		}
+	}
+	buffer.WriteString("</svg>\n")
+	return buffer.Bytes(), nil
+}
+
+// The PDF sheet is A4 with a margin of half an inch and labels of a 120 point QR code over three lines of text, in points
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 36
+	pdfCodeSize   = 120
+	pdfFontSize   = 8
+	pdfCellHeight = pdfCodeSize + 4*(pdfFontSize+2)
+	pdfRows       = (pdfPageHeight - 2*pdfMargin) / pdfCellHeight
+)
+
+// pdfString returns text as a PDF literal string in WinAnsi encoding. Characters beyond Latin-1 are replaced with a
+// question mark
+func pdfString(text string) string {
+	var builder strings.Builder
+	builder.WriteByte('(')
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case r < 0x20 || (r >= 0x7F && r < 0xA0) || r > 0xFF:
+			builder.WriteByte('?')
+		case r < 0x80:
+			builder.WriteRune(r)
+		default:
+			fmt.Fprintf(&builder, "\\%03o", r)
+		}
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// writePDFLabel draws a label into a content stream with its top left corner at x, y
+func writePDFLabel(content *bytes.Buffer, l Label, x, y float64) error {
+	code, err := EncodeQR([]byte(l.Content))
+	if err != nil {
+		return err
+	}
+	moduleSize := float64(pdfCodeSize) / float64(code.Size+2*quietZone)
+	for row := 0; row < code.Size; row++ {
+		for column := 0; column < code.Size; column++ {
+			if code.Dark(column, row) {
+				fmt.Fprintf(content, "%.3f %.3f %.3f %.3f re\n", x+float64(column+quietZone)*moduleSize,
+					y-float64(row+quietZone+1)*moduleSize, moduleSize, moduleSize)
+			}
+		}
+	}
+	content.WriteString("f\n")
+	for i, line := range l.lines() {
+		fmt.Fprintf(content, "BT /F1 %d Tf %.3f %.3f Td %s Tj ET\n", pdfFontSize, x+float64(quietZone)*moduleSize,
+			y-float64(pdfCodeSize+(i+1)*(pdfFontSize+2)), pdfString(line))
+	}
+	return nil
+}
+
+// SheetPDF renders labels as A4 PDF pages, in rows of three
+func SheetPDF(labels []Label) ([]byte, error) {
+	perPage := sheetColumns * pdfRows
+	columnWidth := float64(pdfPageWidth-2*pdfMargin) / sheetColumns
+	var pages [][]byte
+	for start := 0; start < len(labels) || start == 0; start += perPage {
+		var content bytes.Buffer
+		for i := start; i < len(labels) && i < start+perPage; i++ {
+			x := pdfMargin + float64((i-start)%sheetColumns)*columnWidth
+			y := float64(pdfPageHeight - pdfMargin - ((i-start)/sheetColumns)*pdfCellHeight)
+			if err := writePDFLabel(&content, labels[i], x, y); err != nil {
+				return nil, fmt.Errorf("label of %s: %s", labels[i].AssetID, err.Error())
+			}
+		}
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		writer.Write(content.Bytes())
+		writer.Close()
+		pages = append(pages, compressed.Bytes())
+	}
+
+	// Objects 1 to 3 are the catalog, the page tree and the font, every page is followed by its content stream
+	var buffer bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, buffer.Len())
+		fmt.Fprintf(&buffer, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			buffer.WriteString("stream\n")
+			buffer.Write(stream)
+			buffer.WriteString("\nendstream\n")
+		}
+		buffer.WriteString("endobj\n")
+	}
+	buffer.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)), nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> "+
+			"/Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 5+2*i), nil)
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", len(page)), page)
+	}
+
+	xref := buffer.Len()
+	fmt.Fprintf(&buffer, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buffer.Bytes(), nil
+}
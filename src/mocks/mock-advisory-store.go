@@ -0,0 +1,108 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package mocks
+
+import (
+	advisories "chainsource-gateway/advisories"
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockAdvisoryStore is a mock of AdvisoryStore interface
+type MockAdvisoryStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdvisoryStoreMockRecorder
+}
+
+// MockAdvisoryStoreMockRecorder is the mock recorder for MockAdvisoryStore
+type MockAdvisoryStoreMockRecorder struct {
+	mock *MockAdvisoryStore
+}
+
+// NewMockAdvisoryStore creates a new mock instance
+func NewMockAdvisoryStore(ctrl *gomock.Controller) *MockAdvisoryStore {
+	mock := &MockAdvisoryStore{ctrl: ctrl}
+	mock.recorder = &MockAdvisoryStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAdvisoryStore) EXPECT() *MockAdvisoryStoreMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockAdvisoryStore) Create(arg0 context.Context, arg1 advisories.Advisory) (advisories.Advisory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(advisories.Advisory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockAdvisoryStoreMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAdvisoryStore)(nil).Create), arg0, arg1)
+}
+
+// Get mocks base method
+func (m *MockAdvisoryStore) Get(arg0 context.Context, arg1 string) (advisories.Advisory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(advisories.Advisory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockAdvisoryStoreMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockAdvisoryStore)(nil).Get), arg0, arg1)
+}
+
+// List mocks base method
+func (m *MockAdvisoryStore) List(arg0 context.Context) ([]advisories.Advisory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]advisories.Advisory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockAdvisoryStoreMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAdvisoryStore)(nil).List), arg0)
+}
+
+// Withdraw mocks base method
+func (m *MockAdvisoryStore) Withdraw(arg0 context.Context, arg1 string) (advisories.Advisory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Withdraw", arg0, arg1)
+	ret0, _ := ret[0].(advisories.Advisory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Withdraw indicates an expected call of Withdraw
+func (mr *MockAdvisoryStoreMockRecorder) Withdraw(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockAdvisoryStore)(nil).Withdraw), arg0, arg1)
+}
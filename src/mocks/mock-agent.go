@@ -125,6 +125,21 @@ func (mr *MockAgentMockRecorder) QueryStream(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryStream", reflect.TypeOf((*MockAgent)(nil).QueryStream), arg0, arg1)
 }
 
+// QueryAssetsStream mocks base method
+func (m *MockAgent) QueryAssetsStream(arg0 context.Context, arg1 agent.QueryArgs, arg2 agent.RichQueryArgs) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryAssetsStream", arg0, arg1, arg2)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryAssetsStream indicates an expected call of QueryAssetsStream
+func (mr *MockAgentMockRecorder) QueryAssetsStream(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAssetsStream", reflect.TypeOf((*MockAgent)(nil).QueryAssetsStream), arg0, arg1, arg2)
+}
+
 // QueryAssets mocks base method
 func (m *MockAgent) QueryAssets(arg0 context.Context, arg1 agent.QueryArgs, arg2 agent.RichQueryArgs) (map[string]interface{}, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package mocks
+
+import (
+	aliases "chainsource-gateway/aliases"
+	helpers "chainsource-gateway/helpers"
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockAliasIndex is a mock of AliasIndex interface
+type MockAliasIndex struct {
+	ctrl     *gomock.Controller
+	recorder *MockAliasIndexMockRecorder
+}
+
+// MockAliasIndexMockRecorder is the mock recorder for MockAliasIndex
+type MockAliasIndexMockRecorder struct {
+	mock *MockAliasIndex
+}
+
+// NewMockAliasIndex creates a new mock instance
+func NewMockAliasIndex(ctrl *gomock.Controller) *MockAliasIndex {
+	mock := &MockAliasIndex{ctrl: ctrl}
+	mock.recorder = &MockAliasIndexMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAliasIndex) EXPECT() *MockAliasIndexMockRecorder {
+	return m.recorder
+}
+
+// Set mocks base method
+func (m *MockAliasIndex) Set(arg0 context.Context, arg1 aliases.Entry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set
+func (mr *MockAliasIndexMockRecorder) Set(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockAliasIndex)(nil).Set), arg0, arg1)
+}
+
+// Resolve mocks base method
+func (m *MockAliasIndex) Resolve(arg0 context.Context, arg1 string) ([]helpers.AliasMatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", arg0, arg1)
+	ret0, _ := ret[0].([]helpers.AliasMatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resolve indicates an expected call of Resolve
+func (mr *MockAliasIndexMockRecorder) Resolve(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockAliasIndex)(nil).Resolve), arg0, arg1)
+}
+
+// Replace mocks base method
+func (m *MockAliasIndex) Replace(arg0 context.Context, arg1 []aliases.Entry, arg2 func(helpers.AssetElement) bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace
+func (mr *MockAliasIndexMockRecorder) Replace(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockAliasIndex)(nil).Replace), arg0, arg1, arg2)
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package mocks
+
+import (
+	keyregistry "chainsource-gateway/keyregistry"
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	time "time"
+)
+
+// MockKeyRegistry is a mock of KeyRegistry interface
+type MockKeyRegistry struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyRegistryMockRecorder
+}
+
+// MockKeyRegistryMockRecorder is the mock recorder for MockKeyRegistry
+type MockKeyRegistryMockRecorder struct {
+	mock *MockKeyRegistry
+}
+
+// NewMockKeyRegistry creates a new mock instance
+func NewMockKeyRegistry(ctrl *gomock.Controller) *MockKeyRegistry {
+	mock := &MockKeyRegistry{ctrl: ctrl}
+	mock.recorder = &MockKeyRegistryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockKeyRegistry) EXPECT() *MockKeyRegistryMockRecorder {
+	return m.recorder
+}
+
+// Register mocks base method
+func (m *MockKeyRegistry) Register(arg0 context.Context, arg1 keyregistry.ManufacturerKey) (keyregistry.ManufacturerKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", arg0, arg1)
+	ret0, _ := ret[0].(keyregistry.ManufacturerKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register
+func (mr *MockKeyRegistryMockRecorder) Register(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockKeyRegistry)(nil).Register), arg0, arg1)
+}
+
+// Get mocks base method
+func (m *MockKeyRegistry) Get(arg0 context.Context, arg1 string) (keyregistry.ManufacturerKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(keyregistry.ManufacturerKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockKeyRegistryMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockKeyRegistry)(nil).Get), arg0, arg1)
+}
+
+// List mocks base method
+func (m *MockKeyRegistry) List(arg0 context.Context) ([]keyregistry.ManufacturerKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]keyregistry.ManufacturerKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockKeyRegistryMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockKeyRegistry)(nil).List), arg0)
+}
+
+// Revoke mocks base method
+func (m *MockKeyRegistry) Revoke(arg0 context.Context, arg1, arg2 string) (keyregistry.ManufacturerKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", arg0, arg1, arg2)
+	ret0, _ := ret[0].(keyregistry.ManufacturerKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoke indicates an expected call of Revoke
+func (mr *MockKeyRegistryMockRecorder) Revoke(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockKeyRegistry)(nil).Revoke), arg0, arg1, arg2)
+}
+
+// Authorize mocks base method
+func (m *MockKeyRegistry) Authorize(arg0 context.Context, arg1, arg2 string, arg3 time.Time) (keyregistry.ManufacturerKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(keyregistry.ManufacturerKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize
+func (mr *MockKeyRegistryMockRecorder) Authorize(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockKeyRegistry)(nil).Authorize), arg0, arg1, arg2, arg3)
+}
+
+// AuthorizeParty mocks base method
+func (m *MockKeyRegistry) AuthorizeParty(arg0 context.Context, arg1, arg2, arg3 string, arg4 time.Time) (keyregistry.ManufacturerKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeParty", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(keyregistry.ManufacturerKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeParty indicates an expected call of AuthorizeParty
+func (mr *MockKeyRegistryMockRecorder) AuthorizeParty(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeParty", reflect.TypeOf((*MockKeyRegistry)(nil).AuthorizeParty), arg0, arg1, arg2, arg3, arg4)
+}
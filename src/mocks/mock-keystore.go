@@ -0,0 +1,153 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package mocks
+
+import (
+	keystore "chainsource-gateway/keystore"
+	context "context"
+	crypto "crypto"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockKeystore is a mock of Keystore interface
+type MockKeystore struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeystoreMockRecorder
+}
+
+// MockKeystoreMockRecorder is the mock recorder for MockKeystore
+type MockKeystoreMockRecorder struct {
+	mock *MockKeystore
+}
+
+// NewMockKeystore creates a new mock instance
+func NewMockKeystore(ctrl *gomock.Controller) *MockKeystore {
+	mock := &MockKeystore{ctrl: ctrl}
+	mock.recorder = &MockKeystoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockKeystore) EXPECT() *MockKeystoreMockRecorder {
+	return m.recorder
+}
+
+// Enabled mocks base method
+func (m *MockKeystore) Enabled() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enabled")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Enabled indicates an expected call of Enabled
+func (mr *MockKeystoreMockRecorder) Enabled() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enabled", reflect.TypeOf((*MockKeystore)(nil).Enabled))
+}
+
+// Generate mocks base method
+func (m *MockKeystore) Generate(arg0 context.Context, arg1, arg2 string) (keystore.SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Generate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(keystore.SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Generate indicates an expected call of Generate
+func (mr *MockKeystoreMockRecorder) Generate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Generate", reflect.TypeOf((*MockKeystore)(nil).Generate), arg0, arg1, arg2)
+}
+
+// Get mocks base method
+func (m *MockKeystore) Get(arg0 context.Context, arg1 string) (keystore.SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(keystore.SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockKeystoreMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockKeystore)(nil).Get), arg0, arg1)
+}
+
+// List mocks base method
+func (m *MockKeystore) List(arg0 context.Context) ([]keystore.SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]keystore.SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockKeystoreMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockKeystore)(nil).List), arg0)
+}
+
+// Select mocks base method
+func (m *MockKeystore) Select(arg0 context.Context, arg1, arg2, arg3 string) (keystore.SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Select", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(keystore.SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Select indicates an expected call of Select
+func (mr *MockKeystoreMockRecorder) Select(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Select", reflect.TypeOf((*MockKeystore)(nil).Select), arg0, arg1, arg2, arg3)
+}
+
+// Sign mocks base method
+func (m *MockKeystore) Sign(arg0 context.Context, arg1 string, arg2 []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sign", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sign indicates an expected call of Sign
+func (mr *MockKeystoreMockRecorder) Sign(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sign", reflect.TypeOf((*MockKeystore)(nil).Sign), arg0, arg1, arg2)
+}
+
+// ResolvePublicKey mocks base method
+func (m *MockKeystore) ResolvePublicKey(arg0 context.Context, arg1 string) (crypto.PublicKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolvePublicKey", arg0, arg1)
+	ret0, _ := ret[0].(crypto.PublicKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolvePublicKey indicates an expected call of ResolvePublicKey
+func (mr *MockKeystoreMockRecorder) ResolvePublicKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolvePublicKey", reflect.TypeOf((*MockKeystore)(nil).ResolvePublicKey), arg0, arg1)
+}
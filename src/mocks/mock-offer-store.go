@@ -0,0 +1,138 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package mocks
+
+import (
+	offers "chainsource-gateway/offers"
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockOfferStore is a mock of OfferStore interface
+type MockOfferStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOfferStoreMockRecorder
+}
+
+// MockOfferStoreMockRecorder is the mock recorder for MockOfferStore
+type MockOfferStoreMockRecorder struct {
+	mock *MockOfferStore
+}
+
+// NewMockOfferStore creates a new mock instance
+func NewMockOfferStore(ctrl *gomock.Controller) *MockOfferStore {
+	mock := &MockOfferStore{ctrl: ctrl}
+	mock.recorder = &MockOfferStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOfferStore) EXPECT() *MockOfferStoreMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockOfferStore) Create(arg0 context.Context, arg1 offers.Offer) (offers.Offer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(offers.Offer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockOfferStoreMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOfferStore)(nil).Create), arg0, arg1)
+}
+
+// Get mocks base method
+func (m *MockOfferStore) Get(arg0 context.Context, arg1 string) (offers.Offer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(offers.Offer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockOfferStoreMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockOfferStore)(nil).Get), arg0, arg1)
+}
+
+// ListIncoming mocks base method
+func (m *MockOfferStore) ListIncoming(arg0 context.Context, arg1, arg2, arg3 string) ([]offers.Offer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIncoming", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]offers.Offer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIncoming indicates an expected call of ListIncoming
+func (mr *MockOfferStoreMockRecorder) ListIncoming(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIncoming", reflect.TypeOf((*MockOfferStore)(nil).ListIncoming), arg0, arg1, arg2, arg3)
+}
+
+// Reserve mocks base method
+func (m *MockOfferStore) Reserve(arg0 context.Context, arg1 string) (offers.Offer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", arg0, arg1)
+	ret0, _ := ret[0].(offers.Offer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve
+func (mr *MockOfferStoreMockRecorder) Reserve(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockOfferStore)(nil).Reserve), arg0, arg1)
+}
+
+// Release mocks base method
+func (m *MockOfferStore) Release(arg0 context.Context, arg1 string) (offers.Offer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", arg0, arg1)
+	ret0, _ := ret[0].(offers.Offer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Release indicates an expected call of Release
+func (mr *MockOfferStoreMockRecorder) Release(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockOfferStore)(nil).Release), arg0, arg1)
+}
+
+// Decide mocks base method
+func (m *MockOfferStore) Decide(arg0 context.Context, arg1 string, arg2 offers.Decision) (offers.Offer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decide", arg0, arg1, arg2)
+	ret0, _ := ret[0].(offers.Offer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decide indicates an expected call of Decide
+func (mr *MockOfferStoreMockRecorder) Decide(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decide", reflect.TypeOf((*MockOfferStore)(nil).Decide), arg0, arg1, arg2)
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package mocks
+
+import (
+	templateregistry "chainsource-gateway/templateregistry"
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockTemplateRegistry is a mock of TemplateRegistry interface
+type MockTemplateRegistry struct {
+	ctrl     *gomock.Controller
+	recorder *MockTemplateRegistryMockRecorder
+}
+
+// MockTemplateRegistryMockRecorder is the mock recorder for MockTemplateRegistry
+type MockTemplateRegistryMockRecorder struct {
+	mock *MockTemplateRegistry
+}
+
+// NewMockTemplateRegistry creates a new mock instance
+func NewMockTemplateRegistry(ctrl *gomock.Controller) *MockTemplateRegistry {
+	mock := &MockTemplateRegistry{ctrl: ctrl}
+	mock.recorder = &MockTemplateRegistryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTemplateRegistry) EXPECT() *MockTemplateRegistryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockTemplateRegistry) Create(arg0 context.Context, arg1 templateregistry.BomTemplate) (templateregistry.BomTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(templateregistry.BomTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create
+func (mr *MockTemplateRegistryMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTemplateRegistry)(nil).Create), arg0, arg1)
+}
+
+// Get mocks base method
+func (m *MockTemplateRegistry) Get(arg0 context.Context, arg1 string) (templateregistry.BomTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(templateregistry.BomTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockTemplateRegistryMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTemplateRegistry)(nil).Get), arg0, arg1)
+}
+
+// List mocks base method
+func (m *MockTemplateRegistry) List(arg0 context.Context) ([]templateregistry.BomTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]templateregistry.BomTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockTemplateRegistryMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTemplateRegistry)(nil).List), arg0)
+}
+
+// Replace mocks base method
+func (m *MockTemplateRegistry) Replace(arg0 context.Context, arg1 templateregistry.BomTemplate) (templateregistry.BomTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", arg0, arg1)
+	ret0, _ := ret[0].(templateregistry.BomTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Replace indicates an expected call of Replace
+func (mr *MockTemplateRegistryMockRecorder) Replace(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockTemplateRegistry)(nil).Replace), arg0, arg1)
+}
+
+// Delete mocks base method
+func (m *MockTemplateRegistry) Delete(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockTemplateRegistryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTemplateRegistry)(nil).Delete), arg0, arg1)
+}
+
+// ForModel mocks base method
+func (m *MockTemplateRegistry) ForModel(arg0 context.Context, arg1, arg2 string) (templateregistry.BomTemplate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForModel", arg0, arg1, arg2)
+	ret0, _ := ret[0].(templateregistry.BomTemplate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ForModel indicates an expected call of ForModel
+func (mr *MockTemplateRegistryMockRecorder) ForModel(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForModel", reflect.TypeOf((*MockTemplateRegistry)(nil).ForModel), arg0, arg1, arg2)
+}
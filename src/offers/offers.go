@@ -0,0 +1,320 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package offers contains the custody transfer offers waiting for the decision of the receiving party
+package offers
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/spf13/viper"
+)
+
+var log = helpers.GetLogger("TransferOffers")
+
+const collectionName = "transfer-offers"
+const ttlConfigKey = "transferOfferTTL"
+const pendingTransferTTLConfigKey = "pendingTransferTTL"
+const requireOffersConfigKey = "requireTransferOffers"
+const defaultTTL = 72 * time.Hour
+
+// StatusPending is the status of an offer waiting for the receiver
+const StatusPending = "pending"
+
+// StatusAccepted is the status of an offer the receiver accepted, the transfer is committed
+const StatusAccepted = "accepted"
+
+// StatusRejected is the status of an offer the receiver rejected
+const StatusRejected = "rejected"
+
+// StatusExpired is the status of a pending offer past its expiry
+const StatusExpired = "expired"
+
+// StatusDeciding is the status of an offer reserved while the decision of the receiver is committed to the origin
+const StatusDeciding = "deciding"
+
+// ErrOfferExpired is an error when an offer is decided after its expiry
+var ErrOfferExpired = errors.New("transfer offer has expired")
+
+// ErrOfferDecided is an error when an offer that was already accepted or rejected is decided again
+var ErrOfferDecided = errors.New("transfer offer was already decided")
+
+// ErrOfferDeciding is an error when an offer is decided while another decision on it is being committed
+var ErrOfferDeciding = errors.New("transfer offer is already being decided")
+
+// Offer is a type representing an offer to transfer the custody of an asset to a receiving repo and channel
+type Offer struct {
+	OfferID             string                     `json:"offerID"`
+	Status              string                     `json:"status"`
+	Source              helpers.AssetElement       `json:"source"`
+	Destination         helpers.AssetElement       `json:"destination"`
+	TransferID          string                     `json:"transferID,omitempty"`
+	TransferDescription string                     `json:"transferDescription,omitempty"`
+	SenderSignature     *helpers.TransferSignature `json:"senderSignature,omitempty"`
+	ReceiverSignature   *helpers.TransferSignature `json:"receiverSignature,omitempty"`
+	RejectionReason     string                     `json:"rejectionReason,omitempty"`
+	CreatedAt           time.Time                  `json:"createdAt"`
+	ExpiresAt           time.Time                  `json:"expiresAt"`
+	DecidedAt           *time.Time                 `json:"decidedAt,omitempty"`
+}
+
+// Decision is a type representing the outcome the receiver chose for an offer
+type Decision struct {
+	Status            string
+	ReceiverSignature *helpers.TransferSignature
+	RejectionReason   string
+}
+
+// OfferStore is an interface for the transfer offers kept by the gateway
+type OfferStore interface {
+	Create(ctx context.Context, offer Offer) (Offer, error)
+	Get(ctx context.Context, offerID string) (Offer, error)
+	ListIncoming(ctx context.Context, repoID string, channelID string, status string) ([]Offer, error)
+	Reserve(ctx context.Context, offerID string) (Offer, error)
+	Release(ctx context.Context, offerID string) (Offer, error)
+	Decide(ctx context.Context, offerID string, decision Decision) (Offer, error)
+}
+
+// StoreOffers is an implementation of OfferStore persisted in a store collection
+type StoreOffers struct {
+	collection *store.Collection
+	now        func() time.Time
+}
+
+// GetOfferTTL gets how long an offer stays open, from transferOfferTTL in agent-config.yaml
+func GetOfferTTL() time.Duration {
+	return getTTL(ttlConfigKey)
+}
+
+// GetPendingTransferTTL gets how long a signed transfer waits for the countersignature of the receiver,
+// from pendingTransferTTL in agent-config.yaml
+func GetPendingTransferTTL() time.Duration {
+	return getTTL(pendingTransferTTLConfigKey)
+}
+
+// getTTL gets a duration from agent-config.yaml, the default TTL when it is unset or invalid
+func getTTL(configKey string) time.Duration {
+	configured := viper.GetString(configKey)
+	if configured == "" {
+		return defaultTTL
+	}
+	ttl, err := time.ParseDuration(configured)
+	if err != nil || ttl <= 0 {
+		log.Error().Msgf("Invalid %s %q, using %s", configKey, configured, defaultTTL)
+		return defaultTTL
+	}
+	return ttl
+}
+
+// ReceivingChannel is a type representing an entry of the requireTransferOffers list in agent-config.yaml
+// An entry without a channelID applies to every channel of the repo
+type ReceivingChannel struct {
+	RepoID    string
+	ChannelID string
+}
+
+// RequiresOffer reports if a repo and channel only receives custody through offers it accepts,
+// from requireTransferOffers in agent-config.yaml
+func RequiresOffer(repoID string, channelID string) bool {
+	var receivers []ReceivingChannel
+	if err := viper.UnmarshalKey(requireOffersConfigKey, &receivers); err != nil {
+		log.Err(err).Msgf("Invalid %s, requiring offers for every channel", requireOffersConfigKey)
+		return true
+	}
+	for _, receiver := range receivers {
+		if receiver.RepoID == repoID && (receiver.ChannelID == "" || receiver.ChannelID == channelID) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewStoreOffers returns the offer store kept in the gateway data directory
+func NewStoreOffers() *StoreOffers {
+	return NewStoreOffersWith(store.NewCollection(collectionName))
+}
+
+// NewStoreOffersWith returns an offer store kept in a store collection
+func NewStoreOffersWith(collection *store.Collection) *StoreOffers {
+	return &StoreOffers{collection: collection, now: time.Now}
+}
+
+// withStatus reports a pending offer past its expiry as expired
+func (s *StoreOffers) withStatus(offer Offer) Offer {
+	if offer.Status == StatusPending && !s.now().Before(offer.ExpiresAt) {
+		offer.Status = StatusExpired
+	}
+	return offer
+}
+
+// Create stores a new pending offer, expiring after the configured TTL
+func (s *StoreOffers) Create(ctx context.Context, offer Offer) (Offer, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Create transfer offer")
+	defer span.Finish()
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Offer{}, err
+	}
+	offer.OfferID = hex.EncodeToString(id)
+	offer.Status = StatusPending
+	offer.CreatedAt = s.now().UTC()
+	offer.ExpiresAt = offer.CreatedAt.Add(GetOfferTTL())
+	offer.ReceiverSignature = nil
+	offer.RejectionReason = ""
+	offer.DecidedAt = nil
+	if err := s.collection.Create(offer.OfferID, offer); err != nil {
+		return Offer{}, err
+	}
+	log.Info().Msgf("Offered %s/%s/%s to %s/%s/%s as %s", offer.Source.RepoID, offer.Source.ChannelID, offer.Source.AssetID,
+		offer.Destination.RepoID, offer.Destination.ChannelID, offer.Destination.AssetID, offer.OfferID)
+	return offer, nil
+}
+
+// Get returns an offer, returns helpers.ErrNotFound when there is none
+func (s *StoreOffers) Get(ctx context.Context, offerID string) (offer Offer, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Get transfer offer")
+	defer span.Finish()
+
+	err = s.collection.Get(offerID, &offer)
+	return s.withStatus(offer), err
+}
+
+// ListIncoming returns the offers to a repo and channel, oldest first. An empty status returns every offer
+func (s *StoreOffers) ListIncoming(ctx context.Context, repoID string, channelID string, status string) ([]Offer, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "List incoming transfer offers")
+	defer span.Finish()
+
+	all, err := s.collection.All()
+	if err != nil {
+		return nil, err
+	}
+	incoming := make([]Offer, 0)
+	for _, raw := range all {
+		var offer Offer
+		if json.Unmarshal(raw, &offer) != nil {
+			continue
+		}
+		offer = s.withStatus(offer)
+		if offer.Destination.RepoID != repoID || offer.Destination.ChannelID != channelID {
+			continue
+		}
+		if status != "" && offer.Status != status {
+			continue
+		}
+		incoming = append(incoming, offer)
+	}
+	sort.Slice(incoming, func(i, j int) bool { return incoming[i].CreatedAt.Before(incoming[j].CreatedAt) })
+	return incoming, nil
+}
+
+// decidable returns why an offer in a status can not be decided, nil for a pending offer
+func decidable(status string) error {
+	switch status {
+	case StatusPending:
+		return nil
+	case StatusExpired:
+		return ErrOfferExpired
+	case StatusDeciding:
+		return ErrOfferDeciding
+	default:
+		return ErrOfferDecided
+	}
+}
+
+// modify changes a stored offer, returns helpers.ErrNotFound when there is none
+func (s *StoreOffers) modify(offerID string, change func(offer *Offer) error) (offer Offer, err error) {
+	err = s.collection.Modify(func(records map[string]json.RawMessage) error {
+		raw, exists := records[offerID]
+		if !exists {
+			return helpers.ErrNotFound
+		}
+		if err := json.Unmarshal(raw, &offer); err != nil {
+			return err
+		}
+		offer = s.withStatus(offer)
+		if err := change(&offer); err != nil {
+			return err
+		}
+		updated, err := json.Marshal(offer)
+		records[offerID] = updated
+		return err
+	})
+	return
+}
+
+// Reserve marks a pending offer deciding, so only one decision is committed to the origin at a time.
+// A reserved offer does not expire, it is decided with Decide or made pending again with Release
+func (s *StoreOffers) Reserve(ctx context.Context, offerID string) (Offer, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Reserve transfer offer")
+	defer span.Finish()
+
+	return s.modify(offerID, func(offer *Offer) error {
+		if err := decidable(offer.Status); err != nil {
+			return err
+		}
+		offer.Status = StatusDeciding
+		return nil
+	})
+}
+
+// Release makes a reserved offer pending again, when its decision could not be committed
+func (s *StoreOffers) Release(ctx context.Context, offerID string) (Offer, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Release transfer offer")
+	defer span.Finish()
+
+	return s.modify(offerID, func(offer *Offer) error {
+		if offer.Status != StatusDeciding {
+			return ErrOfferDecided
+		}
+		offer.Status = StatusPending
+		return nil
+	})
+}
+
+// Decide records the decision of the receiver on a pending or reserved offer
+func (s *StoreOffers) Decide(ctx context.Context, offerID string, decision Decision) (offer Offer, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Decide transfer offer")
+	defer span.Finish()
+
+	offer, err = s.modify(offerID, func(offer *Offer) error {
+		if offer.Status != StatusDeciding {
+			if err := decidable(offer.Status); err != nil {
+				return err
+			}
+		}
+		now := s.now().UTC()
+		offer.Status = decision.Status
+		offer.ReceiverSignature = decision.ReceiverSignature
+		offer.RejectionReason = decision.RejectionReason
+		offer.DecidedAt = &now
+		return nil
+	})
+	if err == nil {
+		log.Info().Msgf("Transfer offer %s %s", offerID, offer.Status)
+	}
+	return
+}
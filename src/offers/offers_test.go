@@ -0,0 +1,119 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package offers
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetOfferTTL tests the offer expiry configuration
+func TestGetOfferTTL(t *testing.T) {
+	defer viper.Set(ttlConfigKey, nil)
+	assert.Equal(t, defaultTTL, GetOfferTTL(), "Default TTL is used")
+	viper.Set(ttlConfigKey, "1h")
+	assert.Equal(t, time.Hour, GetOfferTTL(), "Configured TTL is used")
+	viper.Set(ttlConfigKey, "soon")
+	assert.Equal(t, defaultTTL, GetOfferTTL(), "Invalid TTL falls back to the default")
+}
+
+// TestGetPendingTransferTTL tests the expiry configuration of signed transfers
+func TestGetPendingTransferTTL(t *testing.T) {
+	defer viper.Set(pendingTransferTTLConfigKey, nil)
+	assert.Equal(t, defaultTTL, GetPendingTransferTTL(), "Default TTL is used")
+	viper.Set(pendingTransferTTLConfigKey, "30m")
+	assert.Equal(t, 30*time.Minute, GetPendingTransferTTL(), "Configured TTL is used")
+}
+
+// TestRequiresOffer tests the configuration of the channels that only receive custody through offers
+func TestRequiresOffer(t *testing.T) {
+	defer viper.Set(requireOffersConfigKey, nil)
+	assert.False(t, RequiresOffer("T1", "C2"), "Offers are optional by default")
+	viper.Set(requireOffersConfigKey, []map[string]interface{}{
+		{"repoID": "T1", "channelID": "C2"},
+		{"repoID": "T2"},
+	})
+	assert.True(t, RequiresOffer("T1", "C2"), "Configured channel requires offers")
+	assert.False(t, RequiresOffer("T1", "C1"), "Other channels of the repo do not")
+	assert.True(t, RequiresOffer("T2", "C9"), "Repo entry applies to every channel")
+}
+
+// TestStoreOffers tests offering, listing, deciding and expiring transfer offers
+func TestStoreOffers(t *testing.T) {
+	directory, err := ioutil.TempDir("", "offers")
+	assert.NoError(t, err, "Data directory is created")
+	defer os.RemoveAll(directory)
+	offerStore := NewStoreOffersWith(store.NewCollectionAt(directory, collectionName))
+	ctx := context.Background()
+	now := time.Now()
+	offerStore.now = func() time.Time { return now }
+
+	destination := helpers.AssetElement{RepoID: "T1", ChannelID: "C2", AssetID: "A2"}
+	first, err := offerStore.Create(ctx, Offer{Source: helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A1"},
+		Destination: destination, Status: StatusAccepted})
+	assert.NoError(t, err, "Offer is created")
+	assert.Equal(t, StatusPending, first.Status, "Offers are created pending")
+	assert.NotEmpty(t, first.OfferID, "Offer ID is assigned")
+	second, _ := offerStore.Create(ctx, Offer{Source: helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: "A3"},
+		Destination: destination})
+	_, _ = offerStore.Create(ctx, Offer{Destination: helpers.AssetElement{RepoID: "T1", ChannelID: "C3", AssetID: "A4"}})
+
+	incoming, err := offerStore.ListIncoming(ctx, "T1", "C2", StatusPending)
+	assert.NoError(t, err, "Offers are listed")
+	assert.Len(t, incoming, 2, "Only offers to the channel are listed")
+
+	reserved, err := offerStore.Reserve(ctx, first.OfferID)
+	assert.NoError(t, err, "Offer is reserved")
+	assert.Equal(t, StatusDeciding, reserved.Status, "Reserved offers are being decided")
+	_, err = offerStore.Reserve(ctx, first.OfferID)
+	assert.Equal(t, ErrOfferDeciding, err, "Offers are reserved once")
+	released, err := offerStore.Release(ctx, first.OfferID)
+	assert.NoError(t, err, "Offer is released")
+	assert.Equal(t, StatusPending, released.Status, "Released offers are pending again")
+	_, err = offerStore.Reserve(ctx, first.OfferID)
+	assert.NoError(t, err, "Released offers can be reserved again")
+
+	rejected, err := offerStore.Decide(ctx, first.OfferID, Decision{Status: StatusRejected, RejectionReason: "damaged"})
+	assert.NoError(t, err, "Offer is rejected")
+	assert.Equal(t, "damaged", rejected.RejectionReason, "Reason is recorded")
+	_, err = offerStore.Decide(ctx, first.OfferID, Decision{Status: StatusAccepted})
+	assert.Equal(t, ErrOfferDecided, err, "Offers are decided once")
+	_, err = offerStore.Release(ctx, first.OfferID)
+	assert.Equal(t, ErrOfferDecided, err, "Decided offers are not released")
+	_, err = offerStore.Decide(ctx, "unknown", Decision{Status: StatusAccepted})
+	assert.Equal(t, helpers.ErrNotFound, err, "Unknown offers are not found")
+
+	now = now.Add(GetOfferTTL())
+	expired, err := offerStore.Get(ctx, second.OfferID)
+	assert.NoError(t, err, "Offer is found")
+	assert.Equal(t, StatusExpired, expired.Status, "Offer has expired")
+	_, err = offerStore.Decide(ctx, second.OfferID, Decision{Status: StatusAccepted})
+	assert.Equal(t, ErrOfferExpired, err, "Expired offers can not be accepted")
+	_, err = offerStore.Reserve(ctx, second.OfferID)
+	assert.Equal(t, ErrOfferExpired, err, "Expired offers can not be reserved")
+	incoming, _ = offerStore.ListIncoming(ctx, "T1", "C2", StatusPending)
+	assert.Empty(t, incoming, "Expired offers are not pending")
+}
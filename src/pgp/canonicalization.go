@@ -0,0 +1,75 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"chainsource-gateway/helpers"
+	"encoding/json"
+	"errors"
+
+	"github.com/spf13/viper"
+)
+
+// CanonicalizationLegacy signs the encoding/json output of AssetNoChildParent, as done by earlier gateway versions
+const CanonicalizationLegacy = "legacy"
+
+// CanonicalizationJCS signs the JSON Canonicalization Scheme (RFC 8785) form of AssetNoChildParent
+const CanonicalizationJCS = "jcs"
+
+const canonicalizationConfigKey = "canonicalization"
+
+// ErrUnknownCanonicalization is an error when a canonicalization mode is not recognized
+var ErrUnknownCanonicalization = errors.New("unknown canonicalization mode")
+
+// CanonicalizationRule is a type representing an entry of the canonicalization list in agent-config.yaml
+type CanonicalizationRule struct {
+	StandardVersion float64
+	Mode            string
+}
+
+// GetCanonicalizationMode returns the canonicalization mode configured for a standardVersion.
+// Versions without a rule fall back to the legacy mode so existing signatures keep verifying
+func GetCanonicalizationMode(standardVersion float64) (mode string) {
+	mode = CanonicalizationLegacy
+	var rules []CanonicalizationRule
+	if err := viper.UnmarshalKey(canonicalizationConfigKey, &rules); err != nil {
+		log.Err(err).Msg("Canonicalization config could not be read, using legacy mode")
+		return
+	}
+	for _, rule := range rules {
+		if rule.StandardVersion == standardVersion && rule.Mode != "" {
+			mode = rule.Mode
+			return
+		}
+	}
+	return
+}
+
+// SigningInput serializes the signing input of an asset in the given canonicalization mode.
+// The returned bytes are exactly what the manufacturer must sign
+func SigningInput(mode string, input helpers.AssetNoChildParent) (result []byte, err error) {
+	switch mode {
+	case CanonicalizationLegacy:
+		result, err = json.Marshal(input)
+	case CanonicalizationJCS:
+		result, err = helpers.CanonicalJSON(input)
+	default:
+		err = ErrUnknownCanonicalization
+	}
+	return
+}
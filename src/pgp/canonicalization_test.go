@@ -0,0 +1,61 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"chainsource-gateway/helpers"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetCanonicalizationMode tests the per standardVersion canonicalization lookup
+func TestGetCanonicalizationMode(t *testing.T) {
+	viper.Set(canonicalizationConfigKey, []map[string]interface{}{
+		{"standardVersion": 2, "mode": CanonicalizationJCS},
+	})
+	defer viper.Set(canonicalizationConfigKey, nil)
+
+	assert.Equal(t, CanonicalizationJCS, GetCanonicalizationMode(2), "Configured version uses JCS")
+	assert.Equal(t, CanonicalizationLegacy, GetCanonicalizationMode(1), "Unconfigured version uses legacy")
+}
+
+// TestSigningInput tests the serialization of a signing input in every mode
+func TestSigningInput(t *testing.T) {
+	input := helpers.AssetNoChildParent{
+		StandardVersion: 2,
+		AssetType:       "Server",
+		AssetMetadata:   map[string]interface{}{"z": 1, "a": "<b>"},
+	}
+	t.Run("When_Legacy", func(t *testing.T) {
+		result, err := SigningInput(CanonicalizationLegacy, input)
+		assert.NoError(t, err, "No error must be returned")
+		assert.Contains(t, string(result), `{"standardVersion":2,`, "Struct field order is kept")
+		assert.Contains(t, string(result), `\u003cb\u003e`, "encoding/json escaping is kept")
+	})
+	t.Run("When_JCS", func(t *testing.T) {
+		result, err := SigningInput(CanonicalizationJCS, input)
+		assert.NoError(t, err, "No error must be returned")
+		assert.Contains(t, string(result), `"assetMetadata":{"a":"<b>","z":1}`, "Members are sorted and not HTML escaped")
+	})
+	t.Run("When_Unknown", func(t *testing.T) {
+		_, err := SigningInput("xml", input)
+		assert.Equal(t, ErrUnknownCanonicalization, err, "Unknown modes are rejected")
+	})
+}
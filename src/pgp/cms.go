@@ -0,0 +1,188 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"chainsource-gateway/tracing"
+	"context"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/smallstep/pkcs7"
+)
+
+// ErrMalformedCMS is an error when a signature is not a DER or PEM encoded CMS SignedData
+var ErrMalformedCMS = errors.New("signature is not a valid CMS SignedData")
+
+// ErrUntrustedCertificate is an error when the signer certificate does not chain to a trust anchor
+var ErrUntrustedCertificate = errors.New("signer certificate is not trusted")
+
+var (
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// CMSValidator is an implementation of the SignatureValidator interface for detached CMS signatures
+// The signer certificate must chain to one of the certificates of the trust anchor bundle
+type CMSValidator struct {
+	trustAnchorsPath string
+}
+
+// NewCMSValidator returns a CMS validator using a PEM bundle of trust anchors
+func NewCMSValidator(trustAnchorsPath string) CMSValidator {
+	return CMSValidator{trustAnchorsPath: trustAnchorsPath}
+}
+
+// Validate a detached CMS signature
+func (v CMSValidator) Validate(ctx context.Context, args ValidateArgs) (result map[string]interface{}, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "CMS Validate")
+	defer span.Finish()
+
+	signingInput, err := signingInputFor(args)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Could not build signing input")
+		return
+	}
+	roots, err := loadTrustAnchors(v.trustAnchorsPath)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Could not load CMS trust anchors")
+		err = newVerifierError(ErrVerifierUnavailable, err, nil)
+		return
+	}
+	signer, err := verifyDetachedCMS(args.Signature, signingInput, roots, time.Now())
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Validate CMS failed")
+		err = classifyLocalError(err)
+		return
+	}
+	fingerprint := certificateFingerprint(signer)
+	if args.Fingerprint != "" && !strings.EqualFold(args.Fingerprint, fingerprint) {
+		err = errors.New("signer certificate does not match the manufacture fingerprint")
+		tracing.LogAndTraceErr(log, span, err, "Validate CMS failed")
+		err = classifyLocalError(err)
+		return
+	}
+	result = map[string]interface{}{
+		"success": true,
+		"valid":   true,
+		"keyID":   fingerprint,
+		"subject": signer.Subject.String(),
+		"issuer":  signer.Issuer.String(),
+	}
+	return
+}
+
+// certificateFingerprint returns the hex encoded SHA-256 fingerprint of a certificate
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadTrustAnchors reads a PEM bundle into a certificate pool
+func loadTrustAnchors(path string) (*x509.CertPool, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, errors.New("no certificates found in trust anchor bundle")
+	}
+	return pool, nil
+}
+
+// decodeCMS accepts a PEM, base64 or raw DER encoded CMS message
+func decodeCMS(signature string) ([]byte, error) {
+	trimmed := strings.TrimSpace(signature)
+	if block, _ := pem.Decode([]byte(trimmed)); block != nil {
+		return block.Bytes, nil
+	}
+	if der, err := base64.StdEncoding.DecodeString(trimmed); err == nil {
+		return der, nil
+	}
+	return []byte(signature), nil
+}
+
+// verifyDetachedCMS verifies a detached CMS SignedData over content and returns the signer certificate.
+// The message and the signature are handled by pkcs7, the chain of the signer is verified at now
+func verifyDetachedCMS(signature string, content []byte, roots *x509.CertPool, now time.Time) (signer *x509.Certificate, err error) {
+	der, err := decodeCMS(signature)
+	if err != nil {
+		return nil, ErrMalformedCMS
+	}
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, ErrMalformedCMS
+	}
+	if len(p7.Content) > 0 {
+		return nil, errors.New("CMS signature must be detached")
+	}
+	if len(p7.Signers) != 1 {
+		return nil, errors.New("CMS signature must have exactly one signer")
+	}
+	signer = p7.GetOnlySigner()
+	if signer == nil {
+		return nil, errors.New("CMS signature does not carry the signer certificate")
+	}
+	// pkcs7 also accepts SHA-1, which is not allowed for manufacture signatures
+	if err = checkDigestAlgorithm(p7.Signers[0].DigestAlgorithm.Algorithm); err != nil {
+		return nil, err
+	}
+	p7.Content = content
+	if err = p7.Verify(); err != nil {
+		log.Err(err).Msg("CMS signature verification failed")
+		return nil, ErrSignatureMismatch
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range p7.Certificates {
+		if cert != signer {
+			intermediates.AddCert(cert)
+		}
+	}
+	_, err = signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		log.Err(err).Msg("CMS signer certificate chain verification failed")
+		return nil, ErrUntrustedCertificate
+	}
+	return
+}
+
+// checkDigestAlgorithm accepts the SHA-2 digest algorithms
+func checkDigestAlgorithm(oid asn1.ObjectIdentifier) error {
+	if oid.Equal(oidSHA256) || oid.Equal(oidSHA384) || oid.Equal(oidSHA512) {
+		return nil
+	}
+	return errors.New("unsupported CMS digest algorithm")
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"chainsource-gateway/helpers"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate is a certificate with its private key
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCertificate creates a certificate, self signed when no parent is given
+func newTestCertificate(t testing.TB, commonName string, isCA bool, parent *testCertificate) testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "Key is generated")
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	assert.NoError(t, err, "Certificate is created")
+	cert, _ := x509.ParseCertificate(der)
+	return testCertificate{cert: cert, key: key}
+}
+
+// buildDetachedCMS creates a base64 encoded detached SignedData with signed attributes over content
+func buildDetachedCMS(t testing.TB, content []byte, signer testCertificate, chain ...*x509.Certificate) string {
+	signedData, err := pkcs7.NewSignedData(content)
+	assert.NoError(t, err, "Signed data is created")
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	assert.NoError(t, signedData.AddSignerChain(signer.cert, signer.key, chain, pkcs7.SignerInfoConfig{}), "Content is signed")
+	signedData.Detach()
+	der, err := signedData.Finish()
+	assert.NoError(t, err, "Signed data is encoded")
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+// TestCMSValidator_Validate tests local validation of detached CMS signatures
+func TestCMSValidator_Validate(t *testing.T) {
+	directory, err := ioutil.TempDir("", "cms-anchors")
+	assert.NoError(t, err, "Trust anchor directory is created")
+	defer os.RemoveAll(directory)
+
+	root := newTestCertificate(t, "Root", true, nil)
+	intermediate := newTestCertificate(t, "Intermediate", true, &root)
+	leaf := newTestCertificate(t, "Supplier", false, &intermediate)
+	untrusted := newTestCertificate(t, "Untrusted", false, nil)
+
+	anchorsPath := filepath.Join(directory, "anchors.pem")
+	anchors := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.cert.Raw})
+	assert.NoError(t, ioutil.WriteFile(anchorsPath, anchors, 0600), "Trust anchors are written")
+
+	input := helpers.AssetNoChildParent{StandardVersion: 1, AssetType: "Server"}
+	content, _ := SigningInput(CanonicalizationLegacy, input)
+	validator := NewCMSValidator(anchorsPath)
+
+	t.Run("When_Chain_Trusted", func(t *testing.T) {
+		result, err := validator.Validate(context.Background(), ValidateArgs{
+			Signature: buildDetachedCMS(t, content, leaf, intermediate.cert),
+			Input:     input,
+		})
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, certificateFingerprint(leaf.cert), result["keyID"], "Signer certificate is reported")
+		assert.Equal(t, "CN=Supplier", result["subject"], "Signer subject is reported")
+	})
+	t.Run("When_Fingerprint_Matches", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint: certificateFingerprint(leaf.cert),
+			Signature:   buildDetachedCMS(t, content, leaf, intermediate.cert),
+			Input:       input,
+		})
+		assert.NoError(t, err, "No error must be returned")
+	})
+	t.Run("When_Fingerprint_Differs", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint: "00",
+			Signature:   buildDetachedCMS(t, content, leaf, intermediate.cert),
+			Input:       input,
+		})
+		assert.Error(t, err, "Error must be returned")
+	})
+	t.Run("When_Content_Tampered", func(t *testing.T) {
+		tampered := input
+		tampered.AssetType = "Switch"
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Signature: buildDetachedCMS(t, content, leaf, intermediate.cert),
+			Input:     tampered,
+		})
+		assert.True(t, errors.Is(err, ErrSignatureMismatch), "Signature must not verify")
+		assert.True(t, errors.Is(err, ErrBadSignature), "Error is classified")
+	})
+	t.Run("When_Chain_Incomplete", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Signature: buildDetachedCMS(t, content, leaf),
+			Input:     input,
+		})
+		assert.True(t, errors.Is(err, ErrUntrustedCertificate), "Signer must chain to a trust anchor")
+		assert.True(t, errors.Is(err, ErrUnknownKey), "Error is classified")
+	})
+	t.Run("When_Signer_Untrusted", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Signature: buildDetachedCMS(t, content, untrusted),
+			Input:     input,
+		})
+		assert.True(t, errors.Is(err, ErrUntrustedCertificate), "Signer must chain to a trust anchor")
+		assert.True(t, errors.Is(err, ErrUnknownKey), "Error is classified")
+	})
+	t.Run("When_Digest_SHA1", func(t *testing.T) {
+		signedData, _ := pkcs7.NewSignedData(content)
+		signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA1)
+		assert.NoError(t, signedData.AddSignerChain(leaf.cert, leaf.key, []*x509.Certificate{intermediate.cert},
+			pkcs7.SignerInfoConfig{}), "Content is signed")
+		signedData.Detach()
+		der, _ := signedData.Finish()
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Signature: base64.StdEncoding.EncodeToString(der),
+			Input:     input,
+		})
+		assert.Error(t, err, "SHA-1 digests must be rejected")
+	})
+	t.Run("When_Malformed", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Signature: base64.StdEncoding.EncodeToString([]byte("garbage")),
+			Input:     input,
+		})
+		assert.True(t, errors.Is(err, ErrMalformedCMS), "Malformed CMS must be rejected")
+		assert.True(t, errors.Is(err, ErrBadSignature), "Error is classified")
+	})
+	t.Run("When_Trust_Anchors_Missing", func(t *testing.T) {
+		_, err := NewCMSValidator(filepath.Join(directory, "missing.pem")).Validate(context.Background(), ValidateArgs{
+			Signature: buildDetachedCMS(t, content, leaf, intermediate.cert),
+			Input:     input,
+		})
+		assert.True(t, errors.Is(err, ErrVerifierUnavailable), "Missing trust anchors are an outage")
+	})
+}
+
+// FuzzVerifyDetachedCMS checks that malformed CMS messages are rejected without panicking
+func FuzzVerifyDetachedCMS(f *testing.F) {
+	root := newTestCertificate(f, "Root", true, nil)
+	leaf := newTestCertificate(f, "Supplier", false, &root)
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	content := []byte("signing input")
+
+	valid, _ := base64.StdEncoding.DecodeString(buildDetachedCMS(f, content, leaf))
+	f.Add(valid)
+	f.Add(valid[:len(valid)/2])
+	f.Add([]byte("garbage"))
+	f.Add([]byte{0x30, 0x80, 0x06, 0x09})
+	f.Fuzz(func(t *testing.T, der []byte) {
+		signer, err := verifyDetachedCMS(string(der), content, roots, time.Now())
+		if err == nil && signer == nil {
+			t.Fatal("A verified CMS signature must return its signer")
+		}
+	})
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import "errors"
+
+// ErrVerifierUnavailable is an error when the signature could not be checked, because the verifier could not be reached
+var ErrVerifierUnavailable = errors.New("signature verifier is unavailable")
+
+// ErrVerifierTimeout is an error when the verifier did not answer in time
+var ErrVerifierTimeout = errors.New("signature verifier timed out")
+
+// ErrUnknownKey is an error when the verifier does not know or trust the signing key
+var ErrUnknownKey = errors.New("signing key is unknown to the verifier")
+
+// ErrBadSignature is an error when the signature was checked and does not verify
+var ErrBadSignature = errors.New("signature is invalid")
+
+// VerifierError is an error returned by a SignatureValidator. Kind is one of ErrVerifierUnavailable,
+// ErrVerifierTimeout, ErrUnknownKey or ErrBadSignature, so callers can tell outages from forged signatures with errors.Is
+type VerifierError struct {
+	Kind   error
+	Err    error
+	Detail map[string]interface{}
+}
+
+// newVerifierError returns a verifier error of a kind, caused by err
+func newVerifierError(kind error, err error, detail map[string]interface{}) *VerifierError {
+	return &VerifierError{Kind: kind, Err: err, Detail: detail}
+}
+
+// Error returns the kind of the error followed by its cause
+func (e *VerifierError) Error() string {
+	if e.Err == nil || e.Err == e.Kind {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the error
+func (e *VerifierError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the target kind
+func (e *VerifierError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// VerifierDetail returns the detail the verifier reported with an error, nil if there is none
+func VerifierDetail(err error) map[string]interface{} {
+	var verifierErr *VerifierError
+	if errors.As(err, &verifierErr) {
+		return verifierErr.Detail
+	}
+	return nil
+}
+
+// classifyLocalError assigns a kind to an error of a validator that runs in the gateway
+func classifyLocalError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var verifierErr *VerifierError
+	if errors.As(err, &verifierErr) {
+		return err
+	}
+	switch err {
+	case ErrKeyNotFound, ErrUntrustedCertificate:
+		return newVerifierError(ErrUnknownKey, err, nil)
+	default:
+		return newVerifierError(ErrBadSignature, err, nil)
+	}
+}
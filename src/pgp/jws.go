@@ -0,0 +1,234 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"bytes"
+	"chainsource-gateway/tracing"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// ErrMalformedJWS is an error when a signature is not a compact JWS
+var ErrMalformedJWS = errors.New("signature is not a valid compact JWS")
+
+// ErrUnsupportedAlgorithm is an error when a JWS uses an algorithm other than EdDSA or ES256
+var ErrUnsupportedAlgorithm = errors.New("unsupported JWS algorithm")
+
+// ErrKeyNotFound is an error when the public key for a key ID is not known
+var ErrKeyNotFound = errors.New("public key not found")
+
+// KeyResolver is an interface that resolves a key ID (the manufactureFingerprint) to a public key
+type KeyResolver interface {
+	ResolvePublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error)
+}
+
+// DirectoryKeyResolver is an implementation of the KeyResolver interface that reads <keyID>.pem files from a directory
+type DirectoryKeyResolver struct {
+	Directory string
+}
+
+// NewDirectoryKeyResolver returns a key resolver for a directory of PEM encoded public keys
+func NewDirectoryKeyResolver(directory string) DirectoryKeyResolver {
+	return DirectoryKeyResolver{Directory: directory}
+}
+
+// ResolvePublicKey reads the PKIX public key stored for the key ID
+func (d DirectoryKeyResolver) ResolvePublicKey(_ context.Context, keyID string) (key crypto.PublicKey, err error) {
+	if keyID == "" || keyID != filepath.Base(keyID) || strings.HasPrefix(keyID, ".") {
+		return nil, ErrKeyNotFound
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(d.Directory, keyID+".pem"))
+	if err != nil {
+		return nil, ErrKeyNotFound
+	}
+	return ParsePublicKeyPEM(raw)
+}
+
+// KeyResolvers is an implementation of the KeyResolver interface that tries a list of resolvers in order
+type KeyResolvers []KeyResolver
+
+// ResolvePublicKey returns the key of the first resolver that knows the key ID
+func (k KeyResolvers) ResolvePublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	for _, resolver := range k {
+		key, err := resolver.ResolvePublicKey(ctx, keyID)
+		if err != ErrKeyNotFound {
+			return key, err
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// ParsePublicKeyPEM parses a PEM encoded PKIX public key or certificate
+func ParsePublicKeyPEM(raw []byte) (key crypto.PublicKey, err error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// jwsHeader is a type representing the protected header of a JWS
+type jwsHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// JWSValidator is an implementation of the SignatureValidator interface for compact JWS signatures
+// The payload may be detached (RFC 7515 Appendix F), if it is attached it must equal the signing input
+type JWSValidator struct {
+	keys KeyResolver
+}
+
+// NewJWSValidator returns a JWS validator that looks up public keys with a key resolver
+func NewJWSValidator(keys KeyResolver) JWSValidator {
+	return JWSValidator{keys: keys}
+}
+
+// Validate a JWS signature
+func (v JWSValidator) Validate(ctx context.Context, args ValidateArgs) (result map[string]interface{}, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "JWS Validate")
+	defer span.Finish()
+
+	signingInput, err := signingInputFor(args)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Could not build signing input")
+		return
+	}
+	header, err := verifyCompactJWS(ctx, v.keys, args.Fingerprint, args.Signature, signingInput)
+	if err != nil {
+		tracing.LogAndTraceErr(log, span, err, "Validate JWS failed")
+		err = classifyLocalError(err)
+		return
+	}
+	result = map[string]interface{}{
+		"success":   true,
+		"valid":     true,
+		"keyID":     args.Fingerprint,
+		"algorithm": header.Algorithm,
+	}
+	return
+}
+
+// SignDetachedJWS returns an EdDSA compact JWS with a detached payload (RFC 7515 Appendix F).
+// The signature is made by sign, so the private key never has to leave its keystore
+func SignDetachedJWS(keyID string, payload []byte, sign func(message []byte) ([]byte, error)) (string, error) {
+	rawHeader, err := json.Marshal(jwsHeader{Algorithm: "EdDSA", KeyID: keyID})
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(rawHeader)
+	signature, err := sign([]byte(protected + "." + base64.RawURLEncoding.EncodeToString(payload)))
+	if err != nil {
+		return "", err
+	}
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verifyCompactJWS verifies a compact JWS over a payload with the key resolved for keyID
+func verifyCompactJWS(ctx context.Context, keys KeyResolver, keyID string, signature string, payload []byte) (header jwsHeader, err error) {
+	parts := strings.Split(strings.TrimSpace(signature), ".")
+	if len(parts) != 3 {
+		err = ErrMalformedJWS
+		return
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		err = ErrMalformedJWS
+		return
+	}
+	if err = json.Unmarshal(rawHeader, &header); err != nil {
+		err = ErrMalformedJWS
+		return
+	}
+	if header.KeyID != "" && header.KeyID != keyID {
+		err = errors.New("JWS kid does not match the manufacture fingerprint")
+		return
+	}
+	if parts[1] != "" {
+		attached, decodeErr := base64.RawURLEncoding.DecodeString(parts[1])
+		if decodeErr != nil || !bytes.Equal(attached, payload) {
+			err = ErrSignatureMismatch
+			return
+		}
+	}
+	rawSignature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		err = ErrMalformedJWS
+		return
+	}
+
+	key, err := keys.ResolvePublicKey(ctx, keyID)
+	if err != nil {
+		return
+	}
+	signedContent := []byte(parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload))
+
+	switch header.Algorithm {
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			err = errors.New("EdDSA requires an Ed25519 key")
+			return
+		}
+		if !ed25519.Verify(edKey, signedContent, rawSignature) {
+			err = ErrSignatureMismatch
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			err = errors.New("ES256 requires a P-256 key")
+			return
+		}
+		if len(rawSignature) != 64 {
+			err = ErrSignatureMismatch
+			return
+		}
+		digest := sha256.Sum256(signedContent)
+		r := new(big.Int).SetBytes(rawSignature[:32])
+		s := new(big.Int).SetBytes(rawSignature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			err = ErrSignatureMismatch
+		}
+	default:
+		err = ErrUnsupportedAlgorithm
+	}
+	return
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"chainsource-gateway/helpers"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writePublicKeyPEM stores a public key as <keyID>.pem in a directory
+func writePublicKeyPEM(t *testing.T, directory string, keyID string, key interface{}) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err, "Public key marshals")
+	raw := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, keyID+".pem"), raw, 0600), "Public key is written")
+}
+
+// signDetachedJWS produces a detached compact JWS with the given header and signer
+func signDetachedJWS(header string, payload []byte, sign func([]byte) []byte) string {
+	protected := base64.RawURLEncoding.EncodeToString([]byte(header))
+	signingInput := protected + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(sign([]byte(signingInput)))
+}
+
+// TestJWSValidator_Validate tests local JWS validation for EdDSA and ES256
+func TestJWSValidator_Validate(t *testing.T) {
+	directory, err := ioutil.TempDir("", "jws-keys")
+	assert.NoError(t, err, "Key directory is created")
+	defer os.RemoveAll(directory)
+
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	writePublicKeyPEM(t, directory, "ed-key", edPublic)
+	ecPrivate, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writePublicKeyPEM(t, directory, "ec-key", &ecPrivate.PublicKey)
+
+	input := helpers.AssetNoChildParent{StandardVersion: 2, AssetType: "Server"}
+	payload, _ := SigningInput(CanonicalizationJCS, input)
+	validator := NewJWSValidator(NewDirectoryKeyResolver(directory))
+
+	edSign := func(message []byte) []byte { return ed25519.Sign(edPrivate, message) }
+	ecSign := func(message []byte) []byte {
+		digest := sha256.Sum256(message)
+		r, s, _ := ecdsa.Sign(rand.Reader, ecPrivate, digest[:])
+		signature := make([]byte, 64)
+		rBytes, sBytes := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rBytes):32], rBytes)
+		copy(signature[64-len(sBytes):], sBytes)
+		return signature
+	}
+
+	t.Run("When_EdDSA_Valid", func(t *testing.T) {
+		result, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "ed-key",
+			Signature:        signDetachedJWS(`{"alg":"EdDSA","kid":"ed-key"}`, payload, edSign),
+			Input:            input,
+			Canonicalization: CanonicalizationJCS,
+		})
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, "EdDSA", result["algorithm"], "Algorithm is reported")
+		assert.Equal(t, "ed-key", result["keyID"], "Key is reported")
+	})
+	t.Run("When_ES256_Valid", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "ec-key",
+			Signature:        signDetachedJWS(`{"alg":"ES256"}`, payload, ecSign),
+			Input:            input,
+			Canonicalization: CanonicalizationJCS,
+		})
+		assert.NoError(t, err, "No error must be returned")
+	})
+	t.Run("When_Input_Tampered", func(t *testing.T) {
+		tampered := input
+		tampered.AssetType = "Switch"
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "ed-key",
+			Signature:        signDetachedJWS(`{"alg":"EdDSA"}`, payload, edSign),
+			Input:            tampered,
+			Canonicalization: CanonicalizationJCS,
+		})
+		assert.True(t, errors.Is(err, ErrSignatureMismatch), "Signature must not verify")
+		assert.True(t, errors.Is(err, ErrBadSignature), "Error is classified")
+	})
+	t.Run("When_Kid_Mismatch", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "ed-key",
+			Signature:        signDetachedJWS(`{"alg":"EdDSA","kid":"other"}`, payload, edSign),
+			Input:            input,
+			Canonicalization: CanonicalizationJCS,
+		})
+		assert.Error(t, err, "Error must be returned")
+	})
+	t.Run("When_Key_Unknown", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "../ed-key",
+			Signature:        signDetachedJWS(`{"alg":"EdDSA"}`, payload, edSign),
+			Input:            input,
+			Canonicalization: CanonicalizationJCS,
+		})
+		assert.True(t, errors.Is(err, ErrKeyNotFound), "Key must not be found")
+		assert.True(t, errors.Is(err, ErrUnknownKey), "Error is classified")
+	})
+	t.Run("When_Algorithm_Unsupported", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "ed-key",
+			Signature:        signDetachedJWS(`{"alg":"none"}`, payload, edSign),
+			Input:            input,
+			Canonicalization: CanonicalizationJCS,
+		})
+		assert.True(t, errors.Is(err, ErrUnsupportedAlgorithm), "Algorithm must be rejected")
+		assert.True(t, errors.Is(err, ErrBadSignature), "Error is classified")
+	})
+	t.Run("When_Malformed", func(t *testing.T) {
+		_, err := validator.Validate(context.Background(), ValidateArgs{
+			Fingerprint: "ed-key",
+			Signature:   "not-a-jws",
+			Input:       input,
+		})
+		assert.True(t, errors.Is(err, ErrMalformedJWS), "Malformed JWS must be rejected")
+		assert.True(t, errors.Is(err, ErrBadSignature), "Error is classified")
+	})
+}
+
+// TestSignDetachedJWS tests that a JWS made with SignDetachedJWS verifies through a chain of key resolvers
+func TestSignDetachedJWS(t *testing.T) {
+	empty, err := ioutil.TempDir("", "jws-keys")
+	assert.NoError(t, err, "Key directory is created")
+	defer os.RemoveAll(empty)
+	directory, err := ioutil.TempDir("", "jws-keys")
+	assert.NoError(t, err, "Key directory is created")
+	defer os.RemoveAll(directory)
+
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	writePublicKeyPEM(t, directory, "ed-key", edPublic)
+	input := helpers.AssetNoChildParent{StandardVersion: 2, AssetType: "Server"}
+	payload, _ := SigningInput(CanonicalizationJCS, input)
+
+	signature, err := SignDetachedJWS("ed-key", payload, func(message []byte) ([]byte, error) {
+		return ed25519.Sign(edPrivate, message), nil
+	})
+	assert.NoError(t, err, "Payload is signed")
+	assert.Contains(t, signature, "..", "Payload is detached")
+
+	keys := KeyResolvers{NewDirectoryKeyResolver(empty), NewDirectoryKeyResolver(directory)}
+	_, err = NewJWSValidator(keys).Validate(context.Background(), ValidateArgs{
+		Signature: signature, Fingerprint: "ed-key", Canonicalization: CanonicalizationJCS, Input: input,
+	})
+	assert.NoError(t, err, "Signature verifies with the key of the second resolver")
+
+	_, err = keys.ResolvePublicKey(context.Background(), "other-key")
+	assert.Equal(t, ErrKeyNotFound, err, "Unknown keys are not found by any resolver")
+}
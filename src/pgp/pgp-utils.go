@@ -20,12 +20,19 @@ package pgp
 import (
 	"chainsource-gateway/helpers"
 	"os"
+	"time"
 )
 
 var log = helpers.GetLogger("PGPUtil")
 
 const pgpServiceAddressVar = "PGP_SERVICE_ADDRESS"
 const defaultPgpServiceAddress = "http://localhost:5000"
+const jwsKeyDirectoryVar = "JWS_KEY_DIRECTORY"
+const defaultJWSKeyDirectory = "./config/jws-keys"
+const cmsTrustAnchorsVar = "CMS_TRUST_ANCHORS"
+const defaultCMSTrustAnchors = "./config/cms-trust-anchors.pem"
+const pgpServiceTimeoutVar = "PGP_SERVICE_TIMEOUT"
+const defaultPgpServiceTimeout = 10 * time.Second
 
 // GetPGPServiceAddress gets the address of the pgp service
 func GetPGPServiceAddress() (pgpServiceAddress string) {
@@ -39,3 +46,37 @@ func GetPGPServiceAddress() (pgpServiceAddress string) {
 	return
 }
 
+
+// GetJWSKeyDirectory gets the directory holding the PEM encoded public keys for JWS signatures
+func GetJWSKeyDirectory() (directory string) {
+	if helpers.ExistsInEnv(jwsKeyDirectoryVar) {
+		directory = os.Getenv(jwsKeyDirectoryVar)
+	} else {
+		directory = defaultJWSKeyDirectory
+	}
+	return
+}
+
+// GetCMSTrustAnchorsPath gets the path of the PEM bundle of trust anchors for CMS signatures
+func GetCMSTrustAnchorsPath() (path string) {
+	if helpers.ExistsInEnv(cmsTrustAnchorsVar) {
+		path = os.Getenv(cmsTrustAnchorsVar)
+	} else {
+		path = defaultCMSTrustAnchors
+	}
+	return
+}
+
+// GetPGPServiceTimeout gets how long to wait for the pgp service, as a duration such as 10s
+func GetPGPServiceTimeout() (timeout time.Duration) {
+	timeout = defaultPgpServiceTimeout
+	if helpers.ExistsInEnv(pgpServiceTimeoutVar) {
+		parsed, err := time.ParseDuration(os.Getenv(pgpServiceTimeoutVar))
+		if err != nil || parsed <= 0 {
+			log.Warn().Msgf("Invalid %s, using %s", pgpServiceTimeoutVar, defaultPgpServiceTimeout)
+			return
+		}
+		timeout = parsed
+	}
+	return
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/magiconair/properties/assert"
 	"os"
 	"testing"
+	"time"
 )
 
 const testAddr = "http://addr"
@@ -37,4 +38,13 @@ func TestGetPGPServiceAddress(t *testing.T) {
 	})
 }
 
-
+// TestGetPGPServiceTimeout tests the pgp service timeout getter
+func TestGetPGPServiceTimeout(t *testing.T) {
+	os.Unsetenv(pgpServiceTimeoutVar)
+	assert.Equal(t, GetPGPServiceTimeout(), defaultPgpServiceTimeout, "Default is used when unset")
+	os.Setenv(pgpServiceTimeoutVar, "3s")
+	assert.Equal(t, GetPGPServiceTimeout(), 3*time.Second, "Environment is used when set")
+	os.Setenv(pgpServiceTimeoutVar, "soon")
+	assert.Equal(t, GetPGPServiceTimeout(), defaultPgpServiceTimeout, "Default is used when invalid")
+	os.Unsetenv(pgpServiceTimeoutVar)
+}
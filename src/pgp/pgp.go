@@ -19,10 +19,16 @@
 package pgp
 
 import (
+	"bytes"
 	"chainsource-gateway/helpers"
 	"chainsource-gateway/tracing"
 	"context"
 	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
 
 	"github.com/opentracing/opentracing-go"
 )
@@ -42,10 +48,27 @@ func NewSigningServiceValidator() SigningServiceValidator {
 }
 
 // ValidateArgs is a type representing the arguments sent to the pgp service to validate a signature
+// Payload is set for signatures over a document other than an asset, such as a custody transfer. It holds
+// the exact signed bytes in the Canonicalization mode, and Input is ignored
 type ValidateArgs struct {
-	Fingerprint string
-	Signature   string
-	Input       helpers.AssetNoChildParent
+	Fingerprint      string
+	Signature        string
+	Input            helpers.AssetNoChildParent
+	Canonicalization string
+	Scheme           string
+	Payload          []byte
+}
+
+// signingInputFor returns the bytes covered by the signature described by the arguments
+func signingInputFor(args ValidateArgs) ([]byte, error) {
+	if args.Payload != nil {
+		return args.Payload, nil
+	}
+	mode := args.Canonicalization
+	if mode == "" {
+		mode = CanonicalizationLegacy
+	}
+	return SigningInput(mode, args.Input)
 }
 
 // Validate a pgp signature
@@ -62,14 +85,79 @@ func (SigningServiceValidator) Validate(ctx context.Context, args ValidateArgs)
 		Signature:   args.Signature,
 		Input:       args.Input,
 	}
+	// Canonical modes send the exact signed bytes, the legacy body is left untouched for older signing services
+	if args.Payload != nil {
+		body.Canonicalization = args.Canonicalization
+		body.CanonicalInput = string(args.Payload)
+	} else if args.Canonicalization != "" && args.Canonicalization != CanonicalizationLegacy {
+		canonicalInput, err := SigningInput(args.Canonicalization, args.Input)
+		if err != nil {
+			tracing.LogAndTraceErr(log, span, err, "Could not canonicalize signing input")
+			return nil, err
+		}
+		body.Canonicalization = args.Canonicalization
+		body.CanonicalInput = string(canonicalInput)
+	}
 	bytesRepresentation, err := json.Marshal(body)
 	if err != nil {
 		tracing.LogAndTraceErr(log, span, err, "Validate signature failed")
+		return nil, err
 	}
-	result, err = helpers.PostJSONRequest(url, validatePath, nil, bytesRepresentation)
+	result, err = postValidateRequest(ctx, url+validatePath, bytesRepresentation)
 
 	if err != nil {
 		tracing.LogAndTraceErr(log, span, err, "Validate signature failed")
 	}
 	return
 }
+
+// postValidateRequest sends a validation request to the signing service and classifies its failures.
+// Transport failures are outages, a 404 is an unknown key and a 400, 422 or a result with valid false is a bad signature
+func postValidateRequest(ctx context.Context, url string, body []byte) (result map[string]interface{}, err error) {
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: GetPGPServiceTimeout()}
+	resp, err := client.Do(req)
+	if err != nil {
+		if netErr, ok := err.(net.Error); (ok && netErr.Timeout()) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, newVerifierError(ErrVerifierTimeout, err, nil)
+		}
+		return nil, newVerifierError(ErrVerifierUnavailable, err, nil)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := ioutil.ReadAll(resp.Body)
+	var detail map[string]interface{}
+	if json.Unmarshal(raw, &detail) != nil && len(raw) > 0 {
+		detail = map[string]interface{}{"body": string(raw)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		if detail == nil {
+			detail = make(map[string]interface{})
+		}
+		detail["statusCode"] = resp.StatusCode
+		statusErr := errors.New("signing service returned status " + strconv.Itoa(resp.StatusCode))
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return nil, newVerifierError(ErrUnknownKey, statusErr, detail)
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return nil, newVerifierError(ErrBadSignature, statusErr, detail)
+		case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+			return nil, newVerifierError(ErrVerifierTimeout, statusErr, detail)
+		default:
+			return nil, newVerifierError(ErrVerifierUnavailable, statusErr, detail)
+		}
+	}
+	if detail == nil {
+		return nil, newVerifierError(ErrVerifierUnavailable, errors.New("signing service returned no result"), nil)
+	}
+	if valid, ok := detail["valid"].(bool); ok && !valid {
+		return nil, newVerifierError(ErrBadSignature, nil, detail)
+	}
+	return detail, nil
+}
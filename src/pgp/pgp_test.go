@@ -20,12 +20,15 @@ package pgp
 import (
 	"chainsource-gateway/helpers"
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
 	"math"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 const mockSigningServiceEndpoint = "http://mock-signer"
@@ -73,6 +76,57 @@ func TestSigningServiceValidator_Validate(t *testing.T) {
 
 		assert.Error(t, err, "Error must be returned")
 	})
+	t.Run("When_Canonicalization_JCS", func(t *testing.T) {
+		gock.New(mockSigningServiceEndpoint).
+			Post("/pgp/validate").
+			MatchType("json").
+			JSON(map[string]interface{}{
+				"fingerprint":      "FP",
+				"signature":        "SIG",
+				"input":            helpers.AssetNoChildParent{StandardVersion: 2},
+				"canonicalization": CanonicalizationJCS,
+				"canonicalInput": `{"assetDescription":"","assetManufacturer":"","assetMetadata":null,"assetModelNumber":"",` +
+					`"assetSubType":"","assetType":"","documentCreatedDate":"","documentCreator":"","documentName":"","standardVersion":2}`,
+			}).
+			Reply(http.StatusOK).
+			JSON(map[string]string{"foo": "bar"})
+		defer gock.Off()
+
+		signingServiceValidator := NewSigningServiceValidator()
+		_, err := signingServiceValidator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "FP",
+			Signature:        "SIG",
+			Input:            helpers.AssetNoChildParent{StandardVersion: 2},
+			Canonicalization: CanonicalizationJCS,
+		})
+
+		assert.NoError(t, err, "Canonical input is sent to the signing service")
+	})
+	t.Run("When_Payload", func(t *testing.T) {
+		gock.New(mockSigningServiceEndpoint).
+			Post("/pgp/validate").
+			MatchType("json").
+			JSON(map[string]interface{}{
+				"fingerprint":      "FP",
+				"signature":        "SIG",
+				"input":            helpers.AssetNoChildParent{},
+				"canonicalization": CanonicalizationJCS,
+				"canonicalInput":   `{"sourceAssetID":"A1"}`,
+			}).
+			Reply(http.StatusOK).
+			JSON(map[string]string{"foo": "bar"})
+		defer gock.Off()
+
+		signingServiceValidator := NewSigningServiceValidator()
+		_, err := signingServiceValidator.Validate(context.Background(), ValidateArgs{
+			Fingerprint:      "FP",
+			Signature:        "SIG",
+			Canonicalization: CanonicalizationJCS,
+			Payload:          []byte(`{"sourceAssetID":"A1"}`),
+		})
+
+		assert.NoError(t, err, "Payload is sent as the canonical input")
+	})
 	t.Run("When_Bad_Payload", func(t *testing.T) {
 
 		signingServiceValidator := NewSigningServiceValidator()
@@ -87,3 +141,60 @@ func TestSigningServiceValidator_Validate(t *testing.T) {
 		assert.Error(t, err, "Error must be returned")
 	})
 }
+
+// TestSigningServiceValidator_ValidateErrors tests that signing service failures are classified
+func TestSigningServiceValidator_ValidateErrors(t *testing.T) {
+	os.Setenv(pgpServiceAddressVar, mockSigningServiceEndpoint)
+	defer os.Unsetenv(pgpServiceAddressVar)
+	cases := []struct {
+		name       string
+		statusCode int
+		body       map[string]interface{}
+		kind       error
+	}{
+		{"When_Key_Unknown", http.StatusNotFound, map[string]interface{}{"status": "no such key"}, ErrUnknownKey},
+		{"When_Signature_Rejected", http.StatusBadRequest, map[string]interface{}{"status": "bad"}, ErrBadSignature},
+		{"When_Signature_Not_Valid", http.StatusOK, map[string]interface{}{"success": true, "valid": false}, ErrBadSignature},
+		{"When_Service_Down", http.StatusServiceUnavailable, map[string]interface{}{}, ErrVerifierUnavailable},
+		{"When_Gateway_Timeout", http.StatusGatewayTimeout, map[string]interface{}{}, ErrVerifierTimeout},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gock.New(mockSigningServiceEndpoint).
+				Post("/pgp/validate").
+				Reply(c.statusCode).
+				JSON(c.body)
+			defer gock.Off()
+
+			_, err := NewSigningServiceValidator().Validate(context.Background(), ValidateArgs{})
+
+			assert.True(t, errors.Is(err, c.kind), "Error is classified")
+			detail := VerifierDetail(err)
+			for key, value := range c.body {
+				assert.Equal(t, value, detail[key], "Verifier detail is reported")
+			}
+		})
+	}
+	t.Run("When_Connection_Refused", func(t *testing.T) {
+		os.Setenv(pgpServiceAddressVar, "http://127.0.0.1:1")
+		defer os.Setenv(pgpServiceAddressVar, mockSigningServiceEndpoint)
+
+		_, err := NewSigningServiceValidator().Validate(context.Background(), ValidateArgs{})
+
+		assert.True(t, errors.Is(err, ErrVerifierUnavailable), "Connection failure is an outage")
+	})
+	t.Run("When_Service_Slow", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+		os.Setenv(pgpServiceAddressVar, server.URL)
+		os.Setenv(pgpServiceTimeoutVar, "20ms")
+		defer os.Setenv(pgpServiceAddressVar, mockSigningServiceEndpoint)
+		defer os.Unsetenv(pgpServiceTimeoutVar)
+
+		_, err := NewSigningServiceValidator().Validate(context.Background(), ValidateArgs{})
+
+		assert.True(t, errors.Is(err, ErrVerifierTimeout), "Slow service is a timeout")
+	})
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// PolicyOff does not check manufacture signatures on create and update
+const PolicyOff = "off"
+
+// PolicyWarn checks manufacture signatures on create and update and records the result, but always commits
+const PolicyWarn = "warn"
+
+// PolicyEnforce rejects creates and updates of assets that are unsigned or badly signed
+const PolicyEnforce = "enforce"
+
+const signaturePolicyConfigKey = "signaturePolicy"
+
+// SignaturePolicyRule is a type representing an entry of the signaturePolicy list in agent-config.yaml
+// A rule without a channelID applies to every channel of the repo
+type SignaturePolicyRule struct {
+	RepoID    string
+	ChannelID string
+	Mode      string
+}
+
+// GetSignaturePolicy returns the signature policy configured for a channel.
+// A channel rule takes precedence over a repo rule, and channels without a rule use the off policy
+func GetSignaturePolicy(repoID string, channelID string) (mode string) {
+	mode = PolicyOff
+	var rules []SignaturePolicyRule
+	if err := viper.UnmarshalKey(signaturePolicyConfigKey, &rules); err != nil {
+		log.Err(err).Msg("Signature policy config could not be read, using off policy")
+		return
+	}
+	matchedChannel := false
+	for _, rule := range rules {
+		if rule.RepoID != repoID || (rule.ChannelID != "" && rule.ChannelID != channelID) {
+			continue
+		}
+		if matchedChannel && rule.ChannelID == "" {
+			continue
+		}
+		mode = normalizePolicy(rule.Mode)
+		matchedChannel = rule.ChannelID != ""
+	}
+	return
+}
+
+// normalizePolicy maps a configured mode to a policy. Unrecognized modes fail closed to enforce
+func normalizePolicy(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case PolicyOff:
+		return PolicyOff
+	case PolicyWarn:
+		return PolicyWarn
+	case PolicyEnforce:
+		return PolicyEnforce
+	default:
+		log.Warn().Msgf("Unknown signature policy %q, using enforce", mode)
+		return PolicyEnforce
+	}
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetSignaturePolicy tests the selection of the signature policy of a channel
+func TestGetSignaturePolicy(t *testing.T) {
+	viper.Set(signaturePolicyConfigKey, []map[string]interface{}{
+		{"repoID": "DB1", "channelID": "C2", "mode": "off"},
+		{"repoID": "DB1", "mode": "Enforce"},
+		{"repoID": "DB2", "channelID": "C1", "mode": "warn"},
+		{"repoID": "DB3", "mode": "strict"},
+	})
+	defer viper.Set(signaturePolicyConfigKey, nil)
+
+	assert.Equal(t, PolicyEnforce, GetSignaturePolicy("DB1", "C1"), "Repo rule applies to its channels")
+	assert.Equal(t, PolicyOff, GetSignaturePolicy("DB1", "C2"), "Channel rule takes precedence over repo rule")
+	assert.Equal(t, PolicyWarn, GetSignaturePolicy("DB2", "C1"), "Channel rule applies")
+	assert.Equal(t, PolicyOff, GetSignaturePolicy("DB2", "C2"), "Channels without a rule are off")
+	assert.Equal(t, PolicyEnforce, GetSignaturePolicy("DB3", "C1"), "Unknown modes fail closed")
+	assert.Equal(t, PolicyOff, GetSignaturePolicy("DB4", "C1"), "Repos without a rule are off")
+}
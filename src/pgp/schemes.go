@@ -0,0 +1,104 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// SchemePGP is an OpenPGP signature verified by the signing service
+const SchemePGP = "pgp"
+
+// SchemeJWS is a compact JWS (EdDSA or ES256) verified locally
+const SchemeJWS = "jws"
+
+// SchemeCMS is a detached CMS SignedData made with an X.509 certificate, verified locally
+const SchemeCMS = "cms"
+
+// ErrUnsupportedScheme is an error when no validator is configured for a signature scheme
+var ErrUnsupportedScheme = errors.New("unsupported signature scheme")
+
+// ErrSignatureMismatch is an error when a signature does not verify against the signing input
+var ErrSignatureMismatch = errors.New("signature does not match the signing input")
+
+// NormalizeScheme maps the signature scheme discriminator of an asset to a known scheme.
+// Assets without a discriminator predate it and are treated as OpenPGP
+func NormalizeScheme(scheme string) string {
+	scheme = strings.ToLower(strings.TrimSpace(scheme))
+	switch scheme {
+	case "", "openpgp":
+		return SchemePGP
+	case "x509", "pkcs7":
+		return SchemeCMS
+	}
+	return scheme
+}
+
+// MultiSchemeValidator is an implementation of the SignatureValidator interface that dispatches on the signature scheme
+type MultiSchemeValidator struct {
+	validators map[string]SignatureValidator
+}
+
+// NewMultiSchemeValidator returns a validator for every supported scheme, configured from the environment
+func NewMultiSchemeValidator() MultiSchemeValidator {
+	return NewMultiSchemeValidatorWith(map[string]SignatureValidator{
+		SchemePGP: NewSigningServiceValidator(),
+		SchemeJWS: NewJWSValidator(NewDirectoryKeyResolver(GetJWSKeyDirectory())),
+		SchemeCMS: NewCMSValidator(GetCMSTrustAnchorsPath()),
+	})
+}
+
+// NewMultiSchemeValidatorWithKeys returns a validator for every supported scheme, where JWS keys that are not
+// in the key directory are looked up with an additional resolver
+func NewMultiSchemeValidatorWithKeys(keys KeyResolver) MultiSchemeValidator {
+	return NewMultiSchemeValidatorWith(map[string]SignatureValidator{
+		SchemePGP: NewSigningServiceValidator(),
+		SchemeJWS: NewJWSValidator(KeyResolvers{NewDirectoryKeyResolver(GetJWSKeyDirectory()), keys}),
+		SchemeCMS: NewCMSValidator(GetCMSTrustAnchorsPath()),
+	})
+}
+
+// NewMultiSchemeValidatorWith returns a validator that dispatches to the given per scheme validators
+func NewMultiSchemeValidatorWith(validators map[string]SignatureValidator) MultiSchemeValidator {
+	return MultiSchemeValidator{validators: validators}
+}
+
+// Validate a signature with the validator registered for its scheme.
+// The result reports the scheme and key that were used
+func (m MultiSchemeValidator) Validate(ctx context.Context, args ValidateArgs) (result map[string]interface{}, err error) {
+	args.Scheme = NormalizeScheme(args.Scheme)
+	validator, ok := m.validators[args.Scheme]
+	if !ok {
+		err = newVerifierError(ErrBadSignature, ErrUnsupportedScheme, nil)
+		return
+	}
+	result, err = validator.Validate(ctx, args)
+	if err != nil {
+		return
+	}
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+	result["scheme"] = args.Scheme
+	if _, ok := result["keyID"]; !ok {
+		result["keyID"] = args.Fingerprint
+	}
+	return
+}
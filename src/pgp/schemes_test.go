@@ -0,0 +1,90 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pgp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubValidator is a SignatureValidator that records the arguments it was called with
+type stubValidator struct {
+	result map[string]interface{}
+	err    error
+	called *ValidateArgs
+}
+
+// Validate records the call and returns the stubbed result
+func (s stubValidator) Validate(_ context.Context, args ValidateArgs) (map[string]interface{}, error) {
+	*s.called = args
+	return s.result, s.err
+}
+
+// TestNormalizeScheme tests the mapping of scheme discriminators
+func TestNormalizeScheme(t *testing.T) {
+	assert.Equal(t, SchemePGP, NormalizeScheme(""), "Missing discriminator is OpenPGP")
+	assert.Equal(t, SchemePGP, NormalizeScheme("OpenPGP"), "OpenPGP alias is recognized")
+	assert.Equal(t, SchemeJWS, NormalizeScheme(" JWS "), "Case and whitespace are ignored")
+	assert.Equal(t, SchemeCMS, NormalizeScheme("x509"), "X.509 alias is recognized")
+}
+
+// TestNewMultiSchemeValidator tests the scheme dispatching validator getter
+func TestNewMultiSchemeValidator(t *testing.T) {
+	assert.Implements(t, (*SignatureValidator)(nil), NewMultiSchemeValidator(), "Implements signature validator interface")
+}
+
+// TestMultiSchemeValidator_Validate tests dispatching on the signature scheme
+func TestMultiSchemeValidator_Validate(t *testing.T) {
+	t.Run("When_Scheme_Known", func(t *testing.T) {
+		var called ValidateArgs
+		validator := NewMultiSchemeValidatorWith(map[string]SignatureValidator{
+			SchemeJWS: stubValidator{result: map[string]interface{}{"valid": true}, called: &called},
+		})
+		result, err := validator.Validate(context.Background(), ValidateArgs{Fingerprint: "FP", Scheme: "jws"})
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, SchemeJWS, called.Scheme, "Validator for the scheme is called")
+		assert.Equal(t, SchemeJWS, result["scheme"], "Scheme is reported")
+		assert.Equal(t, "FP", result["keyID"], "Key is reported")
+	})
+	t.Run("When_Scheme_Missing", func(t *testing.T) {
+		var called ValidateArgs
+		validator := NewMultiSchemeValidatorWith(map[string]SignatureValidator{
+			SchemePGP: stubValidator{called: &called},
+		})
+		result, err := validator.Validate(context.Background(), ValidateArgs{Fingerprint: "FP"})
+		assert.NoError(t, err, "No error must be returned")
+		assert.Equal(t, SchemePGP, result["scheme"], "OpenPGP is the default scheme")
+	})
+	t.Run("When_Scheme_Unsupported", func(t *testing.T) {
+		validator := NewMultiSchemeValidatorWith(map[string]SignatureValidator{})
+		_, err := validator.Validate(context.Background(), ValidateArgs{Scheme: "cosign"})
+		assert.True(t, errors.Is(err, ErrUnsupportedScheme), "Unsupported scheme is rejected")
+		assert.True(t, errors.Is(err, ErrBadSignature), "Error is classified")
+	})
+	t.Run("When_Validation_Fails", func(t *testing.T) {
+		var called ValidateArgs
+		validator := NewMultiSchemeValidatorWith(map[string]SignatureValidator{
+			SchemeCMS: stubValidator{err: errors.New("bad"), called: &called},
+		})
+		_, err := validator.Validate(context.Background(), ValidateArgs{Scheme: "cms"})
+		assert.Error(t, err, "Error is passed through")
+	})
+}
@@ -18,6 +18,7 @@
 package responses
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/render"
@@ -32,6 +33,8 @@ type ErrResponse struct {
 	StatusText string `json:"status"`          // user-level status message
 	AppCode    int64  `json:"code,omitempty"`  // application-specific error code
 	ErrorText  string `json:"error,omitempty"` // application-level error message, for debugging
+
+	Detail map[string]interface{} `json:"detail,omitempty"` // detail reported by a downstream service
 }
 
 // Render renders the error response
@@ -141,11 +144,48 @@ func ErrNoSignature() render.Renderer {
 }
 
 //ErrInvalidSignature returns the json response for when an invalid signature is found during validation
-func ErrInvalidSignature() render.Renderer {
+func ErrInvalidSignature(err error, detail map[string]interface{}) render.Renderer {
 	return &ErrResponse{
 		IsSuccessful:   false,
+		Err:            err,
 		HTTPStatusCode: 400,
 		StatusText:     "Invalid Signature",
+		ErrorText:      err.Error(),
+		Detail:         detail,
+	}
+}
+
+//ErrUnknownSigningKey returns the json response for when the verifier does not know the signing key
+func ErrUnknownSigningKey(err error, detail map[string]interface{}) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+		StatusText:     "Unknown signing key",
+		ErrorText:      err.Error(),
+		Detail:         detail,
+	}
+}
+
+//ErrUntrustedSigner returns the json response for when a valid signature is made with a key that is not trusted for the asset
+func ErrUntrustedSigner(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: 400,
+		StatusText:     "Untrusted Signer",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrKeyDoesNotExist returns error for when a manufacturer key is not registered
+func ErrKeyDoesNotExist(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusNotFound,
+		StatusText:     "Key is not registered",
+		ErrorText:      err.Error(),
 	}
 }
 
@@ -158,6 +198,83 @@ func ErrNoFingerprint() render.Renderer {
 	}
 }
 
+//ErrSignaturePolicy returns the json response for when an asset is rejected by the signature policy of its channel
+func ErrSignaturePolicy(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+		StatusText:     "Signature policy violation",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrSigningDisabled returns the json response for when the gateway is asked to sign, but gateway signing is not enabled
+func ErrSigningDisabled(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusForbidden,
+		StatusText:     "Gateway signing is not enabled",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrNoSigningKey returns the json response for when no gateway signing key may be used for an asset
+func ErrNoSigningKey(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+		StatusText:     "No signing key for asset",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrNoPendingTransfer returns the json response for when an asset has no transfer waiting for a countersignature
+func ErrNoPendingTransfer(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusNotFound,
+		StatusText:     "No pending transfer",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrOfferDoesNotExist returns the json response for when a transfer offer does not exist
+func ErrOfferDoesNotExist(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusNotFound,
+		StatusText:     "Transfer offer does not exist",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrOfferExpired returns the json response for when a transfer offer is decided after its expiry
+func ErrOfferExpired(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusGone,
+		StatusText:     "Transfer offer has expired",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrOfferRequired returns the json response for when a transfer is sent directly to a channel that only accepts offers
+func ErrOfferRequired(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+		StatusText:     "Transfer offer required",
+		ErrorText:      err.Error(),
+	}
+}
+
 //ErrReadOnly returns the json response for when an asset is read only
 func ErrReadOnly() render.Renderer {
 	return &ErrResponse{
@@ -290,6 +407,30 @@ func ErrUnimplemented(err error) render.Renderer {
 	}
 }
 
+//ErrVerifierUnavailable returns the json response for when the signature verifier cannot be reached
+func ErrVerifierUnavailable(err error, detail map[string]interface{}) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusServiceUnavailable,
+		StatusText:     "Signature verifier unavailable",
+		ErrorText:      err.Error(),
+		Detail:         detail,
+	}
+}
+
+//ErrVerifierTimeout returns the json response for when the signature verifier does not answer in time
+func ErrVerifierTimeout(err error, detail map[string]interface{}) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusGatewayTimeout,
+		StatusText:     "Signature verifier timed out",
+		ErrorText:      err.Error(),
+		Detail:         detail,
+	}
+}
+
 //ErrFailedExport returns the json response for when an export fails
 func ErrFailedExport(err error) render.Renderer {
 	return &ErrResponse{
@@ -346,3 +487,76 @@ func ErrUnauthorizedModifyDestination(err error) render.Renderer {
 	}
 }
 
+
+//ErrTemplateDoesNotExist returns error for when there is no BOM template with an ID or for a model
+func ErrTemplateDoesNotExist(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusNotFound,
+		StatusText:     "BOM template does not exist",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrTemplateViolation returns the json response for when a child does not fit the BOM template of the parent
+func ErrTemplateViolation(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+		StatusText:     "BOM template violation",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrAdvisoryDoesNotExist returns error for when there is no advisory with an ID
+func ErrAdvisoryDoesNotExist(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusNotFound,
+		StatusText:     "Advisory does not exist",
+		ErrorText:      err.Error(),
+	}
+}
+
+//ErrDuplicateIdentity returns the json response for when a created asset has the identity of an existing asset
+func ErrDuplicateIdentity(err error) render.Renderer {
+	return &ErrResponse{
+		IsSuccessful:   false,
+		Err:            err,
+		HTTPStatusCode: http.StatusConflict,
+		StatusText:     "Duplicate identity",
+		ErrorText:      err.Error(),
+	}
+}
+
+//PartialTransferResponse is a type for the error response to a subtree transfer that stopped part way. Repeating the
+//request with its transferID resumes the transfer after the assets that were moved
+type PartialTransferResponse struct {
+	*ErrResponse
+
+	TransferID  string            `json:"transferID"`
+	Transferred []TransferMapping `json:"transferred"`
+	Failed      TransferMapping   `json:"failed"`
+	Remaining   []TransferMapping `json:"remaining"`
+}
+
+//ErrPartialTransfer returns the json response for when a subtree transfer failed after some of its assets were moved.
+//The status and the error are the ones of the failure that stopped the transfer
+func ErrPartialTransfer(failure render.Renderer, transferID string, transferred []TransferMapping, failed TransferMapping,
+	remaining []TransferMapping) render.Renderer {
+	response, ok := failure.(*ErrResponse)
+	if !ok {
+		response = ErrAgent(errors.New("subtree transfer failed")).(*ErrResponse)
+	}
+	response.StatusText = "Subtree transfer stopped part way: " + response.StatusText
+	return &PartialTransferResponse{
+		ErrResponse: response,
+		TransferID:  transferID,
+		Transferred: transferred,
+		Failed:      failed,
+		Remaining:   remaining,
+	}
+}
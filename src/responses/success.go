@@ -19,6 +19,7 @@
 package responses
 
 import (
+	"chainsource-gateway/helpers"
 	"net/http"
 
 	"github.com/go-chi/render"
@@ -67,7 +68,67 @@ func SuccessfulTransferResponse() render.Renderer {
 	return successfulResponse(200, "Successfully transferred asset")
 }
 
+//TransferMapping is a type reporting where a transferred asset was moved to
+type TransferMapping struct {
+	Source      helpers.AssetElement `json:"source"`
+	Destination helpers.AssetElement `json:"destination"`
+}
+
+//SubtreeTransferResponse is a type for the response to the transfer of an asset together with its attached children
+type SubtreeTransferResponse struct {
+	SuccessResponse
+
+	Transferred []TransferMapping `json:"transferred"`
+}
+
+//SuccessfulSubtreeTransferResponse returns success when an asset is transferred with its attached children
+func SuccessfulSubtreeTransferResponse(transferred []TransferMapping) render.Renderer {
+	return &SubtreeTransferResponse{
+		SuccessResponse: SuccessResponse{
+			IsSuccessful:   true,
+			HTTPStatusCode: 200,
+			StatusText:     "Successfully transferred asset and attached children",
+		},
+		Transferred: transferred,
+	}
+}
+
+//SuccessfulTransferPendingResponse returns success when a signed transfer is waiting for the countersignature of the receiver
+func SuccessfulTransferPendingResponse() render.Renderer {
+	return successfulResponse(202, "Transfer is pending the countersignature of the receiver")
+}
+
+//SuccessfulTransferRejectedResponse returns success when a pending transfer is cancelled or declined
+func SuccessfulTransferRejectedResponse() render.Renderer {
+	return successfulResponse(200, "Pending transfer is rejected")
+}
+
 //SuccessfulDetachResponse returns success when a subasset is detached on an agent
 func SuccessfulDetachResponse() render.Renderer {
 	return successfulResponse(200, "Successfully detached on agent")
 }
+
+//SignResponse is a type for the response to an asset signed by the gateway. It never carries key material
+type SignResponse struct {
+	SuccessResponse
+
+	KeyID                string `json:"keyID"`
+	Scheme               string `json:"scheme"`
+	Canonicalization     string `json:"canonicalization"`
+	ManufactureSignature string `json:"manufactureSignature"`
+}
+
+//SuccessfulSignResponse returns success when an asset is signed by the gateway
+func SuccessfulSignResponse(keyID string, scheme string, canonicalization string, signature string) render.Renderer {
+	return &SignResponse{
+		SuccessResponse: SuccessResponse{
+			IsSuccessful:   true,
+			HTTPStatusCode: 200,
+			StatusText:     "Successfully signed on agent",
+		},
+		KeyID:                keyID,
+		Scheme:               scheme,
+		Canonicalization:     canonicalization,
+		ManufactureSignature: signature,
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package routes contains all the routes for the Gateway API
+package routes
+
+import (
+	"chainsource-gateway/advisories"
+	advisoryController "chainsource-gateway/controller/advisories"
+	"chainsource-gateway/controller/asset"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/opentracing/opentracing-go"
+)
+
+// advisorySubRouting defines the sub routes for issuing advisories and recording them on the assets they affect
+func advisorySubRouting(r chi.Router) {
+	r.Use(injectSpanMiddleware)
+	r.Use(agentProvider)
+	r.Use(advisoryStoreProvider)
+	r.Use(unmarshalBody)
+
+	r.Post("/", advisoryController.CreateAdvisory)
+	r.Get("/", advisoryController.ListAdvisories)
+	r.Get("/{advisoryID}", advisoryController.GetAdvisory)
+	r.Post("/{advisoryID}/withdraw", advisoryController.WithdrawAdvisory)
+	r.Post("/{advisoryID}/apply", asset.ApplyAdvisory)
+}
+
+// advisoryStoreProvider injects the store of advisories into the request context
+func advisoryStoreProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span, ctx := opentracing.StartSpanFromContext(r.Context(), "Embedding Advisory Store")
+		advisoryStore := advisories.NewStoreAdvisories()
+		ctx = context.WithValue(r.Context(), "advisoryStore", advisoryStore)
+		span.Finish()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"chainsource-gateway/agent"
 	"chainsource-gateway/controller/asset"
 	"chainsource-gateway/helpers"
+	"chainsource-gateway/keystore"
 	"chainsource-gateway/pgp"
 	"chainsource-gateway/responses"
 	"chainsource-gateway/schema"
@@ -30,6 +31,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 
@@ -49,6 +51,15 @@ func AssetRouter() (r chi.Router) {
 	r.Route("/repo/{repoID}/chan/{channelID}", channelSubRouting)
 	r.Route("/repo/{repoID}/chan/{channelID}/asset/{assetID}", assetSubRouting)
 	r.Route("/repo/{repoID}/chan/{channelID}/asset/_query", assetFunctionSubRouting)
+	r.Route("/keys", keySubRouting)
+	r.Route("/signing-keys", signingKeySubRouting)
+	r.Route("/transfer-offers", transferOfferSubRouting)
+	r.Route("/bom-templates", templateSubRouting)
+	r.Route("/where-used", whereUsedSubRouting)
+	r.Route("/advisories", advisorySubRouting)
+	r.Route("/duplicates", duplicateSubRouting)
+	r.Route("/resolve", resolveSubRouting)
+	r.Route("/_query", federatedQuerySubRouting)
 	return
 }
 
@@ -57,6 +68,12 @@ func assetSubRouting(r chi.Router) {
 	r.Use(injectSpanMiddleware)
 	r.Use(agentProvider)
 	r.Use(signingServiceProvider)
+	r.Use(signerRegistryProvider)
+	r.Use(keystoreProvider)
+	r.Use(offerStoreProvider)
+	r.Use(templateRegistryProvider)
+	r.Use(advisoryStoreProvider)
+	r.Use(aliasIndexProvider)
 	r.Use(assetContext)
 	r.Use(assetSchemaValidator)
 	r.Use(unmarshalBody)
@@ -68,11 +85,24 @@ func assetSubRouting(r chi.Router) {
 	r.Delete("/", asset.DeleteAsset)
 
 	// Link/Unlink APIs
-	r.Post("/attach", asset.AttachSubasset)
-	r.Post("/detach", asset.DetachSubasset)
+	r.With(expandAssetURI).Post("/attach", asset.AttachSubasset)
+	r.With(expandAssetURI).Post("/detach", asset.DetachSubasset)
 	r.Get("/trail", asset.AuditAsset)
-	r.Post("/transfer", asset.TransferAsset)
+	r.Get("/provenance", asset.GetProvenance)
+	r.Get("/diff", asset.DiffAsset)
+	r.Get("/bom-diff", asset.DiffBOM)
+	r.Get("/conformance", asset.CheckConformance)
+	r.With(expandAssetURI).Post("/transfer", asset.TransferAsset)
+	r.Post("/transfer/countersign", asset.CountersignTransfer)
+	r.Post("/transfer/cancel", asset.CancelTransfer)
+	r.Post("/transfer/decline", asset.DeclineTransfer)
+	r.With(expandAssetURI).Post("/transfer/offer", asset.OfferTransfer)
+	r.Get("/transfer/signing-input", asset.GetTransferSigningInput)
 	r.Get("/validate", asset.ValidateAsset)
+	r.Get("/signing-input", asset.GetSigningInput)
+	r.Post("/sign", asset.SignAsset)
+	r.Get("/label", asset.GetLabel)
+	r.Get("/labels", asset.GetLabelSheet)
 
 	// Export API
 	r.Group(func(r chi.Router) {
@@ -108,11 +138,12 @@ func agentProvider(next http.Handler) http.Handler {
 	})
 }
 
-// signingServiceProvider injects a "SignatureValidator" that works based on the signing service
+// signingServiceProvider injects a "SignatureValidator" that dispatches on the signature scheme of the asset
+// JWS signatures made by the gateway keystore verify with the public keys of the keystore
 func signingServiceProvider(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		span, ctx := opentracing.StartSpanFromContext(r.Context(), "Embedding Signing Service Provider")
-		provider := pgp.NewSigningServiceValidator()
+		provider := pgp.NewMultiSchemeValidatorWithKeys(keystore.NewStoreKeystore())
 		ctx = context.WithValue(r.Context(), "signatureValidator", provider)
 		span.Finish()
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -237,6 +268,43 @@ func unmarshalBody(next http.Handler) http.Handler {
 	})
 }
 
+// expandAssetURI replaces a "uri" in the body of a link or transfer request with the repoID, channelID and assetID
+// it names, so that dbom://<repoID>/<channelID>/<assetID> is accepted in place of the three fields.
+// Fields passed along with the URI must agree with it
+func expandAssetURI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := r.Context().Value("JSONBody").(map[string]interface{})
+		uri, hasURI := body["uri"]
+		if !ok || !hasURI {
+			next.ServeHTTP(w, r)
+			return
+		}
+		uriString, _ := uri.(string)
+		element, err := helpers.ParseAssetURI(uriString)
+		if err != nil {
+			_ = render.Render(w, r, responses.ErrInvalidRequest(err))
+			return
+		}
+		fields := map[string]string{"repoID": element.RepoID, "channelID": element.ChannelID, "assetID": element.AssetID}
+		for field, value := range fields {
+			if passed, exists := body[field]; exists && passed != value {
+				_ = render.Render(w, r, responses.ErrInvalidRequest(fmt.Errorf("%s does not match the uri", field)))
+				return
+			}
+			body[field] = value
+		}
+		delete(body, "uri")
+
+		b, err := json.Marshal(body)
+		if err != nil {
+			_ = render.Render(w, r, responses.ErrInternalServer(err))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewBuffer(b))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "JSONBody", body)))
+	})
+}
+
 // assetSchemaValidator injects an schema validator into the request context
 func assetSchemaValidator(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -24,6 +24,7 @@ import (
 	"chainsource-gateway/pgp"
 	"chainsource-gateway/schema"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -242,3 +243,42 @@ func Test_unmarshalBody(t *testing.T) {
 	})
 
 }
+
+// Test_expandAssetURI tests if a uri in the body is replaced with the fields it names
+func Test_expandAssetURI(t *testing.T) {
+	t.Run("When_URI_Valid", func(t *testing.T) {
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{"uri": "dbom://DB1/C1/A%201", "role": "cpu", "repoID": "DB1"}`))
+		responseRecorder := httptest.NewRecorder()
+		unmarshalBody(expandAssetURI(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var link helpers.AssetLinkElement
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&link), "Body is rewritten")
+			assert.Equal(t, helpers.AssetElement{RepoID: "DB1", ChannelID: "C1", AssetID: "A 1"}, link.AssetElement,
+				"Fields are taken from the uri")
+			assert.Equal(t, "cpu", link.Role, "Other fields are kept")
+			body := r.Context().Value("JSONBody").(map[string]interface{})
+			assert.NotContains(t, body, "uri", "uri is removed for schema validation")
+			assert.Equal(t, "C1", body["channelID"], "JSONBody has the fields")
+		}))).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "A 200 OK is returned")
+	})
+	t.Run("When_No_URI", func(t *testing.T) {
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{"repoID": "DB1"}`))
+		responseRecorder := httptest.NewRecorder()
+		unmarshalBody(expandAssetURI(getContextAssertionMiddleware(func(ctx context.Context) {
+			assert.Equal(t, map[string]interface{}{"repoID": "DB1"}, ctx.Value("JSONBody"), "Body is unchanged")
+		}))).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "A 200 OK is returned")
+	})
+	t.Run("When_URI_Invalid", func(t *testing.T) {
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{"uri": "DB1/C1/A1"}`))
+		responseRecorder := httptest.NewRecorder()
+		unmarshalBody(expandAssetURI(nil)).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "A 400 BAD REQUEST is returned")
+	})
+	t.Run("When_Fields_Disagree", func(t *testing.T) {
+		mockRequest := httptest.NewRequest("POST", "/", strings.NewReader(`{"uri": "dbom://DB1/C1/A1", "assetID": "A2"}`))
+		responseRecorder := httptest.NewRecorder()
+		unmarshalBody(expandAssetURI(nil)).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, "A 400 BAD REQUEST is returned")
+	})
+}
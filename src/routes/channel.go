@@ -45,6 +45,8 @@ func channelSubRouting(r chi.Router) {
 
 	// List assets
 	r.Get("/asset", channel.ListAssets)
+	r.With(offerStoreProvider).Get("/transfer-offers", channel.ListTransferOffers)
+	r.Get("/labels", channel.GetLabelSheet)
 
 }
 
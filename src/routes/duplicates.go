@@ -0,0 +1,33 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package routes contains all the routes for the Gateway API
+package routes
+
+import (
+	"chainsource-gateway/controller/asset"
+
+	"github.com/go-chi/chi"
+)
+
+// duplicateSubRouting defines the sub routes for finding assets registered under the same identity, across every repo
+func duplicateSubRouting(r chi.Router) {
+	r.Use(injectSpanMiddleware)
+	r.Use(agentProvider)
+
+	r.Get("/", asset.FindDuplicates)
+}
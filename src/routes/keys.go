@@ -0,0 +1,88 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package routes contains all the routes for the Gateway API
+package routes
+
+import (
+	"chainsource-gateway/controller/keys"
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/keystore"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/opentracing/opentracing-go"
+)
+
+// keySubRouting defines the sub routes for the manufacturer key registry APIs
+func keySubRouting(r chi.Router) {
+	r.Use(injectSpanMiddleware)
+	r.Use(keyRegistryProvider)
+	r.Use(unmarshalBody)
+
+	r.Post("/", keys.RegisterKey)
+	r.Get("/", keys.ListKeys)
+	r.Get("/{fingerprint}", keys.GetKey)
+	r.Post("/{fingerprint}/revoke", keys.RevokeKey)
+}
+
+// signingKeySubRouting defines the sub routes for the keys the gateway signs assets with
+func signingKeySubRouting(r chi.Router) {
+	r.Use(injectSpanMiddleware)
+	r.Use(keystoreProvider)
+	r.Use(keyRegistryProvider)
+	r.Use(unmarshalBody)
+
+	r.Post("/", keys.CreateSigningKey)
+	r.Get("/", keys.ListSigningKeys)
+	r.Get("/{keyID}", keys.GetSigningKey)
+}
+
+// keyRegistryProvider injects the registry of trusted manufacturer keys into the request context
+func keyRegistryProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span, ctx := opentracing.StartSpanFromContext(r.Context(), "Embedding Key Registry")
+		registry := keyregistry.NewStoreRegistry()
+		ctx = context.WithValue(r.Context(), "keyRegistry", registry)
+		span.Finish()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// keystoreProvider injects the keystore of the keys the gateway signs assets with into the request context
+func keystoreProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span, ctx := opentracing.StartSpanFromContext(r.Context(), "Embedding Keystore")
+		signingKeys := keystore.NewStoreKeystore()
+		ctx = context.WithValue(r.Context(), "keystore", signingKeys)
+		span.Finish()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// signerRegistryProvider injects the registry of trusted manufacturer keys into the request context when signers are
+// checked against it, see keyregistry.IsEnforced
+func signerRegistryProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !keyregistry.IsEnforced() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		keyRegistryProvider(next).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package routes
+
+import (
+	"chainsource-gateway/keyregistry"
+	"chainsource-gateway/keystore"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_keySubRouting tests if the key sub router mounts successfully
+func Test_keySubRouting(t *testing.T) {
+	assert.NotPanics(t, func() {
+		keySubRouting(chi.NewRouter())
+	}, "Router mounts without panic")
+}
+
+// Test_keyRegistryProvider tests if the key registry is injected
+func Test_keyRegistryProvider(t *testing.T) {
+	mockRequest := httptest.NewRequest("GET", "/", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	keyRegistryProvider(getContextAssertionMiddleware(func(ctx context.Context) {
+		val := ctx.Value("keyRegistry")
+		assert.NotNil(t, val, "keyRegistry must be injected")
+		assert.Implements(t, (*keyregistry.KeyRegistry)(nil), val, "Implements key registry interface")
+	})).ServeHTTP(responseRecorder, mockRequest)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "A 200 OK is returned")
+}
+
+// Test_signerRegistryProvider tests if the key registry is only injected when signers are checked against it
+func Test_signerRegistryProvider(t *testing.T) {
+	defer viper.Set("enforceKeyRegistry", nil)
+	for _, enforced := range []bool{false, true} {
+		viper.Set("enforceKeyRegistry", enforced)
+		mockRequest := httptest.NewRequest("GET", "/", strings.NewReader(""))
+		responseRecorder := httptest.NewRecorder()
+		signerRegistryProvider(getContextAssertionMiddleware(func(ctx context.Context) {
+			_, injected := ctx.Value("keyRegistry").(keyregistry.KeyRegistry)
+			assert.Equal(t, enforced, injected, "keyRegistry is injected when enforced")
+		})).ServeHTTP(responseRecorder, mockRequest)
+		assert.Equal(t, http.StatusOK, responseRecorder.Code, "A 200 OK is returned")
+	}
+}
+
+// Test_signingKeySubRouting tests if the signing key sub router mounts successfully
+func Test_signingKeySubRouting(t *testing.T) {
+	assert.NotPanics(t, func() {
+		signingKeySubRouting(chi.NewRouter())
+	}, "Router mounts without panic")
+}
+
+// Test_keystoreProvider tests if the keystore is injected
+func Test_keystoreProvider(t *testing.T) {
+	mockRequest := httptest.NewRequest("GET", "/", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	keystoreProvider(getContextAssertionMiddleware(func(ctx context.Context) {
+		val := ctx.Value("keystore")
+		assert.NotNil(t, val, "keystore must be injected")
+		assert.Implements(t, (*keystore.Keystore)(nil), val, "Implements keystore interface")
+	})).ServeHTTP(responseRecorder, mockRequest)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "A 200 OK is returned")
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package routes contains all the routes for the Gateway API
+package routes
+
+import (
+	"chainsource-gateway/controller/asset"
+	"chainsource-gateway/offers"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/opentracing/opentracing-go"
+)
+
+// transferOfferSubRouting defines the sub routes for deciding on transfer offers
+func transferOfferSubRouting(r chi.Router) {
+	r.Use(injectSpanMiddleware)
+	r.Use(agentProvider)
+	r.Use(signingServiceProvider)
+	r.Use(signerRegistryProvider)
+	r.Use(offerStoreProvider)
+	r.Use(unmarshalBody)
+
+	r.Get("/{offerID}", asset.GetTransferOffer)
+	r.Post("/{offerID}/accept", asset.AcceptTransferOffer)
+	r.Post("/{offerID}/reject", asset.RejectTransferOffer)
+}
+
+// offerStoreProvider injects the store of custody transfer offers into the request context
+func offerStoreProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span, ctx := opentracing.StartSpanFromContext(r.Context(), "Embedding Offer Store")
+		offerStore := offers.NewStoreOffers()
+		ctx = context.WithValue(r.Context(), "offerStore", offerStore)
+		span.Finish()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
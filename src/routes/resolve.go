@@ -0,0 +1,50 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package routes contains all the routes for the Gateway API
+package routes
+
+import (
+	"chainsource-gateway/aliases"
+	"chainsource-gateway/controller/asset"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/opentracing/opentracing-go"
+)
+
+// resolveSubRouting defines the sub routes for resolving external identifiers of assets to their locations
+func resolveSubRouting(r chi.Router) {
+	r.Use(injectSpanMiddleware)
+	r.Use(agentProvider)
+	r.Use(aliasIndexProvider)
+
+	r.Get("/", asset.Resolve)
+	r.Post("/_rebuild", asset.RebuildAliases)
+}
+
+// aliasIndexProvider injects the alias index into the request context
+func aliasIndexProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span, ctx := opentracing.StartSpanFromContext(r.Context(), "Embedding Alias Index")
+		aliasIndex := aliases.NewStoreIndex()
+		ctx = context.WithValue(r.Context(), "aliasIndex", aliasIndex)
+		span.Finish()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
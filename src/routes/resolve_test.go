@@ -0,0 +1,49 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package routes
+
+import (
+	"chainsource-gateway/aliases"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_resolveSubRouting tests if the resolve sub router mounts successfully
+func Test_resolveSubRouting(t *testing.T) {
+	assert.NotPanics(t, func() {
+		resolveSubRouting(chi.NewRouter())
+	}, "Router mounts without panic")
+}
+
+// Test_aliasIndexProvider tests if the alias index is injected
+func Test_aliasIndexProvider(t *testing.T) {
+	mockRequest := httptest.NewRequest("GET", "/", strings.NewReader(""))
+	responseRecorder := httptest.NewRecorder()
+	aliasIndexProvider(getContextAssertionMiddleware(func(ctx context.Context) {
+		val := ctx.Value("aliasIndex")
+		assert.NotNil(t, val, "aliasIndex must be injected")
+		assert.Implements(t, (*aliases.AliasIndex)(nil), val, "Implements alias index interface")
+	})).ServeHTTP(responseRecorder, mockRequest)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code, "A 200 OK is returned")
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package routes contains all the routes for the Gateway API
+package routes
+
+import (
+	"chainsource-gateway/controller/templates"
+	"chainsource-gateway/templateregistry"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/opentracing/opentracing-go"
+)
+
+// templateSubRouting defines the sub routes for the BOM template registry APIs
+func templateSubRouting(r chi.Router) {
+	r.Use(injectSpanMiddleware)
+	r.Use(templateRegistryProvider)
+	r.Use(unmarshalBody)
+
+	r.Post("/", templates.CreateTemplate)
+	r.Get("/", templates.ListTemplates)
+	r.Get("/{templateID}", templates.GetTemplate)
+	r.Put("/{templateID}", templates.ReplaceTemplate)
+	r.Delete("/{templateID}", templates.DeleteTemplate)
+}
+
+// templateRegistryProvider injects the registry of BOM templates into the request context
+func templateRegistryProvider(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span, ctx := opentracing.StartSpanFromContext(r.Context(), "Embedding Template Registry")
+		registry := templateregistry.NewStoreRegistry()
+		ctx = context.WithValue(r.Context(), "templateRegistry", registry)
+		span.Finish()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
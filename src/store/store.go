@@ -0,0 +1,205 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package store persists the state the gateway keeps itself, outside of the agents
+package store
+
+import (
+	"chainsource-gateway/helpers"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var log = helpers.GetLogger("Store")
+
+const dataDirectoryVar = "GATEWAY_DATA_DIR"
+const defaultDataDirectory = "./data"
+
+// ErrAlreadyExists is an error when a record is created with an ID that is already taken
+var ErrAlreadyExists = errors.New("record already exists")
+
+// locks serializes access to each collection file within the process
+var locks sync.Map
+
+// GetDataDirectory gets the directory the gateway keeps its own state in
+func GetDataDirectory() (directory string) {
+	if helpers.ExistsInEnv(dataDirectoryVar) {
+		directory = os.Getenv(dataDirectoryVar)
+	} else {
+		directory = defaultDataDirectory
+	}
+	return
+}
+
+// Collection is a named set of JSON records, persisted as a single file in the data directory
+type Collection struct {
+	path string
+}
+
+// NewCollection returns the collection with a name in the data directory
+func NewCollection(name string) *Collection {
+	return NewCollectionAt(GetDataDirectory(), name)
+}
+
+// NewCollectionAt returns the collection with a name in a directory
+func NewCollectionAt(directory string, name string) *Collection {
+	return &Collection{path: filepath.Join(directory, name+".json")}
+}
+
+// lock returns the mutex guarding the collection file
+func (c *Collection) lock() *sync.Mutex {
+	mutex, _ := locks.LoadOrStore(c.path, &sync.Mutex{})
+	return mutex.(*sync.Mutex)
+}
+
+// load reads all records of the collection. A missing file is an empty collection
+func (c *Collection) load() (records map[string]json.RawMessage, err error) {
+	records = make(map[string]json.RawMessage)
+	raw, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return
+	}
+	err = json.Unmarshal(raw, &records)
+	return
+}
+
+// save replaces the collection file, writing to a temporary file first so readers never see a partial write
+func (c *Collection) save(records map[string]json.RawMessage) error {
+	raw, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = temp.Write(raw); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return os.Rename(temp.Name(), c.path)
+}
+
+// Modify runs fn over all records of the collection and saves them when fn succeeds.
+// No other Modify or read of the collection runs concurrently within the process
+func (c *Collection) Modify(fn func(records map[string]json.RawMessage) error) error {
+	mutex := c.lock()
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	records, err := c.load()
+	if err != nil {
+		log.Err(err).Msgf("Failed to load %s", c.path)
+		return err
+	}
+	if err = fn(records); err != nil {
+		return err
+	}
+	return c.save(records)
+}
+
+// Get unmarshals the record with an ID into v, returns helpers.ErrNotFound when there is none
+func (c *Collection) Get(id string, v interface{}) error {
+	all, err := c.All()
+	if err != nil {
+		return err
+	}
+	raw, exists := all[id]
+	if !exists {
+		return helpers.ErrNotFound
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Create stores a new record, returns ErrAlreadyExists when the ID is taken
+func (c *Collection) Create(id string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Modify(func(records map[string]json.RawMessage) error {
+		if _, exists := records[id]; exists {
+			return ErrAlreadyExists
+		}
+		records[id] = raw
+		return nil
+	})
+}
+
+// Put stores a record, replacing any record with the same ID
+func (c *Collection) Put(id string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Modify(func(records map[string]json.RawMessage) error {
+		records[id] = raw
+		return nil
+	})
+}
+
+// Delete removes a record, returns helpers.ErrNotFound when there is none
+func (c *Collection) Delete(id string) error {
+	return c.Modify(func(records map[string]json.RawMessage) error {
+		if _, exists := records[id]; !exists {
+			return helpers.ErrNotFound
+		}
+		delete(records, id)
+		return nil
+	})
+}
+
+// All returns every record of the collection by ID
+func (c *Collection) All() (map[string]json.RawMessage, error) {
+	mutex := c.lock()
+	mutex.Lock()
+	defer mutex.Unlock()
+	return c.load()
+}
+
+// IDs returns the sorted IDs of the records of the collection
+func (c *Collection) IDs() ([]string, error) {
+	all, err := c.All()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(all))
+	for id := range all {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
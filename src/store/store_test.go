@@ -0,0 +1,80 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package store
+
+import (
+	"chainsource-gateway/helpers"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	Name string `json:"name"`
+}
+
+// TestGetDataDirectory tests the data directory getter
+func TestGetDataDirectory(t *testing.T) {
+	os.Unsetenv(dataDirectoryVar)
+	assert.Equal(t, defaultDataDirectory, GetDataDirectory(), "Default is used when unset")
+	os.Setenv(dataDirectoryVar, "/tmp/gateway")
+	defer os.Unsetenv(dataDirectoryVar)
+	assert.Equal(t, "/tmp/gateway", GetDataDirectory(), "Environment is used when set")
+}
+
+// TestCollection tests storing, reading and removing records
+func TestCollection(t *testing.T) {
+	directory, err := ioutil.TempDir("", "store")
+	assert.NoError(t, err, "Data directory is created")
+	defer os.RemoveAll(directory)
+	collection := NewCollectionAt(directory, "records")
+
+	ids, err := collection.IDs()
+	assert.NoError(t, err, "Missing file is an empty collection")
+	assert.Empty(t, ids, "Collection starts empty")
+
+	assert.NoError(t, collection.Create("b", record{Name: "B"}), "Record is created")
+	assert.NoError(t, collection.Create("a", record{Name: "A"}), "Record is created")
+	assert.Equal(t, ErrAlreadyExists, collection.Create("a", record{}), "IDs are unique")
+
+	var result record
+	assert.NoError(t, NewCollectionAt(directory, "records").Get("a", &result), "Record is persisted")
+	assert.Equal(t, "A", result.Name, "Record is read back")
+	assert.Equal(t, helpers.ErrNotFound, collection.Get("c", &result), "Missing record is not found")
+
+	assert.NoError(t, collection.Put("a", record{Name: "A2"}), "Record is replaced")
+	_ = collection.Get("a", &result)
+	assert.Equal(t, "A2", result.Name, "Replaced record is read back")
+
+	ids, _ = collection.IDs()
+	assert.Equal(t, []string{"a", "b"}, ids, "IDs are sorted")
+
+	failure := errors.New("abort")
+	assert.Equal(t, failure, collection.Modify(func(records map[string]json.RawMessage) error {
+		delete(records, "a")
+		return failure
+	}), "Modify error is returned")
+	assert.NoError(t, collection.Get("a", &result), "Failed modification is not saved")
+
+	assert.NoError(t, collection.Delete("a"), "Record is deleted")
+	assert.Equal(t, helpers.ErrNotFound, collection.Delete("a"), "Missing record is not deleted")
+}
@@ -0,0 +1,369 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package templateregistry contains the registry of BOM templates, the children an assembly of a model is designed with
+package templateregistry
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/spf13/viper"
+)
+
+var log = helpers.GetLogger("TemplateRegistry")
+
+const collectionName = "bom-templates"
+
+const attachPolicyConfigKey = "bomTemplateAttach"
+
+// PolicyOff attaches children without looking at the template of the parent
+const PolicyOff = "off"
+
+// PolicyWarn logs attaches that do not fit the template of the parent, but always commits them
+const PolicyWarn = "warn"
+
+// PolicyEnforce rejects attaches that do not fit the template of the parent
+const PolicyEnforce = "enforce"
+
+// ErrTemplateExists is an error when a template ID or a model is registered twice
+var ErrTemplateExists = errors.New("a template with this ID or for this model already exists")
+
+const (
+	// DeviationMissing is a slot with fewer children than its minCount
+	DeviationMissing = "missing"
+	// DeviationExcess is a slot with more children than its maxCount
+	DeviationExcess = "excess"
+	// DeviationUnexpectedRole is a child in a role the template has no slot for
+	DeviationUnexpectedRole = "unexpectedRole"
+	// DeviationUnexpectedType is a child with an assetType its slot does not accept
+	DeviationUnexpectedType = "unexpectedType"
+)
+
+// Slot is a type representing a child role of a template. A slot without a subRole takes children of any subRole
+type Slot struct {
+	Role       string   `json:"role"`
+	SubRole    string   `json:"subRole,omitempty"`
+	AssetTypes []string `json:"assetTypes,omitempty"`
+	MinCount   int      `json:"minCount"`
+	MaxCount   int      `json:"maxCount,omitempty"`
+}
+
+// BomTemplate is a type representing the as-designed children of an assetType and assetModelNumber
+type BomTemplate struct {
+	TemplateID       string    `json:"templateID"`
+	AssetType        string    `json:"assetType"`
+	AssetModelNumber string    `json:"assetModelNumber"`
+	Description      string    `json:"description,omitempty"`
+	Children         []Slot    `json:"children"`
+	AllowOtherRoles  bool      `json:"allowOtherRoles"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Child is a type representing an attached child as a template sees it
+type Child struct {
+	Link      helpers.AssetLinkElement
+	AssetType string
+}
+
+// Validate checks the fields of a template
+func (t BomTemplate) Validate() error {
+	if strings.TrimSpace(t.TemplateID) == "" {
+		return errors.New("templateID is required")
+	}
+	if strings.TrimSpace(t.AssetType) == "" || strings.TrimSpace(t.AssetModelNumber) == "" {
+		return errors.New("assetType and assetModelNumber are required")
+	}
+	for _, slot := range t.Children {
+		if strings.TrimSpace(slot.Role) == "" {
+			return errors.New("every child slot needs a role")
+		}
+		if slot.MinCount < 0 || slot.MaxCount < 0 {
+			return fmt.Errorf("counts of slot %s must not be negative", slot.Role)
+		}
+		if slot.MaxCount > 0 && slot.MaxCount < slot.MinCount {
+			return fmt.Errorf("maxCount of slot %s is below its minCount", slot.Role)
+		}
+	}
+	return nil
+}
+
+// accepts reports whether the slot takes a child of an assetType
+func (s Slot) accepts(assetType string) bool {
+	if len(s.AssetTypes) == 0 {
+		return true
+	}
+	for _, accepted := range s.AssetTypes {
+		if accepted == assetType {
+			return true
+		}
+	}
+	return false
+}
+
+// slotIndex returns the slot a child in a role goes in, -1 when there is none.
+// A slot for the exact subRole takes precedence over a slot for any subRole
+func (t BomTemplate) slotIndex(role string, subRole string) int {
+	index := -1
+	for i, slot := range t.Children {
+		if slot.Role != role {
+			continue
+		}
+		if slot.SubRole == subRole {
+			return i
+		}
+		if slot.SubRole == "" && index < 0 {
+			index = i
+		}
+	}
+	return index
+}
+
+// describeTypes lists the assetTypes a slot accepts
+func describeTypes(slot Slot) string {
+	return strings.Join(slot.AssetTypes, ", ")
+}
+
+// Deviations checks the children of an asset against the template. Paths of the deviations start at path
+func (t BomTemplate) Deviations(path string, children []Child) []helpers.TemplateDeviation {
+	deviations := make([]helpers.TemplateDeviation, 0)
+	counts := make([]int, len(t.Children))
+	for _, child := range children {
+		element := child.Link.AssetElement
+		deviation := helpers.TemplateDeviation{
+			Path:    path + "/" + child.Link.Role,
+			Role:    child.Link.Role,
+			SubRole: child.Link.SubRole,
+			Asset:   &element,
+		}
+		i := t.slotIndex(child.Link.Role, child.Link.SubRole)
+		if i < 0 {
+			if !t.AllowOtherRoles {
+				deviation.Kind = DeviationUnexpectedRole
+				deviation.Detail = fmt.Sprintf("role %s is not in template %s", child.Link.Role, t.TemplateID)
+				deviations = append(deviations, deviation)
+			}
+			continue
+		}
+		counts[i]++
+		if !t.Children[i].accepts(child.AssetType) {
+			deviation.Kind = DeviationUnexpectedType
+			deviation.Detail = fmt.Sprintf("assetType %s, expected %s", child.AssetType, describeTypes(t.Children[i]))
+			deviations = append(deviations, deviation)
+		}
+	}
+	for i, slot := range t.Children {
+		deviation := helpers.TemplateDeviation{Path: path + "/" + slot.Role, Role: slot.Role, SubRole: slot.SubRole}
+		if counts[i] < slot.MinCount {
+			deviation.Kind = DeviationMissing
+			deviation.Detail = fmt.Sprintf("%d of at least %d", counts[i], slot.MinCount)
+			deviations = append(deviations, deviation)
+		} else if slot.MaxCount > 0 && counts[i] > slot.MaxCount {
+			deviation.Kind = DeviationExcess
+			deviation.Detail = fmt.Sprintf("%d of at most %d", counts[i], slot.MaxCount)
+			deviations = append(deviations, deviation)
+		}
+	}
+	return deviations
+}
+
+// CheckAttach checks a child about to be attached next to the existing children. Slots that are not filled yet
+// are not a reason to refuse, an assembly is built one child at a time
+func (t BomTemplate) CheckAttach(existing []helpers.AssetLinkElement, child Child) error {
+	i := t.slotIndex(child.Link.Role, child.Link.SubRole)
+	if i < 0 {
+		if t.AllowOtherRoles {
+			return nil
+		}
+		return fmt.Errorf("role %s is not in template %s", child.Link.Role, t.TemplateID)
+	}
+	slot := t.Children[i]
+	if !slot.accepts(child.AssetType) {
+		return fmt.Errorf("role %s of template %s takes %s, not %s", slot.Role, t.TemplateID, describeTypes(slot), child.AssetType)
+	}
+	if slot.MaxCount == 0 {
+		return nil
+	}
+	count := 0
+	for _, link := range existing {
+		if t.slotIndex(link.Role, link.SubRole) == i {
+			count++
+		}
+	}
+	if count >= slot.MaxCount {
+		return fmt.Errorf("role %s of template %s is full with %d children", slot.Role, t.TemplateID, count)
+	}
+	return nil
+}
+
+// GetAttachPolicy gets how attaches are checked against templates, from bomTemplateAttach in agent-config.yaml.
+// Attaches are not checked when it is not set, and unrecognized modes fail closed to enforce
+func GetAttachPolicy() string {
+	switch mode := strings.ToLower(strings.TrimSpace(viper.GetString(attachPolicyConfigKey))); mode {
+	case "", PolicyOff, "false":
+		return PolicyOff
+	case PolicyWarn:
+		return PolicyWarn
+	case PolicyEnforce:
+		return PolicyEnforce
+	default:
+		log.Error().Msgf("Invalid %s %q, using %s", attachPolicyConfigKey, mode, PolicyEnforce)
+		return PolicyEnforce
+	}
+}
+
+// TemplateRegistry is an interface for the registry of BOM templates
+type TemplateRegistry interface {
+	Create(ctx context.Context, template BomTemplate) (BomTemplate, error)
+	Get(ctx context.Context, templateID string) (BomTemplate, error)
+	List(ctx context.Context) ([]BomTemplate, error)
+	Replace(ctx context.Context, template BomTemplate) (BomTemplate, error)
+	Delete(ctx context.Context, templateID string) error
+	ForModel(ctx context.Context, assetType string, assetModelNumber string) (BomTemplate, error)
+}
+
+// StoreRegistry is an implementation of TemplateRegistry persisted in a store collection
+type StoreRegistry struct {
+	collection *store.Collection
+}
+
+// NewStoreRegistry returns a template registry kept in the gateway data directory
+func NewStoreRegistry() *StoreRegistry {
+	return NewStoreRegistryWith(store.NewCollection(collectionName))
+}
+
+// NewStoreRegistryWith returns a template registry kept in a store collection
+func NewStoreRegistryWith(collection *store.Collection) *StoreRegistry {
+	return &StoreRegistry{collection: collection}
+}
+
+// put stores a template, the ID must exist exactly when it is replaced and no other template may be for the same model
+func (s *StoreRegistry) put(template BomTemplate, replace bool) (BomTemplate, error) {
+	template.UpdatedAt = time.Now().UTC()
+	raw, err := json.Marshal(template)
+	if err != nil {
+		return BomTemplate{}, err
+	}
+	err = s.collection.Modify(func(records map[string]json.RawMessage) error {
+		if _, exists := records[template.TemplateID]; exists != replace {
+			if replace {
+				return helpers.ErrNotFound
+			}
+			return ErrTemplateExists
+		}
+		for id, record := range records {
+			var other BomTemplate
+			if id == template.TemplateID || json.Unmarshal(record, &other) != nil {
+				continue
+			}
+			if other.AssetType == template.AssetType && other.AssetModelNumber == template.AssetModelNumber {
+				return ErrTemplateExists
+			}
+		}
+		records[template.TemplateID] = raw
+		return nil
+	})
+	if err != nil {
+		return BomTemplate{}, err
+	}
+	return template, nil
+}
+
+// Create adds a template to the registry
+func (s *StoreRegistry) Create(ctx context.Context, template BomTemplate) (BomTemplate, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Create BOM template")
+	defer span.Finish()
+
+	created, err := s.put(template, false)
+	if err == nil {
+		log.Info().Msgf("Created template %s for %s %s", template.TemplateID, template.AssetType, template.AssetModelNumber)
+	}
+	return created, err
+}
+
+// Replace replaces a template, returns helpers.ErrNotFound when there is none with the ID
+func (s *StoreRegistry) Replace(ctx context.Context, template BomTemplate) (BomTemplate, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Replace BOM template")
+	defer span.Finish()
+
+	return s.put(template, true)
+}
+
+// Get returns a template, returns helpers.ErrNotFound when there is none with the ID
+func (s *StoreRegistry) Get(ctx context.Context, templateID string) (template BomTemplate, err error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Get BOM template")
+	defer span.Finish()
+
+	err = s.collection.Get(templateID, &template)
+	return
+}
+
+// List returns all templates ordered by ID
+func (s *StoreRegistry) List(ctx context.Context) ([]BomTemplate, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "List BOM templates")
+	defer span.Finish()
+
+	ids, err := s.collection.IDs()
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.collection.All()
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]BomTemplate, 0, len(ids))
+	for _, id := range ids {
+		var template BomTemplate
+		if raw, exists := all[id]; exists && json.Unmarshal(raw, &template) == nil {
+			templates = append(templates, template)
+		}
+	}
+	return templates, nil
+}
+
+// Delete removes a template, returns helpers.ErrNotFound when there is none with the ID
+func (s *StoreRegistry) Delete(ctx context.Context, templateID string) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Delete BOM template")
+	defer span.Finish()
+
+	return s.collection.Delete(templateID)
+}
+
+// ForModel returns the template of an assetType and assetModelNumber, returns helpers.ErrNotFound when there is none
+func (s *StoreRegistry) ForModel(ctx context.Context, assetType string, assetModelNumber string) (BomTemplate, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Find BOM template")
+	defer span.Finish()
+
+	all, err := s.collection.All()
+	if err != nil {
+		return BomTemplate{}, err
+	}
+	for _, raw := range all {
+		var template BomTemplate
+		if json.Unmarshal(raw, &template) == nil && template.AssetType == assetType && template.AssetModelNumber == assetModelNumber {
+			return template, nil
+		}
+	}
+	return BomTemplate{}, helpers.ErrNotFound
+}
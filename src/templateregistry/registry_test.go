@@ -0,0 +1,149 @@
+/*
+ * Copyright 2020 Unisys Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package templateregistry
+
+import (
+	"chainsource-gateway/helpers"
+	"chainsource-gateway/store"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// serverTemplate is a server with one or two processors, any amount of memory and no other roles
+var serverTemplate = BomTemplate{
+	TemplateID:       "server",
+	AssetType:        "Server",
+	AssetModelNumber: "SRV-1",
+	Children: []Slot{
+		{Role: "cpu", AssetTypes: []string{"Processor"}, MinCount: 1, MaxCount: 2},
+		{Role: "memory", AssetTypes: []string{"Memory"}, MinCount: 1},
+	},
+}
+
+// link returns the link of a child in a role
+func link(assetID string, role string) helpers.AssetLinkElement {
+	return helpers.AssetLinkElement{AssetElement: helpers.AssetElement{RepoID: "T1", ChannelID: "C1", AssetID: assetID}, Role: role}
+}
+
+// TestValidate tests the checks on the fields of a template
+func TestValidate(t *testing.T) {
+	assert.NoError(t, serverTemplate.Validate(), "Template is valid")
+	assert.Error(t, BomTemplate{AssetType: "Server", AssetModelNumber: "SRV-1"}.Validate(), "ID is required")
+	assert.Error(t, BomTemplate{TemplateID: "server", AssetType: "Server"}.Validate(), "Model is required")
+	assert.Error(t, BomTemplate{TemplateID: "server", AssetType: "Server", AssetModelNumber: "SRV-1",
+		Children: []Slot{{Role: "cpu", MinCount: 2, MaxCount: 1}}}.Validate(), "Counts must be in order")
+}
+
+// TestDeviations tests checking the children of an asset against a template
+func TestDeviations(t *testing.T) {
+	assert.Empty(t, serverTemplate.Deviations("", []Child{
+		{Link: link("A2", "cpu"), AssetType: "Processor"},
+		{Link: link("A3", "memory"), AssetType: "Memory"},
+	}), "Children fit the template")
+
+	deviations := serverTemplate.Deviations("/board", []Child{
+		{Link: link("A2", "cpu"), AssetType: "Processor"},
+		{Link: link("A3", "cpu"), AssetType: "Processor"},
+		{Link: link("A4", "cpu"), AssetType: "Memory"},
+		{Link: link("A5", "light"), AssetType: "Light"},
+	})
+	kinds := make(map[string]helpers.TemplateDeviation)
+	for _, deviation := range deviations {
+		kinds[deviation.Kind] = deviation
+	}
+	assert.Len(t, deviations, 4, "Every deviation is reported")
+	assert.Equal(t, "A4", kinds[DeviationUnexpectedType].Asset.AssetID, "Child of the wrong type is reported")
+	assert.Equal(t, "/board/light", kinds[DeviationUnexpectedRole].Path, "Child in an unknown role is reported")
+	assert.Equal(t, "3 of at most 2", kinds[DeviationExcess].Detail, "Full slot is reported")
+	assert.Equal(t, "/board/memory", kinds[DeviationMissing].Path, "Empty slot is reported")
+}
+
+// TestCheckAttach tests checking a child about to be attached against a template
+func TestCheckAttach(t *testing.T) {
+	one := []helpers.AssetLinkElement{link("A2", "cpu")}
+	two := []helpers.AssetLinkElement{link("A2", "cpu"), link("A3", "cpu")}
+	assert.NoError(t, serverTemplate.CheckAttach(nil, Child{Link: link("A4", "cpu"), AssetType: "Processor"}),
+		"Missing siblings do not block")
+	assert.NoError(t, serverTemplate.CheckAttach(one, Child{Link: link("A4", "cpu"), AssetType: "Processor"}),
+		"Slot has room")
+	assert.Error(t, serverTemplate.CheckAttach(two, Child{Link: link("A4", "cpu"), AssetType: "Processor"}),
+		"Full slot blocks")
+	assert.Error(t, serverTemplate.CheckAttach(nil, Child{Link: link("A4", "cpu"), AssetType: "Memory"}),
+		"Wrong type blocks")
+	assert.Error(t, serverTemplate.CheckAttach(nil, Child{Link: link("A4", "light"), AssetType: "Light"}),
+		"Unknown role blocks")
+	open := serverTemplate
+	open.AllowOtherRoles = true
+	assert.NoError(t, open.CheckAttach(nil, Child{Link: link("A4", "light"), AssetType: "Light"}),
+		"Other roles are allowed when the template says so")
+}
+
+// TestGetAttachPolicy tests the attach policy configuration
+func TestGetAttachPolicy(t *testing.T) {
+	defer viper.Set(attachPolicyConfigKey, nil)
+	assert.Equal(t, PolicyOff, GetAttachPolicy(), "Attaches are not checked by default")
+	viper.Set(attachPolicyConfigKey, "Warn")
+	assert.Equal(t, PolicyWarn, GetAttachPolicy(), "Configured policy is used")
+	viper.Set(attachPolicyConfigKey, "strict")
+	assert.Equal(t, PolicyEnforce, GetAttachPolicy(), "Unknown policies fail closed")
+}
+
+// TestStoreRegistry tests creating, finding, replacing and deleting templates
+func TestStoreRegistry(t *testing.T) {
+	directory, err := ioutil.TempDir("", "templateregistry")
+	assert.NoError(t, err, "Data directory is created")
+	defer os.RemoveAll(directory)
+	registry := NewStoreRegistryWith(store.NewCollectionAt(directory, collectionName))
+	ctx := context.Background()
+
+	created, err := registry.Create(ctx, serverTemplate)
+	assert.NoError(t, err, "Template is created")
+	assert.False(t, created.UpdatedAt.IsZero(), "Update time is set")
+	_, err = registry.Create(ctx, serverTemplate)
+	assert.Equal(t, ErrTemplateExists, err, "IDs are taken once")
+	sameModel := serverTemplate
+	sameModel.TemplateID = "server-2"
+	_, err = registry.Create(ctx, sameModel)
+	assert.Equal(t, ErrTemplateExists, err, "A model has one template")
+
+	found, err := registry.ForModel(ctx, "Server", "SRV-1")
+	assert.NoError(t, err, "Template is found by model")
+	assert.Equal(t, "server", found.TemplateID, "Template of the model is returned")
+	_, err = registry.ForModel(ctx, "Server", "SRV-2")
+	assert.Equal(t, helpers.ErrNotFound, err, "Models without a template are not found")
+
+	replacement := serverTemplate
+	replacement.Description = "Rev B"
+	_, err = registry.Replace(ctx, replacement)
+	assert.NoError(t, err, "Template is replaced")
+	found, _ = registry.Get(ctx, "server")
+	assert.Equal(t, "Rev B", found.Description, "Replacement is stored")
+	_, err = registry.Replace(ctx, sameModel)
+	assert.Equal(t, helpers.ErrNotFound, err, "Unknown templates are not replaced")
+
+	list, err := registry.List(ctx)
+	assert.NoError(t, err, "Templates are listed")
+	assert.Len(t, list, 1, "Every template is listed")
+	assert.NoError(t, registry.Delete(ctx, "server"), "Template is deleted")
+	assert.Equal(t, helpers.ErrNotFound, registry.Delete(ctx, "server"), "Deleted templates are gone")
+}